@@ -39,8 +39,8 @@ var helpText = func(colors logger.Colors) string {
                         is browser and cjs when platform is node)
   --loader:X=L          Use loader L to load file extension X, where L is
                         one of: base64 | binary | copy | css | dataurl |
-                        empty | file | global-css | js | json | jsx |
-                        local-css | text | ts | tsx
+                        empty | file | global-css | html | js | json |
+                        jsx | local-css | text | ts | tsx | wasm
   --minify              Minify the output (sets all --minify-* flags)
   --outdir=...          The output directory (for multiple entry points)
   --outfile=...         The output file (for one entry point)
@@ -49,7 +49,7 @@ var helpText = func(colors logger.Colors) string {
                         default browser)
   --serve=...           Start a local HTTP server on this host:port for outputs
   --sourcemap           Emit a source map
-  --splitting           Enable code splitting (currently only for esm)
+  --splitting           Enable code splitting
   --target=...          Environment target (e.g. es2017, chrome58, firefox57,
                         safari11, edge16, node10, ie9, opera45, default esnext)
   --watch               Watch mode: rebuild on file system changes (stops when
@@ -63,11 +63,17 @@ var helpText = func(colors logger.Colors) string {
                             (default "[name]-[hash]")
   --banner:T=...            Text to be prepended to each output file of type T
                             where T is one of: css | js
+  --budget:P=...            Fail if output files matching P are too big (e.g.
+                            "--budget:*.js=100kb,gzip=30kb", add ",warn" to
+                            only warn instead)
+  --cache-dir=...           Reuse parse results stored in this directory across
+                            separate runs (currently only for JSON files)
   --certfile=...            Certificate for serving HTTPS (see also "--keyfile")
   --charset=utf8            Do not escape UTF-8 code points
   --chunk-names=...         Path template to use for code splitting chunks
                             (default "[name]-[hash]")
   --color=...               Force use of color terminal escapes (true | false)
+  --debug-ids               Add a "debugId" to each output file and source map
   --drop:...                Remove certain constructs (console | debugger)
   --drop-labels=...         Remove labeled statements with these label names
   --entry-names=...         Path template to use for entry point output paths
@@ -75,6 +81,8 @@ var helpText = func(colors logger.Colors) string {
   --footer:T=...            Text to be appended to each output file of type T
                             where T is one of: css | js
   --global-name=...         The name of the global for the IIFE format
+  --hmr                     Enable hot module replacement via "import.meta.hot"
+                            (requires --bundle, use with --serve)
   --ignore-annotations      Enable this to work with packages that have
                             incorrect tree-shaking annotations
   --inject:F                Import the file F into all input files and
@@ -100,11 +108,14 @@ var helpText = func(colors logger.Colors) string {
   --main-fields=...         Override the main file order in package.json
                             (default "browser,module,main" when platform is
                             browser and "main,module" when platform is node)
+  --manual-chunk:N=...      Put these comma-separated package names or paths
+                            into a chunk named N (requires --splitting)
   --mangle-cache=...        Save "mangle props" decisions to a JSON file
   --mangle-props=...        Rename all properties matching a regular expression
   --mangle-quoted=...       Enable renaming of quoted properties (true | false)
   --metafile=...            Write metadata about the build to a JSON file
                             (see also: ` + colors.Underline + `https://esbuild.github.io/analyze/` + colors.Reset + `)
+  --metafile-included-by    Record why each input is included in the metafile
   --minify-whitespace       Remove whitespace in output files
   --minify-identifiers      Shorten identifiers in output files
   --minify-syntax           Use equivalent but shorter syntax in output files
@@ -117,16 +128,38 @@ var helpText = func(colors logger.Colors) string {
   --reserve-props=...       Do not mangle these properties
   --resolve-extensions=...  A comma-separated list of implicit extensions
                             (default ".tsx,.ts,.jsx,.js,.css,.json")
+  --serve-cache-control:P=V
+                            Send the Cache-Control header V for paths that
+                            match the glob pattern P when using --serve
+  --serve-compress          Compress responses from --serve with brotli or gzip
+  --serve-error-overlay     Show build errors on top of HTML pages from --serve
   --serve-fallback=...      Serve this HTML page when the request doesn't match
+  --serve-proxy:P=U         Forward requests under the path P to the server at
+                            the URL U (e.g. --serve-proxy:/api=http://[::1]:3000)
   --servedir=...            What to serve in addition to generated output files
   --source-root=...         Sets the "sourceRoot" field in generated source maps
   --sourcefile=...          Set the source file for the source map (for stdin)
   --sourcemap=external      Do not link to the source map with a comment
   --sourcemap=inline        Emit the source map with an inline data URL
+  --sourcemap-ignore-list=... Comma-separated glob patterns for sources that
+                            debuggers should skip (default "**/node_modules/**")
+  --sourcemap-sections      Emit index source maps with one section per file,
+                            which is faster to generate for large bundles
   --sources-content=false   Omit "sourcesContent" in generated source maps
   --supported:F=...         Consider syntax F to be supported (true | false)
   --tree-shaking=...        Force tree shaking on or off (false | true)
   --tsconfig=...            Use this tsconfig.json file instead of other ones
+  --wasm-bytes=...          How the "wasm" loader includes module bytes
+                            (inline | file, default inline)
+  --watch-backend=...       How to detect changes in watch mode (polling |
+                            native, default polling)
+  --watch-debounce=...      Wait until files stop changing for this many
+                            milliseconds before rebuilding (default 0)
+  --watch-ignore:P          Do not watch files matching the glob pattern P
+  --watch-poll-interval=... Milliseconds between polls in watch mode
+                            (default 100)
+  --why=...                 Print why inputs whose path contains this text are
+                            included in each output file
   --version                 Print the current version (` + esbuildVersion + `) and exit
 
 ` + colors.Bold + `Examples:` + colors.Reset + `
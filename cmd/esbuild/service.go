@@ -242,6 +242,13 @@ func (service *serviceType) handleIncomingPacket(bytes []byte) {
 			service.sendPacket(service.handleTransformRequest(p.id, request))
 		}()
 
+	case "transform-many":
+		service.keepAliveWaitGroup.Add(1)
+		go func() {
+			defer service.keepAliveWaitGroup.Done()
+			service.sendPacket(service.handleTransformManyRequest(p.id, request))
+		}()
+
 	case "resolve":
 		key := request["key"].(int)
 		if build := service.getActiveBuild(key); build != nil {
@@ -334,7 +341,27 @@ func (service *serviceType) handleIncomingPacket(bytes []byte) {
 				go func() {
 					defer service.keepAliveWaitGroup.Done()
 					defer build.disposeWaitGroup.Done()
-					if err := ctx.Watch(api.WatchOptions{}); err != nil {
+					var options api.WatchOptions
+					if value, ok := request["backend"]; ok {
+						switch value.(string) {
+						case "polling":
+							options.Backend = api.WatchBackendPolling
+						case "native":
+							options.Backend = api.WatchBackendNative
+						}
+					}
+					if value, ok := request["pollInterval"]; ok {
+						options.PollInterval = value.(int)
+					}
+					if value, ok := request["debounce"]; ok {
+						options.Debounce = value.(int)
+					}
+					if value, ok := request["ignore"]; ok {
+						for _, pattern := range value.([]interface{}) {
+							options.Ignore = append(options.Ignore, pattern.(string))
+						}
+					}
+					if err := ctx.Watch(options); err != nil {
 						service.sendPacket(encodeErrorPacket(p.id, err))
 					} else {
 						service.sendPacket(encodePacket(packet{
@@ -386,6 +413,40 @@ func (service *serviceType) handleIncomingPacket(bytes []byte) {
 					if value, ok := request["fallback"]; ok {
 						options.Fallback = value.(string)
 					}
+					if value, ok := request["errorOverlay"]; ok {
+						options.ErrorOverlay = value.(bool)
+					}
+					if value, ok := request["compress"]; ok {
+						options.Compress = value.(bool)
+					}
+					if value, ok := request["cacheControl"]; ok {
+						for _, item := range value.([]interface{}) {
+							rule := item.(map[string]interface{})
+							options.CacheControl = append(options.CacheControl, api.ServeCacheControl{
+								Path:  rule["path"].(string),
+								Value: rule["value"].(string),
+							})
+						}
+					}
+					if value, ok := request["proxy"]; ok {
+						for _, item := range value.([]interface{}) {
+							rule := item.(map[string]interface{})
+							proxy := api.ServeProxy{
+								Path:   rule["path"].(string),
+								Target: rule["target"].(string),
+							}
+							if value, ok := rule["rewrite"]; ok {
+								proxy.Rewrite = value.(string)
+							}
+							if value, ok := rule["headers"]; ok {
+								proxy.Headers = make(map[string]string)
+								for k, v := range value.(map[string]interface{}) {
+									proxy.Headers[k] = v.(string)
+								}
+							}
+							options.Proxy = append(options.Proxy, proxy)
+						}
+					}
 					if request["onRequest"].(bool) {
 						options.OnRequest = func(args api.ServeOnRequestArgs) {
 							// This could potentially be called after we return from
@@ -836,6 +897,8 @@ func (service *serviceType) convertPlugins(key int, jsPlugins interface{}, activ
 
 	var onResolveCallbacks []filteredCallback
 	var onLoadCallbacks []filteredCallback
+	var onTransformCallbacks []filteredCallback
+	var onRenderChunkCallbacks []filteredCallback
 	hasOnStart := false
 	hasOnEnd := false
 
@@ -879,6 +942,19 @@ func (service *serviceType) convertPlugins(key int, jsPlugins interface{}, activ
 		} else {
 			onLoadCallbacks = append(onLoadCallbacks, callbacks...)
 		}
+
+		if callbacks, err := filteredCallbacks(pluginName, "onTransform", p["onTransform"].([]interface{})); err != nil {
+			return nil, false, err
+		} else {
+			onTransformCallbacks = append(onTransformCallbacks, callbacks...)
+		}
+
+		for _, id := range p["onRenderChunk"].([]interface{}) {
+			onRenderChunkCallbacks = append(onRenderChunkCallbacks, filteredCallback{
+				pluginName: pluginName,
+				id:         id.(int),
+			})
+		}
 	}
 
 	// We want to minimize the amount of IPC traffic. Instead of adding one Go
@@ -919,6 +995,9 @@ func (service *serviceType) convertPlugins(key int, jsPlugins interface{}, activ
 				if value, ok := request["pluginData"]; ok {
 					options.PluginData = value.(int)
 				}
+				if value, ok := request["with"]; ok {
+					options.With = decodeStringMap(value.(map[string]interface{}))
+				}
 
 				result := build.Resolve(path, options)
 				return encodePacket(packet{
@@ -980,6 +1059,7 @@ func (service *serviceType) convertPlugins(key int, jsPlugins interface{}, activ
 						"resolveDir": args.ResolveDir,
 						"kind":       resolveKindToString(args.Kind),
 						"pluginData": args.PluginData,
+						"with":       encodeStringMap(args.With),
 					}).(map[string]interface{})
 					if !ok {
 						return result, errors.New("The service was stopped")
@@ -1063,6 +1143,7 @@ func (service *serviceType) convertPlugins(key int, jsPlugins interface{}, activ
 						"namespace":  args.Namespace,
 						"suffix":     args.Suffix,
 						"pluginData": args.PluginData,
+						"with":       encodeStringMap(args.With),
 					}).(map[string]interface{})
 					if !ok {
 						return result, errors.New("The service was stopped")
@@ -1094,6 +1175,10 @@ func (service *serviceType) convertPlugins(key int, jsPlugins interface{}, activ
 						contents := string(value.([]byte))
 						result.Contents = &contents
 					}
+					if value, ok := response["sourceMap"]; ok {
+						sourceMap := string(value.([]byte))
+						result.SourceMap = &sourceMap
+					}
 					if value, ok := response["resolveDir"]; ok {
 						result.ResolveDir = value.(string)
 					}
@@ -1116,6 +1201,115 @@ func (service *serviceType) convertPlugins(key int, jsPlugins interface{}, activ
 					return result, nil
 				})
 			}
+
+			// Unlike "OnLoad", every matching transform runs and each one can return
+			// its own source map. So each one is registered separately, which also
+			// keeps them in the order they were registered in.
+			for _, item := range onTransformCallbacks {
+				item := item
+				build.OnTransform(api.OnTransformOptions{Filter: ".*"}, func(args api.OnTransformArgs) (api.OnTransformResult, error) {
+					result := api.OnTransformResult{PluginName: item.pluginName}
+					if !config.PluginAppliesToPath(logger.Path{Text: args.Path, Namespace: args.Namespace}, item.filter, item.namespace) {
+						return result, nil
+					}
+
+					response, ok := service.sendRequest(map[string]interface{}{
+						"command":    "on-transform",
+						"key":        key,
+						"id":         item.id,
+						"path":       args.Path,
+						"namespace":  args.Namespace,
+						"suffix":     args.Suffix,
+						"contents":   []byte(args.Contents),
+						"loader":     cli_helpers.LoaderName(args.Loader),
+						"pluginData": args.PluginData,
+						"with":       encodeStringMap(args.With),
+					}).(map[string]interface{})
+					if !ok {
+						return result, errors.New("The service was stopped")
+					}
+
+					if value, ok := response["error"]; ok {
+						return result, errors.New(value.(string))
+					}
+					if value, ok := response["pluginName"]; ok {
+						result.PluginName = value.(string)
+					}
+					if value, ok := response["loader"]; ok {
+						loader, err := cli_helpers.ParseLoader(value.(string))
+						if err != nil {
+							return result, errors.New(err.Text)
+						}
+						result.Loader = loader
+					}
+					if value, ok := response["contents"]; ok {
+						contents := string(value.([]byte))
+						result.Contents = &contents
+					}
+					if value, ok := response["sourceMap"]; ok {
+						sourceMap := string(value.([]byte))
+						result.SourceMap = &sourceMap
+					}
+					if value, ok := response["pluginData"]; ok {
+						result.PluginData = value.(int)
+					}
+					if value, ok := response["errors"]; ok {
+						result.Errors = decodeMessages(value.([]interface{}))
+					}
+					if value, ok := response["warnings"]; ok {
+						result.Warnings = decodeMessages(value.([]interface{}))
+					}
+					if value, ok := response["watchFiles"]; ok {
+						result.WatchFiles = decodeStringArray(value.([]interface{}))
+					}
+					if value, ok := response["watchDirs"]; ok {
+						result.WatchDirs = decodeStringArray(value.([]interface{}))
+					}
+
+					return result, nil
+				})
+			}
+
+			for _, item := range onRenderChunkCallbacks {
+				item := item
+				build.OnRenderChunk(func(args api.OnRenderChunkArgs) (api.OnRenderChunkResult, error) {
+					result := api.OnRenderChunkResult{PluginName: item.pluginName}
+					response, ok := service.sendRequest(map[string]interface{}{
+						"command":      "on-render-chunk",
+						"key":          key,
+						"id":           item.id,
+						"pathTemplate": args.PathTemplate,
+						"entryPoint":   args.EntryPoint,
+						"contents":     []byte(args.Contents),
+					}).(map[string]interface{})
+					if !ok {
+						return result, errors.New("The service was stopped")
+					}
+
+					if value, ok := response["error"]; ok {
+						return result, errors.New(value.(string))
+					}
+					if value, ok := response["pluginName"]; ok {
+						result.PluginName = value.(string)
+					}
+					if value, ok := response["contents"]; ok {
+						contents := string(value.([]byte))
+						result.Contents = &contents
+					}
+					if value, ok := response["sourceMap"]; ok {
+						sourceMap := string(value.([]byte))
+						result.SourceMap = &sourceMap
+					}
+					if value, ok := response["errors"]; ok {
+						result.Errors = decodeMessages(value.([]interface{}))
+					}
+					if value, ok := response["warnings"]; ok {
+						result.Warnings = decodeMessages(value.([]interface{}))
+					}
+
+					return result, nil
+				})
+			}
 		},
 	}}, hasOnEnd, nil
 }
@@ -1169,15 +1363,54 @@ func (service *serviceType) handleTransformRequest(id uint32, request map[string
 		fs.AfterFileClose()
 	}
 
+	response := encodeTransformResult(result)
+	response["codeFS"] = codeFS
+	response["mapFS"] = mapFS
+
+	return encodePacket(packet{
+		id:    id,
+		value: response,
+	})
+}
+
+func (service *serviceType) handleTransformManyRequest(id uint32, request map[string]interface{}) []byte {
+	requestInputs := request["inputs"].([]interface{})
+	inputs := make([]api.TransformInput, len(requestInputs))
+
+	for i, value := range requestInputs {
+		input := value.(map[string]interface{})
+		flags := decodeStringArray(input["flags"].([]interface{}))
+		options, err := cli.ParseTransformOptions(flags)
+		if err != nil {
+			return encodeErrorPacket(id, err)
+		}
+		options.MangleCache, _ = input["mangleCache"].(map[string]interface{})
+		inputs[i] = api.TransformInput{
+			Contents: string(input["input"].([]byte)),
+			Options:  options,
+		}
+	}
+
+	results := api.TransformMany(inputs)
+	responses := make([]interface{}, len(results))
+	for i, result := range results {
+		responses[i] = encodeTransformResult(result)
+	}
+
+	return encodePacket(packet{
+		id: id,
+		value: map[string]interface{}{
+			"results": responses,
+		},
+	})
+}
+
+func encodeTransformResult(result api.TransformResult) map[string]interface{} {
 	response := map[string]interface{}{
 		"errors":   encodeMessages(result.Errors),
 		"warnings": encodeMessages(result.Warnings),
-
-		"codeFS": codeFS,
-		"code":   string(result.Code),
-
-		"mapFS": mapFS,
-		"map":   string(result.Map),
+		"code":     string(result.Code),
+		"map":      string(result.Map),
 	}
 
 	if result.LegalComments != nil {
@@ -1188,10 +1421,7 @@ func (service *serviceType) handleTransformRequest(id uint32, request map[string
 		response["mangleCache"] = result.MangleCache
 	}
 
-	return encodePacket(packet{
-		id:    id,
-		value: response,
-	})
+	return response
 }
 
 func (service *serviceType) handleFormatMessagesRequest(id uint32, request map[string]interface{}) []byte {
@@ -1230,6 +1460,15 @@ func (service *serviceType) handleAnalyzeMetafileRequest(id uint32, request map[
 	if value, ok := request["verbose"].(bool); ok {
 		options.Verbose = value
 	}
+	if value, ok := request["why"].(string); ok {
+		options.Why = value
+	}
+	if value, ok := request["html"].(bool); ok {
+		options.HTML = value
+	}
+	if value, ok := request["baseline"].(string); ok {
+		options.Baseline = value
+	}
 
 	result := api.AnalyzeMetafile(metafile, options)
 
@@ -1257,6 +1496,22 @@ func decodeStringArray(values []interface{}) []string {
 	return strings
 }
 
+func decodeStringMap(values map[string]interface{}) map[string]string {
+	strings := make(map[string]string, len(values))
+	for k, v := range values {
+		strings[k] = v.(string)
+	}
+	return strings
+}
+
+func encodeStringMap(values map[string]string) map[string]interface{} {
+	result := make(map[string]interface{}, len(values))
+	for k, v := range values {
+		result[k] = v
+	}
+	return result
+}
+
 func encodeOutputFiles(outputFiles []api.OutputFile) []interface{} {
 	values := make([]interface{}, len(outputFiles))
 	for i, outputFile := range outputFiles {
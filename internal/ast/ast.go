@@ -142,6 +142,10 @@ const (
 	// Unique keys are randomly-generated strings that are used to replace paths
 	// in the source code after it's printed. These must not ever be split apart.
 	ContainsUniqueKey
+
+	// If true, this is an "import()" of another chunk generated by code
+	// splitting. Output formats without "import()" load the chunk differently.
+	ImportsChunk
 )
 
 func (flags ImportRecordFlags) Has(flag ImportRecordFlags) bool {
@@ -170,6 +174,20 @@ type ImportRecord struct {
 	Kind  ImportKind
 }
 
+type ImportAssertOrWith uint8
+
+const (
+	AssertKeyword ImportAssertOrWith = iota
+	WithKeyword
+)
+
+func (kw ImportAssertOrWith) String() string {
+	if kw == WithKeyword {
+		return "with"
+	}
+	return "assert"
+}
+
 type ImportAssertions struct {
 	Entries            []AssertEntry
 	AssertLoc          logger.Loc
@@ -177,6 +195,7 @@ type ImportAssertions struct {
 	InnerCloseBraceLoc logger.Loc
 	OuterOpenBraceLoc  logger.Loc
 	OuterCloseBraceLoc logger.Loc
+	AssertOrWith       ImportAssertOrWith
 }
 
 type AssertEntry struct {
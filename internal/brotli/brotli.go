@@ -0,0 +1,378 @@
+// Package brotli implements a small brotli encoder as described in RFC 7932
+// (https://www.rfc-editor.org/rfc/rfc7932). It's used by the development
+// server, which cares more about encoding speed than about the size of the
+// output. It only uses a subset of the format: greedy LZ77 matching, a single
+// prefix code for each category, and no context modeling, block switching, or
+// static dictionary references. Any brotli decoder can decode the result.
+package brotli
+
+import (
+	"math/bits"
+	"sort"
+)
+
+const (
+	windowBits   = 22
+	maxDistance  = (1 << windowBits) - 16
+	maxBlockSize = 1 << 20
+	minMatch     = 4
+	hashBits     = 16
+)
+
+func Compress(data []byte) []byte {
+	w := bitWriter{out: make([]byte, 0, len(data)/2+16)}
+
+	// A window size of 22 bits is encoded as a 1 bit followed by 22 - 17
+	w.write(1, 1)
+	w.write(3, windowBits-17)
+
+	// Each meta-block is compressed separately, but matches can still refer
+	// back to data in earlier meta-blocks
+	table := make([]int32, 1<<hashBits)
+	for start := 0; start < len(data); start += maxBlockSize {
+		end := start + maxBlockSize
+		if end > len(data) {
+			end = len(data)
+		}
+		writeMetaBlock(&w, data, start, end, findCommands(data, start, end, table))
+	}
+
+	// ISLAST and ISLASTEMPTY
+	w.write(2, 3)
+	w.flush()
+	return w.out
+}
+
+type bitWriter struct {
+	out   []byte
+	bits  uint64
+	count uint
+}
+
+// Bits are packed starting with the least significant bit of each byte
+func (w *bitWriter) write(count uint, value uint64) {
+	w.bits |= value << w.count
+	w.count += count
+	for w.count >= 8 {
+		w.out = append(w.out, byte(w.bits))
+		w.bits >>= 8
+		w.count -= 8
+	}
+}
+
+func (w *bitWriter) flush() {
+	if w.count > 0 {
+		w.out = append(w.out, byte(w.bits))
+		w.bits = 0
+		w.count = 0
+	}
+}
+
+type command struct {
+	literalStart int
+	insertLen    uint32
+	copyLen      uint32 // This is zero for the final command in a meta-block
+	distance     uint32
+	insertCode   uint8
+	copyCode     uint8
+	commandCode  uint16
+	distanceCode uint8
+}
+
+func hash4(data []byte, i int) uint32 {
+	v := uint32(data[i]) | uint32(data[i+1])<<8 | uint32(data[i+2])<<16 | uint32(data[i+3])<<24
+	return (v * 0x1e35a7bd) >> (32 - hashBits)
+}
+
+// This does greedy matching using a hash table that remembers the most recent
+// position for each 4-byte sequence. The hash table stores positions plus one
+// so that zero can mean "empty".
+func findCommands(data []byte, start int, end int, table []int32) (commands []command) {
+	literalStart := start
+	i := start
+	for i+minMatch <= end {
+		h := hash4(data, i)
+		candidate := int(table[h]) - 1
+		table[h] = int32(i + 1)
+		if candidate >= 0 && i-candidate <= maxDistance &&
+			data[candidate] == data[i] && data[candidate+1] == data[i+1] &&
+			data[candidate+2] == data[i+2] && data[candidate+3] == data[i+3] {
+			length := minMatch
+			for i+length < end && data[candidate+length] == data[i+length] {
+				length++
+			}
+			commands = append(commands, command{
+				literalStart: literalStart,
+				insertLen:    uint32(i - literalStart),
+				copyLen:      uint32(length),
+				distance:     uint32(i - candidate),
+			})
+			for j := i + 1; j < i+length && j+minMatch <= len(data); j++ {
+				table[hash4(data, j)] = int32(j + 1)
+			}
+			i += length
+			literalStart = i
+		} else {
+			i++
+		}
+	}
+	if literalStart < end {
+		commands = append(commands, command{
+			literalStart: literalStart,
+			insertLen:    uint32(end - literalStart),
+		})
+	}
+	return
+}
+
+var insertBase = [24]uint32{0, 1, 2, 3, 4, 5, 6, 8, 10, 14, 18, 26, 34, 50, 66, 98, 130, 194, 322, 578, 1090, 2114, 6210, 22594}
+var insertExtra = [24]uint8{0, 0, 0, 0, 0, 0, 1, 1, 2, 2, 3, 3, 4, 4, 5, 5, 6, 7, 8, 9, 10, 12, 14, 24}
+var copyBase = [24]uint32{2, 3, 4, 5, 6, 7, 8, 9, 10, 12, 14, 18, 22, 30, 38, 54, 70, 102, 134, 198, 326, 582, 1094, 2118}
+var copyExtra = [24]uint8{0, 0, 0, 0, 0, 0, 0, 0, 1, 1, 2, 2, 3, 3, 4, 4, 5, 5, 6, 7, 8, 9, 10, 24}
+
+// Commands always use an explicit distance, so the cells of the insert-and-copy
+// alphabet that reuse the last distance are never used
+var commandCellBase = [3][3]uint16{{128, 192, 384}, {256, 320, 512}, {448, 576, 640}}
+
+func lengthCode(base *[24]uint32, value uint32) uint8 {
+	code := uint8(23)
+	for base[code] > value {
+		code--
+	}
+	return code
+}
+
+// This assumes NPOSTFIX and NDIRECT are both zero
+func distanceCode(distance uint32) (code uint8, extraBits uint, extra uint32) {
+	x := distance + 3
+	extraBits = uint(bits.Len32(x) - 2)
+	code = uint8(16 + 2*(extraBits-1) + uint((x>>extraBits)&1))
+	extra = x & (1<<extraBits - 1)
+	return
+}
+
+func writeMetaBlock(w *bitWriter, data []byte, start int, end int, commands []command) {
+	var literalCounts [256]uint32
+	var commandCounts [704]uint32
+	var distanceCounts [64]uint32
+
+	for i := range commands {
+		cmd := &commands[i]
+		cmd.insertCode = lengthCode(&insertBase, cmd.insertLen)
+		if cmd.copyLen > 0 {
+			cmd.copyCode = lengthCode(&copyBase, cmd.copyLen)
+			cmd.distanceCode, _, _ = distanceCode(cmd.distance)
+			distanceCounts[cmd.distanceCode]++
+		}
+		cmd.commandCode = commandCellBase[cmd.insertCode>>3][cmd.copyCode>>3] + uint16(cmd.insertCode&7)<<3 + uint16(cmd.copyCode&7)
+		commandCounts[cmd.commandCode]++
+		for _, c := range data[cmd.literalStart : cmd.literalStart+int(cmd.insertLen)] {
+			literalCounts[c]++
+		}
+	}
+
+	literalDepths := buildDepths(literalCounts[:], 15)
+	commandDepths := buildDepths(commandCounts[:], 15)
+	distanceDepths := buildDepths(distanceCounts[:], 15)
+	literalCodes := canonicalCodes(literalDepths)
+	commandCodes := canonicalCodes(commandDepths)
+	distanceCodes := canonicalCodes(distanceDepths)
+
+	// The meta-block header
+	length := uint64(end - start)
+	nibbles := uint(4)
+	if length-1 >= 1<<20 {
+		nibbles = 6
+	} else if length-1 >= 1<<16 {
+		nibbles = 5
+	}
+	w.write(1, 0) // ISLAST
+	w.write(2, uint64(nibbles-4))
+	w.write(nibbles*4, length-1)
+	w.write(1, 0) // ISUNCOMPRESSED
+	w.write(3, 0) // NBLTYPESL, NBLTYPESI, and NBLTYPESD are all 1
+	w.write(6, 0) // NPOSTFIX and NDIRECT are both 0
+	w.write(2, 0) // The context mode for the only literal block type
+	w.write(2, 0) // NTREESL and NTREESD are both 1
+	writePrefixCode(w, literalCounts[:], literalDepths, 8)
+	writePrefixCode(w, commandCounts[:], commandDepths, 10)
+	writePrefixCode(w, distanceCounts[:], distanceDepths, 6)
+
+	// The meta-block data
+	for _, cmd := range commands {
+		w.write(uint(commandDepths[cmd.commandCode]), uint64(commandCodes[cmd.commandCode]))
+		w.write(uint(insertExtra[cmd.insertCode]), uint64(cmd.insertLen-insertBase[cmd.insertCode]))
+		if cmd.copyLen > 0 {
+			w.write(uint(copyExtra[cmd.copyCode]), uint64(cmd.copyLen-copyBase[cmd.copyCode]))
+		}
+		for _, c := range data[cmd.literalStart : cmd.literalStart+int(cmd.insertLen)] {
+			w.write(uint(literalDepths[c]), uint64(literalCodes[c]))
+		}
+		if cmd.copyLen > 0 {
+			_, extraBits, extra := distanceCode(cmd.distance)
+			w.write(uint(distanceDepths[cmd.distanceCode]), uint64(distanceCodes[cmd.distanceCode]))
+			w.write(extraBits, uint64(extra))
+		}
+	}
+}
+
+type huffmanNode struct {
+	count uint32
+	left  int32 // This is -1 for leaves
+	right int32 // This is the symbol for leaves
+}
+
+// This builds a Huffman tree and returns the depth of each symbol. If the tree
+// is too deep, small counts are raised and the tree is built again, which is
+// the same approach that the reference encoder uses.
+func buildDepths(counts []uint32, limit int) []uint8 {
+	depths := make([]uint8, len(counts))
+	for countLimit := uint32(1); ; countLimit *= 2 {
+		nodes := make([]huffmanNode, 0, 2*len(counts))
+		for symbol, count := range counts {
+			if count > 0 {
+				if count < countLimit {
+					count = countLimit
+				}
+				nodes = append(nodes, huffmanNode{count: count, left: -1, right: int32(symbol)})
+			}
+		}
+		if len(nodes) < 2 {
+			return depths
+		}
+		sort.SliceStable(nodes, func(i int, j int) bool {
+			return nodes[i].count < nodes[j].count
+		})
+
+		// Leaves are sorted and inner nodes are created in order of increasing
+		// count, so the two smallest nodes are always at the front of one of them
+		leafCount := len(nodes)
+		nextLeaf := 0
+		nextInner := leafCount
+		pick := func() int32 {
+			if nextLeaf < leafCount && (nextInner == len(nodes) || nodes[nextLeaf].count <= nodes[nextInner].count) {
+				nextLeaf++
+				return int32(nextLeaf - 1)
+			}
+			nextInner++
+			return int32(nextInner - 1)
+		}
+		for len(nodes) < 2*leafCount-1 {
+			a := pick()
+			b := pick()
+			nodes = append(nodes, huffmanNode{count: nodes[a].count + nodes[b].count, left: a, right: b})
+		}
+
+		// Children always come before their parent
+		nodeDepths := make([]int, len(nodes))
+		maxDepth := 0
+		for i := len(nodes) - 1; i >= leafCount; i-- {
+			depth := nodeDepths[i] + 1
+			nodeDepths[nodes[i].left] = depth
+			nodeDepths[nodes[i].right] = depth
+			if depth > maxDepth {
+				maxDepth = depth
+			}
+		}
+		if maxDepth <= limit {
+			for i := 0; i < leafCount; i++ {
+				depths[nodes[i].right] = uint8(nodeDepths[i])
+			}
+			return depths
+		}
+	}
+}
+
+// Canonical codes are assigned like in DEFLATE. The bits are reversed because
+// codes are packed starting with their most significant bit.
+func canonicalCodes(depths []uint8) []uint16 {
+	var depthCounts [16]uint16
+	for _, depth := range depths {
+		if depth > 0 {
+			depthCounts[depth]++
+		}
+	}
+	var nextCode [16]uint16
+	code := uint16(0)
+	for i := 1; i < 16; i++ {
+		code = (code + depthCounts[i-1]) << 1
+		nextCode[i] = code
+	}
+	codes := make([]uint16, len(depths))
+	for symbol, depth := range depths {
+		if depth > 0 {
+			codes[symbol] = bits.Reverse16(nextCode[depth]) >> (16 - depth)
+			nextCode[depth]++
+		}
+	}
+	return codes
+}
+
+var codeLengthOrder = [18]uint8{1, 2, 3, 4, 0, 5, 17, 6, 16, 7, 8, 9, 10, 11, 12, 13, 14, 15}
+
+// The fixed code for the code lengths of the code length alphabet, as bit
+// count and value
+var codeLengthCodeLengths = [6][2]uint8{{2, 0}, {4, 7}, {3, 3}, {2, 2}, {2, 1}, {4, 15}}
+
+func writePrefixCode(w *bitWriter, counts []uint32, depths []uint8, alphabetBits uint) {
+	last := -1
+	usedCount := 0
+	for symbol, count := range counts {
+		if count > 0 {
+			last = symbol
+			usedCount++
+		}
+	}
+
+	// A simple prefix code with a single symbol uses zero bits per symbol. This
+	// is also used when the alphabet isn't used at all.
+	if usedCount < 2 {
+		if last == -1 {
+			last = 0
+		}
+		w.write(2, 1) // HSKIP of 1 means a simple prefix code
+		w.write(2, 0) // NSYM - 1
+		w.write(alphabetBits, uint64(last))
+		return
+	}
+
+	// The depths are themselves compressed with a prefix code. Only the code
+	// lengths 0 through 15 are used, not the repeat codes 16 and 17. Trailing
+	// zeros are omitted because the decoder stops once the code is complete.
+	var lengthCounts [18]uint32
+	for _, depth := range depths[:last+1] {
+		lengthCounts[depth]++
+	}
+	lengthDepths := buildDepths(lengthCounts[:], 5)
+	lengthCodes := canonicalCodes(lengthDepths)
+
+	w.write(2, 0) // HSKIP
+	if allSame := depths[0]; lengthCounts[allSame] == uint32(last+1) {
+		// If every depth is the same, the code length code has a single symbol
+		// with a zero-bit code. All 18 code lengths must be written in this case.
+		for _, symbol := range codeLengthOrder {
+			entry := codeLengthCodeLengths[0]
+			if symbol == allSame {
+				entry = codeLengthCodeLengths[1]
+			}
+			w.write(uint(entry[0]), uint64(entry[1]))
+		}
+	} else {
+		space := 32
+		for _, symbol := range codeLengthOrder {
+			depth := lengthDepths[symbol]
+			entry := codeLengthCodeLengths[depth]
+			w.write(uint(entry[0]), uint64(entry[1]))
+			if depth > 0 {
+				space -= 32 >> depth
+				if space == 0 {
+					break
+				}
+			}
+		}
+	}
+
+	for _, depth := range depths[:last+1] {
+		w.write(uint(lengthDepths[depth]), uint64(lengthCodes[depth]))
+	}
+}
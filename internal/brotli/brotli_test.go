@@ -0,0 +1,54 @@
+package brotli
+
+import (
+	"encoding/hex"
+	"testing"
+
+	"github.com/evanw/esbuild/internal/test"
+)
+
+// These were checked against the reference decoder
+func TestCompress(t *testing.T) {
+	check := func(input string, expected string) {
+		t.Helper()
+		test.AssertEqual(t, hex.EncodeToString(Compress([]byte(input))), expected)
+	}
+
+	check("", "3b")
+	check("a", "0b00000020c202910006")
+	check("hello hello hello hello\n", "8b0b000000360e800100c000000000000000003023e28003000000000000"+
+		"00000000000000000000080000000000000000000000006048fd814d06")
+}
+
+func TestBuildDepths(t *testing.T) {
+	// Fibonacci counts produce the deepest possible tree
+	counts := make([]uint32, 30)
+	a, b := uint32(1), uint32(1)
+	for i := range counts {
+		counts[i] = a
+		a, b = b, a+b
+	}
+
+	for _, limit := range []int{5, 15} {
+		depths := buildDepths(counts, limit)
+		space := 0
+		for _, depth := range depths {
+			test.AssertEqual(t, depth > 0 && int(depth) <= limit, true)
+			space += 1 << (15 - depth)
+		}
+
+		// The code must be complete
+		test.AssertEqual(t, space, 1<<15)
+	}
+}
+
+func TestDistanceCode(t *testing.T) {
+	// The inverse of the formula in section 4 of RFC 7932
+	for _, distance := range []uint32{1, 2, 3, 4, 5, 100, 1 << 16, maxDistance} {
+		code, extraBits, extra := distanceCode(distance)
+		ndistbits := 1 + ((uint32(code) - 16) >> 1)
+		offset := ((2 + ((uint32(code) - 16) & 1)) << ndistbits) - 4
+		test.AssertEqual(t, uint32(extraBits), ndistbits)
+		test.AssertEqual(t, offset+extra+1, distance)
+	}
+}
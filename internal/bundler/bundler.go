@@ -29,6 +29,8 @@ import (
 	"github.com/evanw/esbuild/internal/fs"
 	"github.com/evanw/esbuild/internal/graph"
 	"github.com/evanw/esbuild/internal/helpers"
+	"github.com/evanw/esbuild/internal/html_ast"
+	"github.com/evanw/esbuild/internal/html_parser"
 	"github.com/evanw/esbuild/internal/js_ast"
 	"github.com/evanw/esbuild/internal/js_lexer"
 	"github.com/evanw/esbuild/internal/js_parser"
@@ -36,6 +38,7 @@ import (
 	"github.com/evanw/esbuild/internal/resolver"
 	"github.com/evanw/esbuild/internal/runtime"
 	"github.com/evanw/esbuild/internal/sourcemap"
+	"github.com/evanw/esbuild/internal/wasm_parser"
 	"github.com/evanw/esbuild/internal/xxhash"
 )
 
@@ -45,6 +48,10 @@ type scannerFile struct {
 	// fully assembled later.
 	jsonMetadataChunk string
 
+	// The "wasm" loader replaces the source contents with generated JavaScript
+	// code, so the original bytes of the WebAssembly module are kept here
+	wasmBytes string
+
 	pluginData interface{}
 	inputFile  graph.InputFile
 }
@@ -130,6 +137,7 @@ func parseFile(args parseArgs) {
 	var absResolveDir string
 	var pluginName string
 	var pluginData interface{}
+	var pluginSourceMap *string
 
 	if stdin := args.options.Stdin; stdin != nil {
 		// Special-case stdin
@@ -165,6 +173,7 @@ func parseFile(args parseArgs) {
 		absResolveDir = result.absResolveDir
 		pluginName = result.pluginName
 		pluginData = result.pluginData
+		pluginSourceMap = result.sourceMap
 	}
 
 	_, base, ext := logger.PlatformIndependentPathDirBaseExt(source.KeyPath.Text)
@@ -174,6 +183,32 @@ func parseFile(args parseArgs) {
 		loader = loaderFromFileExtension(args.options.ExtensionToLoader, base+ext)
 	}
 
+	// Transform plugins run on the loaded contents in registration order
+	var transformSourceMap *sourcemap.SourceMap
+	if args.options.Stdin == nil {
+		var ok bool
+		transformSourceMap, ok = runOnTransformPlugins(
+			args.options.Plugins,
+			args.fs,
+			&args.caches.FSCache,
+			args.log,
+			&source,
+			args.importSource,
+			args.importPathRange,
+			&loader,
+			&pluginData,
+		)
+		if !ok {
+			if args.inject != nil {
+				args.inject <- config.InjectedFile{
+					Source: source,
+				}
+			}
+			args.results <- parseResult{}
+			return
+		}
+	}
+
 	if loader == config.LoaderEmpty {
 		source.Contents = ""
 	}
@@ -242,6 +277,19 @@ func parseFile(args parseArgs) {
 		result.file.inputFile.Repr = &graph.CSSRepr{AST: ast}
 		result.ok = true
 
+	case config.LoaderHTML:
+		// HTML files are rewritten to point at the output files for the scripts
+		// and style sheets they reference, which only makes sense when bundling
+		if args.options.Mode != config.ModeBundle {
+			tracker := logger.MakeLineColumnTracker(args.importSource)
+			args.log.AddError(&tracker, args.importPathRange,
+				fmt.Sprintf("Cannot use the \"html\" loader without bundling: %s", source.PrettyPath))
+			break
+		}
+		ast := html_parser.Parse(source)
+		result.file.inputFile.Repr = &graph.HTMLRepr{AST: ast}
+		result.ok = true
+
 	case config.LoaderJSON:
 		expr, ok := args.caches.JSONCache.Parse(args.log, source, js_parser.JSONOptions{})
 		ast := js_parser.LazyExportAST(args.log, source, js_parser.OptionsFromConfig(&args.options), expr, "")
@@ -331,6 +379,35 @@ func parseFile(args parseArgs) {
 		// Mark that this file is from the "file" loader
 		result.file.inputFile.UniqueKeyForAdditionalFile = uniqueKey
 
+	case config.LoaderWasm:
+		module, err := wasm_parser.Parse(source.Contents)
+		if err != nil {
+			tracker := logger.MakeLineColumnTracker(args.importSource)
+			args.log.AddError(&tracker, args.importPathRange,
+				fmt.Sprintf("Invalid WebAssembly module %s: %s", source.PrettyPath, err.Error()))
+			break
+		}
+
+		// The bytes are either embedded in the generated code or emitted as a
+		// separate file using the same mechanism as the "file" loader
+		var uniqueKey string
+		var uniqueKeyPath string
+		if args.options.WasmBytesAsFile {
+			uniqueKey = fmt.Sprintf("%sA%08d", args.uniqueKeyPrefix, args.sourceIndex)
+			uniqueKeyPath = uniqueKey + source.KeyPath.IgnoredSuffix
+		}
+
+		// The module is parsed as generated JavaScript code that imports and
+		// exports the same names as the WebAssembly module. The source contents
+		// are replaced so that locations in log messages refer to that code.
+		result.file.wasmBytes = source.Contents
+		source.Contents = generateWasmGlueCode(module, source.Contents, uniqueKeyPath, args.options.Platform)
+		result.file.inputFile.Source = source
+		ast, ok := args.caches.JSCache.Parse(args.log, source, js_parser.OptionsFromConfig(&args.options))
+		result.file.inputFile.Repr = &graph.JSRepr{AST: ast}
+		result.file.inputFile.UniqueKeyForAdditionalFile = uniqueKey
+		result.ok = ok
+
 	case config.LoaderCopy:
 		uniqueKey := fmt.Sprintf("%sA%08d", args.uniqueKeyPrefix, args.sourceIndex)
 		uniqueKeyPath := uniqueKey + source.KeyPath.IgnoredSuffix
@@ -370,7 +447,13 @@ func parseFile(args parseArgs) {
 					didLogError   bool
 				}
 
-				resolverCache := make(map[ast.ImportKind]map[string]cacheEntry)
+				type cacheKey struct {
+					path  string
+					attrs logger.ImportAttributes
+					kind  ast.ImportKind
+				}
+
+				resolverCache := make(map[cacheKey]cacheEntry)
 				tracker := logger.MakeLineColumnTracker(&source)
 
 				for importRecordIndex := range records {
@@ -415,13 +498,12 @@ func parseFile(args parseArgs) {
 					}
 
 					// Cache the path in case it's imported multiple times in this file
-					cache, ok := resolverCache[record.Kind]
-					if !ok {
-						cache = make(map[string]cacheEntry)
-						resolverCache[record.Kind] = cache
+					key := cacheKey{
+						kind:  record.Kind,
+						path:  record.Path.Text,
+						attrs: importAttributesFromAssertions(record.Assertions),
 					}
-
-					entry, ok := cache[record.Path.Text]
+					entry, ok := resolverCache[key]
 					if ok {
 						result.resolveResults[importRecordIndex] = entry.resolveResult
 					} else {
@@ -437,6 +519,7 @@ func parseFile(args parseArgs) {
 							source.KeyPath,
 							record.Path.Text,
 							record.Kind,
+							key.attrs,
 							absResolveDir,
 							pluginData,
 						)
@@ -445,7 +528,7 @@ func parseFile(args parseArgs) {
 							debug:         debug,
 							didLogError:   didLogError,
 						}
-						cache[record.Path.Text] = entry
+						resolverCache[key] = entry
 
 						// All "require.resolve()" imports should be external because we don't
 						// want to waste effort traversing into them
@@ -478,7 +561,7 @@ func parseFile(args parseArgs) {
 
 							// Only report this error once per unique import path in the file
 							entry.didLogError = true
-							cache[record.Path.Text] = entry
+							resolverCache[key] = entry
 						} else if !entry.didLogError && record.Flags.Has(ast.HandlesImportErrors) {
 							// Report a debug message about why there was no error
 							args.log.AddIDWithNotes(logger.MsgID_Bundler_IgnoredDynamicImport, logger.Debug, &tracker, record.Range,
@@ -496,74 +579,108 @@ func parseFile(args parseArgs) {
 
 		// Attempt to parse the source map if present
 		if loader.CanHaveSourceMap() && args.options.SourceMap != config.SourceMapNone {
-			var sourceMapComment logger.Span
-			switch repr := result.file.inputFile.Repr.(type) {
-			case *graph.JSRepr:
-				sourceMapComment = repr.AST.SourceMapComment
-			case *graph.CSSRepr:
-				sourceMapComment = repr.AST.SourceMapComment
-			}
+			var sourceMap *sourcemap.SourceMap
+			var sourceMapPath logger.Path
+
+			if pluginSourceMap != nil {
+				// A source map returned by the plugin that loaded this file takes
+				// precedence over any source map comment in the loaded contents
+				log := logger.NewDeferLog(logger.DeferLogNoVerboseOrDebug, args.log.Overrides)
+				sourceMapPath = source.KeyPath
+				sourceMap = js_parser.ParseSourceMap(log, logger.Source{
+					KeyPath:    source.KeyPath,
+					PrettyPath: source.PrettyPath,
+					Contents:   *pluginSourceMap,
+				})
+				if msgs := log.Done(); len(msgs) > 0 {
+					note := logger.MsgData{Text: fmt.Sprintf("This source map came from plugin %q", pluginName)}
+					for _, msg := range msgs {
+						msg.Notes = append(msg.Notes, note)
+						args.log.AddMsg(msg)
+					}
+				}
+			} else {
+				var sourceMapComment logger.Span
+				switch repr := result.file.inputFile.Repr.(type) {
+				case *graph.JSRepr:
+					sourceMapComment = repr.AST.SourceMapComment
+				case *graph.CSSRepr:
+					sourceMapComment = repr.AST.SourceMapComment
+				}
 
-			if sourceMapComment.Text != "" {
-				tracker := logger.MakeLineColumnTracker(&source)
+				if sourceMapComment.Text != "" {
+					tracker := logger.MakeLineColumnTracker(&source)
 
-				if path, contents := extractSourceMapFromComment(args.log, args.fs, &args.caches.FSCache,
-					args.res, &source, &tracker, sourceMapComment, absResolveDir); contents != nil {
-					prettyPath := resolver.PrettyPath(args.fs, path)
-					log := logger.NewDeferLog(logger.DeferLogNoVerboseOrDebug, args.log.Overrides)
+					if path, contents := extractSourceMapFromComment(args.log, args.fs, &args.caches.FSCache,
+						args.res, &source, &tracker, sourceMapComment, absResolveDir); contents != nil {
+						prettyPath := resolver.PrettyPath(args.fs, path)
+						log := logger.NewDeferLog(logger.DeferLogNoVerboseOrDebug, args.log.Overrides)
+						sourceMapPath = path
 
-					sourceMap := js_parser.ParseSourceMap(log, logger.Source{
-						KeyPath:    path,
-						PrettyPath: prettyPath,
-						Contents:   *contents,
-					})
+						sourceMap = js_parser.ParseSourceMap(log, logger.Source{
+							KeyPath:    path,
+							PrettyPath: prettyPath,
+							Contents:   *contents,
+						})
 
-					if msgs := log.Done(); len(msgs) > 0 {
-						var text string
-						if path.Namespace == "file" {
-							text = fmt.Sprintf("The source map %q was referenced by the file %q here:", prettyPath, args.prettyPath)
-						} else {
-							text = fmt.Sprintf("This source map came from the file %q here:", args.prettyPath)
-						}
-						note := tracker.MsgData(sourceMapComment.Range, text)
-						for _, msg := range msgs {
-							msg.Notes = append(msg.Notes, note)
-							args.log.AddMsg(msg)
+						if msgs := log.Done(); len(msgs) > 0 {
+							var text string
+							if path.Namespace == "file" {
+								text = fmt.Sprintf("The source map %q was referenced by the file %q here:", prettyPath, args.prettyPath)
+							} else {
+								text = fmt.Sprintf("This source map came from the file %q here:", args.prettyPath)
+							}
+							note := tracker.MsgData(sourceMapComment.Range, text)
+							for _, msg := range msgs {
+								msg.Notes = append(msg.Notes, note)
+								args.log.AddMsg(msg)
+							}
 						}
 					}
+				}
+			}
 
-					// If "sourcesContent" entries aren't present, try filling them in
-					// using the file system. This includes both generating the entire
-					// "sourcesContent" array if it's absent as well as filling in
-					// individual null entries in the array if the array is present.
-					if sourceMap != nil && !args.options.ExcludeSourcesContent {
-						// Make sure "sourcesContent" is big enough
-						if len(sourceMap.SourcesContent) < len(sourceMap.Sources) {
-							slice := make([]sourcemap.SourceContent, len(sourceMap.Sources))
-							copy(slice, sourceMap.SourcesContent)
-							sourceMap.SourcesContent = slice
-						}
+			// If "sourcesContent" entries aren't present, try filling them in
+			// using the file system. This includes both generating the entire
+			// "sourcesContent" array if it's absent as well as filling in
+			// individual null entries in the array if the array is present.
+			if sourceMap != nil && !args.options.ExcludeSourcesContent {
+				// Make sure "sourcesContent" is big enough
+				if len(sourceMap.SourcesContent) < len(sourceMap.Sources) {
+					slice := make([]sourcemap.SourceContent, len(sourceMap.Sources))
+					copy(slice, sourceMap.SourcesContent)
+					sourceMap.SourcesContent = slice
+				}
 
-						// Attempt to fill in null entries using the file system
-						for i, source := range sourceMap.Sources {
-							if sourceMap.SourcesContent[i].Value == nil {
-								var absPath string
-								if args.fs.IsAbs(source) {
-									absPath = source
-								} else if path.Namespace == "file" {
-									absPath = args.fs.Join(args.fs.Dir(path.Text), source)
-								} else {
-									continue
-								}
-								if contents, err, _ := args.caches.FSCache.ReadFile(args.fs, absPath); err == nil {
-									sourceMap.SourcesContent[i].Value = helpers.StringToUTF16(contents)
-								}
-							}
+				// Attempt to fill in null entries using the file system
+				for i, source := range sourceMap.Sources {
+					if sourceMap.SourcesContent[i].Value == nil {
+						var absPath string
+						if args.fs.IsAbs(source) {
+							absPath = source
+						} else if sourceMapPath.Namespace == "file" {
+							absPath = args.fs.Join(args.fs.Dir(sourceMapPath.Text), source)
+						} else {
+							continue
+						}
+						if contents, err, _ := args.caches.FSCache.ReadFile(args.fs, absPath); err == nil {
+							sourceMap.SourcesContent[i].Value = helpers.StringToUTF16(contents)
 						}
 					}
+				}
+			}
 
-					result.file.inputFile.InputSourceMap = sourceMap
+			if sourceMap != nil {
+				result.file.inputFile.InputSourceMap = sourceMap
+			}
+
+			// A source map from transform plugins maps the transformed code back
+			// to the loaded code, so it goes in front of any source map comment
+			if transformSourceMap != nil {
+				if sourceMap := result.file.inputFile.InputSourceMap; sourceMap != nil {
+					transformSourceMap = sourcemap.Compose(transformSourceMap, sourceMap)
 				}
+				result.file.inputFile.InputSourceMap = transformSourceMap
 			}
 		}
 	}
@@ -710,6 +827,140 @@ func guessMimeType(extension string, contents string) string {
 	return strings.ReplaceAll(mimeType, "; ", ";")
 }
 
+// This generates the JavaScript code for a WebAssembly module following the
+// WebAssembly ESM integration proposal. Each import of the module becomes an
+// ESM import from the module name, the module is instantiated using top-level
+// await, and each export of the instance becomes an ESM export. If the unique
+// key path is present, the bytes are loaded from that path (which will be
+// substituted with the path of the additional output file) instead of being
+// embedded in the code.
+func generateWasmGlueCode(module wasm_parser.Module, bytes string, uniqueKeyPath string, platform config.Platform) string {
+	sb := strings.Builder{}
+	quote := func(text string) string {
+		return string(helpers.QuoteForJSON(text, false))
+	}
+
+	// Avoid shadowing the globals and the variables that the code below uses
+	taken := map[string]bool{
+		"Buffer":      true,
+		"URL":         true,
+		"Uint8Array":  true,
+		"WebAssembly": true,
+		"atob":        true,
+		"bytes":       true,
+		"fetch":       true,
+		"fs":          true,
+		"instance":    true,
+	}
+
+	// Group the imports by module name, preserving the order of first use
+	type importName struct {
+		name string
+		ref  string
+	}
+	var moduleNames []string
+	importsByModule := make(map[string][]importName)
+	refs := make(map[[2]string]string)
+	for _, imp := range module.Imports {
+		key := [2]string{imp.Module, imp.Name}
+		if _, ok := refs[key]; ok {
+			continue
+		}
+		ref := fmt.Sprintf("wasm$import%d", len(refs))
+		refs[key] = ref
+		if _, ok := importsByModule[imp.Module]; !ok {
+			moduleNames = append(moduleNames, imp.Module)
+		}
+		importsByModule[imp.Module] = append(importsByModule[imp.Module], importName{name: imp.Name, ref: ref})
+	}
+	for _, moduleName := range moduleNames {
+		sb.WriteString("import {")
+		for i, it := range importsByModule[moduleName] {
+			if i > 0 {
+				sb.WriteString(",")
+			}
+			if js_ast.IsIdentifier(it.name) {
+				sb.WriteString(fmt.Sprintf(" %s as %s", it.name, it.ref))
+			} else {
+				sb.WriteString(fmt.Sprintf(" %s as %s", quote(it.name), it.ref))
+			}
+		}
+		sb.WriteString(fmt.Sprintf(" } from %s;\n", quote(moduleName)))
+	}
+
+	// Load the bytes
+	switch {
+	case uniqueKeyPath != "" && platform == config.PlatformNode:
+		sb.WriteString("import * as fs from \"fs\";\n")
+		sb.WriteString(fmt.Sprintf("var bytes = await fs.promises.readFile(new URL(%s, import.meta.url));\n", quote(uniqueKeyPath)))
+
+	case uniqueKeyPath != "":
+		sb.WriteString(fmt.Sprintf("var bytes = await fetch(new URL(%s, import.meta.url)).then((response) => response.arrayBuffer());\n", quote(uniqueKeyPath)))
+
+	case platform == config.PlatformNode:
+		sb.WriteString(fmt.Sprintf("var bytes = Buffer.from(%s, \"base64\");\n", quote(base64.StdEncoding.EncodeToString([]byte(bytes)))))
+
+	default:
+		sb.WriteString(fmt.Sprintf("var bytes = Uint8Array.from(atob(%s), (c) => c.charCodeAt(0));\n", quote(base64.StdEncoding.EncodeToString([]byte(bytes)))))
+	}
+
+	// Instantiate the module
+	sb.WriteString("var { instance } = await WebAssembly.instantiate(bytes, {")
+	for i, moduleName := range moduleNames {
+		if i > 0 {
+			sb.WriteString(",")
+		}
+		sb.WriteString(fmt.Sprintf("\n  %s: {", wasmObjectKey(quote(moduleName))))
+		for j, it := range importsByModule[moduleName] {
+			if j > 0 {
+				sb.WriteString(",")
+			}
+			sb.WriteString(fmt.Sprintf(" %s: %s", wasmObjectKey(quote(it.name)), it.ref))
+		}
+		sb.WriteString(" }")
+	}
+	if len(moduleNames) > 0 {
+		sb.WriteString("\n")
+	}
+	sb.WriteString("});\n")
+
+	// Export the exports of the instance
+	if len(module.Exports) > 0 {
+		var clauses []string
+		for i, exp := range module.Exports {
+			ref := exp.Name
+			if !js_ast.IsIdentifier(ref) || js_lexer.Keywords[ref] != 0 || js_lexer.StrictModeReservedWords[ref] ||
+				ref == "arguments" || ref == "await" || ref == "eval" || strings.HasPrefix(ref, "wasm$") || taken[ref] {
+				ref = fmt.Sprintf("wasm$export%d", i)
+			}
+			if js_ast.IsIdentifier(exp.Name) {
+				sb.WriteString(fmt.Sprintf("var %s = instance.exports.%s;\n", ref, exp.Name))
+			} else {
+				sb.WriteString(fmt.Sprintf("var %s = instance.exports[%s];\n", ref, quote(exp.Name)))
+			}
+			if ref == exp.Name {
+				clauses = append(clauses, ref)
+			} else if js_ast.IsIdentifier(exp.Name) {
+				clauses = append(clauses, fmt.Sprintf("%s as %s", ref, exp.Name))
+			} else {
+				clauses = append(clauses, fmt.Sprintf("%s as %s", ref, quote(exp.Name)))
+			}
+		}
+		sb.WriteString(fmt.Sprintf("export { %s };\n", strings.Join(clauses, ", ")))
+	}
+
+	return sb.String()
+}
+
+// A "__proto__" key in an object literal sets the prototype instead of
+// defining a property, so it must be written as a computed key instead
+func wasmObjectKey(quoted string) string {
+	if quoted == "\"__proto__\"" {
+		return "[" + quoted + "]"
+	}
+	return quoted
+}
+
 func extractSourceMapFromComment(
 	log logger.Log,
 	fs fs.FS,
@@ -767,7 +1018,7 @@ func sanitizeLocation(fs fs.FS, loc *logger.MsgLocation) {
 	}
 }
 
-func logPluginMessages(
+func LogPluginMessages(
 	fs fs.FS,
 	log logger.Log,
 	name string,
@@ -837,6 +1088,7 @@ func RunOnResolvePlugins(
 	importer logger.Path,
 	path string,
 	kind ast.ImportKind,
+	importAttributes logger.ImportAttributes,
 	absResolveDir string,
 	pluginData interface{},
 ) (*resolver.ResolveResult, bool, resolver.DebugMeta) {
@@ -846,6 +1098,7 @@ func RunOnResolvePlugins(
 		Kind:       kind,
 		PluginData: pluginData,
 		Importer:   importer,
+		With:       importAttributes,
 	}
 	applyPath := logger.Path{
 		Text:      path,
@@ -865,7 +1118,7 @@ func RunOnResolvePlugins(
 			if pluginName == "" {
 				pluginName = plugin.Name
 			}
-			didLogError := logPluginMessages(fs, log, pluginName, result.Msgs, result.ThrownError, importSource, importPathRange)
+			didLogError := LogPluginMessages(fs, log, pluginName, result.Msgs, result.ThrownError, importSource, importPathRange)
 
 			// Plugins can also provide additional file system paths to watch
 			for _, file := range result.AbsWatchFiles {
@@ -918,6 +1171,11 @@ func RunOnResolvePlugins(
 				}
 			}
 
+			// The import attributes become part of the identity of the module
+			if !result.External {
+				result.Path.ImportAttributes = importAttributes
+			}
+
 			return &resolver.ResolveResult{
 				PathPair:               resolver.PathPair{Primary: result.Path},
 				IsExternal:             result.External,
@@ -931,6 +1189,12 @@ func RunOnResolvePlugins(
 	// "file" namespace automatically have a resolve directory. Loader plugins
 	// can also configure a custom resolve directory for files in other namespaces.
 	result, debug := res.Resolve(absResolveDir, path, kind)
+	if result != nil && !result.IsExternal {
+		result.PathPair.Primary.ImportAttributes = importAttributes
+		if result.PathPair.HasSecondary() {
+			result.PathPair.Secondary.ImportAttributes = importAttributes
+		}
+	}
 
 	// Warn when the case used for importing differs from the actual file name
 	if result != nil && result.DifferentCase != nil && !helpers.IsInsideNodeModules(absResolveDir) {
@@ -945,10 +1209,22 @@ func RunOnResolvePlugins(
 	return result, false, debug
 }
 
+func importAttributesFromAssertions(assertions *ast.ImportAssertions) logger.ImportAttributes {
+	if assertions == nil {
+		return logger.ImportAttributes{}
+	}
+	value := make(map[string]string, len(assertions.Entries))
+	for _, entry := range assertions.Entries {
+		value[helpers.UTF16ToString(entry.Key)] = helpers.UTF16ToString(entry.Value)
+	}
+	return logger.EncodeImportAttributes(value)
+}
+
 type loaderPluginResult struct {
 	pluginData    interface{}
 	absResolveDir string
 	pluginName    string
+	sourceMap     *string
 	loader        config.Loader
 }
 
@@ -982,7 +1258,7 @@ func runOnLoadPlugins(
 			if pluginName == "" {
 				pluginName = plugin.Name
 			}
-			didLogError := logPluginMessages(fs, log, pluginName, result.Msgs, result.ThrownError, importSource, importPathRange)
+			didLogError := LogPluginMessages(fs, log, pluginName, result.Msgs, result.ThrownError, importSource, importPathRange)
 
 			// Plugins can also provide additional file system paths to watch
 			for _, file := range result.AbsWatchFiles {
@@ -1023,6 +1299,7 @@ func runOnLoadPlugins(
 				absResolveDir: result.AbsResolveDir,
 				pluginName:    pluginName,
 				pluginData:    result.PluginData,
+				sourceMap:     result.SourceMap,
 			}, true
 		}
 	}
@@ -1084,6 +1361,124 @@ func runOnLoadPlugins(
 	return loaderPluginResult{loader: config.LoaderNone}, true
 }
 
+func runOnTransformPlugins(
+	plugins []config.Plugin,
+	fs fs.FS,
+	fsCache *cache.FSCache,
+	log logger.Log,
+	source *logger.Source,
+	importSource *logger.Source,
+	importPathRange logger.Range,
+	loader *config.Loader,
+	pluginData *interface{},
+) (*sourcemap.SourceMap, bool) {
+	// There's nothing to transform if the file failed to load or is disabled
+	if *loader == config.LoaderNone || *loader == config.LoaderEmpty {
+		return nil, true
+	}
+
+	// Source maps returned by transforms are chained together. The composed
+	// source map maps the current contents back to the "base" contents, which
+	// is what the file looked like before the first transform with a source
+	// map. A transform that changes the contents without returning a source
+	// map makes the chain start over from the new contents.
+	var composed *sourcemap.SourceMap
+	baseContents := source.Contents
+
+	// Apply all matching transform plugins in order
+	for _, plugin := range plugins {
+		for _, onTransform := range plugin.OnTransform {
+			if !config.PluginAppliesToPath(source.KeyPath, onTransform.Filter, onTransform.Namespace) {
+				continue
+			}
+
+			result := onTransform.Callback(config.OnTransformArgs{
+				Path:       source.KeyPath,
+				Contents:   source.Contents,
+				Loader:     *loader,
+				PluginData: *pluginData,
+			})
+			pluginName := result.PluginName
+			if pluginName == "" {
+				pluginName = plugin.Name
+			}
+			didLogError := LogPluginMessages(fs, log, pluginName, result.Msgs, result.ThrownError, importSource, importPathRange)
+
+			// Plugins can also provide additional file system paths to watch
+			for _, file := range result.AbsWatchFiles {
+				fsCache.ReadFile(fs, file)
+			}
+			for _, dir := range result.AbsWatchDirs {
+				if entries, err, _ := fs.ReadDirectory(dir); err == nil {
+					entries.SortedKeys()
+				}
+			}
+
+			// Stop now if there was an error
+			if didLogError {
+				return nil, false
+			}
+
+			if result.Loader != config.LoaderNone && result.Loader != config.LoaderDefault {
+				*loader = result.Loader
+			}
+			if result.PluginData != nil {
+				*pluginData = result.PluginData
+			}
+
+			// Leaving the contents alone passes them through unchanged
+			if result.Contents == nil || *result.Contents == source.Contents {
+				continue
+			}
+			source.Contents = *result.Contents
+
+			if result.SourceMap == nil {
+				composed = nil
+				baseContents = source.Contents
+				continue
+			}
+
+			deferLog := logger.NewDeferLog(logger.DeferLogNoVerboseOrDebug, log.Overrides)
+			sourceMap := js_parser.ParseSourceMap(deferLog, logger.Source{
+				KeyPath:    source.KeyPath,
+				PrettyPath: source.PrettyPath,
+				Contents:   *result.SourceMap,
+			})
+			if msgs := deferLog.Done(); len(msgs) > 0 {
+				note := logger.MsgData{Text: fmt.Sprintf("This source map came from the transform in plugin %q", pluginName)}
+				for _, msg := range msgs {
+					msg.Notes = append(msg.Notes, note)
+					log.AddMsg(msg)
+				}
+			}
+			if sourceMap == nil {
+				composed = nil
+				baseContents = source.Contents
+				continue
+			}
+
+			if composed == nil {
+				// The source map must be relative to the contents that were passed
+				// to the transform, so its sources are replaced with this file
+				sourcePath := source.PrettyPath
+				if source.KeyPath.Namespace == "file" {
+					sourcePath = fs.Base(source.KeyPath.Text)
+				}
+				for i := range sourceMap.Mappings {
+					sourceMap.Mappings[i].SourceIndex = 0
+				}
+				sourceMap.Sources = []string{sourcePath}
+				sourceMap.SourcesContent = []sourcemap.SourceContent{{Value: helpers.StringToUTF16(baseContents)}}
+				composed = sourceMap
+			} else {
+				composed = sourcemap.Compose(sourceMap, composed)
+			}
+		}
+	}
+
+	return composed, true
+}
+
 func loaderFromFileExtension(extensionToLoader map[string]config.Loader, base string) config.Loader {
 	// Pick the loader with the longest matching extension. So if there's an
 	// extension for ".css" and for ".module.css", we want to match the one for
@@ -1192,7 +1587,7 @@ func ScanBundle(
 			onStartWaitGroup.Add(1)
 			go func(plugin config.Plugin, onStart config.OnStart) {
 				result := onStart.Callback()
-				logPluginMessages(fs, log, plugin.Name, result.Msgs, result.ThrownError, nil, logger.Range{})
+				LogPluginMessages(fs, log, plugin.Name, result.Msgs, result.ThrownError, nil, logger.Range{})
 				onStartWaitGroup.Done()
 			}(plugin, onStart)
 		}
@@ -1300,6 +1695,7 @@ func ScanBundle(
 		return Bundle{options: options}
 	}
 
+	entryPointMeta = s.addEntryPointsForHTML(entryPointMeta)
 	files := s.processScannedFiles(entryPointMeta)
 
 	if options.CancelFlag.DidCancel() {
@@ -1410,6 +1806,29 @@ func (s *scanner) maybeParseFile(
 		}
 	}
 
+	// Attach the import attributes to the pretty path so that the same file
+	// imported with different attributes still has a unique pretty path
+	if attrs := path.ImportAttributes.DecodeIntoArray(); len(attrs) > 0 {
+		sb := strings.Builder{}
+		sb.WriteString(prettyPath)
+		sb.WriteString(" with {")
+		for i, attr := range attrs {
+			if i > 0 {
+				sb.WriteByte(',')
+			}
+			sb.WriteByte(' ')
+			if js_ast.IsIdentifier(attr.Key) {
+				sb.WriteString(attr.Key)
+			} else {
+				sb.Write(helpers.QuoteSingle(attr.Key, false))
+			}
+			sb.WriteString(": ")
+			sb.Write(helpers.QuoteSingle(attr.Value, false))
+		}
+		sb.WriteString(" }")
+		prettyPath = sb.String()
+	}
+
 	var sideEffects graph.SideEffects
 	if resolveResult.PrimarySideEffectsData != nil {
 		sideEffects.Kind = graph.NoSideEffects_PackageJSON
@@ -1569,6 +1988,7 @@ func (s *scanner) preprocessInjectedFiles() {
 				importer,
 				importPath,
 				ast.ImportEntryPoint,
+				logger.ImportAttributes{},
 				injectAbsResolveDir,
 				nil,
 			)
@@ -1748,6 +2168,7 @@ func (s *scanner) addEntryPoints(entryPoints []EntryPoint) []graph.EntryPoint {
 				importer,
 				entryPoint.InputPath,
 				ast.ImportEntryPoint,
+				logger.ImportAttributes{},
 				entryPointAbsResolveDir,
 				nil,
 			)
@@ -1933,6 +2354,100 @@ func lowestCommonAncestorDirectory(fs fs.FS, entryPoints []graph.EntryPoint) str
 	return lowestAbsDir
 }
 
+// HTML entry points reference scripts and style sheets that are bundled as
+// separate entry points. Each one generates an output file that the rewritten
+// HTML file can point to. This must be done after all files have been scanned
+// because we need to know what kind of file each reference resolved to.
+func (s *scanner) addEntryPointsForHTML(entryPointMeta []graph.EntryPoint) []graph.EntryPoint {
+	isEntryPoint := make(map[uint32]bool, len(entryPointMeta))
+	var htmlSourceIndices []uint32
+	for _, entryPoint := range entryPointMeta {
+		isEntryPoint[entryPoint.SourceIndex] = true
+		if result := &s.results[entryPoint.SourceIndex]; result.ok {
+			if _, ok := result.file.inputFile.Repr.(*graph.HTMLRepr); ok {
+				htmlSourceIndices = append(htmlSourceIndices, entryPoint.SourceIndex)
+			}
+		}
+	}
+
+	for _, sourceIndex := range htmlSourceIndices {
+		result := &s.results[sourceIndex]
+		repr := result.file.inputFile.Repr.(*graph.HTMLRepr)
+		tracker := logger.MakeLineColumnTracker(&result.file.inputFile.Source)
+
+		for _, url := range repr.AST.URLs {
+			record := &repr.AST.ImportRecords[url.ImportRecordIndex]
+			if !url.Kind.IsEntryPoint() || !record.SourceIndex.IsValid() {
+				continue
+			}
+			otherSourceIndex := record.SourceIndex.GetIndex()
+			otherResult := &s.results[otherSourceIndex]
+			if !otherResult.ok {
+				continue
+			}
+			otherFile := &otherResult.file.inputFile
+
+			// Make sure the file is the right kind of file for the tag
+			switch otherFile.Repr.(type) {
+			case *graph.JSRepr:
+				if url.Kind == html_ast.URLStylesheet {
+					s.log.AddErrorWithNotes(&tracker, record.Range,
+						fmt.Sprintf("Cannot use %q as a style sheet", otherFile.Source.PrettyPath),
+						[]logger.MsgData{{Text: fmt.Sprintf(
+							"A \"<link rel=\"stylesheet\">\" tag can only reference a CSS file and %q is not a CSS file (it was loaded with the %q loader).",
+							otherFile.Source.PrettyPath, config.LoaderToString[otherFile.Loader])}})
+					continue
+				}
+
+			case *graph.CSSRepr:
+				if url.Kind != html_ast.URLStylesheet {
+					s.log.AddErrorWithNotes(&tracker, record.Range,
+						fmt.Sprintf("Cannot use %q as a script", otherFile.Source.PrettyPath),
+						[]logger.MsgData{{Text: fmt.Sprintf(
+							"A \"<script>\" tag can only reference a JavaScript file and %q is not a JavaScript file (it was loaded with the %q loader).",
+							otherFile.Source.PrettyPath, config.LoaderToString[otherFile.Loader])}})
+					continue
+				}
+
+			default:
+				s.log.AddError(&tracker, record.Range,
+					fmt.Sprintf("Cannot reference %q from an HTML file (it was loaded with the %q loader)",
+						otherFile.Source.PrettyPath, config.LoaderToString[otherFile.Loader]))
+				continue
+			}
+
+			if isEntryPoint[otherSourceIndex] {
+				continue
+			}
+			isEntryPoint[otherSourceIndex] = true
+
+			// There must be somewhere to put the additional output files
+			if s.options.WriteToStdout || s.options.AbsOutputFile != "" {
+				hint := ""
+				switch logger.API {
+				case logger.CLIAPI:
+					hint = " (use \"--outdir\" instead)"
+				case logger.JSAPI:
+					hint = " (use \"outdir\" instead)"
+				case logger.GoAPI:
+					hint = " (use \"Outdir\" instead)"
+				}
+				s.log.AddError(&tracker, record.Range,
+					fmt.Sprintf("Cannot bundle %q without an output directory%s", otherFile.Source.PrettyPath, hint))
+				continue
+			}
+
+			// The output path is derived from the input path relative to "outbase"
+			entryPointMeta = append(entryPointMeta, graph.EntryPoint{
+				SourceIndex:                otherSourceIndex,
+				OutputPathWasAutoGenerated: true,
+			})
+		}
+	}
+
+	return entryPointMeta
+}
+
 func (s *scanner) scanAllDependencies() {
 	s.timer.Begin("Scan all dependencies")
 	defer s.timer.End("Scan all dependencies")
@@ -2129,8 +2644,12 @@ func (s *scanner) processScannedFiles(entryPointMeta []graph.EntryPoint) []scann
 
 		// Begin the metadata chunk
 		if s.options.NeedsMetafile {
+			inputBytes := len(result.file.inputFile.Source.Contents)
+			if result.file.inputFile.Loader == config.LoaderWasm {
+				inputBytes = len(result.file.wasmBytes)
+			}
 			sb.Write(helpers.QuoteForJSON(result.file.inputFile.Source.PrettyPath, s.options.ASCIIOnly))
-			sb.WriteString(fmt.Sprintf(": {\n      \"bytes\": %d,\n      \"imports\": [", len(result.file.inputFile.Source.Contents)))
+			sb.WriteString(fmt.Sprintf(": {\n      \"bytes\": %d,\n      \"imports\": [", inputBytes))
 		}
 
 		// Don't try to resolve paths if we're not bundling
@@ -2201,13 +2720,20 @@ func (s *scanner) processScannedFiles(entryPointMeta []graph.EntryPoint) []scann
 				// runtime evaluates them, not us).
 				if record.Flags.Has(ast.AssertTypeJSON) && otherResult.ok && otherFile.inputFile.Loader != config.LoaderJSON && otherFile.inputFile.Loader != config.LoaderCopy {
 					s.log.AddErrorWithNotes(&tracker, record.Range,
-						fmt.Sprintf("The file %q was loaded with the %q loader", otherFile.inputFile.Source.PrettyPath, config.LoaderToString[otherFile.inputFile.Loader]),
+						fmt.Sprintf("The file %q was loaded with the %q loader", resolver.PrettyPath(s.fs, otherFile.inputFile.Source.KeyPath), config.LoaderToString[otherFile.inputFile.Loader]),
 						[]logger.MsgData{
 							tracker.MsgData(js_lexer.RangeOfImportAssertion(result.file.inputFile.Source, *ast.FindAssertion(record.Assertions.Entries, "type")),
 								"This import assertion requires the loader to be \"json\" instead:"),
 							{Text: "You need to either reconfigure esbuild to ensure that the loader for this file is \"json\" or you need to remove this import assertion."}})
 				}
 
+				// HTML files can't be imported since they are rewritten as a whole
+				if _, ok := otherFile.inputFile.Repr.(*graph.HTMLRepr); ok {
+					s.log.AddError(&tracker, record.Range,
+						fmt.Sprintf("Cannot import %q because HTML files can only be entry points", otherFile.inputFile.Source.PrettyPath))
+					continue
+				}
+
 				switch record.Kind {
 				case ast.ImportComposesFrom:
 					// Using a JavaScript file with CSS "composes" is not allowed
@@ -2362,9 +2888,13 @@ func (s *scanner) processScannedFiles(entryPointMeta []graph.EntryPoint) []scann
 
 		result.file.jsonMetadataChunk = sb.String()
 
-		// If this file is from the "file" or "copy" loaders, generate an additional file
+		// If this file is from the "file" or "copy" loaders (or from the "wasm"
+		// loader with separate bytes), generate an additional file
 		if result.file.inputFile.UniqueKeyForAdditionalFile != "" {
 			bytes := []byte(result.file.inputFile.Source.Contents)
+			if result.file.inputFile.Loader == config.LoaderWasm {
+				bytes = []byte(result.file.wasmBytes)
+			}
 			template := s.options.AssetPathTemplate
 
 			// Use the entry path template instead of the asset path template if this
@@ -2640,6 +3170,7 @@ type Linker func(
 	uniqueKeyPrefix string,
 	reachableFiles []uint32,
 	dataForSourceMaps func() []DataForSourceMap,
+	entryPointOutputs map[uint32]graph.EntryPointOutput,
 ) []graph.OutputFile
 
 func (b *Bundle) Compile(log logger.Log, timer *helpers.Timer, mangleCache map[string]interface{}, link Linker) ([]graph.OutputFile, string) {
@@ -2674,17 +3205,35 @@ func (b *Bundle) Compile(log logger.Log, timer *helpers.Timer, mangleCache map[s
 	dataForSourceMaps := b.computeDataForSourceMapsInParallel(&options, allReachableFiles)
 	timer.End("Spawn source map tasks")
 
+	// HTML entry points are linked last because they refer to the output files
+	// that are generated for the other entry points
+	var entryPoints []graph.EntryPoint
+	var htmlEntryPoints []graph.EntryPoint
+	for _, entryPoint := range b.entryPoints {
+		if _, ok := files[entryPoint.SourceIndex].Repr.(*graph.HTMLRepr); ok {
+			htmlEntryPoints = append(htmlEntryPoints, entryPoint)
+		} else {
+			entryPoints = append(entryPoints, entryPoint)
+		}
+	}
+
 	var resultGroups [][]graph.OutputFile
-	if options.CodeSplitting || len(b.entryPoints) == 1 {
+	if len(entryPoints) == 0 {
+		// There's nothing to link except HTML entry points
+	} else if options.CodeSplitting || len(entryPoints) == 1 {
 		// If code splitting is enabled or if there's only one entry point, link all entry points together
+		reachableFiles := allReachableFiles
+		if len(htmlEntryPoints) > 0 {
+			reachableFiles = findReachableFiles(files, entryPoints)
+		}
 		resultGroups = [][]graph.OutputFile{link(&options, timer, log, b.fs, b.res,
-			files, b.entryPoints, b.uniqueKeyPrefix, allReachableFiles, dataForSourceMaps)}
+			files, entryPoints, b.uniqueKeyPrefix, reachableFiles, dataForSourceMaps, nil)}
 	} else {
 		// Otherwise, link each entry point with the runtime file separately
 		waitGroup := sync.WaitGroup{}
-		resultGroups = make([][]graph.OutputFile, len(b.entryPoints))
-		serializer := helpers.MakeSerializer(len(b.entryPoints))
-		for i, entryPoint := range b.entryPoints {
+		resultGroups = make([][]graph.OutputFile, len(entryPoints))
+		serializer := helpers.MakeSerializer(len(entryPoints))
+		for i, entryPoint := range entryPoints {
 			waitGroup.Add(1)
 			go func(i int, entryPoint graph.EntryPoint) {
 				entryPoints := []graph.EntryPoint{entryPoint}
@@ -2703,7 +3252,7 @@ func (b *Bundle) Compile(log logger.Log, timer *helpers.Timer, mangleCache map[s
 				}
 
 				resultGroups[i] = link(&optionsClone, forked, log, b.fs, b.res, files, entryPoints,
-					b.uniqueKeyPrefix, findReachableFiles(files, entryPoints), dataForSourceMaps)
+					b.uniqueKeyPrefix, findReachableFiles(files, entryPoints), dataForSourceMaps, nil)
 				timer.Join(forked)
 				waitGroup.Done()
 			}(i, entryPoint)
@@ -2711,6 +3260,24 @@ func (b *Bundle) Compile(log logger.Log, timer *helpers.Timer, mangleCache map[s
 		waitGroup.Wait()
 	}
 
+	// Now that the final paths of all other output files are known, link each
+	// HTML entry point separately. Each one only references its own assets.
+	if len(htmlEntryPoints) > 0 {
+		entryPointOutputs := make(map[uint32]graph.EntryPointOutput)
+		for _, group := range resultGroups {
+			for _, outputFile := range group {
+				if outputFile.EntryPoint != nil {
+					entryPointOutputs[outputFile.EntryPoint.SourceIndex] = *outputFile.EntryPoint
+				}
+			}
+		}
+		for _, entryPoint := range htmlEntryPoints {
+			entryPoints := []graph.EntryPoint{entryPoint}
+			resultGroups = append(resultGroups, link(&options, timer, log, b.fs, b.res, files, entryPoints,
+				b.uniqueKeyPrefix, findReachableFiles(files, entryPoints), dataForSourceMaps, entryPointOutputs))
+		}
+	}
+
 	// Join the results in entry point order for determinism
 	var outputFiles []graph.OutputFile
 	for _, group := range resultGroups {
@@ -2812,6 +3379,10 @@ func findReachableFiles(files []graph.InputFile, entryPoints []graph.EntryPoint)
 			}
 			if recordsPtr := file.Repr.ImportRecords(); recordsPtr != nil {
 				for _, record := range *recordsPtr {
+					if record.Kind == ast.ImportEntryPoint {
+						// Scripts and style sheets in HTML files are separate entry points
+						continue
+					}
 					if record.SourceIndex.IsValid() {
 						visit(record.SourceIndex.GetIndex())
 					} else if record.CopySourceIndex.IsValid() {
@@ -1339,6 +1339,262 @@ func TestSourceMap(t *testing.T) {
 	})
 }
 
+func TestSourceMapIgnoreList(t *testing.T) {
+	default_suite.expectBundled(t, bundled{
+		files: map[string]string{
+			"/Users/user/project/src/entry.js": `
+				import {bar} from 'pkg'
+				import {baz} from './vendor/baz.min.js'
+				bar(baz)
+			`,
+			"/Users/user/project/node_modules/pkg/index.js": `
+				export function bar(x) { throw new Error(x) }
+			`,
+			"/Users/user/project/src/vendor/baz.min.js": `
+				export let baz = 'baz'
+			`,
+		},
+		entryPaths: []string{"/Users/user/project/src/entry.js"},
+		options: config.Options{
+			Mode:          config.ModeBundle,
+			SourceMap:     config.SourceMapLinkedWithComment,
+			AbsOutputFile: "/Users/user/project/out.js",
+			SourcemapIgnoreList: []*regexp.Regexp{
+				helpers.GlobPatternToRegexp(helpers.ParseGlobPattern("**/node_modules/**")),
+				helpers.GlobPatternToRegexp(helpers.ParseGlobPattern("**/*.min.js")),
+			},
+		},
+	})
+}
+
+func TestSourceMapSections(t *testing.T) {
+	default_suite.expectBundled(t, bundled{
+		files: map[string]string{
+			"/entry.js": `
+				import {foo} from './foo'
+				import {bar} from './bar'
+				foo(bar)
+			`,
+			"/foo.js": `
+				export function foo(x) { console.log(x) }
+			`,
+			"/bar.js": `
+				export let bar = 123
+			`,
+		},
+		entryPaths: []string{"/entry.js"},
+		options: config.Options{
+			Mode:              config.ModeBundle,
+			SourceMap:         config.SourceMapLinkedWithComment,
+			AbsOutputFile:     "/out.js",
+			SourcemapSections: true,
+		},
+	})
+}
+
+func TestDebugIDs(t *testing.T) {
+	default_suite.expectBundled(t, bundled{
+		files: map[string]string{
+			"/entry.js": `#!/usr/bin/env node
+				'use strict'
+				import './entry.css'
+				import('./lazy').then(x => console.log(x))
+			`,
+			"/lazy.js": `
+				export default 123
+			`,
+			"/entry.css": `
+				a { color: red }
+			`,
+		},
+		entryPaths: []string{"/entry.js"},
+		options: config.Options{
+			Mode:              config.ModeBundle,
+			CodeSplitting:     true,
+			OutputFormat:      config.FormatESModule,
+			SourceMap:         config.SourceMapLinkedWithComment,
+			AbsOutputDir:      "/out",
+			ChunkPathTemplate: []config.PathTemplate{{Data: "./", Placeholder: config.NamePlaceholder}, {Data: "-", Placeholder: config.HashPlaceholder}},
+			DebugIDs:          true,
+		},
+	})
+}
+
+func TestPluginOnTransformChain(t *testing.T) {
+	appendCode := func(code string) func(config.OnTransformArgs) config.OnTransformResult {
+		return func(args config.OnTransformArgs) config.OnTransformResult {
+			contents := args.Contents + code
+			return config.OnTransformResult{Contents: &contents}
+		}
+	}
+
+	default_suite.expectBundled(t, bundled{
+		files: map[string]string{
+			"/entry.js": `
+				import data from './data.txt'
+				console.log(data)
+			`,
+			"/data.txt": `hello`,
+		},
+		entryPaths: []string{"/entry.js"},
+		options: config.Options{
+			Mode:          config.ModeBundle,
+			AbsOutputFile: "/out.js",
+			Plugins: []config.Plugin{
+				{
+					Name: "first",
+					OnTransform: []config.OnTransform{
+						{
+							// Turn text files into JavaScript
+							Filter: regexp.MustCompile(`\.txt$`),
+							Callback: func(args config.OnTransformArgs) config.OnTransformResult {
+								if args.Loader != config.LoaderText {
+									return config.OnTransformResult{}
+								}
+								contents := "export default " + string(helpers.QuoteSingle(strings.ToUpper(args.Contents), false)) + "\n"
+								return config.OnTransformResult{Contents: &contents, Loader: config.LoaderJS}
+							},
+						},
+						{Filter: regexp.MustCompile(`.`), Callback: appendCode("console.log('first')\n")},
+					},
+				},
+				{
+					Name: "second",
+					OnTransform: []config.OnTransform{
+						{Filter: regexp.MustCompile(`.`), Callback: appendCode("console.log('second')\n")},
+					},
+				},
+			},
+		},
+	})
+}
+
+func TestPluginOnTransformSourceMap(t *testing.T) {
+	// Each transform inserts a line at the top of the file
+	insertLine := func(code string) func(config.OnTransformArgs) config.OnTransformResult {
+		return func(args config.OnTransformArgs) config.OnTransformResult {
+			contents := code + "\n" + args.Contents
+			sourceMap := `{"version":3,"sources":["input.js"],"mappings":";AAAA;AACA;AACA","names":[]}`
+			return config.OnTransformResult{Contents: &contents, SourceMap: &sourceMap}
+		}
+	}
+
+	default_suite.expectBundled(t, bundled{
+		files: map[string]string{
+			"/Users/user/project/src/entry.js": "foo()\nbar()\n",
+		},
+		entryPaths: []string{"/Users/user/project/src/entry.js"},
+		options: config.Options{
+			Mode:          config.ModeBundle,
+			SourceMap:     config.SourceMapLinkedWithComment,
+			AbsOutputFile: "/Users/user/project/out.js",
+			Plugins: []config.Plugin{{
+				Name: "plugin",
+				OnTransform: []config.OnTransform{
+					{Filter: regexp.MustCompile(`.`), Callback: insertLine("first()")},
+					{Filter: regexp.MustCompile(`.`), Callback: insertLine("second()")},
+				},
+			}},
+		},
+	})
+}
+
+func TestPluginOnTransformError(t *testing.T) {
+	default_suite.expectBundled(t, bundled{
+		files: map[string]string{
+			"/entry.js": `console.log(123)`,
+		},
+		entryPaths: []string{"/entry.js"},
+		options: config.Options{
+			Mode:          config.ModeBundle,
+			AbsOutputFile: "/out.js",
+			Plugins: []config.Plugin{{
+				Name: "plugin",
+				OnTransform: []config.OnTransform{{
+					Filter: regexp.MustCompile(`.`),
+					Callback: func(args config.OnTransformArgs) config.OnTransformResult {
+						return config.OnTransformResult{Msgs: []logger.Msg{{Kind: logger.Error, Data: logger.MsgData{Text: "Cannot transform"}}}}
+					},
+				}},
+			}},
+		},
+		expectedScanLog: `ERROR: Cannot transform
+`,
+	})
+}
+
+func TestPluginOnRenderChunk(t *testing.T) {
+	default_suite.expectBundled(t, bundled{
+		files: map[string]string{
+			"/Users/user/project/src/entry.js": `
+				import('./other')
+				console.log(1)
+			`,
+			"/Users/user/project/src/other.js": `
+				console.log(2)
+			`,
+		},
+		entryPaths: []string{"/Users/user/project/src/entry.js"},
+		options: config.Options{
+			Mode:          config.ModeBundle,
+			OutputFormat:  config.FormatESModule,
+			CodeSplitting: true,
+			SourceMap:     config.SourceMapLinkedWithComment,
+			AbsOutputDir:  "/Users/user/project/out",
+			EntryPathTemplate: []config.PathTemplate{
+				{Data: "./", Placeholder: config.NamePlaceholder},
+				{Data: "-", Placeholder: config.HashPlaceholder},
+			},
+			Plugins: []config.Plugin{{
+				Name: "plugin",
+				OnRenderChunk: []config.OnRenderChunk{{
+					// Insert a line at the top of every chunk
+					Callback: func(args config.OnRenderChunkArgs) config.OnRenderChunkResult {
+						contents := "/* " + args.PathTemplate + " " + args.EntryPoint + " */\n" + args.Contents
+						sourceMap := `{"version":3,"sources":["chunk.js"],"mappings":";AAAA;AACA;AACA;AACA","names":[]}`
+						return config.OnRenderChunkResult{Contents: &contents, SourceMap: &sourceMap}
+					},
+				}},
+			}},
+		},
+	})
+}
+
+func TestPluginImportAttributes(t *testing.T) {
+	default_suite.expectBundled(t, bundled{
+		files: map[string]string{
+			"/entry.js": `
+				import a from './data.txt'
+				import b from './data.txt' with { type: 'upper' }
+				import c from './data.txt' assert { type: 'upper' }
+				console.log(a, b, c)
+			`,
+			"/data.txt": `hello`,
+		},
+		entryPaths: []string{"/entry.js"},
+		options: config.Options{
+			Mode:          config.ModeBundle,
+			AbsOutputFile: "/out.js",
+			Plugins: []config.Plugin{{
+				Name: "plugin",
+				OnLoad: []config.OnLoad{{
+					// The same file is loaded once for each set of import attributes
+					Filter: regexp.MustCompile(`\.txt$`),
+					Callback: func(args config.OnLoadArgs) config.OnLoadResult {
+						contents := "hello"
+						for _, attr := range args.Path.ImportAttributes.DecodeIntoArray() {
+							if attr.Key == "type" && attr.Value == "upper" {
+								contents = "HELLO"
+							}
+						}
+						return config.OnLoadResult{Contents: &contents, Loader: config.LoaderText}
+					},
+				}},
+			}},
+		},
+	})
+}
+
 // This test covers a bug where a "var" in a nested scope did not correctly
 // bind with references to that symbol in sibling scopes. Instead, the
 // references were incorrectly considered to be unbound even though the symbol
@@ -3816,9 +4072,6 @@ func TestTopLevelAwaitIIFE(t *testing.T) {
 			OutputFormat:  config.FormatIIFE,
 			AbsOutputFile: "/out.js",
 		},
-		expectedScanLog: `entry.js: ERROR: Top-level await is currently not supported with the "iife" output format
-entry.js: ERROR: Top-level await is currently not supported with the "iife" output format
-`,
 	})
 }
 
@@ -3853,9 +4106,6 @@ func TestTopLevelAwaitCJS(t *testing.T) {
 			OutputFormat:  config.FormatCommonJS,
 			AbsOutputFile: "/out.js",
 		},
-		expectedScanLog: `entry.js: ERROR: Top-level await is currently not supported with the "cjs" output format
-entry.js: ERROR: Top-level await is currently not supported with the "cjs" output format
-`,
 	})
 }
 
@@ -3988,9 +4238,6 @@ func TestTopLevelAwaitNoBundleCommonJS(t *testing.T) {
 			Mode:          config.ModeConvertFormat,
 			AbsOutputFile: "/out.js",
 		},
-		expectedScanLog: `entry.js: ERROR: Top-level await is currently not supported with the "cjs" output format
-entry.js: ERROR: Top-level await is currently not supported with the "cjs" output format
-`,
 	})
 }
 
@@ -4025,9 +4272,6 @@ func TestTopLevelAwaitNoBundleIIFE(t *testing.T) {
 			Mode:          config.ModeConvertFormat,
 			AbsOutputFile: "/out.js",
 		},
-		expectedScanLog: `entry.js: ERROR: Top-level await is currently not supported with the "iife" output format
-entry.js: ERROR: Top-level await is currently not supported with the "iife" output format
-`,
 	})
 }
 
@@ -4177,6 +4421,137 @@ func TestTopLevelAwaitAllowedImportWithSplitting(t *testing.T) {
 	})
 }
 
+func TestTopLevelAwaitCJSWithExports(t *testing.T) {
+	default_suite.expectBundled(t, bundled{
+		files: map[string]string{
+			"/entry.js": `
+				import { value } from './a'
+				export let result = value + await import('./b').then(b => b.default)
+			`,
+			"/a.js": `
+				export let value = await Promise.resolve(1)
+			`,
+			"/b.js": `
+				export default await 2
+			`,
+		},
+		entryPaths: []string{"/entry.js"},
+		options: config.Options{
+			Mode:          config.ModeBundle,
+			OutputFormat:  config.FormatCommonJS,
+			Platform:      config.PlatformNode,
+			AbsOutputFile: "/out.js",
+		},
+	})
+}
+
+func TestTopLevelAwaitIIFEWithGlobalName(t *testing.T) {
+	default_suite.expectBundled(t, bundled{
+		files: map[string]string{
+			"/entry.js": `
+				import { value } from './a'
+				export let result = value
+			`,
+			"/a.js": `
+				export let value = await Promise.resolve(1)
+			`,
+		},
+		entryPaths: []string{"/entry.js"},
+		options: config.Options{
+			Mode:          config.ModeBundle,
+			OutputFormat:  config.FormatIIFE,
+			GlobalName:    []string{"globalName"},
+			AbsOutputFile: "/out.js",
+		},
+	})
+}
+
+func TestTopLevelAwaitIIFENoArrow(t *testing.T) {
+	default_suite.expectBundled(t, bundled{
+		files: map[string]string{
+			"/entry.js": `
+				await foo
+			`,
+		},
+		entryPaths: []string{"/entry.js"},
+		options: config.Options{
+			Mode:                  config.ModeBundle,
+			OutputFormat:          config.FormatIIFE,
+			UnsupportedJSFeatures: compat.Arrow,
+			AbsOutputFile:         "/out.js",
+		},
+	})
+}
+
+func TestTopLevelAwaitCJSNoAsyncAwait(t *testing.T) {
+	default_suite.expectBundled(t, bundled{
+		files: map[string]string{
+			"/entry.js": `
+				await foo
+			`,
+		},
+		entryPaths: []string{"/entry.js"},
+		options: config.Options{
+			Mode:                  config.ModeBundle,
+			OutputFormat:          config.FormatCommonJS,
+			UnsupportedJSFeatures: compat.AsyncAwait,
+			AbsOutputFile:         "/out.js",
+		},
+		expectedScanLog: `entry.js: ERROR: Top-level await is not available in the configured target environment with the "cjs" output format
+`,
+	})
+}
+
+func TestTopLevelAwaitCJSWithSplitting(t *testing.T) {
+	default_suite.expectBundled(t, bundled{
+		files: map[string]string{
+			"/a.js": `
+				import { shared } from './shared'
+				console.log(await shared())
+			`,
+			"/b.js": `
+				import { shared } from './shared'
+				console.log(shared())
+			`,
+			"/shared.js": `
+				export function shared() { return 1 }
+			`,
+		},
+		entryPaths: []string{"/a.js", "/b.js"},
+		options: config.Options{
+			Mode:          config.ModeBundle,
+			OutputFormat:  config.FormatCommonJS,
+			CodeSplitting: true,
+			AbsOutputDir:  "/out",
+		},
+	})
+}
+
+func TestTopLevelAwaitCJSWithSplittingShared(t *testing.T) {
+	default_suite.expectBundled(t, bundled{
+		files: map[string]string{
+			"/a.js": `
+				import './shared'
+			`,
+			"/b.js": `
+				import './shared'
+			`,
+			"/shared.js": `
+				await 0
+			`,
+		},
+		entryPaths: []string{"/a.js", "/b.js"},
+		options: config.Options{
+			Mode:          config.ModeBundle,
+			OutputFormat:  config.FormatCommonJS,
+			CodeSplitting: true,
+			AbsOutputDir:  "/out",
+		},
+		expectedCompileLog: `shared.js: ERROR: Top-level await is currently not supported in code shared between entry points with the "cjs" output format
+`,
+	})
+}
+
 func TestAssignToImport(t *testing.T) {
 	default_suite.expectBundled(t, bundled{
 		files: map[string]string{
@@ -8030,6 +8405,44 @@ func TestMetafileVeryLongExternalPaths(t *testing.T) {
 	})
 }
 
+func TestMetafileIncludedBy(t *testing.T) {
+	default_suite.expectBundled(t, bundled{
+		files: map[string]string{
+			"/project/entry.js": `
+				import { used } from './lib'
+				import './side-effects'
+				import './style.css'
+				console.log(used())
+			`,
+			"/project/lib.js": `
+				import { helper } from './helper'
+				export function used() { return helper() }
+				export function unused() { return 'unused' }
+			`,
+			"/project/helper.js": `
+				export let helper = () => 'helper'
+			`,
+			"/project/side-effects.js": `
+				console.log('side effects')
+			`,
+			"/project/style.css": `
+				@import "./base.css";
+				a { color: red }
+			`,
+			"/project/base.css": `
+				body { margin: 0 }
+			`,
+		},
+		entryPaths: []string{"/project/entry.js"},
+		options: config.Options{
+			Mode:                    config.ModeBundle,
+			AbsOutputDir:            "/out",
+			NeedsMetafile:           true,
+			NeedsMetafileIncludedBy: true,
+		},
+	})
+}
+
 func TestCommentPreservation(t *testing.T) {
 	default_suite.expectBundled(t, bundled{
 		files: map[string]string{
@@ -8448,3 +8861,56 @@ NOTE: You can mark the path "bar" as external to exclude it from the bundle, whi
 `,
 	})
 }
+
+func TestHMR(t *testing.T) {
+	default_suite.expectBundled(t, bundled{
+		files: map[string]string{
+			"/entry.js": `
+				import { count, inc } from './counter'
+				import def, * as ns from './counter'
+				import cjs from './cjs'
+				inc()
+				console.log(count, def, ns, cjs)
+				if (import.meta.hot) import.meta.hot.accept()
+			`,
+			"/counter.js": `
+				export let count = 0
+				export function inc() { count++ }
+				export let unused = 1
+				export default 'default'
+				import.meta.hot.dispose(data => { data.count = count })
+			`,
+			"/cjs.js": `
+				module.exports = 123
+			`,
+		},
+		entryPaths: []string{"/entry.js"},
+		options: config.Options{
+			Mode:          config.ModeBundle,
+			OutputFormat:  config.FormatESModule,
+			AbsOutputFile: "/out.js",
+			HMR:           true,
+		},
+	})
+}
+
+func TestHMREntryPointWithExports(t *testing.T) {
+	default_suite.expectBundled(t, bundled{
+		files: map[string]string{
+			"/entry.js": `
+				import { value } from './value'
+				export let doubled = value * 2
+			`,
+			"/value.js": `
+				export let value = 1
+			`,
+		},
+		entryPaths: []string{"/entry.js"},
+		options: config.Options{
+			Mode:          config.ModeBundle,
+			OutputFormat:  config.FormatIIFE,
+			AbsOutputFile: "/out.js",
+			HMR:           true,
+		},
+	})
+}
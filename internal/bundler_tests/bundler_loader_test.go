@@ -1537,3 +1537,247 @@ func TestLoaderCopyWithInjectedFileBundle(t *testing.T) {
 		},
 	})
 }
+
+func TestLoaderHTML(t *testing.T) {
+	loader_suite.expectBundled(t, bundled{
+		files: map[string]string{
+			"/src/index.html": `<!DOCTYPE html>
+<html>
+  <head>
+    <!-- <script src="commented-out.js"></script> -->
+    <link rel="icon" href="icon.png">
+    <link rel="stylesheet" href="global.css">
+    <script src="entry.js"></script>
+    <script type="importmap">{ "imports": {} }</script>
+    <script>if (a < b) document.write("<img src='inline.png'>")</script>
+  </head>
+  <body>
+    <img src="./icon.png" srcset="icon.png 1x, ./icon.png?x=1&amp;y=2 2x">
+    <img src="https://example.com/remote.png">
+    <a href="#top">top</a>
+  </body>
+</html>
+`,
+			"/src/entry.js":   `import './entry.css'; console.log('entry')`,
+			"/src/entry.css":  `body { color: red }`,
+			"/src/global.css": `html { margin: 0 }`,
+			"/src/icon.png":   `PNG`,
+		},
+		entryPaths: []string{"/src/index.html"},
+		options: config.Options{
+			Mode:         config.ModeBundle,
+			AbsOutputDir: "/out",
+			EntryPathTemplate: []config.PathTemplate{
+				{Data: "./", Placeholder: config.NamePlaceholder},
+				{Data: "-", Placeholder: config.HashPlaceholder},
+			},
+			ExtensionToLoader: map[string]config.Loader{
+				".html": config.LoaderHTML,
+				".js":   config.LoaderJS,
+				".css":  config.LoaderCSS,
+				".png":  config.LoaderFile,
+			},
+		},
+	})
+}
+
+func TestLoaderHTMLSplitting(t *testing.T) {
+	loader_suite.expectBundled(t, bundled{
+		files: map[string]string{
+			"/src/index.html": `<html>
+  <head>
+    <script type="module" src="a.js"></script>
+    <script type="module" src="b.js"></script>
+  </head>
+</html>
+`,
+			"/src/a.js":      `import { shared } from './shared.js'; shared('a'); import('./lazy.js')`,
+			"/src/b.js":      `import { shared } from './shared.js'; shared('b')`,
+			"/src/shared.js": `export function shared(x) { console.log(x) }`,
+			"/src/lazy.js":   `console.log('lazy')`,
+		},
+		entryPaths: []string{"/src/index.html"},
+		options: config.Options{
+			Mode:          config.ModeBundle,
+			AbsOutputDir:  "/out",
+			CodeSplitting: true,
+			OutputFormat:  config.FormatESModule,
+			ExtensionToLoader: map[string]config.Loader{
+				".html": config.LoaderHTML,
+				".js":   config.LoaderJS,
+			},
+		},
+	})
+}
+
+func TestLoaderHTMLErrors(t *testing.T) {
+	loader_suite.expectBundled(t, bundled{
+		files: map[string]string{
+			"/src/index.html": `<script src="style.css"></script><link rel="stylesheet" href="script.js">`,
+			"/src/entry.js":   `import './index.html'`,
+			"/src/style.css":  `a { color: red }`,
+			"/src/script.js":  `console.log(1)`,
+		},
+		entryPaths: []string{"/src/index.html", "/src/entry.js"},
+		options: config.Options{
+			Mode:         config.ModeBundle,
+			AbsOutputDir: "/out",
+			ExtensionToLoader: map[string]config.Loader{
+				".html": config.LoaderHTML,
+				".js":   config.LoaderJS,
+				".css":  config.LoaderCSS,
+			},
+		},
+		expectedScanLog: `src/entry.js: ERROR: Cannot import "src/index.html" because HTML files can only be entry points
+src/index.html: ERROR: Cannot use "src/style.css" as a script
+NOTE: A "<script>" tag can only reference a JavaScript file and "src/style.css" is not a JavaScript file (it was loaded with the "css" loader).
+src/index.html: ERROR: Cannot use "src/script.js" as a style sheet
+NOTE: A "<link rel="stylesheet">" tag can only reference a CSS file and "src/script.js" is not a CSS file (it was loaded with the "js" loader).
+`,
+	})
+}
+
+func TestLoaderHTMLOutfile(t *testing.T) {
+	loader_suite.expectBundled(t, bundled{
+		files: map[string]string{
+			"/src/index.html": `<script src="entry.js"></script>`,
+			"/src/entry.js":   `console.log(1)`,
+		},
+		entryPaths: []string{"/src/index.html"},
+		options: config.Options{
+			Mode:          config.ModeBundle,
+			AbsOutputFile: "/out/index.html",
+			ExtensionToLoader: map[string]config.Loader{
+				".html": config.LoaderHTML,
+				".js":   config.LoaderJS,
+			},
+		},
+		expectedScanLog: `src/index.html: ERROR: Cannot bundle "src/entry.js" without an output directory (use "Outdir" instead)
+`,
+	})
+}
+
+func TestLoaderHTMLWithoutBundling(t *testing.T) {
+	loader_suite.expectBundled(t, bundled{
+		files: map[string]string{
+			"/src/index.html": `<script src="entry.js"></script>`,
+		},
+		entryPaths: []string{"/src/index.html"},
+		options: config.Options{
+			Mode:         config.ModePassThrough,
+			AbsOutputDir: "/out",
+			ExtensionToLoader: map[string]config.Loader{
+				".html": config.LoaderHTML,
+			},
+		},
+		expectedScanLog: `ERROR: Cannot use the "html" loader without bundling: src/index.html
+`,
+	})
+}
+
+// A WebAssembly module that imports the function "log" from "./log.js" and
+// exports the function "add", the memory "memory", and the function "not valid"
+const wasmAddModule = "\x00asm\x01\x00\x00\x00" +
+	"\x01\x0b\x02\x60\x01\x7f\x00\x60\x02\x7f\x7f\x01\x7f" + // Type section
+	"\x02\x10\x01\x08./log.js\x03log\x00\x00" + // Import section
+	"\x03\x02\x01\x01" + // Function section
+	"\x05\x03\x01\x00\x01" + // Memory section
+	"\x07\x1c\x03\x03add\x00\x01\x06memory\x02\x00\x09not valid\x00\x01" + // Export section
+	"\x0a\x0d\x01\x0b\x00\x20\x00\x10\x00\x20\x00\x20\x01\x6a\x0b" // Code section
+
+func TestLoaderWasm(t *testing.T) {
+	loader_suite.expectBundled(t, bundled{
+		files: map[string]string{
+			"/entry.js": `
+				import { add, memory } from './add.wasm'
+				import * as ns from './add.wasm'
+				console.log(add(1, 2), memory, ns['not valid'])
+			`,
+			"/add.wasm": wasmAddModule,
+			"/log.js":   `export function log(x) { console.log(x) }`,
+		},
+		entryPaths: []string{"/entry.js"},
+		options: config.Options{
+			Mode:         config.ModeBundle,
+			OutputFormat: config.FormatESModule,
+			AbsOutputDir: "/out",
+			ExtensionToLoader: map[string]config.Loader{
+				".js":   config.LoaderJS,
+				".wasm": config.LoaderWasm,
+			},
+		},
+	})
+}
+
+func TestLoaderWasmPlatformNode(t *testing.T) {
+	loader_suite.expectBundled(t, bundled{
+		files: map[string]string{
+			"/entry.js": `
+				import { add } from './add.wasm'
+				console.log(add(1, 2))
+			`,
+			"/add.wasm": wasmAddModule,
+			"/log.js":   `export function log(x) { console.log(x) }`,
+		},
+		entryPaths: []string{"/entry.js"},
+		options: config.Options{
+			Mode:         config.ModeBundle,
+			OutputFormat: config.FormatESModule,
+			Platform:     config.PlatformNode,
+			AbsOutputDir: "/out",
+			ExtensionToLoader: map[string]config.Loader{
+				".js":   config.LoaderJS,
+				".wasm": config.LoaderWasm,
+			},
+		},
+	})
+}
+
+func TestLoaderWasmWithoutBundling(t *testing.T) {
+	loader_suite.expectBundled(t, bundled{
+		files: map[string]string{
+			"/add.wasm": wasmAddModule,
+		},
+		entryPaths: []string{"/add.wasm"},
+		options: config.Options{
+			Mode:         config.ModePassThrough,
+			AbsOutputDir: "/out",
+			ExtensionToLoader: map[string]config.Loader{
+				".wasm": config.LoaderWasm,
+			},
+		},
+	})
+}
+
+func TestLoaderWasmErrors(t *testing.T) {
+	loader_suite.expectBundled(t, bundled{
+		files: map[string]string{
+			"/entry.js": `
+				import './add.wasm'
+				import './invalid.wasm'
+				import './truncated.wasm'
+				require('./other.wasm')
+			`,
+			"/add.wasm":       wasmAddModule,
+			"/invalid.wasm":   "\x00asm\x02\x00\x00\x00",
+			"/truncated.wasm": wasmAddModule[:30],
+			"/other.wasm":     "\x00asm\x01\x00\x00\x00",
+		},
+		entryPaths: []string{"/entry.js"},
+		options: config.Options{
+			Mode:         config.ModeBundle,
+			OutputFormat: config.FormatESModule,
+			AbsOutputDir: "/out",
+			ExtensionToLoader: map[string]config.Loader{
+				".js":   config.LoaderJS,
+				".wasm": config.LoaderWasm,
+			},
+		},
+		expectedScanLog: `add.wasm: ERROR: Could not resolve "./log.js"
+entry.js: ERROR: Invalid WebAssembly module invalid.wasm: Unsupported version 2
+entry.js: ERROR: Invalid WebAssembly module truncated.wasm: Unexpected end of file
+entry.js: ERROR: This require call is not allowed because the imported file "other.wasm" contains a top-level await
+other.wasm: NOTE: The top-level await in "other.wasm" is here:
+`,
+	})
+}
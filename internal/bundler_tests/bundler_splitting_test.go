@@ -3,6 +3,7 @@ package bundler_tests
 import (
 	"testing"
 
+	"github.com/evanw/esbuild/internal/compat"
 	"github.com/evanw/esbuild/internal/config"
 )
 
@@ -597,3 +598,330 @@ func TestEdgeCaseIssue2793WithoutSplitting(t *testing.T) {
 		},
 	})
 }
+
+func TestSplittingManualChunks(t *testing.T) {
+	splitting_suite.expectBundled(t, bundled{
+		files: map[string]string{
+			"/a.js": `
+				import React from "react"
+				import { debounce } from "lodash"
+				import { shared } from "./shared"
+				console.log(React, debounce, shared)
+			`,
+			"/b.js": `
+				import React from "react"
+				import { format } from "@scope/format/lib"
+				import { shared } from "./shared"
+				console.log(React, format, shared)
+			`,
+			"/shared.js": `
+				export let shared = 123
+			`,
+			"/node_modules/react/index.js": `
+				export default { createElement() {} }
+			`,
+			"/node_modules/lodash/index.js": `
+				export let debounce = () => {}
+			`,
+			"/node_modules/@scope/format/lib/index.js": `
+				export let format = () => {}
+			`,
+		},
+		entryPaths: []string{"/a.js", "/b.js"},
+		options: config.Options{
+			Mode:          config.ModeBundle,
+			OutputFormat:  config.FormatESModule,
+			CodeSplitting: true,
+			AbsOutputDir:  "/out",
+			ManualChunks: []config.ManualChunk{
+				{
+					Name:     "vendor",
+					Packages: config.ExternalMatchers{Exact: map[string]bool{"react": true, "lodash": true}, Patterns: []config.WildcardPattern{{Prefix: "@scope/"}}},
+				},
+			},
+		},
+	})
+}
+
+func TestSplittingManualChunksPathsMetafile(t *testing.T) {
+	splitting_suite.expectBundled(t, bundled{
+		files: map[string]string{
+			"/entry.js": `
+				import { a } from "./lib/a"
+				import { b } from "./lib/b"
+				import { c } from "./other/c"
+				import("./lazy").then(console.log)
+				console.log(a, b, c)
+			`,
+			"/lazy.js": `
+				import { b } from "./lib/b"
+				export let lazy = b
+			`,
+			"/lib/a.js": `
+				export let a = 1
+			`,
+			"/lib/b.js": `
+				export let b = 2
+			`,
+			"/other/c.js": `
+				export let c = 3
+			`,
+		},
+		entryPaths: []string{"/entry.js"},
+		options: config.Options{
+			Mode:          config.ModeBundle,
+			OutputFormat:  config.FormatESModule,
+			CodeSplitting: true,
+			NeedsMetafile: true,
+			AbsOutputDir:  "/out",
+			ManualChunks: []config.ManualChunk{
+				{
+					Name:  "lib",
+					Paths: config.ExternalMatchers{Patterns: []config.WildcardPattern{{Prefix: "/lib/", Suffix: ".js"}}},
+				},
+				{
+					Name:  "other",
+					Paths: config.ExternalMatchers{Exact: map[string]bool{"/other/c.js": true}},
+				},
+			},
+		},
+	})
+}
+
+func TestSplittingManualChunksPullInDependencies(t *testing.T) {
+	splitting_suite.expectBundled(t, bundled{
+		files: map[string]string{
+			"/a.js": `
+				import { widget } from "ui"
+				import { helper } from "./helper"
+				console.log(widget, helper)
+			`,
+			"/b.js": `
+				import("./a")
+			`,
+			"/helper.js": `
+				export let helper = 1
+			`,
+			"/node_modules/ui/index.js": `
+				import { helper } from "../../helper"
+				export let widget = helper + 1
+			`,
+		},
+		entryPaths: []string{"/a.js", "/b.js"},
+		options: config.Options{
+			Mode:          config.ModeBundle,
+			OutputFormat:  config.FormatESModule,
+			CodeSplitting: true,
+			AbsOutputDir:  "/out",
+			ManualChunks: []config.ManualChunk{
+				{
+					Name:     "vendor",
+					Packages: config.ExternalMatchers{Exact: map[string]bool{"ui": true}},
+				},
+			},
+		},
+	})
+}
+
+func TestSplittingManualChunksCycle(t *testing.T) {
+	splitting_suite.expectBundled(t, bundled{
+		files: map[string]string{
+			"/entry.js": `
+				import { a } from "pa"
+				console.log(a)
+			`,
+			"/node_modules/pa/index.js": `
+				import { b } from "pb"
+				export let a = () => b
+			`,
+			"/node_modules/pb/index.js": `
+				import { a } from "pa"
+				export let b = () => a
+			`,
+		},
+		entryPaths: []string{"/entry.js"},
+		options: config.Options{
+			Mode:          config.ModeBundle,
+			OutputFormat:  config.FormatESModule,
+			CodeSplitting: true,
+			AbsOutputDir:  "/out",
+			ManualChunks: []config.ManualChunk{
+				{
+					Name:     "A",
+					Packages: config.ExternalMatchers{Exact: map[string]bool{"pa": true}},
+				},
+				{
+					Name:     "B",
+					Packages: config.ExternalMatchers{Exact: map[string]bool{"pb": true}},
+				},
+			},
+		},
+		expectedCompileLog: `ERROR: Manual chunks "A" and "B" cannot import each other
+node_modules/pa/index.js: NOTE: Manual chunk "A" imports manual chunk "B" here:
+node_modules/pb/index.js: NOTE: Manual chunk "B" imports manual chunk "A" here:
+NOTE: Put these files in the same manual chunk to avoid this problem.
+`,
+	})
+}
+
+func TestSplittingManualChunksImportEntryPoint(t *testing.T) {
+	splitting_suite.expectBundled(t, bundled{
+		files: map[string]string{
+			"/src/a.js": `
+				import { lib } from "lib"
+				import { util } from "util"
+				export let a = () => lib() + util()
+			`,
+			"/src/b.js": `
+				import { lib } from "lib"
+				import { util } from "util"
+				console.log(lib(), util())
+			`,
+			"/node_modules/lib/index.js": `
+				import { a } from "../../src/a.js"
+				export let lib = () => a
+			`,
+			"/node_modules/util/index.js": `
+				export let util = () => 123
+			`,
+		},
+		entryPaths: []string{"/src/a.js", "/src/b.js"},
+		options: config.Options{
+			Mode:          config.ModeBundle,
+			OutputFormat:  config.FormatESModule,
+			CodeSplitting: true,
+			AbsOutputDir:  "/out",
+			ManualChunks: []config.ManualChunk{
+				{
+					Name:     "vendor",
+					Packages: config.ExternalMatchers{Exact: map[string]bool{"lib": true, "util": true}},
+				},
+			},
+		},
+	})
+}
+
+func TestSplittingManualChunksCommonJS(t *testing.T) {
+	splitting_suite.expectBundled(t, bundled{
+		files: map[string]string{
+			"/entry.js": `
+				import value from "pkg"
+				console.log(value)
+			`,
+			"/node_modules/pkg/index.js": `
+				module.exports = 123
+			`,
+		},
+		entryPaths: []string{"/entry.js"},
+		options: config.Options{
+			Mode:          config.ModeBundle,
+			OutputFormat:  config.FormatESModule,
+			CodeSplitting: true,
+			AbsOutputDir:  "/out",
+			ManualChunks: []config.ManualChunk{
+				{
+					Name:     "vendor",
+					Packages: config.ExternalMatchers{Exact: map[string]bool{"pkg": true}},
+				},
+			},
+		},
+	})
+}
+
+func TestSplittingFormatCommonJS(t *testing.T) {
+	splitting_suite.expectBundled(t, bundled{
+		files: map[string]string{
+			"/a.js": `
+				import { count, increment } from "./shared"
+				increment()
+				console.log(count)
+				export let loadLazy = () => import("./lazy")
+			`,
+			"/b.js": `
+				import { count } from "./shared"
+				import value from "./cjs"
+				console.log(count, value)
+			`,
+			"/shared.js": `
+				export let count = 0
+				export function increment() { count++ }
+			`,
+			"/cjs.js": `
+				module.exports = 123
+			`,
+			"/lazy.js": `
+				import value from "./cjs"
+				export default value
+			`,
+		},
+		entryPaths: []string{"/a.js", "/b.js"},
+		options: config.Options{
+			Mode:          config.ModeBundle,
+			OutputFormat:  config.FormatCommonJS,
+			CodeSplitting: true,
+			AbsOutputDir:  "/out",
+		},
+	})
+}
+
+func TestSplittingFormatIIFE(t *testing.T) {
+	splitting_suite.expectBundled(t, bundled{
+		files: map[string]string{
+			"/a.js": `
+				import { count, increment } from "./shared"
+				increment()
+				console.log(count)
+				export let loadLazy = () => import("./lazy")
+			`,
+			"/b.js": `
+				import { count } from "./shared"
+				import value from "./cjs"
+				console.log(count, value)
+			`,
+			"/shared.js": `
+				export let count = 0
+				export function increment() { count++ }
+			`,
+			"/cjs.js": `
+				module.exports = 123
+			`,
+			"/lazy.js": `
+				import value from "./cjs"
+				export default value
+			`,
+		},
+		entryPaths: []string{"/a.js", "/b.js"},
+		options: config.Options{
+			Mode:          config.ModeBundle,
+			OutputFormat:  config.FormatIIFE,
+			CodeSplitting: true,
+			AbsOutputDir:  "/out",
+		},
+	})
+}
+
+func TestSplittingFormatIIFENoArrow(t *testing.T) {
+	splitting_suite.expectBundled(t, bundled{
+		files: map[string]string{
+			"/a.js": `
+				import { shared } from "./shared"
+				console.log(shared)
+			`,
+			"/b.js": `
+				import { shared } from "./shared"
+				console.log(shared)
+			`,
+			"/shared.js": `
+				export function shared() {}
+			`,
+		},
+		entryPaths: []string{"/a.js", "/b.js"},
+		options: config.Options{
+			Mode:                  config.ModeBundle,
+			OutputFormat:          config.FormatIIFE,
+			CodeSplitting:         true,
+			AbsOutputDir:          "/out",
+			UnsupportedJSFeatures: compat.Arrow,
+		},
+	})
+}
@@ -150,6 +150,20 @@ func (s *suite) __expectBundledImpl(t *testing.T, args bundled, fsKind fs.MockKi
 			}
 			args.options.ExternalSettings.PostResolve.Exact = replace
 
+			for i, manualChunk := range args.options.ManualChunks {
+				replace := make(map[string]bool)
+				for k, v := range manualChunk.Paths.Exact {
+					replace[unix2win(k)] = v
+				}
+				manualChunk.Paths.Exact = replace
+				patterns := make([]config.WildcardPattern, len(manualChunk.Paths.Patterns))
+				for j, pattern := range manualChunk.Paths.Patterns {
+					patterns[j] = config.WildcardPattern{Prefix: unix2win(pattern.Prefix), Suffix: unix2win(pattern.Suffix)}
+				}
+				manualChunk.Paths.Patterns = patterns
+				args.options.ManualChunks[i] = manualChunk
+			}
+
 			args.options.AbsOutputFile = unix2win(args.options.AbsOutputFile)
 			args.options.AbsOutputBase = unix2win(args.options.AbsOutputBase)
 			args.options.AbsOutputDir = unix2win(args.options.AbsOutputDir)
@@ -60,6 +60,14 @@ func MakeCacheSet() *CacheSet {
 	}
 }
 
+// This makes parse results persist across processes by storing them in the
+// given directory (in addition to keeping them in memory)
+func (c *CacheSet) EnableDiskCache(disk *DiskCache) {
+	c.CSSCache.disk = disk
+	c.JSONCache.disk = disk
+	c.JSCache.disk = disk
+}
+
 type SourceIndexCache struct {
 	globEntries     map[uint64]uint32
 	entries         map[sourceIndexKey]uint32
@@ -28,6 +28,7 @@ import (
 
 type CSSCache struct {
 	entries map[logger.Path]*cssCacheEntry
+	disk    *DiskCache
 	mutex   sync.Mutex
 }
 
@@ -54,10 +55,30 @@ func (c *CSSCache) Parse(log logger.Log, source logger.Source, options css_parse
 		return entry.ast
 	}
 
+	// Check the disk cache, if there is one
+	var ast css_ast.AST
+	var msgs []logger.Msg
+	var diskKey []byte
+	var foundOnDisk bool
+	if c.disk != nil {
+		if diskKey = c.disk.cssKey(source, &options, log.Overrides); diskKey != nil {
+			if payload, found := c.disk.read(diskCacheKindCSS, diskKey); found {
+				ast, msgs, foundOnDisk = decodeCSSCacheEntry(payload, source.Index)
+			}
+		}
+	}
+
 	// Cache miss
-	tempLog := logger.NewDeferLog(logger.DeferLogAll, log.Overrides)
-	ast := css_parser.Parse(tempLog, source, options)
-	msgs := tempLog.Done()
+	if !foundOnDisk {
+		tempLog := logger.NewDeferLog(logger.DeferLogAll, log.Overrides)
+		ast = css_parser.Parse(tempLog, source, options)
+		msgs = tempLog.Done()
+		if diskKey != nil {
+			if payload, canEncode := encodeCSSCacheEntry(ast, msgs, source.Index); canEncode {
+				c.disk.write(diskCacheKindCSS, diskKey, payload)
+			}
+		}
+	}
 	for _, msg := range msgs {
 		log.AddMsg(msg)
 	}
@@ -82,6 +103,7 @@ func (c *CSSCache) Parse(log logger.Log, source logger.Source, options css_parse
 
 type JSONCache struct {
 	entries map[logger.Path]*jsonCacheEntry
+	disk    *DiskCache
 	mutex   sync.Mutex
 }
 
@@ -109,10 +131,30 @@ func (c *JSONCache) Parse(log logger.Log, source logger.Source, options js_parse
 		return entry.expr, entry.ok
 	}
 
+	// Check the disk cache, if there is one
+	var expr js_ast.Expr
+	var msgs []logger.Msg
+	var ok bool
+	var diskKey []byte
+	var foundOnDisk bool
+	if c.disk != nil {
+		diskKey = diskCacheJSONKey(source, options, log.Overrides)
+		if payload, found := c.disk.read(diskCacheKindJSON, diskKey); found {
+			expr, ok, msgs, foundOnDisk = decodeJSONCacheEntry(payload)
+		}
+	}
+
 	// Cache miss
-	tempLog := logger.NewDeferLog(logger.DeferLogAll, log.Overrides)
-	expr, ok := js_parser.ParseJSON(tempLog, source, options)
-	msgs := tempLog.Done()
+	if !foundOnDisk {
+		tempLog := logger.NewDeferLog(logger.DeferLogAll, log.Overrides)
+		expr, ok = js_parser.ParseJSON(tempLog, source, options)
+		msgs = tempLog.Done()
+		if c.disk != nil {
+			if payload, canEncode := encodeJSONCacheEntry(expr, ok, msgs); canEncode {
+				c.disk.write(diskCacheKindJSON, diskKey, payload)
+			}
+		}
+	}
 	for _, msg := range msgs {
 		log.AddMsg(msg)
 	}
@@ -138,6 +180,7 @@ func (c *JSONCache) Parse(log logger.Log, source logger.Source, options js_parse
 
 type JSCache struct {
 	entries map[logger.Path]*jsCacheEntry
+	disk    *DiskCache
 	mutex   sync.Mutex
 }
 
@@ -165,10 +208,31 @@ func (c *JSCache) Parse(log logger.Log, source logger.Source, options js_parser.
 		return entry.ast, entry.ok
 	}
 
+	// Check the disk cache, if there is one
+	var ast js_ast.AST
+	var msgs []logger.Msg
+	var ok bool
+	var diskKey []byte
+	var foundOnDisk bool
+	if c.disk != nil {
+		if diskKey = c.disk.jsKey(source, &options, log.Overrides); diskKey != nil {
+			if payload, found := c.disk.read(diskCacheKindJS, diskKey); found {
+				ast, ok, msgs, foundOnDisk = decodeJSCacheEntry(payload, source.Index)
+			}
+		}
+	}
+
 	// Cache miss
-	tempLog := logger.NewDeferLog(logger.DeferLogAll, log.Overrides)
-	ast, ok := js_parser.Parse(tempLog, source, options)
-	msgs := tempLog.Done()
+	if !foundOnDisk {
+		tempLog := logger.NewDeferLog(logger.DeferLogAll, log.Overrides)
+		ast, ok = js_parser.Parse(tempLog, source, options)
+		msgs = tempLog.Done()
+		if diskKey != nil {
+			if payload, canEncode := encodeJSCacheEntry(ast, ok, msgs, source.Index); canEncode {
+				c.disk.write(diskCacheKindJS, diskKey, payload)
+			}
+		}
+	}
 	for _, msg := range msgs {
 		log.AddMsg(msg)
 	}
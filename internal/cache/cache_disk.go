@@ -0,0 +1,515 @@
+package cache
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"io/ioutil"
+	"math"
+	"os"
+	"runtime/debug"
+	"sort"
+	"strconv"
+	"sync"
+
+	"github.com/evanw/esbuild/internal/config"
+	"github.com/evanw/esbuild/internal/fs"
+	"github.com/evanw/esbuild/internal/js_ast"
+	"github.com/evanw/esbuild/internal/js_parser"
+	"github.com/evanw/esbuild/internal/logger"
+	"github.com/evanw/esbuild/internal/xxhash"
+)
+
+// This is an optional persistent layer underneath the in-memory caches. It
+// lets separate esbuild processes (e.g. consecutive CI jobs or CLI runs) share
+// parse results for files that haven't changed. Entries are keyed by a hash of
+// the file contents, the file path, and every parser option that can affect
+// the result, so a stale entry can never be returned for a changed input.
+//
+// The cache directory is namespaced by a fingerprint of the esbuild build and
+// the serialization format below. Entries written by a different version of
+// esbuild are therefore never read, and the old directories can be deleted
+// manually at any time. Any error while reading or writing the cache is
+// silently treated as a cache miss since the cache is only an optimization.
+//
+// JSON ASTs are encoded by hand below. JS and CSS ASTs are encoded generically
+// in "cache_disk_ast.go".
+
+// Bump this whenever the encoding of any cache entry changes
+const diskCacheFormatVersion = 2
+
+var diskCacheMagic = []byte("esbuild-disk-cache\x00")
+
+type DiskCache struct {
+	fs  fs.FS
+	dir string
+
+	// See "appendOptionsToKey" for what this is used for
+	mutex         sync.Mutex
+	definesHashes map[*config.ProcessedDefines][]byte
+}
+
+func MakeDiskCache(fs fs.FS, cacheDir string) *DiskCache {
+	return &DiskCache{
+		fs:            fs,
+		dir:           fs.Join(cacheDir, "esbuild-"+diskCacheFingerprint()),
+		definesHashes: make(map[*config.ProcessedDefines][]byte),
+	}
+}
+
+// The fingerprint identifies the code that produced a cache entry. Released
+// builds are identified by their module version. Development builds don't
+// have a version, so the executable's modification time is used instead,
+// which means rebuilding esbuild invalidates the cache.
+func diskCacheFingerprint() string {
+	hash := sha256.New()
+	hash.Write([]byte(strconv.Itoa(diskCacheFormatVersion)))
+	if info, ok := debug.ReadBuildInfo(); ok {
+		for _, dep := range append([]*debug.Module{&info.Main}, info.Deps...) {
+			if dep.Path == "github.com/evanw/esbuild" {
+				hash.Write([]byte("\x00" + dep.Version + "\x00" + dep.Sum))
+			}
+		}
+	}
+	if exe, err := os.Executable(); err == nil {
+		if stat, err := os.Stat(exe); err == nil {
+			hash.Write([]byte("\x00" + exe + "\x00" + stat.ModTime().String()))
+		}
+	}
+	return hex.EncodeToString(hash.Sum(nil)[:8])
+}
+
+func (c *DiskCache) entryPath(kind string, keyHash []byte) string {
+	name := hex.EncodeToString(keyHash)
+	return c.fs.Join(c.dir, kind, name[:2], name[2:])
+}
+
+func (c *DiskCache) read(kind string, key []byte) ([]byte, bool) {
+	keyHash := sha256.Sum256(key)
+	fs.BeforeFileOpen()
+	defer fs.AfterFileClose()
+	buffer, err := ioutil.ReadFile(c.entryPath(kind, keyHash[:]))
+	if err != nil {
+		return nil, false
+	}
+
+	// Validate the header, the full key hash, and the payload checksum. This
+	// guards against hash prefix collisions and against truncated entries.
+	if !bytes.HasPrefix(buffer, diskCacheMagic) {
+		return nil, false
+	}
+	buffer = buffer[len(diskCacheMagic):]
+	if len(buffer) < len(keyHash)+8 || !bytes.Equal(buffer[:len(keyHash)], keyHash[:]) {
+		return nil, false
+	}
+	buffer = buffer[len(keyHash):]
+	checksum := binary.LittleEndian.Uint64(buffer)
+	payload := buffer[8:]
+	if xxhash.Sum64(payload) != checksum {
+		return nil, false
+	}
+	return payload, true
+}
+
+func (c *DiskCache) write(kind string, key []byte, payload []byte) {
+	keyHash := sha256.Sum256(key)
+	path := c.entryPath(kind, keyHash[:])
+	var checksum [8]byte
+	binary.LittleEndian.PutUint64(checksum[:], xxhash.Sum64(payload))
+	buffer := make([]byte, 0, len(diskCacheMagic)+len(keyHash)+len(checksum)+len(payload))
+	buffer = append(buffer, diskCacheMagic...)
+	buffer = append(buffer, keyHash[:]...)
+	buffer = append(buffer, checksum[:]...)
+	buffer = append(buffer, payload...)
+
+	fs.BeforeFileOpen()
+	defer fs.AfterFileClose()
+	if err := fs.MkdirAll(c.fs, c.fs.Dir(path), 0755); err != nil {
+		return
+	}
+
+	// Write to a temporary file and then rename it into place so that other
+	// processes reading the cache concurrently never see a partial entry
+	temp, err := ioutil.TempFile(c.fs.Dir(path), ".tmp-")
+	if err != nil {
+		return
+	}
+	_, writeErr := temp.Write(buffer)
+	closeErr := temp.Close()
+	if writeErr != nil || closeErr != nil || os.Rename(temp.Name(), path) != nil {
+		os.Remove(temp.Name())
+	}
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// Serialization helpers
+
+type diskEncoder struct {
+	buffer []byte
+}
+
+func (e *diskEncoder) uint32(value uint32) {
+	var bytes [4]byte
+	binary.LittleEndian.PutUint32(bytes[:], value)
+	e.buffer = append(e.buffer, bytes[:]...)
+}
+
+func (e *diskEncoder) int32(value int32) {
+	e.uint32(uint32(value))
+}
+
+func (e *diskEncoder) float64(value float64) {
+	var bytes [8]byte
+	binary.LittleEndian.PutUint64(bytes[:], math.Float64bits(value))
+	e.buffer = append(e.buffer, bytes[:]...)
+}
+
+func (e *diskEncoder) bool(value bool) {
+	if value {
+		e.buffer = append(e.buffer, 1)
+	} else {
+		e.buffer = append(e.buffer, 0)
+	}
+}
+
+func (e *diskEncoder) byte(value byte) {
+	e.buffer = append(e.buffer, value)
+}
+
+func (e *diskEncoder) string(value string) {
+	e.uint32(uint32(len(value)))
+	e.buffer = append(e.buffer, value...)
+}
+
+func (e *diskEncoder) uint16s(value []uint16) {
+	e.uint32(uint32(len(value)))
+	for _, c := range value {
+		e.buffer = append(e.buffer, byte(c), byte(c>>8))
+	}
+}
+
+func (e *diskEncoder) msgData(data logger.MsgData) {
+	e.string(data.Text)
+	e.bool(data.DisableMaximumWidth)
+	e.bool(data.Location != nil)
+	if loc := data.Location; loc != nil {
+		e.string(loc.File)
+		e.string(loc.Namespace)
+		e.string(loc.LineText)
+		e.string(loc.Suggestion)
+		e.int32(int32(loc.Line))
+		e.int32(int32(loc.Column))
+		e.int32(int32(loc.Length))
+	}
+}
+
+func (e *diskEncoder) msgs(msgs []logger.Msg) {
+	e.uint32(uint32(len(msgs)))
+	for _, msg := range msgs {
+		e.byte(byte(msg.Kind))
+		e.byte(msg.ID)
+		e.string(msg.PluginName)
+		e.msgData(msg.Data)
+		e.uint32(uint32(len(msg.Notes)))
+		for _, note := range msg.Notes {
+			e.msgData(note)
+		}
+	}
+}
+
+// The decoder records the first error and returns zero values afterward,
+// which avoids checking for errors after every single read
+type diskDecoder struct {
+	buffer []byte
+	failed bool
+}
+
+func (d *diskDecoder) take(n int) []byte {
+	if d.failed || n < 0 || n > len(d.buffer) {
+		d.failed = true
+		return nil
+	}
+	bytes := d.buffer[:n]
+	d.buffer = d.buffer[n:]
+	return bytes
+}
+
+func (d *diskDecoder) uint32() uint32 {
+	if bytes := d.take(4); bytes != nil {
+		return binary.LittleEndian.Uint32(bytes)
+	}
+	return 0
+}
+
+func (d *diskDecoder) int32() int32 {
+	return int32(d.uint32())
+}
+
+func (d *diskDecoder) float64() float64 {
+	if bytes := d.take(8); bytes != nil {
+		return math.Float64frombits(binary.LittleEndian.Uint64(bytes))
+	}
+	return 0
+}
+
+func (d *diskDecoder) byte() byte {
+	if bytes := d.take(1); bytes != nil {
+		return bytes[0]
+	}
+	return 0
+}
+
+func (d *diskDecoder) bool() bool {
+	return d.byte() != 0
+}
+
+// Lengths are validated against the remaining input before allocating so
+// that a corrupt entry can't cause a huge allocation
+func (d *diskDecoder) length(minBytesPerItem int) int {
+	n := int(d.uint32())
+	if n*minBytesPerItem > len(d.buffer) {
+		d.failed = true
+		return 0
+	}
+	return n
+}
+
+func (d *diskDecoder) string() string {
+	return string(d.take(d.length(1)))
+}
+
+func (d *diskDecoder) uint16s() []uint16 {
+	bytes := d.take(d.length(2) * 2)
+	value := make([]uint16, len(bytes)/2)
+	for i := range value {
+		value[i] = uint16(bytes[i*2]) | uint16(bytes[i*2+1])<<8
+	}
+	return value
+}
+
+func (d *diskDecoder) msgData() (data logger.MsgData) {
+	data.Text = d.string()
+	data.DisableMaximumWidth = d.bool()
+	if d.bool() {
+		data.Location = &logger.MsgLocation{
+			File:       d.string(),
+			Namespace:  d.string(),
+			LineText:   d.string(),
+			Suggestion: d.string(),
+			Line:       int(d.int32()),
+			Column:     int(d.int32()),
+			Length:     int(d.int32()),
+		}
+	}
+	return
+}
+
+func (d *diskDecoder) msgs() []logger.Msg {
+	var msgs []logger.Msg
+	for i, n := 0, d.length(1); i < n && !d.failed; i++ {
+		msg := logger.Msg{
+			Kind:       logger.MsgKind(d.byte()),
+			ID:         d.byte(),
+			PluginName: d.string(),
+			Data:       d.msgData(),
+		}
+		for j, m := 0, d.length(1); j < m && !d.failed; j++ {
+			msg.Notes = append(msg.Notes, d.msgData())
+		}
+		msgs = append(msgs, msg)
+	}
+	return msgs
+}
+
+// Every key starts with the source identity. The file contents are included
+// directly because hashing is done on the whole key anyway. Log overrides are
+// included because they change the kind of the messages stored in the entry.
+func diskCacheSourceKey(source logger.Source, overrides map[logger.MsgID]logger.LogLevel) diskEncoder {
+	key := diskEncoder{}
+	ids := make([]int, 0, len(overrides))
+	for id := range overrides {
+		ids = append(ids, int(id))
+	}
+	sort.Ints(ids)
+	key.uint32(uint32(len(ids)))
+	for _, id := range ids {
+		key.byte(byte(id))
+		key.byte(byte(overrides[logger.MsgID(id)]))
+	}
+	key.string(source.KeyPath.Namespace)
+	key.string(source.KeyPath.Text)
+	key.string(source.KeyPath.IgnoredSuffix)
+	key.byte(byte(source.KeyPath.Flags))
+	key.string(source.PrettyPath)
+	key.string(source.IdentifierName)
+	key.string(source.Contents)
+	return key
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// JSON
+
+const diskCacheKindJSON = "json"
+
+func diskCacheJSONKey(source logger.Source, options js_parser.JSONOptions, overrides map[logger.MsgID]logger.LogLevel) []byte {
+	key := diskCacheSourceKey(source, overrides)
+	key.byte(byte(options.Flavor))
+	key.string(options.ErrorSuffix)
+	return key.buffer
+}
+
+func encodeJSONCacheEntry(expr js_ast.Expr, ok bool, msgs []logger.Msg) ([]byte, bool) {
+	e := diskEncoder{}
+	e.bool(ok)
+	e.msgs(msgs)
+	if !e.jsonExpr(expr) {
+		return nil, false
+	}
+	return e.buffer, true
+}
+
+func decodeJSONCacheEntry(payload []byte) (expr js_ast.Expr, ok bool, msgs []logger.Msg, valid bool) {
+	d := diskDecoder{buffer: payload}
+	ok = d.bool()
+	msgs = d.msgs()
+	expr = d.jsonExpr(0)
+	if d.failed || len(d.buffer) != 0 {
+		return js_ast.Expr{}, false, nil, false
+	}
+	return expr, ok, msgs, true
+}
+
+const (
+	jsonTagMissing byte = iota
+	jsonTagNull
+	jsonTagFalse
+	jsonTagTrue
+	jsonTagString
+	jsonTagNumber
+	jsonTagArray
+	jsonTagObject
+)
+
+// This only handles the subset of expressions that the JSON parser produces.
+// It returns false for anything else, in which case the entry isn't written.
+func (e *diskEncoder) jsonExpr(expr js_ast.Expr) bool {
+	if expr.Data == nil {
+		e.byte(jsonTagMissing)
+		return true
+	}
+
+	switch v := expr.Data.(type) {
+	case *js_ast.ENull:
+		e.byte(jsonTagNull)
+
+	case *js_ast.EBoolean:
+		if v.Value {
+			e.byte(jsonTagTrue)
+		} else {
+			e.byte(jsonTagFalse)
+		}
+
+	case *js_ast.EString:
+		e.byte(jsonTagString)
+		e.uint16s(v.Value)
+
+	case *js_ast.ENumber:
+		e.byte(jsonTagNumber)
+		e.float64(v.Value)
+
+	case *js_ast.EArray:
+		e.byte(jsonTagArray)
+		e.bool(v.IsSingleLine)
+		e.int32(v.CloseBracketLoc.Start)
+		e.uint32(uint32(len(v.Items)))
+		for _, item := range v.Items {
+			if !e.jsonExpr(item) {
+				return false
+			}
+		}
+
+	case *js_ast.EObject:
+		e.byte(jsonTagObject)
+		e.bool(v.IsSingleLine)
+		e.int32(v.CloseBraceLoc.Start)
+		e.uint32(uint32(len(v.Properties)))
+		for _, property := range v.Properties {
+			if property.Kind != js_ast.PropertyNormal || property.Flags != 0 {
+				return false
+			}
+			e.int32(property.Loc.Start)
+			if !e.jsonExpr(property.Key) || !e.jsonExpr(property.ValueOrNil) {
+				return false
+			}
+		}
+
+	default:
+		return false
+	}
+
+	e.int32(expr.Loc.Start)
+	return true
+}
+
+// Nesting is limited to avoid a stack overflow from a corrupt cache entry. The
+// limit is far beyond what the JSON parser itself can handle in practice.
+const maxJSONCacheDepth = 1 << 16
+
+func (d *diskDecoder) jsonExpr(depth int) (expr js_ast.Expr) {
+	if depth > maxJSONCacheDepth {
+		d.failed = true
+		return
+	}
+
+	switch d.byte() {
+	case jsonTagMissing:
+		return
+
+	case jsonTagNull:
+		expr.Data = js_ast.ENullShared
+
+	case jsonTagFalse:
+		expr.Data = &js_ast.EBoolean{Value: false}
+
+	case jsonTagTrue:
+		expr.Data = &js_ast.EBoolean{Value: true}
+
+	case jsonTagString:
+		expr.Data = &js_ast.EString{Value: d.uint16s()}
+
+	case jsonTagNumber:
+		expr.Data = &js_ast.ENumber{Value: d.float64()}
+
+	case jsonTagArray:
+		array := &js_ast.EArray{IsSingleLine: d.bool()}
+		array.CloseBracketLoc.Start = d.int32()
+		n := d.length(1)
+		array.Items = make([]js_ast.Expr, 0, n)
+		for i := 0; i < n && !d.failed; i++ {
+			array.Items = append(array.Items, d.jsonExpr(depth+1))
+		}
+		expr.Data = array
+
+	case jsonTagObject:
+		object := &js_ast.EObject{IsSingleLine: d.bool()}
+		object.CloseBraceLoc.Start = d.int32()
+		n := d.length(1)
+		object.Properties = make([]js_ast.Property, 0, n)
+		for i := 0; i < n && !d.failed; i++ {
+			property := js_ast.Property{Kind: js_ast.PropertyNormal}
+			property.Loc.Start = d.int32()
+			property.Key = d.jsonExpr(depth + 1)
+			property.ValueOrNil = d.jsonExpr(depth + 1)
+			object.Properties = append(object.Properties, property)
+		}
+		expr.Data = object
+
+	default:
+		d.failed = true
+		return
+	}
+
+	expr.Loc.Start = d.int32()
+	return
+}
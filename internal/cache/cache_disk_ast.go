@@ -0,0 +1,761 @@
+package cache
+
+// JavaScript and CSS syntax trees use almost every AST node type and contain
+// cyclic pointers (e.g. each scope points to both its parent and its
+// children), so unlike JSON they are serialized generically using reflection:
+//
+//   - Each pointer is written once and is referred to by number afterward,
+//     which preserves both sharing and cycles when the tree is decoded.
+//
+//   - Interface values are written as an index into "diskCacheConcreteTypes"
+//     followed by the value itself. Any other type inside an interface (e.g. an
+//     AST node type that hasn't been added to that list yet) means the entry
+//     can't be encoded, in which case it's just not written.
+//
+//   - Symbol references are written relative to the file being parsed since
+//     source indices are assigned in a different order by every build.
+//
+//   - Unexported fields can't be set when decoding. The few types in syntax
+//     trees that have unexported fields are encoded explicitly, and any other
+//     type with unexported fields can't be encoded.
+//
+// The same encoder is also used for the parser options that are part of each
+// cache key. Keys are only ever hashed and never decoded, so unexported fields
+// are fine there.
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"reflect"
+	"sort"
+	"sync"
+
+	"github.com/evanw/esbuild/internal/ast"
+	"github.com/evanw/esbuild/internal/config"
+	"github.com/evanw/esbuild/internal/css_ast"
+	"github.com/evanw/esbuild/internal/css_parser"
+	"github.com/evanw/esbuild/internal/js_ast"
+	"github.com/evanw/esbuild/internal/js_parser"
+	"github.com/evanw/esbuild/internal/logger"
+	"github.com/evanw/esbuild/internal/runtime"
+)
+
+// The order of this list is part of the serialization format
+var diskCacheConcreteTypes = []reflect.Type{
+	// js_ast.B
+	reflect.TypeOf((*js_ast.BMissing)(nil)),
+	reflect.TypeOf((*js_ast.BIdentifier)(nil)),
+	reflect.TypeOf((*js_ast.BArray)(nil)),
+	reflect.TypeOf((*js_ast.BObject)(nil)),
+
+	// js_ast.E
+	reflect.TypeOf((*js_ast.EArray)(nil)),
+	reflect.TypeOf((*js_ast.EUnary)(nil)),
+	reflect.TypeOf((*js_ast.EBinary)(nil)),
+	reflect.TypeOf((*js_ast.EBoolean)(nil)),
+	reflect.TypeOf((*js_ast.ESuper)(nil)),
+	reflect.TypeOf((*js_ast.ENull)(nil)),
+	reflect.TypeOf((*js_ast.EUndefined)(nil)),
+	reflect.TypeOf((*js_ast.EThis)(nil)),
+	reflect.TypeOf((*js_ast.ENew)(nil)),
+	reflect.TypeOf((*js_ast.ENewTarget)(nil)),
+	reflect.TypeOf((*js_ast.EImportMeta)(nil)),
+	reflect.TypeOf((*js_ast.ECall)(nil)),
+	reflect.TypeOf((*js_ast.EDot)(nil)),
+	reflect.TypeOf((*js_ast.EIndex)(nil)),
+	reflect.TypeOf((*js_ast.EArrow)(nil)),
+	reflect.TypeOf((*js_ast.EFunction)(nil)),
+	reflect.TypeOf((*js_ast.EClass)(nil)),
+	reflect.TypeOf((*js_ast.EIdentifier)(nil)),
+	reflect.TypeOf((*js_ast.EImportIdentifier)(nil)),
+	reflect.TypeOf((*js_ast.EPrivateIdentifier)(nil)),
+	reflect.TypeOf((*js_ast.ENameOfSymbol)(nil)),
+	reflect.TypeOf((*js_ast.EJSXElement)(nil)),
+	reflect.TypeOf((*js_ast.EMissing)(nil)),
+	reflect.TypeOf((*js_ast.ENumber)(nil)),
+	reflect.TypeOf((*js_ast.EBigInt)(nil)),
+	reflect.TypeOf((*js_ast.EObject)(nil)),
+	reflect.TypeOf((*js_ast.ESpread)(nil)),
+	reflect.TypeOf((*js_ast.EString)(nil)),
+	reflect.TypeOf((*js_ast.ETemplate)(nil)),
+	reflect.TypeOf((*js_ast.ERegExp)(nil)),
+	reflect.TypeOf((*js_ast.EInlinedEnum)(nil)),
+	reflect.TypeOf((*js_ast.EAnnotation)(nil)),
+	reflect.TypeOf((*js_ast.EAwait)(nil)),
+	reflect.TypeOf((*js_ast.EYield)(nil)),
+	reflect.TypeOf((*js_ast.EIf)(nil)),
+	reflect.TypeOf((*js_ast.ERequireString)(nil)),
+	reflect.TypeOf((*js_ast.ERequireResolveString)(nil)),
+	reflect.TypeOf((*js_ast.EImportString)(nil)),
+	reflect.TypeOf((*js_ast.EImportCall)(nil)),
+
+	// js_ast.S
+	reflect.TypeOf((*js_ast.SBlock)(nil)),
+	reflect.TypeOf((*js_ast.SComment)(nil)),
+	reflect.TypeOf((*js_ast.SDebugger)(nil)),
+	reflect.TypeOf((*js_ast.SDirective)(nil)),
+	reflect.TypeOf((*js_ast.SEmpty)(nil)),
+	reflect.TypeOf((*js_ast.STypeScript)(nil)),
+	reflect.TypeOf((*js_ast.SExportClause)(nil)),
+	reflect.TypeOf((*js_ast.SExportFrom)(nil)),
+	reflect.TypeOf((*js_ast.SExportDefault)(nil)),
+	reflect.TypeOf((*js_ast.SExportStar)(nil)),
+	reflect.TypeOf((*js_ast.SExportEquals)(nil)),
+	reflect.TypeOf((*js_ast.SLazyExport)(nil)),
+	reflect.TypeOf((*js_ast.SExpr)(nil)),
+	reflect.TypeOf((*js_ast.SEnum)(nil)),
+	reflect.TypeOf((*js_ast.SNamespace)(nil)),
+	reflect.TypeOf((*js_ast.SFunction)(nil)),
+	reflect.TypeOf((*js_ast.SClass)(nil)),
+	reflect.TypeOf((*js_ast.SLabel)(nil)),
+	reflect.TypeOf((*js_ast.SIf)(nil)),
+	reflect.TypeOf((*js_ast.SFor)(nil)),
+	reflect.TypeOf((*js_ast.SForIn)(nil)),
+	reflect.TypeOf((*js_ast.SForOf)(nil)),
+	reflect.TypeOf((*js_ast.SDoWhile)(nil)),
+	reflect.TypeOf((*js_ast.SWhile)(nil)),
+	reflect.TypeOf((*js_ast.SWith)(nil)),
+	reflect.TypeOf((*js_ast.STry)(nil)),
+	reflect.TypeOf((*js_ast.SSwitch)(nil)),
+	reflect.TypeOf((*js_ast.SImport)(nil)),
+	reflect.TypeOf((*js_ast.SReturn)(nil)),
+	reflect.TypeOf((*js_ast.SThrow)(nil)),
+	reflect.TypeOf((*js_ast.SLocal)(nil)),
+	reflect.TypeOf((*js_ast.SBreak)(nil)),
+	reflect.TypeOf((*js_ast.SContinue)(nil)),
+
+	// js_ast.TSNamespaceMemberData
+	reflect.TypeOf((*js_ast.TSNamespaceMemberProperty)(nil)),
+	reflect.TypeOf((*js_ast.TSNamespaceMemberNamespace)(nil)),
+	reflect.TypeOf((*js_ast.TSNamespaceMemberEnumNumber)(nil)),
+	reflect.TypeOf((*js_ast.TSNamespaceMemberEnumString)(nil)),
+
+	// css_ast.R
+	reflect.TypeOf((*css_ast.RAtCharset)(nil)),
+	reflect.TypeOf((*css_ast.RAtImport)(nil)),
+	reflect.TypeOf((*css_ast.RAtKeyframes)(nil)),
+	reflect.TypeOf((*css_ast.RKnownAt)(nil)),
+	reflect.TypeOf((*css_ast.RUnknownAt)(nil)),
+	reflect.TypeOf((*css_ast.RSelector)(nil)),
+	reflect.TypeOf((*css_ast.RQualified)(nil)),
+	reflect.TypeOf((*css_ast.RDeclaration)(nil)),
+	reflect.TypeOf((*css_ast.RBadDeclaration)(nil)),
+	reflect.TypeOf((*css_ast.RComment)(nil)),
+	reflect.TypeOf((*css_ast.RAtLayer)(nil)),
+
+	// css_ast.SS
+	reflect.TypeOf((*css_ast.SSHash)(nil)),
+	reflect.TypeOf((*css_ast.SSClass)(nil)),
+	reflect.TypeOf((*css_ast.SSAttribute)(nil)),
+	reflect.TypeOf((*css_ast.SSPseudoClass)(nil)),
+	reflect.TypeOf((*css_ast.SSPseudoClassWithSelectorList)(nil)),
+}
+
+var diskCacheConcreteTypeIndices = func() map[reflect.Type]uint64 {
+	indices := make(map[reflect.Type]uint64, len(diskCacheConcreteTypes))
+	for i, t := range diskCacheConcreteTypes {
+		indices[t] = uint64(i)
+	}
+	return indices
+}()
+
+var (
+	diskCacheRefType              = reflect.TypeOf(ast.Ref{})
+	diskCacheDependencyType       = reflect.TypeOf(js_ast.Dependency{})
+	diskCacheIndex32Type          = reflect.TypeOf(ast.Index32{})
+	diskCacheImportAttributesType = reflect.TypeOf(logger.ImportAttributes{})
+	diskCacheUint16sType          = reflect.TypeOf([]uint16{})
+)
+
+// Looking up struct fields and comparing types using reflection is slow, so
+// the encoder and decoder for each type are only built once and then reused
+type diskCacheCodec struct {
+	encode func(e *diskValueEncoder, v reflect.Value) bool // Returns false if "v" can't be encoded
+	decode func(d *diskValueDecoder, v reflect.Value)      // "v" must be settable and hold the zero value
+}
+
+type diskCacheCodecSet struct {
+	mutex          sync.Mutex
+	codecs         map[reflect.Type]*diskCacheCodec
+	concreteCodecs []*diskCacheCodec // This is parallel to "diskCacheConcreteTypes"
+	isKey          bool
+}
+
+var diskCacheASTCodecs = &diskCacheCodecSet{}
+var diskCacheKeyCodecs = &diskCacheCodecSet{isKey: true}
+
+func (s *diskCacheCodecSet) codecFor(t reflect.Type) *diskCacheCodec {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if s.codecs == nil {
+		s.codecs = make(map[reflect.Type]*diskCacheCodec)
+		s.concreteCodecs = make([]*diskCacheCodec, len(diskCacheConcreteTypes))
+		for i, concrete := range diskCacheConcreteTypes {
+			s.concreteCodecs[i] = s.build(concrete)
+		}
+	}
+	return s.build(t)
+}
+
+func (s *diskCacheCodecSet) build(t reflect.Type) *diskCacheCodec {
+	if codec, ok := s.codecs[t]; ok {
+		return codec
+	}
+
+	// Register the codec before building it since types can be recursive
+	codec := &diskCacheCodec{}
+	s.codecs[t] = codec
+	codec.encode, codec.decode = s.buildFuncs(t, codec)
+	return codec
+}
+
+type diskCachePointer struct {
+	codec   *diskCacheCodec
+	address uintptr
+}
+
+type diskValueEncoder struct {
+	diskEncoder
+	pointers    map[diskCachePointer]uint64
+	sourceIndex uint32
+}
+
+type diskValueDecoder struct {
+	diskDecoder
+	pointers    []reflect.Value
+	sourceIndex uint32
+	depth       int
+}
+
+// The number of pointers is written before the value so that the decoder can
+// allocate space for all of them up front
+func (e *diskValueEncoder) finish() []byte {
+	header := diskEncoder{}
+	header.uvarint(uint64(len(e.pointers)))
+	return append(header.buffer, e.buffer...)
+}
+
+func (d *diskValueDecoder) start() {
+	n := d.uvarint()
+	if n > uint64(len(d.buffer)) {
+		d.failed = true
+		return
+	}
+	d.pointers = make([]reflect.Value, 0, int(n))
+}
+
+// Nesting is limited to avoid a stack overflow from a corrupt cache entry
+const maxASTCacheDepth = 1 << 16
+
+func (s *diskCacheCodecSet) buildFuncs(
+	t reflect.Type, codec *diskCacheCodec,
+) (func(*diskValueEncoder, reflect.Value) bool, func(*diskValueDecoder, reflect.Value)) {
+	if !s.isKey {
+		switch t {
+		case diskCacheRefType, diskCacheDependencyType:
+			// Both of these types are a source index followed by another index
+			return func(e *diskValueEncoder, v reflect.Value) bool {
+					sourceIndex := uint32(v.Field(0).Uint())
+					if sourceIndex == e.sourceIndex {
+						e.byte(1)
+					} else if sourceIndex == ^uint32(0) || sourceIndex == runtime.SourceIndex {
+						e.byte(0)
+						e.uvarint(uint64(sourceIndex))
+					} else {
+						return false
+					}
+					e.uvarint(v.Field(1).Uint())
+					return true
+				}, func(d *diskValueDecoder, v reflect.Value) {
+					if d.bool() {
+						v.Field(0).SetUint(uint64(d.sourceIndex))
+					} else {
+						v.Field(0).SetUint(uint64(uint32(d.uvarint())))
+					}
+					v.Field(1).SetUint(uint64(uint32(d.uvarint())))
+				}
+
+		case diskCacheIndex32Type:
+			return func(e *diskValueEncoder, v reflect.Value) bool {
+					index := v.Interface().(ast.Index32)
+					e.bool(index.IsValid())
+					if index.IsValid() {
+						e.uvarint(uint64(index.GetIndex()))
+					}
+					return true
+				}, func(d *diskValueDecoder, v reflect.Value) {
+					if d.bool() {
+						v.Set(reflect.ValueOf(ast.MakeIndex32(uint32(d.uvarint()))))
+					}
+				}
+
+		case diskCacheImportAttributesType:
+			return func(e *diskValueEncoder, v reflect.Value) bool {
+					attrs := v.Interface().(logger.ImportAttributes).DecodeIntoArray()
+					e.uvarint(uint64(len(attrs)))
+					for _, attr := range attrs {
+						e.string(attr.Key)
+						e.string(attr.Value)
+					}
+					return true
+				}, func(d *diskValueDecoder, v reflect.Value) {
+					n := d.uvarint()
+					if n > uint64(len(d.buffer)) {
+						d.failed = true
+						return
+					}
+					attrs := make(map[string]string, int(n))
+					for i := uint64(0); i < n && !d.failed; i++ {
+						key := d.string()
+						attrs[key] = d.string()
+					}
+					v.Set(reflect.ValueOf(logger.EncodeImportAttributes(attrs)))
+				}
+
+		case diskCacheUint16sType:
+			// This is a fast path for the contents of string literals
+			return func(e *diskValueEncoder, v reflect.Value) bool {
+					value := v.Interface().([]uint16)
+					if value == nil {
+						e.uvarint(0)
+						return true
+					}
+					e.uvarint(uint64(len(value)) + 1)
+					for _, c := range value {
+						e.buffer = append(e.buffer, byte(c), byte(c>>8))
+					}
+					return true
+				}, func(d *diskValueDecoder, v reflect.Value) {
+					if n, ok := d.lengthOrNil(); ok {
+						bytes := d.take(n * 2)
+						if d.failed {
+							return
+						}
+						value := make([]uint16, n)
+						for i := range value {
+							value[i] = uint16(bytes[i*2]) | uint16(bytes[i*2+1])<<8
+						}
+						v.Set(reflect.ValueOf(value))
+					}
+				}
+		}
+	}
+
+	switch t.Kind() {
+	case reflect.Bool:
+		return func(e *diskValueEncoder, v reflect.Value) bool {
+				e.bool(v.Bool())
+				return true
+			}, func(d *diskValueDecoder, v reflect.Value) {
+				v.SetBool(d.bool())
+			}
+
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return func(e *diskValueEncoder, v reflect.Value) bool {
+				e.varint(v.Int())
+				return true
+			}, func(d *diskValueDecoder, v reflect.Value) {
+				v.SetInt(d.varint())
+			}
+
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return func(e *diskValueEncoder, v reflect.Value) bool {
+				e.uvarint(v.Uint())
+				return true
+			}, func(d *diskValueDecoder, v reflect.Value) {
+				v.SetUint(d.uvarint())
+			}
+
+	case reflect.Float32, reflect.Float64:
+		return func(e *diskValueEncoder, v reflect.Value) bool {
+				e.float64(v.Float())
+				return true
+			}, func(d *diskValueDecoder, v reflect.Value) {
+				v.SetFloat(d.float64())
+			}
+
+	case reflect.String:
+		return func(e *diskValueEncoder, v reflect.Value) bool {
+				e.string(v.String())
+				return true
+			}, func(d *diskValueDecoder, v reflect.Value) {
+				v.SetString(d.string())
+			}
+
+	case reflect.Array:
+		elem := s.build(t.Elem())
+		n := t.Len()
+		return func(e *diskValueEncoder, v reflect.Value) bool {
+				for i := 0; i < n; i++ {
+					if !elem.encode(e, v.Index(i)) {
+						return false
+					}
+				}
+				return true
+			}, func(d *diskValueDecoder, v reflect.Value) {
+				for i := 0; i < n && !d.failed; i++ {
+					elem.decode(d, v.Index(i))
+				}
+			}
+
+	case reflect.Slice:
+		// Nil and empty slices are kept apart since some code checks for nil
+		elem := s.build(t.Elem())
+		return func(e *diskValueEncoder, v reflect.Value) bool {
+				if v.IsNil() {
+					e.uvarint(0)
+					return true
+				}
+				n := v.Len()
+				e.uvarint(uint64(n) + 1)
+				for i := 0; i < n; i++ {
+					if !elem.encode(e, v.Index(i)) {
+						return false
+					}
+				}
+				return true
+			}, func(d *diskValueDecoder, v reflect.Value) {
+				if n, ok := d.lengthOrNil(); ok {
+					slice := reflect.MakeSlice(t, n, n)
+					for i := 0; i < n && !d.failed; i++ {
+						elem.decode(d, slice.Index(i))
+					}
+					v.Set(slice)
+				}
+			}
+
+	case reflect.Map:
+		key := s.build(t.Key())
+		elem := s.build(t.Elem())
+		return func(e *diskValueEncoder, v reflect.Value) bool {
+				if v.IsNil() {
+					e.uvarint(0)
+					return true
+				}
+
+				// Sort the keys so that equal maps always have the same encoding
+				keys := v.MapKeys()
+				sort.Slice(keys, func(i int, j int) bool {
+					return diskCacheLess(keys[i], keys[j])
+				})
+				e.uvarint(uint64(len(keys)) + 1)
+				for _, k := range keys {
+					if !key.encode(e, k) || !elem.encode(e, v.MapIndex(k)) {
+						return false
+					}
+				}
+				return true
+			}, func(d *diskValueDecoder, v reflect.Value) {
+				if n, ok := d.lengthOrNil(); ok {
+					m := reflect.MakeMapWithSize(t, n)
+					for i := 0; i < n && !d.failed; i++ {
+						k := reflect.New(t.Key()).Elem()
+						key.decode(d, k)
+						value := reflect.New(t.Elem()).Elem()
+						elem.decode(d, value)
+						m.SetMapIndex(k, value)
+					}
+					v.Set(m)
+				}
+			}
+
+	case reflect.Ptr:
+		// Each pointer is written once and then referred to by number
+		elem := s.build(t.Elem())
+		return func(e *diskValueEncoder, v reflect.Value) bool {
+				if v.IsNil() {
+					e.byte(0)
+					return true
+				}
+				pointer := diskCachePointer{codec: codec, address: v.Pointer()}
+				if id, ok := e.pointers[pointer]; ok {
+					e.byte(1)
+					e.uvarint(id)
+					return true
+				}
+				if e.pointers == nil {
+					e.pointers = make(map[diskCachePointer]uint64)
+				}
+				e.pointers[pointer] = uint64(len(e.pointers))
+				e.byte(2)
+				return elem.encode(e, v.Elem())
+			}, func(d *diskValueDecoder, v reflect.Value) {
+				switch d.byte() {
+				case 0:
+				case 1:
+					id := d.uvarint()
+					if id >= uint64(len(d.pointers)) || d.pointers[id].Type() != t {
+						d.failed = true
+						return
+					}
+					v.Set(d.pointers[id])
+				case 2:
+					if d.depth >= maxASTCacheDepth {
+						d.failed = true
+						return
+					}
+					pointer := reflect.New(t.Elem())
+					d.pointers = append(d.pointers, pointer)
+					d.depth++
+					elem.decode(d, pointer.Elem())
+					d.depth--
+					v.Set(pointer)
+				default:
+					d.failed = true
+				}
+			}
+
+	case reflect.Interface:
+		implements := make([]bool, len(diskCacheConcreteTypes))
+		for i, concrete := range diskCacheConcreteTypes {
+			implements[i] = concrete.Implements(t)
+		}
+		return func(e *diskValueEncoder, v reflect.Value) bool {
+				if v.IsNil() {
+					e.uvarint(0)
+					return true
+				}
+				concrete := v.Elem()
+				index, ok := diskCacheConcreteTypeIndices[concrete.Type()]
+				if !ok {
+					return false
+				}
+				e.uvarint(index + 1)
+				return s.concreteCodecs[index].encode(e, concrete)
+			}, func(d *diskValueDecoder, v reflect.Value) {
+				index := d.uvarint()
+				if index == 0 {
+					return
+				}
+				if index > uint64(len(implements)) || !implements[index-1] {
+					d.failed = true
+					return
+				}
+				concrete := reflect.New(diskCacheConcreteTypes[index-1]).Elem()
+				s.concreteCodecs[index-1].decode(d, concrete)
+				v.Set(concrete)
+			}
+
+	case reflect.Struct:
+		fields := make([]*diskCacheCodec, t.NumField())
+		for i := range fields {
+			if !s.isKey && t.Field(i).PkgPath != "" {
+				return diskCacheUnsupported()
+			}
+			fields[i] = s.build(t.Field(i).Type)
+		}
+		return func(e *diskValueEncoder, v reflect.Value) bool {
+				for i, field := range fields {
+					if !field.encode(e, v.Field(i)) {
+						return false
+					}
+				}
+				return true
+			}, func(d *diskValueDecoder, v reflect.Value) {
+				for i, field := range fields {
+					if d.failed {
+						return
+					}
+					field.decode(d, v.Field(i))
+				}
+			}
+	}
+
+	return diskCacheUnsupported()
+}
+
+func diskCacheUnsupported() (func(*diskValueEncoder, reflect.Value) bool, func(*diskValueDecoder, reflect.Value)) {
+	return func(*diskValueEncoder, reflect.Value) bool {
+			return false
+		}, func(d *diskValueDecoder, v reflect.Value) {
+			d.failed = true
+		}
+}
+
+// This only needs to handle the kinds of values that are used as map keys
+func diskCacheLess(a reflect.Value, b reflect.Value) bool {
+	switch a.Kind() {
+	case reflect.Bool:
+		return !a.Bool() && b.Bool()
+
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return a.Int() < b.Int()
+
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return a.Uint() < b.Uint()
+
+	case reflect.Float32, reflect.Float64:
+		return a.Float() < b.Float()
+
+	case reflect.String:
+		return a.String() < b.String()
+
+	case reflect.Struct:
+		for i, n := 0, a.NumField(); i < n; i++ {
+			if diskCacheLess(a.Field(i), b.Field(i)) {
+				return true
+			}
+			if diskCacheLess(b.Field(i), a.Field(i)) {
+				return false
+			}
+		}
+	}
+	return false
+}
+
+func (e *diskEncoder) uvarint(value uint64) {
+	var bytes [binary.MaxVarintLen64]byte
+	e.buffer = append(e.buffer, bytes[:binary.PutUvarint(bytes[:], value)]...)
+}
+
+func (e *diskEncoder) varint(value int64) {
+	var bytes [binary.MaxVarintLen64]byte
+	e.buffer = append(e.buffer, bytes[:binary.PutVarint(bytes[:], value)]...)
+}
+
+func (d *diskDecoder) uvarint() uint64 {
+	if d.failed {
+		return 0
+	}
+	value, n := binary.Uvarint(d.buffer)
+	if n <= 0 {
+		d.failed = true
+		return 0
+	}
+	d.buffer = d.buffer[n:]
+	return value
+}
+
+func (d *diskDecoder) varint() int64 {
+	if d.failed {
+		return 0
+	}
+	value, n := binary.Varint(d.buffer)
+	if n <= 0 {
+		d.failed = true
+		return 0
+	}
+	d.buffer = d.buffer[n:]
+	return value
+}
+
+// Lengths are stored plus one so that zero can mean nil. They are validated
+// against the remaining input before allocating so that a corrupt entry can't
+// cause a huge allocation.
+func (d *diskDecoder) lengthOrNil() (int, bool) {
+	n := d.uvarint()
+	if n == 0 {
+		return 0, false
+	}
+	if n-1 > uint64(len(d.buffer)) {
+		d.failed = true
+		return 0, false
+	}
+	return int(n - 1), true
+}
+
+// Parser options are part of the key. The defines are usually the same object
+// for every file in a build and are expensive to encode, so they are encoded
+// once and then represented by a hash in every key.
+func (c *DiskCache) appendOptionsToKey(key *diskEncoder, data []interface{}) bool {
+	for _, item := range data {
+		if defines, ok := item.(*config.ProcessedDefines); ok && defines != nil {
+			c.mutex.Lock()
+			hash, ok := c.definesHashes[defines]
+			c.mutex.Unlock()
+			if !ok {
+				value := reflect.ValueOf(defines)
+				encoder := diskValueEncoder{}
+				if !diskCacheKeyCodecs.codecFor(value.Type()).encode(&encoder, value) {
+					return false
+				}
+				sum := sha256.Sum256(encoder.buffer)
+				hash = sum[:]
+				c.mutex.Lock()
+				c.definesHashes[defines] = hash
+				c.mutex.Unlock()
+			}
+			key.string(string(hash))
+			continue
+		}
+		value := reflect.ValueOf(item)
+		encoder := diskValueEncoder{diskEncoder: *key}
+		if !diskCacheKeyCodecs.codecFor(value.Type()).encode(&encoder, value) {
+			return false
+		}
+		*key = encoder.diskEncoder
+	}
+	return true
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// JS
+
+const diskCacheKindJS = "js"
+
+// This returns nil if the options can't be part of a key
+func (c *DiskCache) jsKey(source logger.Source, options *js_parser.Options, overrides map[logger.MsgID]logger.LogLevel) []byte {
+	key := diskCacheSourceKey(source, overrides)
+	if !c.appendOptionsToKey(&key, options.CacheKeyData()) {
+		return nil
+	}
+	return key.buffer
+}
+
+func encodeJSCacheEntry(tree js_ast.AST, ok bool, msgs []logger.Msg, sourceIndex uint32) ([]byte, bool) {
+	e := diskValueEncoder{sourceIndex: sourceIndex}
+	e.bool(ok)
+	e.msgs(msgs)
+	if value := reflect.ValueOf(&tree).Elem(); !diskCacheASTCodecs.codecFor(value.Type()).encode(&e, value) {
+		return nil, false
+	}
+	return e.finish(), true
+}
+
+func decodeJSCacheEntry(payload []byte, sourceIndex uint32) (tree js_ast.AST, ok bool, msgs []logger.Msg, valid bool) {
+	d := diskValueDecoder{diskDecoder: diskDecoder{buffer: payload}, sourceIndex: sourceIndex}
+	d.start()
+	ok = d.bool()
+	msgs = d.msgs()
+	value := reflect.ValueOf(&tree).Elem()
+	diskCacheASTCodecs.codecFor(value.Type()).decode(&d, value)
+	if d.failed || len(d.buffer) != 0 {
+		return js_ast.AST{}, false, nil, false
+	}
+	return tree, ok, msgs, true
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// CSS
+
+const diskCacheKindCSS = "css"
+
+// This returns nil if the options can't be part of a key
+func (c *DiskCache) cssKey(source logger.Source, options *css_parser.Options, overrides map[logger.MsgID]logger.LogLevel) []byte {
+	key := diskCacheSourceKey(source, overrides)
+	if !c.appendOptionsToKey(&key, options.CacheKeyData()) {
+		return nil
+	}
+	return key.buffer
+}
+
+func encodeCSSCacheEntry(tree css_ast.AST, msgs []logger.Msg, sourceIndex uint32) ([]byte, bool) {
+	e := diskValueEncoder{sourceIndex: sourceIndex}
+	e.msgs(msgs)
+	if value := reflect.ValueOf(&tree).Elem(); !diskCacheASTCodecs.codecFor(value.Type()).encode(&e, value) {
+		return nil, false
+	}
+	return e.finish(), true
+}
+
+func decodeCSSCacheEntry(payload []byte, sourceIndex uint32) (tree css_ast.AST, msgs []logger.Msg, valid bool) {
+	d := diskValueDecoder{diskDecoder: diskDecoder{buffer: payload}, sourceIndex: sourceIndex}
+	d.start()
+	msgs = d.msgs()
+	value := reflect.ValueOf(&tree).Elem()
+	diskCacheASTCodecs.codecFor(value.Type()).decode(&d, value)
+	if d.failed || len(d.buffer) != 0 {
+		return css_ast.AST{}, nil, false
+	}
+	return tree, msgs, true
+}
@@ -0,0 +1,344 @@
+package cache
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"testing"
+
+	"github.com/evanw/esbuild/internal/ast"
+	"github.com/evanw/esbuild/internal/config"
+	"github.com/evanw/esbuild/internal/css_ast"
+	"github.com/evanw/esbuild/internal/css_parser"
+	"github.com/evanw/esbuild/internal/css_printer"
+	"github.com/evanw/esbuild/internal/fs"
+	"github.com/evanw/esbuild/internal/js_ast"
+	"github.com/evanw/esbuild/internal/js_parser"
+	"github.com/evanw/esbuild/internal/js_printer"
+	"github.com/evanw/esbuild/internal/logger"
+	"github.com/evanw/esbuild/internal/renamer"
+	"github.com/evanw/esbuild/internal/test"
+)
+
+func parseJSONForTest(t *testing.T, contents string) (js_ast.Expr, []logger.Msg) {
+	t.Helper()
+	log := logger.NewDeferLog(logger.DeferLogAll, nil)
+	expr, ok := js_parser.ParseJSON(log, test.SourceForTest(contents), js_parser.JSONOptions{})
+	if !ok {
+		t.Fatalf("Failed to parse %q", contents)
+	}
+	return expr, log.Done()
+}
+
+func makeDiskCacheForTest(t *testing.T) (*DiskCache, func()) {
+	t.Helper()
+	dir, err := ioutil.TempDir("", "esbuild-disk-cache-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	realFS, err := fs.RealFS(fs.RealFSOptions{AbsWorkingDir: dir})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return MakeDiskCache(realFS, dir), func() { os.RemoveAll(dir) }
+}
+
+func TestDiskCacheJSONRoundTrip(t *testing.T) {
+	expr, msgs := parseJSONForTest(t, `{"a": [1, -2.5, true, false, null], "b": {"c": " "}, "a": {}}`)
+	test.AssertEqual(t, len(msgs), 1)
+
+	payload, ok := encodeJSONCacheEntry(expr, true, msgs)
+	test.AssertEqual(t, ok, true)
+	decodedExpr, decodedOK, decodedMsgs, valid := decodeJSONCacheEntry(payload)
+	test.AssertEqual(t, valid, true)
+	test.AssertEqual(t, decodedOK, true)
+	test.AssertEqual(t, decodedMsgs[0].Data.Text, msgs[0].Data.Text)
+	test.AssertEqual(t, *decodedMsgs[0].Data.Location, *msgs[0].Data.Location)
+	test.AssertEqual(t, *decodedMsgs[0].Notes[0].Location, *msgs[0].Notes[0].Location)
+
+	// Re-encoding the decoded value must produce identical bytes
+	reencoded, ok := encodeJSONCacheEntry(decodedExpr, decodedOK, decodedMsgs)
+	test.AssertEqual(t, ok, true)
+	test.AssertEqual(t, string(reencoded), string(payload))
+
+	// Truncated entries must be rejected instead of partially decoded
+	for i := 0; i < len(payload); i++ {
+		if _, _, _, valid := decodeJSONCacheEntry(payload[:i]); valid {
+			t.Fatalf("Truncated entry of length %d was accepted", i)
+		}
+	}
+}
+
+func TestDiskCacheJSONParse(t *testing.T) {
+	disk, cleanup := makeDiskCacheForTest(t)
+	defer cleanup()
+	source := test.SourceForTest(`{"a": 1}`)
+	options := js_parser.JSONOptions{}
+
+	// Store a different result under this file's key. If the cache set below
+	// returns it, then the result must have come from the disk cache.
+	other, _ := parseJSONForTest(t, `{"b": 2}`)
+	payload, _ := encodeJSONCacheEntry(other, true, nil)
+	disk.write(diskCacheKindJSON, diskCacheJSONKey(source, options, nil), payload)
+
+	caches := MakeCacheSet()
+	caches.EnableDiskCache(disk)
+	expr, ok := caches.JSONCache.Parse(logger.NewDeferLog(logger.DeferLogAll, nil), source, options)
+	test.AssertEqual(t, ok, true)
+	key := expr.Data.(*js_ast.EObject).Properties[0].Key.Data.(*js_ast.EString)
+	test.AssertEqual(t, string(rune(key.Value[0])), "b")
+
+	// A different file with the same path must not hit the cache entry
+	changed := test.SourceForTest(`{"c": 3}`)
+	expr, ok = caches.JSONCache.Parse(logger.NewDeferLog(logger.DeferLogAll, nil), changed, options)
+	test.AssertEqual(t, ok, true)
+	key = expr.Data.(*js_ast.EObject).Properties[0].Key.Data.(*js_ast.EString)
+	test.AssertEqual(t, string(rune(key.Value[0])), "c")
+
+	// The result of the miss above should have been written back to disk
+	_, found := disk.read(diskCacheKindJSON, diskCacheJSONKey(changed, options, nil))
+	test.AssertEqual(t, found, true)
+}
+
+func parseJSForTest(t *testing.T, contents string, sourceIndex uint32, options config.Options) (js_ast.AST, []logger.Msg) {
+	t.Helper()
+	log := logger.NewDeferLog(logger.DeferLogAll, nil)
+	source := test.SourceForTest(contents)
+	source.Index = sourceIndex
+	tree, ok := js_parser.Parse(log, source, js_parser.OptionsFromConfig(&options))
+	if !ok {
+		t.Fatalf("Failed to parse %q", contents)
+	}
+	return tree, log.Done()
+}
+
+func printJSForTest(tree js_ast.AST, sourceIndex uint32) string {
+	symbols := ast.NewSymbolMap(int(sourceIndex) + 1)
+	symbols.SymbolsForSource[sourceIndex] = tree.Symbols
+	r := renamer.NewNoOpRenamer(symbols)
+	return string(js_printer.Print(tree, symbols, r, js_printer.Options{}).JS)
+}
+
+func TestDiskCacheJSRoundTrip(t *testing.T) {
+	tests := []struct {
+		contents string
+		options  config.Options
+	}{
+		{
+			contents: `
+				import def, { a as b } from "./foo"
+				export * from "./bar"
+				export let [x, { y = 1, ...z }] = [b, def, /re/g, 1n, 0.5, "\uD800"]
+				label: for (const k in x) { if (k) break label; else continue }
+				async function* f(a = 1, ...args) { yield* await import("./baz", { with: { type: "json" } }) }
+				class C extends f { static #p = 1; get [Symbol.iterator]() { return super.x?.(this) ?? new.target } }
+				try { eval("x") } catch { debugger } finally { with ({}) ; }
+				switch (x) { case 1: throw tag` + "`a${x}b`" + `; default: }
+				export default () => ({ ...require("./qux"), y })
+			`,
+		},
+		{
+			contents: `
+				namespace ns { export enum E { A = 1, B = "b", C = A << 1 } export let v: number = E.C }
+				declare module "m" { export = x }
+				abstract class D<T> { constructor(private readonly p: T) { super() } @dec m(): void {} }
+				let el = <div key="1" {...ns}>text {ns.v}</div>
+			`,
+			options: config.Options{
+				TS:  config.TSOptions{Parse: true},
+				JSX: config.JSXOptions{Parse: true},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		tree, msgs := parseJSForTest(t, tt.contents, 5, tt.options)
+		expected := printJSForTest(tree, 5)
+
+		payload, ok := encodeJSCacheEntry(tree, true, msgs, 5)
+		test.AssertEqual(t, ok, true)
+
+		// The decoded tree must refer to symbols in the new source index
+		decodedTree, decodedOK, decodedMsgs, valid := decodeJSCacheEntry(payload, 7)
+		test.AssertEqual(t, valid, true)
+		test.AssertEqual(t, decodedOK, true)
+		test.AssertEqual(t, len(decodedMsgs), len(msgs))
+		test.AssertEqual(t, decodedTree.ModuleRef.SourceIndex, uint32(7))
+		test.AssertEqualWithDiff(t, printJSForTest(decodedTree, 7), expected)
+
+		// Re-encoding the decoded value must produce identical bytes
+		reencoded, ok := encodeJSCacheEntry(decodedTree, decodedOK, decodedMsgs, 7)
+		test.AssertEqual(t, ok, true)
+		test.AssertEqual(t, string(reencoded), string(payload))
+
+		// Truncated entries must be rejected instead of partially decoded
+		for i := 0; i < len(payload); i += 7 {
+			if _, _, _, valid := decodeJSCacheEntry(payload[:i], 7); valid {
+				t.Fatalf("Truncated entry of length %d was accepted", i)
+			}
+		}
+	}
+}
+
+func TestDiskCacheJSForeignRef(t *testing.T) {
+	tree, msgs := parseJSForTest(t, `let x = 1`, 5, config.Options{})
+
+	// References into other files can't be relocated, so they can't be cached
+	tree.ExportsRef.SourceIndex = 3
+	_, ok := encodeJSCacheEntry(tree, true, msgs, 5)
+	test.AssertEqual(t, ok, false)
+}
+
+func TestDiskCacheCSSRoundTrip(t *testing.T) {
+	contents := `
+		@charset "UTF-8";
+		@import url("foo.css") layer(base) supports(display: grid) screen;
+		@layer base, components;
+		@keyframes spin { from { transform: rotate(0) } to { transform: rotate(360deg) } }
+		@media (min-width: 100px) { .a > .b:hover::before, #c[data-x="y" i] { color: red !important; --custom: { a: b } } }
+		@font-face { font-family: x; src: url(x.woff2) format("woff2") }
+		.d { composes: e from "./f.css"; & .g { background: url(data:image/png;base64,AAAA) } }
+		@unknown foo { bar }
+		/*! legal */
+		.h { color: ; }
+	`
+	log := logger.NewDeferLog(logger.DeferLogAll, nil)
+	source := test.SourceForTest(contents)
+	source.Index = 5
+	tree := css_parser.Parse(log, source, css_parser.OptionsFromConfig(config.LoaderLocalCSS, &config.Options{}))
+	msgs := log.Done()
+	print := func(tree css_ast.AST, sourceIndex uint32) string {
+		symbols := ast.NewSymbolMap(int(sourceIndex) + 1)
+		symbols.SymbolsForSource[sourceIndex] = tree.Symbols
+		return string(css_printer.Print(tree, symbols, css_printer.Options{}).CSS)
+	}
+	expected := print(tree, 5)
+
+	payload, ok := encodeCSSCacheEntry(tree, msgs, 5)
+	test.AssertEqual(t, ok, true)
+	decodedTree, decodedMsgs, valid := decodeCSSCacheEntry(payload, 7)
+	test.AssertEqual(t, valid, true)
+	test.AssertEqual(t, len(decodedMsgs), len(msgs))
+	test.AssertEqualWithDiff(t, print(decodedTree, 7), expected)
+
+	// Re-encoding the decoded value must produce identical bytes
+	reencoded, ok := encodeCSSCacheEntry(decodedTree, decodedMsgs, 7)
+	test.AssertEqual(t, ok, true)
+	test.AssertEqual(t, string(reencoded), string(payload))
+
+	// Truncated entries must be rejected instead of partially decoded
+	for i := 0; i < len(payload); i += 7 {
+		if _, _, valid := decodeCSSCacheEntry(payload[:i], 7); valid {
+			t.Fatalf("Truncated entry of length %d was accepted", i)
+		}
+	}
+}
+
+func TestDiskCacheJSParse(t *testing.T) {
+	disk, cleanup := makeDiskCacheForTest(t)
+	defer cleanup()
+	source := test.SourceForTest(`let a = 1`)
+	options := js_parser.OptionsFromConfig(&config.Options{})
+	key := disk.jsKey(source, &options, nil)
+	if key == nil {
+		t.Fatal("Expected the options to be usable as a key")
+	}
+
+	// Different options must result in a different key
+	minifyOptions := js_parser.OptionsFromConfig(&config.Options{MinifySyntax: true})
+	test.AssertEqual(t, string(disk.jsKey(source, &minifyOptions, nil)) == string(key), false)
+
+	// Store a different result under this file's key. If the cache set below
+	// returns it, then the result must have come from the disk cache.
+	other, _ := parseJSForTest(t, `let b = 2`, 0, config.Options{})
+	payload, _ := encodeJSCacheEntry(other, true, nil, 0)
+	disk.write(diskCacheKindJS, key, payload)
+
+	caches := MakeCacheSet()
+	caches.EnableDiskCache(disk)
+	tree, ok := caches.JSCache.Parse(logger.NewDeferLog(logger.DeferLogAll, nil), source, options)
+	test.AssertEqual(t, ok, true)
+	test.AssertEqual(t, printJSForTest(tree, 0), "let b = 2;\n")
+
+	// A miss must parse the file and then write it to the disk cache
+	source = test.SourceForTest(`let c = 3`)
+	caches.JSCache.Parse(logger.NewDeferLog(logger.DeferLogAll, nil), source, options)
+	_, found := disk.read(diskCacheKindJS, disk.jsKey(source, &options, nil))
+	test.AssertEqual(t, found, true)
+}
+
+// Every type that can be stored in an AST interface must be registered, and
+// every struct in an AST must be decodable (i.e. have no unexported fields)
+func TestDiskCacheASTTypes(t *testing.T) {
+	registered := make(map[string]bool)
+	for _, typ := range diskCacheConcreteTypes {
+		registered[typ.String()] = true
+	}
+	implementers := regexp.MustCompile(`func \((?:\w+ )?\*?(\w+)\) (?:isExpr|isStmt|isBinding|isTSNamespaceMember|Equal\(rule R,|Equal\(ss SS,)`)
+	for _, pkg := range []string{"js_ast", "css_ast"} {
+		files, err := filepath.Glob(filepath.Join("..", pkg, "*.go"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, file := range files {
+			contents, err := ioutil.ReadFile(file)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, match := range implementers.FindAllStringSubmatch(string(contents), -1) {
+				if name := "*" + pkg + "." + match[1]; !registered[name] {
+					t.Errorf("The type %q is missing from \"diskCacheConcreteTypes\"", name)
+				}
+			}
+		}
+	}
+
+	knownInterfaces := map[reflect.Type]bool{
+		reflect.TypeOf((*js_ast.B)(nil)).Elem():                     true,
+		reflect.TypeOf((*js_ast.E)(nil)).Elem():                     true,
+		reflect.TypeOf((*js_ast.S)(nil)).Elem():                     true,
+		reflect.TypeOf((*js_ast.TSNamespaceMemberData)(nil)).Elem(): true,
+		reflect.TypeOf((*css_ast.R)(nil)).Elem():                    true,
+		reflect.TypeOf((*css_ast.SS)(nil)).Elem():                   true,
+	}
+	visited := make(map[reflect.Type]bool)
+	var visit func(typ reflect.Type)
+	visit = func(typ reflect.Type) {
+		if visited[typ] {
+			return
+		}
+		visited[typ] = true
+		switch typ {
+		case diskCacheRefType, diskCacheDependencyType, diskCacheIndex32Type, diskCacheImportAttributesType:
+			return
+		}
+		switch typ.Kind() {
+		case reflect.Array, reflect.Slice, reflect.Ptr:
+			visit(typ.Elem())
+		case reflect.Map:
+			visit(typ.Key())
+			visit(typ.Elem())
+		case reflect.Struct:
+			for i := 0; i < typ.NumField(); i++ {
+				if field := typ.Field(i); field.PkgPath != "" {
+					t.Errorf("The field %s.%s is unexported", typ, field.Name)
+				} else {
+					visit(field.Type)
+				}
+			}
+		case reflect.Interface:
+			if !knownInterfaces[typ] {
+				t.Errorf("The interface %s isn't known to be in \"diskCacheConcreteTypes\"", typ)
+			}
+		case reflect.Func, reflect.Chan, reflect.UnsafePointer:
+			t.Errorf("The type %s can't be encoded", typ)
+		}
+	}
+	visit(reflect.TypeOf(js_ast.AST{}))
+	visit(reflect.TypeOf(css_ast.AST{}))
+	for _, typ := range diskCacheConcreteTypes {
+		visit(typ)
+	}
+}
@@ -194,6 +194,7 @@ const (
 	LoaderEmpty
 	LoaderFile
 	LoaderGlobalCSS
+	LoaderHTML
 	LoaderJS
 	LoaderJSON
 	LoaderJSX
@@ -202,6 +203,7 @@ const (
 	LoaderTS
 	LoaderTSNoAmbiguousLessThan // Used with ".mts" and ".cts"
 	LoaderTSX
+	LoaderWasm
 )
 
 var LoaderToString = []string{
@@ -215,6 +217,7 @@ var LoaderToString = []string{
 	"empty",
 	"file",
 	"global-css",
+	"html",
 	"js",
 	"json",
 	"jsx",
@@ -223,6 +226,7 @@ var LoaderToString = []string{
 	"ts",
 	"ts",
 	"tsx",
+	"wasm",
 }
 
 func (loader Loader) IsTypeScript() bool {
@@ -330,6 +334,15 @@ func (matchers ExternalMatchers) HasMatchers() bool {
 	return len(matchers.Exact) > 0 || len(matchers.Patterns) > 0
 }
 
+// Files that match one of these are grouped together into a chunk with this
+// name when code splitting, instead of being placed in the chunk determined by
+// the set of entry points that can reach them.
+type ManualChunk struct {
+	Name     string
+	Packages ExternalMatchers // Package names such as "react" or "@babel/*"
+	Paths    ExternalMatchers // Absolute paths such as "/project/src/vendor/*"
+}
+
 type ExternalSettings struct {
 	PreResolve  ExternalMatchers
 	PostResolve ExternalMatchers
@@ -434,6 +447,10 @@ type Options struct {
 	ChunkPathTemplate []PathTemplate
 	AssetPathTemplate []PathTemplate
 
+	// These are sorted by name. A file that matches more than one manual chunk
+	// goes in the first one.
+	ManualChunks []ManualChunk
+
 	Plugins    []Plugin
 	SourceRoot string
 	Stdin      *StdinInfo
@@ -458,9 +475,14 @@ type Options struct {
 	ProfilerNames     bool
 	CodeSplitting     bool
 	WatchMode         bool
+	HMR               bool
 	AllowOverwrite    bool
 	LegalComments     LegalComments
 
+	// If true, files that use the "wasm" loader have their bytes written to a
+	// separate output file instead of being embedded in the generated code
+	WasmBytesAsFile bool
+
 	// If true, make sure to generate a single file that can be written to stdout
 	WriteToStdout bool
 
@@ -477,6 +499,22 @@ type Options struct {
 	NeedsMetafile          bool
 	SourceMap              SourceMap
 	ExcludeSourcesContent  bool
+
+	// If true, the metafile also records why each input file and each top-level
+	// statement in each output file survived tree shaking
+	NeedsMetafileIncludedBy bool
+
+	// Sources with paths matching any of these are listed in the "ignoreList"
+	// field of generated source maps, which tells debuggers to skip over them
+	SourcemapIgnoreList []*regexp.Regexp
+
+	// If true, each output file and its source map get a "debugId" derived from
+	// the content hash of that output file
+	DebugIDs bool
+
+	// If true, source maps are generated as index source maps with one section
+	// per file, which avoids re-encoding the mappings for each file
+	SourcemapSections bool
 }
 
 type TSImportsNotUsedAsValues uint8
@@ -740,10 +778,12 @@ func PluginAppliesToPath(path logger.Path, filter *regexp.Regexp, namespace stri
 // Plugin API
 
 type Plugin struct {
-	Name      string
-	OnStart   []OnStart
-	OnResolve []OnResolve
-	OnLoad    []OnLoad
+	Name          string
+	OnStart       []OnStart
+	OnResolve     []OnResolve
+	OnLoad        []OnLoad
+	OnTransform   []OnTransform
+	OnRenderChunk []OnRenderChunk
 }
 
 type OnStart struct {
@@ -769,6 +809,7 @@ type OnResolveArgs struct {
 	PluginData interface{}
 	Importer   logger.Path
 	Kind       ast.ImportKind
+	With       logger.ImportAttributes
 }
 
 type OnResolveResult struct {
@@ -802,6 +843,7 @@ type OnLoadResult struct {
 	PluginName string
 
 	Contents      *string
+	SourceMap     *string
 	AbsResolveDir string
 	PluginData    interface{}
 
@@ -813,3 +855,59 @@ type OnLoadResult struct {
 
 	Loader Loader
 }
+
+type OnTransform struct {
+	Filter    *regexp.Regexp
+	Callback  func(OnTransformArgs) OnTransformResult
+	Name      string
+	Namespace string
+}
+
+type OnTransformArgs struct {
+	PluginData interface{}
+	Path       logger.Path
+	Contents   string
+	Loader     Loader
+}
+
+type OnTransformResult struct {
+	PluginName string
+
+	Contents   *string
+	SourceMap  *string
+	PluginData interface{}
+
+	Msgs        []logger.Msg
+	ThrownError error
+
+	AbsWatchFiles []string
+	AbsWatchDirs  []string
+
+	Loader Loader
+}
+
+type OnRenderChunk struct {
+	Callback func(OnRenderChunkArgs) OnRenderChunkResult
+	Name     string
+}
+
+type OnRenderChunkArgs struct {
+	// This is relative to the output directory and still contains any "[hash]"
+	// placeholder, since the hash isn't known until after these plugins run
+	PathTemplate string
+
+	// This is the pretty path of the entry point, if the chunk is for one
+	EntryPoint string
+
+	Contents string
+}
+
+type OnRenderChunkResult struct {
+	PluginName string
+
+	Contents  *string
+	SourceMap *string
+
+	Msgs        []logger.Msg
+	ThrownError error
+}
@@ -119,6 +119,16 @@ func (a *Options) Equal(b *Options) bool {
 	return true
 }
 
+// The persistent cache can't use "Equal" since it compares options from
+// different processes. It serializes these values instead, which together
+// cover everything that "Equal" compares.
+func (a *Options) CacheKeyData() []interface{} {
+	return []interface{}{
+		a.optionsThatSupportStructuralEquality,
+		a.cssPrefixData,
+	}
+}
+
 func Parse(log logger.Log, source logger.Source, options Options) css_ast.AST {
 	result := css_lexer.Tokenize(log, source, css_lexer.Options{
 		RecordAllComments: options.minifyIdentifiers,
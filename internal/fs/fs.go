@@ -73,6 +73,37 @@ type accessedEntries struct {
 	mutex sync.Mutex
 }
 
+// This returns a non-empty path if the entries in the directory have changed
+// in a way that the build could have observed. See "accessedEntries" above.
+func (accessed *accessedEntries) changedPath(fs FS, dir string, names []string) string {
+	accessed.mutex.Lock()
+	defer accessed.mutex.Unlock()
+	if allEntries := accessed.allEntries; allEntries != nil {
+		// Check all entries
+		if len(names) != len(allEntries) {
+			return dir
+		}
+		sort.Strings(names)
+		for i, s := range names {
+			if s != allEntries[i] {
+				return dir
+			}
+		}
+	} else {
+		// Check individual entries
+		lookup := make(map[string]string, len(names))
+		for _, name := range names {
+			lookup[strings.ToLower(name)] = name
+		}
+		for name, wasPresent := range accessed.wasPresent {
+			if originalName, isPresent := lookup[name]; wasPresent != isPresent {
+				return fs.Join(dir, originalName)
+			}
+		}
+	}
+	return ""
+}
+
 type DirEntries struct {
 	data            map[string]*Entry
 	accessedEntries *accessedEntries
@@ -226,6 +257,9 @@ type ModKey struct {
 	mtime_nsec int64
 	mode       uint32
 	uid        uint32
+
+	// This is only used by file systems that are provided by an API user
+	custom string
 }
 
 // Some file systems have a time resolution of only a few seconds. If a mtime
@@ -0,0 +1,355 @@
+package fs
+
+// This is an implementation of the "FS" interface that forwards to a file
+// system provided by an API user. That lets an entire build run against a
+// file system that lives in memory, in a database, or that overlays some
+// files on top of another file system without writing anything to disk.
+//
+// Paths always use Unix-style forward slashes regardless of the host operating
+// system since the file system is typically virtual. Custom file systems don't
+// have symbolic links or Yarn's ".zip" overlay.
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"syscall"
+)
+
+type CustomEntry struct {
+	Name  string
+	IsDir bool
+}
+
+type CustomFSOptions struct {
+	AbsWorkingDir string
+	WantWatchData bool
+	DoNotCache    bool
+
+	ReadDirectory func(path string) ([]CustomEntry, error)
+	ReadFile      func(path string) ([]byte, error)
+
+	// This is optional. If present, it should return a string that changes
+	// whenever the contents of the file change. Otherwise the file contents
+	// are compared instead.
+	ModKey func(path string) (string, error)
+}
+
+type customFS struct {
+	options CustomFSOptions
+	fp      goFilepath
+
+	// Stores the file entries for directories we've listed before
+	entries      map[string]entriesOrErr
+	entriesMutex sync.Mutex
+
+	// This stores data that will end up being returned by "WatchData()"
+	watchData  map[string]privateWatchData
+	watchMutex sync.Mutex
+}
+
+func CustomFS(options CustomFSOptions) (FS, error) {
+	fp := goFilepath{
+		cwd:           options.AbsWorkingDir,
+		isWindows:     false,
+		pathSeparator: '/',
+	}
+
+	if fp.cwd == "" {
+		fp.cwd = "/"
+	} else if !fp.isAbs(fp.cwd) {
+		return nil, fmt.Errorf("The working directory %q is not an absolute path", fp.cwd)
+	} else {
+		fp.cwd = fp.clean(fp.cwd)
+	}
+
+	// Only allocate memory for watch data if necessary
+	var watchData map[string]privateWatchData
+	if options.WantWatchData {
+		watchData = make(map[string]privateWatchData)
+	}
+
+	return &customFS{
+		options:   options,
+		fp:        fp,
+		entries:   make(map[string]entriesOrErr),
+		watchData: watchData,
+	}, nil
+}
+
+// Custom file systems report missing files using the standard library's error
+// values, which need to be converted to the errors esbuild checks for
+func canonicalizeCustomError(err error) error {
+	if err == nil {
+		return nil
+	}
+	if errors.Is(err, syscall.ENOTDIR) {
+		return syscall.ENOTDIR
+	}
+	if errors.Is(err, os.ErrNotExist) {
+		return syscall.ENOENT
+	}
+	return err
+}
+
+func (fs *customFS) readdir(dir string) (names []string, kinds []EntryKind, canonicalError error, originalError error) {
+	entries, originalError := fs.options.ReadDirectory(dir)
+	canonicalError = canonicalizeCustomError(originalError)
+	if canonicalError != nil {
+		return
+	}
+	names = make([]string, len(entries))
+	kinds = make([]EntryKind, len(entries))
+	for i, entry := range entries {
+		names[i] = entry.Name
+		if entry.IsDir {
+			kinds[i] = DirEntry
+		} else {
+			kinds[i] = FileEntry
+		}
+	}
+	return
+}
+
+func (fs *customFS) ReadDirectory(dir string) (entries DirEntries, canonicalError error, originalError error) {
+	if !fs.options.DoNotCache {
+		// First, check the cache
+		fs.entriesMutex.Lock()
+		cached, ok := fs.entries[dir]
+		fs.entriesMutex.Unlock()
+		if ok {
+			// Cache hit: stop now
+			return cached.entries, cached.canonicalError, cached.originalError
+		}
+	}
+
+	// Cache miss: read the directory entries
+	names, kinds, canonicalError, originalError := fs.readdir(dir)
+	entries = DirEntries{dir: dir, data: make(map[string]*Entry)}
+	for i, name := range names {
+		entries.data[strings.ToLower(name)] = &Entry{
+			dir:  dir,
+			base: name,
+			kind: kinds[i],
+		}
+	}
+
+	// Store data for watch mode
+	if fs.watchData != nil {
+		fs.watchMutex.Lock()
+		state := stateDirHasAccessedEntries
+		if canonicalError != nil {
+			state = stateDirUnreadable
+		}
+		entries.accessedEntries = &accessedEntries{wasPresent: make(map[string]bool)}
+		fs.watchData[dir] = privateWatchData{
+			accessedEntries: entries.accessedEntries,
+			state:           state,
+		}
+		fs.watchMutex.Unlock()
+	}
+
+	// Update the cache unconditionally. Even if the read failed, we don't want to
+	// retry again later. The directory is inaccessible so trying again is wasted.
+	if canonicalError != nil {
+		entries.data = nil
+	}
+	if !fs.options.DoNotCache {
+		fs.entriesMutex.Lock()
+		fs.entries[dir] = entriesOrErr{
+			entries:        entries,
+			canonicalError: canonicalError,
+			originalError:  originalError,
+		}
+		fs.entriesMutex.Unlock()
+	}
+	return entries, canonicalError, originalError
+}
+
+func (fs *customFS) ReadFile(path string) (contents string, canonicalError error, originalError error) {
+	buffer, originalError := fs.options.ReadFile(path)
+	canonicalError = canonicalizeCustomError(originalError)
+
+	// Allocate the string once
+	fileContents := string(buffer)
+
+	// Store data for watch mode
+	if fs.watchData != nil {
+		fs.watchMutex.Lock()
+		data, ok := fs.watchData[path]
+		if canonicalError != nil {
+			data.state = stateFileMissing
+		} else if !ok || data.state == stateDirUnreadable {
+			data.state = stateFileNeedModKey
+		}
+		data.fileContents = fileContents
+		fs.watchData[path] = data
+		fs.watchMutex.Unlock()
+	}
+
+	return fileContents, canonicalError, originalError
+}
+
+func (fs *customFS) OpenFile(path string) (OpenedFile, error, error) {
+	buffer, originalError := fs.options.ReadFile(path)
+	if canonicalError := canonicalizeCustomError(originalError); canonicalError != nil {
+		return nil, canonicalError, originalError
+	}
+	return &InMemoryOpenedFile{Contents: buffer}, nil, nil
+}
+
+func (fs *customFS) modKey(path string) (ModKey, error) {
+	if fs.options.ModKey == nil {
+		return ModKey{}, modKeyUnusable
+	}
+	key, err := fs.options.ModKey(path)
+	if err != nil {
+		return ModKey{}, err
+	}
+	if key == "" {
+		return ModKey{}, modKeyUnusable
+	}
+	return ModKey{custom: key}, nil
+}
+
+func (fs *customFS) ModKey(path string) (ModKey, error) {
+	key, err := fs.modKey(path)
+
+	// Store data for watch mode
+	if fs.watchData != nil {
+		fs.watchMutex.Lock()
+		data, ok := fs.watchData[path]
+		if !ok {
+			if err == modKeyUnusable {
+				data.state = stateFileUnusableModKey
+			} else if err != nil {
+				data.state = stateFileMissing
+			} else {
+				data.state = stateFileHasModKey
+			}
+		} else if data.state == stateFileNeedModKey {
+			data.state = stateFileHasModKey
+		}
+		data.modKey = key
+		fs.watchData[path] = data
+		fs.watchMutex.Unlock()
+	}
+
+	return key, err
+}
+
+func (fs *customFS) IsAbs(p string) bool {
+	return fs.fp.isAbs(p)
+}
+
+func (fs *customFS) Abs(p string) (string, bool) {
+	abs, err := fs.fp.abs(p)
+	return abs, err == nil
+}
+
+func (fs *customFS) Dir(p string) string {
+	return fs.fp.dir(p)
+}
+
+func (fs *customFS) Base(p string) string {
+	return fs.fp.base(p)
+}
+
+func (fs *customFS) Ext(p string) string {
+	return fs.fp.ext(p)
+}
+
+func (fs *customFS) Join(parts ...string) string {
+	return fs.fp.clean(fs.fp.join(parts))
+}
+
+func (fs *customFS) Cwd() string {
+	return fs.fp.cwd
+}
+
+func (fs *customFS) Rel(base string, target string) (string, bool) {
+	if rel, err := fs.fp.rel(base, target); err == nil {
+		return rel, true
+	}
+	return "", false
+}
+
+// Entries are created with their kind already known, so this isn't called
+func (fs *customFS) kind(dir string, base string) (symlink string, kind EntryKind) {
+	return "", 0
+}
+
+func (fs *customFS) WatchData() WatchData {
+	paths := make(map[string]func() string)
+
+	fs.watchMutex.Lock()
+	defer fs.watchMutex.Unlock()
+
+	for path, data := range fs.watchData {
+		// Each closure below needs its own copy of these loop variables
+		path := path
+		data := data
+
+		// Each function should return true if the state has been changed
+		if data.state == stateFileNeedModKey {
+			key, err := fs.modKey(path)
+			if err == modKeyUnusable {
+				data.state = stateFileUnusableModKey
+			} else if err != nil {
+				data.state = stateFileMissing
+			} else {
+				data.state = stateFileHasModKey
+				data.modKey = key
+			}
+		}
+
+		switch data.state {
+		case stateDirUnreadable:
+			paths[path] = func() string {
+				if _, _, err, _ := fs.readdir(path); err == nil {
+					return path
+				}
+				return ""
+			}
+
+		case stateDirHasAccessedEntries:
+			paths[path] = func() string {
+				names, _, err, _ := fs.readdir(path)
+				if err != nil {
+					return path
+				}
+				return data.accessedEntries.changedPath(fs, path, names)
+			}
+
+		case stateFileMissing:
+			paths[path] = func() string {
+				if _, err := fs.options.ReadFile(path); err == nil {
+					return path
+				}
+				return ""
+			}
+
+		case stateFileHasModKey:
+			paths[path] = func() string {
+				if key, err := fs.modKey(path); err != nil || key != data.modKey {
+					return path
+				}
+				return ""
+			}
+
+		case stateFileUnusableModKey:
+			paths[path] = func() string {
+				if buffer, err := fs.options.ReadFile(path); err != nil || string(buffer) != data.fileContents {
+					return path
+				}
+				return ""
+			}
+		}
+	}
+
+	return WatchData{
+		Paths: paths,
+	}
+}
@@ -5,7 +5,6 @@ import (
 	"io"
 	"io/ioutil"
 	"os"
-	"sort"
 	"strings"
 	"sync"
 	"syscall"
@@ -476,32 +475,7 @@ func (fs *realFS) WatchData() WatchData {
 				if err != nil {
 					return path
 				}
-				data.accessedEntries.mutex.Lock()
-				defer data.accessedEntries.mutex.Unlock()
-				if allEntries := data.accessedEntries.allEntries; allEntries != nil {
-					// Check all entries
-					if len(names) != len(allEntries) {
-						return path
-					}
-					sort.Strings(names)
-					for i, s := range names {
-						if s != allEntries[i] {
-							return path
-						}
-					}
-				} else {
-					// Check individual entries
-					lookup := make(map[string]string, len(names))
-					for _, name := range names {
-						lookup[strings.ToLower(name)] = name
-					}
-					for name, wasPresent := range data.accessedEntries.wasPresent {
-						if originalName, isPresent := lookup[name]; wasPresent != isPresent {
-							return fs.Join(path, originalName)
-						}
-					}
-				}
-				return ""
+				return data.accessedEntries.changedPath(fs, path, names)
 			}
 
 		case stateFileMissing:
@@ -51,6 +51,12 @@ type LinkerFile struct {
 	// corresponding entry point chunk.
 	EntryPointChunkIndex uint32
 
+	// If code splitting is enabled and this file matches one of the manual
+	// chunks, this is the index of that chunk in "options.ManualChunks". All
+	// files in a manual chunk are grouped together regardless of which entry
+	// points can reach them.
+	ManualChunkIndex ast.Index32
+
 	// This file is an entry point if and only if this is not "entryPointNone".
 	// Note that dynamically-imported files are allowed to also be specified by
 	// the user as top-level entry points, so some dynamically-imported files
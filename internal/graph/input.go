@@ -11,6 +11,7 @@ import (
 	"github.com/evanw/esbuild/internal/ast"
 	"github.com/evanw/esbuild/internal/config"
 	"github.com/evanw/esbuild/internal/css_ast"
+	"github.com/evanw/esbuild/internal/html_ast"
 	"github.com/evanw/esbuild/internal/js_ast"
 	"github.com/evanw/esbuild/internal/logger"
 	"github.com/evanw/esbuild/internal/resolver"
@@ -40,11 +41,35 @@ type OutputFile struct {
 	// fully assembled later.
 	JSONMetadataChunk string
 
+	// If hot module replacement is enabled, this maps the path of each module
+	// in this file to a hash of that module's generated code. The hash for the
+	// empty path covers all code that can't be replaced individually.
+	ModuleHashesForHMR map[string]uint64
+
+	// This is only present for the main output file of each entry point. It's
+	// used when linking HTML entry points, which need to know which output files
+	// were generated for the scripts and style sheets that they reference.
+	EntryPoint *EntryPointOutput
+
 	AbsPath      string
 	Contents     []byte
 	IsExecutable bool
 }
 
+type EntryPointOutput struct {
+	AbsPath string
+
+	// A JavaScript entry point that imports CSS also generates a CSS file
+	CSSAbsPath string
+
+	// When code splitting is active, these are the chunks that this entry point
+	// imports using static import statements (including indirect imports). They
+	// are in the order that they are imported.
+	StaticImportAbsPaths []string
+
+	SourceIndex uint32
+}
+
 type SideEffects struct {
 	// This is optional additional information for use in error messages
 	Data *resolver.SideEffectsData
@@ -125,3 +150,11 @@ type CopyRepr struct {
 func (repr *CopyRepr) ImportRecords() *[]ast.ImportRecord {
 	return nil
 }
+
+type HTMLRepr struct {
+	AST html_ast.AST
+}
+
+func (repr *HTMLRepr) ImportRecords() *[]ast.ImportRecord {
+	return &repr.AST.ImportRecords
+}
@@ -156,6 +156,12 @@ type JSReprMeta struct {
 	// flag is used during the traversal that enforces this invariant, and is used
 	// to detect when the fixed point has been reached.
 	DidWrapDependencies bool
+
+	// This is set for ECMAScript modules that were converted into CommonJS
+	// closures so they can be replaced individually when hot module replacement
+	// is enabled. Their ESM exports are attached to the closure's "exports"
+	// object, which is also marked with the "__esModule" flag.
+	IsESMWrappedForHMR bool
 }
 
 type ImportData struct {
@@ -18,6 +18,13 @@ func (bs BitSet) SetBit(bit uint) {
 	bs.entries[bit/8] |= 1 << (bit & 7)
 }
 
+// Sets every bit that is set in the other bit set, which must be the same size
+func (bs BitSet) Union(other BitSet) {
+	for i, entry := range other.entries {
+		bs.entries[i] |= entry
+	}
+}
+
 func (bs BitSet) Equals(other BitSet) bool {
 	return bytes.Equal(bs.entries, other.entries)
 }
@@ -1,6 +1,9 @@
 package helpers
 
-import "strings"
+import (
+	"regexp"
+	"strings"
+)
 
 type GlobWildcard uint8
 
@@ -56,3 +59,32 @@ func GlobPatternToString(pattern []GlobPart) string {
 	}
 	return sb.String()
 }
+
+// This turns a glob pattern into a regular expression that matches an entire
+// path. Single wildcards only match within a path segment while a "globstar"
+// path segment matches zero or more path segments. Paths are expected to use
+// "/" as the separator.
+func GlobPatternToRegexp(pattern []GlobPart) *regexp.Regexp {
+	wasGlobStar := false
+	sb := strings.Builder{}
+	sb.WriteByte('^')
+	for _, part := range pattern {
+		prefix := strings.ReplaceAll(part.Prefix, "\\", "/")
+		if wasGlobStar && strings.HasPrefix(prefix, "/") {
+			prefix = prefix[1:] // Move over the "/" after a globstar
+		}
+		sb.WriteString(regexp.QuoteMeta(prefix))
+		switch part.Wildcard {
+		case GlobAllIncludingSlash:
+			sb.WriteString("(?:[^/]*(?:/|$))*")
+			wasGlobStar = true
+		case GlobAllExceptSlash:
+			sb.WriteString("[^/]*")
+			wasGlobStar = false
+		default:
+			wasGlobStar = false
+		}
+	}
+	sb.WriteByte('$')
+	return regexp.MustCompile(sb.String())
+}
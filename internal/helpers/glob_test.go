@@ -0,0 +1,37 @@
+package helpers_test
+
+import (
+	"testing"
+
+	"github.com/evanw/esbuild/internal/helpers"
+	"github.com/evanw/esbuild/internal/test"
+)
+
+func TestGlobPatternToRegexp(t *testing.T) {
+	check := func(pattern string, path string, expected bool) {
+		t.Helper()
+		t.Run(pattern+" "+path, func(t *testing.T) {
+			re := helpers.GlobPatternToRegexp(helpers.ParseGlobPattern(pattern))
+			test.AssertEqual(t, re.MatchString(path), expected)
+		})
+	}
+
+	check("foo.js", "foo.js", true)
+	check("foo.js", "foo_js", false)
+	check("foo.js", "dir/foo.js", false)
+
+	check("*.js", "foo.js", true)
+	check("*.js", "dir/foo.js", false)
+	check("dir/*", "dir/foo.js", true)
+	check("dir/*", "dir/sub/foo.js", false)
+
+	check("**/*.js", "foo.js", true)
+	check("**/*.js", "dir/foo.js", true)
+	check("**/*.js", "dir/sub/foo.js", true)
+	check("**/*.js", "dir/sub/foo.css", false)
+	check("node_modules/**", "node_modules/a/b.js", true)
+	check("**/node_modules/**", "node_modules/a/b.js", true)
+	check("**/node_modules/**", "x/node_modules/a/b.js", true)
+	check("**/node_modules/**", "x/node_modules_a/b.js", false)
+	check("dir\\**\\*.js", "dir/sub/foo.js", true)
+}
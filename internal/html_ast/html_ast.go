@@ -0,0 +1,56 @@
+package html_ast
+
+import (
+	"github.com/evanw/esbuild/internal/ast"
+	"github.com/evanw/esbuild/internal/logger"
+)
+
+// HTML files are not parsed into a tree. The bundler only cares about the
+// URLs in the file that reference other files, so the AST is just a list of
+// those URLs along with their locations. Everything else in the file is
+// passed through to the output verbatim.
+
+type AST struct {
+	ImportRecords []ast.ImportRecord
+
+	// These are sorted in source order and don't overlap
+	URLs []URL
+}
+
+type URLKind uint8
+
+const (
+	// A file referenced by an attribute such as "<img src>". These are
+	// resolved and then loaded using the loader configured for that file.
+	URLAsset URLKind = iota
+
+	// A "<script src>" tag without "type=module". This is bundled as a
+	// separate JavaScript entry point.
+	URLClassicScript
+
+	// A "<script type=module src>" tag. This is bundled as a separate
+	// JavaScript entry point. Chunks that it imports get "modulepreload" hints.
+	URLModuleScript
+
+	// A "<link rel=stylesheet href>" tag. This is bundled as a separate CSS
+	// entry point.
+	URLStylesheet
+)
+
+func (kind URLKind) IsEntryPoint() bool {
+	return kind != URLAsset
+}
+
+type URL struct {
+	// The range of the URL itself, excluding any surrounding quotes or
+	// whitespace. This is what gets replaced with the final output path.
+	Range logger.Range
+
+	// The location of the "<" that starts the tag containing this URL. Extra
+	// tags such as "<link rel=stylesheet>" for CSS imported by a script are
+	// inserted here.
+	TagStart logger.Loc
+
+	ImportRecordIndex uint32
+	Kind              URLKind
+}
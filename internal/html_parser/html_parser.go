@@ -0,0 +1,396 @@
+package html_parser
+
+// This is not a full HTML parser. It only understands enough of the HTML
+// tokenization rules to find the tags and attributes that reference other
+// files (scripts, stylesheets, images, etc.) and to avoid being confused by
+// comments and by the contents of raw text elements such as "<script>". The
+// rest of the file is not interpreted and is passed through unchanged.
+
+import (
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/evanw/esbuild/internal/ast"
+	"github.com/evanw/esbuild/internal/html_ast"
+	"github.com/evanw/esbuild/internal/logger"
+)
+
+type parser struct {
+	source        logger.Source
+	importRecords []ast.ImportRecord
+	urls          []html_ast.URL
+}
+
+type attribute struct {
+	name     string
+	value    string // With character references decoded
+	valueLoc logger.Loc
+	rawValue string // Exactly as it appears in the source
+}
+
+func Parse(source logger.Source) html_ast.AST {
+	p := parser{source: source}
+	text := source.Contents
+	i := 0
+
+	for {
+		lt := strings.IndexByte(text[i:], '<')
+		if lt == -1 {
+			break
+		}
+		i += lt
+		rest := text[i:]
+
+		switch {
+		case strings.HasPrefix(rest, "<!--"):
+			if end := strings.Index(rest[4:], "-->"); end != -1 {
+				i += 4 + end + 3
+			} else {
+				i = len(text)
+			}
+
+		case strings.HasPrefix(rest, "</"), strings.HasPrefix(rest, "<!"), strings.HasPrefix(rest, "<?"):
+			if end := strings.IndexByte(rest, '>'); end != -1 {
+				i += end + 1
+			} else {
+				i = len(text)
+			}
+
+		case len(rest) > 1 && isASCIILetter(rest[1]):
+			i = p.parseTag(i)
+
+		default:
+			i++
+		}
+	}
+
+	return html_ast.AST{
+		ImportRecords: p.importRecords,
+		URLs:          p.urls,
+	}
+}
+
+// Returns the index after the end of the tag. The contents of raw text
+// elements are also skipped over since they can contain "<" characters.
+func (p *parser) parseTag(start int) int {
+	text := p.source.Contents
+	i := start + 1
+	for i < len(text) && !isWhitespace(text[i]) && text[i] != '/' && text[i] != '>' {
+		i++
+	}
+	name := strings.ToLower(text[start+1 : i])
+	var attrs []attribute
+
+	for {
+		for i < len(text) && (isWhitespace(text[i]) || text[i] == '/') {
+			i++
+		}
+		if i == len(text) {
+			break
+		}
+		if text[i] == '>' {
+			i++
+			break
+		}
+
+		// Parse the attribute name. Note that the name is allowed to start with
+		// "=" according to the HTML specification.
+		nameStart := i
+		i++
+		for i < len(text) && !isWhitespace(text[i]) && text[i] != '/' && text[i] != '>' && text[i] != '=' {
+			i++
+		}
+		attr := attribute{name: strings.ToLower(text[nameStart:i])}
+
+		// Parse the optional attribute value
+		j := i
+		for j < len(text) && isWhitespace(text[j]) {
+			j++
+		}
+		if j < len(text) && text[j] == '=' {
+			j++
+			for j < len(text) && isWhitespace(text[j]) {
+				j++
+			}
+			if j < len(text) && (text[j] == '"' || text[j] == '\'') {
+				valueStart := j + 1
+				valueEnd := len(text)
+				if end := strings.IndexByte(text[valueStart:], text[j]); end != -1 {
+					valueEnd = valueStart + end
+					i = valueEnd + 1
+				} else {
+					i = valueEnd
+				}
+				attr.rawValue = text[valueStart:valueEnd]
+				attr.valueLoc = logger.Loc{Start: int32(valueStart)}
+			} else {
+				valueStart := j
+				for j < len(text) && !isWhitespace(text[j]) && text[j] != '>' {
+					j++
+				}
+				attr.rawValue = text[valueStart:j]
+				attr.valueLoc = logger.Loc{Start: int32(valueStart)}
+				i = j
+			}
+			attr.value = decodeCharacterReferences(attr.rawValue)
+		}
+
+		attrs = append(attrs, attr)
+	}
+
+	p.visitTag(name, logger.Loc{Start: int32(start)}, attrs)
+
+	// Skip over the contents of elements that can't contain other tags
+	switch name {
+	case "script", "style", "textarea", "title", "xmp", "iframe", "noembed", "noframes":
+		if end := indexCaseInsensitive(text[i:], "</"+name); end != -1 {
+			i += end
+		} else {
+			i = len(text)
+		}
+	}
+
+	return i
+}
+
+func (p *parser) visitTag(name string, tagStart logger.Loc, attrs []attribute) {
+	switch name {
+	case "script":
+		if src, ok := findAttribute(attrs, "src"); ok {
+			kind := html_ast.URLClassicScript
+			if scriptType, ok := findAttribute(attrs, "type"); ok {
+				switch strings.ToLower(strings.TrimSpace(scriptType.value)) {
+				case "module":
+					kind = html_ast.URLModuleScript
+				case "", "text/javascript", "application/javascript":
+				default:
+					// Leave other script types alone (e.g. "importmap" or templates)
+					return
+				}
+			}
+			p.addURL(src, tagStart, kind)
+		}
+
+	case "link":
+		if href, ok := findAttribute(attrs, "href"); ok {
+			if rel, ok := findAttribute(attrs, "rel"); ok {
+				for _, token := range strings.Fields(strings.ToLower(rel.value)) {
+					switch token {
+					case "stylesheet":
+						p.addURL(href, tagStart, html_ast.URLStylesheet)
+						return
+
+					case "icon", "apple-touch-icon", "mask-icon":
+						p.addURL(href, tagStart, html_ast.URLAsset)
+						return
+					}
+				}
+			}
+		}
+
+	case "img", "source":
+		if src, ok := findAttribute(attrs, "src"); ok {
+			p.addURL(src, tagStart, html_ast.URLAsset)
+		}
+		if srcset, ok := findAttribute(attrs, "srcset"); ok {
+			p.addSrcSet(srcset, tagStart)
+		}
+
+	case "video":
+		if src, ok := findAttribute(attrs, "src"); ok {
+			p.addURL(src, tagStart, html_ast.URLAsset)
+		}
+		if poster, ok := findAttribute(attrs, "poster"); ok {
+			p.addURL(poster, tagStart, html_ast.URLAsset)
+		}
+
+	case "audio", "track", "embed":
+		if src, ok := findAttribute(attrs, "src"); ok {
+			p.addURL(src, tagStart, html_ast.URLAsset)
+		}
+
+	case "input":
+		if inputType, ok := findAttribute(attrs, "type"); ok && strings.EqualFold(strings.TrimSpace(inputType.value), "image") {
+			if src, ok := findAttribute(attrs, "src"); ok {
+				p.addURL(src, tagStart, html_ast.URLAsset)
+			}
+		}
+	}
+}
+
+func (p *parser) addURL(attr attribute, tagStart logger.Loc, kind html_ast.URLKind) {
+	// Leading and trailing whitespace is ignored in URLs
+	raw := attr.rawValue
+	loc := attr.valueLoc
+	trimmed := strings.TrimLeft(raw, " \t\n\f\r")
+	loc.Start += int32(len(raw) - len(trimmed))
+	trimmed = strings.TrimRight(trimmed, " \t\n\f\r")
+	p.addURLText(trimmed, loc, tagStart, kind)
+}
+
+// A "srcset" attribute contains a comma-separated list of image candidates,
+// each of which is a URL followed by an optional descriptor such as "2x"
+func (p *parser) addSrcSet(attr attribute, tagStart logger.Loc) {
+	raw := attr.rawValue
+	i := 0
+	for i < len(raw) {
+		for i < len(raw) && (isWhitespace(raw[i]) || raw[i] == ',') {
+			i++
+		}
+		start := i
+		for i < len(raw) && !isWhitespace(raw[i]) {
+			i++
+		}
+		end := i
+
+		// A trailing comma after the URL ends the candidate early
+		if end > start && raw[end-1] == ',' {
+			for end > start && raw[end-1] == ',' {
+				end--
+			}
+		} else {
+			for i < len(raw) && raw[i] != ',' {
+				i++
+			}
+		}
+
+		if end > start {
+			p.addURLText(raw[start:end], logger.Loc{Start: attr.valueLoc.Start + int32(start)}, tagStart, html_ast.URLAsset)
+		}
+	}
+}
+
+func (p *parser) addURLText(raw string, loc logger.Loc, tagStart logger.Loc, kind html_ast.URLKind) {
+	url := decodeCharacterReferences(raw)
+	if !isLocalURL(url) {
+		return
+	}
+
+	importKind := ast.ImportURL
+	if kind.IsEntryPoint() {
+		// URLs in HTML are always relative to the HTML file. Scripts and style
+		// sheets become entry points, and entry point paths without a "./" are
+		// not otherwise treated as relative paths.
+		importKind = ast.ImportEntryPoint
+		if !strings.HasPrefix(url, "./") && !strings.HasPrefix(url, "../") {
+			url = "./" + url
+		}
+	}
+
+	p.urls = append(p.urls, html_ast.URL{
+		Range:             logger.Range{Loc: loc, Len: int32(len(raw))},
+		TagStart:          tagStart,
+		ImportRecordIndex: uint32(len(p.importRecords)),
+		Kind:              kind,
+	})
+	p.importRecords = append(p.importRecords, ast.ImportRecord{
+		Kind:  importKind,
+		Path:  logger.Path{Text: url},
+		Range: logger.Range{Loc: loc, Len: int32(len(raw))},
+	})
+}
+
+// Only URLs that are relative to the HTML file are bundled. Absolute URLs,
+// root-relative URLs (which depend on how the files are served), and URLs
+// with a scheme such as "https:" or "data:" are left alone.
+func isLocalURL(url string) bool {
+	if url == "" || url[0] == '#' || url[0] == '/' || url[0] == '?' {
+		return false
+	}
+	if isASCIILetter(url[0]) {
+		for i := 1; i < len(url); i++ {
+			c := url[i]
+			if c == ':' {
+				return false
+			}
+			if !isASCIILetter(c) && (c < '0' || c > '9') && c != '+' && c != '-' && c != '.' {
+				break
+			}
+		}
+	}
+	return true
+}
+
+func findAttribute(attrs []attribute, name string) (attribute, bool) {
+	for _, attr := range attrs {
+		if attr.name == name {
+			return attr, true
+		}
+	}
+	return attribute{}, false
+}
+
+func indexCaseInsensitive(text string, lowerNeedle string) int {
+	for i := 0; i+len(lowerNeedle) <= len(text); i++ {
+		if strings.EqualFold(text[i:i+len(lowerNeedle)], lowerNeedle) {
+			return i
+		}
+	}
+	return -1
+}
+
+var namedCharacterReferences = map[string]string{
+	"amp":  "&",
+	"apos": "'",
+	"gt":   ">",
+	"lt":   "<",
+	"quot": "\"",
+}
+
+// This only handles numeric character references and the few named
+// character references that are likely to appear in a URL
+func decodeCharacterReferences(text string) string {
+	amp := strings.IndexByte(text, '&')
+	if amp == -1 {
+		return text
+	}
+	sb := strings.Builder{}
+	for amp != -1 {
+		sb.WriteString(text[:amp])
+		text = text[amp:]
+		semicolon := strings.IndexByte(text, ';')
+		if semicolon == -1 {
+			break
+		}
+		name := text[1:semicolon]
+		if value, ok := namedCharacterReferences[name]; ok {
+			sb.WriteString(value)
+			text = text[semicolon+1:]
+		} else if codePoint, ok := parseNumericCharacterReference(name); ok {
+			sb.WriteRune(codePoint)
+			text = text[semicolon+1:]
+		} else {
+			sb.WriteByte('&')
+			text = text[1:]
+		}
+		amp = strings.IndexByte(text, '&')
+	}
+	sb.WriteString(text)
+	return sb.String()
+}
+
+func parseNumericCharacterReference(name string) (rune, bool) {
+	if len(name) < 2 || name[0] != '#' {
+		return 0, false
+	}
+	var value uint64
+	var err error
+	if name[1] == 'x' || name[1] == 'X' {
+		value, err = strconv.ParseUint(name[2:], 16, 32)
+	} else {
+		value, err = strconv.ParseUint(name[1:], 10, 32)
+	}
+	if err != nil || value == 0 || !utf8.ValidRune(rune(value)) {
+		return 0, false
+	}
+	return rune(value), true
+}
+
+func isASCIILetter(c byte) bool {
+	return (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
+
+func isWhitespace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n' || c == '\f' || c == '\r'
+}
@@ -0,0 +1,76 @@
+package html_parser
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/evanw/esbuild/internal/html_ast"
+	"github.com/evanw/esbuild/internal/test"
+)
+
+// Each URL is printed as its kind, the path of its import record, and the
+// text of the source range that will be replaced
+func expectURLs(t *testing.T, contents string, expected string) {
+	t.Helper()
+	t.Run(contents, func(t *testing.T) {
+		t.Helper()
+		tree := Parse(test.SourceForTest(contents))
+		var lines []string
+		for _, url := range tree.URLs {
+			var kind string
+			switch url.Kind {
+			case html_ast.URLAsset:
+				kind = "asset"
+			case html_ast.URLClassicScript:
+				kind = "script"
+			case html_ast.URLModuleScript:
+				kind = "module"
+			case html_ast.URLStylesheet:
+				kind = "stylesheet"
+			}
+			record := tree.ImportRecords[url.ImportRecordIndex]
+			lines = append(lines, fmt.Sprintf("%s %s %s", kind, record.Path.Text,
+				contents[url.Range.Loc.Start:url.Range.End()]))
+		}
+		test.AssertEqualWithDiff(t, strings.Join(lines, "\n"), expected)
+	})
+}
+
+func TestScripts(t *testing.T) {
+	expectURLs(t, `<script src="a.js"></script>`, "script ./a.js a.js")
+	expectURLs(t, `<SCRIPT SRC=a.js></SCRIPT>`, "script ./a.js a.js")
+	expectURLs(t, `<script type="module" src="./a.js"></script>`, "module ./a.js ./a.js")
+	expectURLs(t, `<script type=text/javascript src='../a.js'></script>`, "script ../a.js ../a.js")
+	expectURLs(t, `<script type="importmap" src="a.json"></script>`, "")
+	expectURLs(t, `<script src="https://example.com/a.js"></script>`, "")
+	expectURLs(t, `<script src="/a.js"></script>`, "")
+	expectURLs(t, `<script src="//example.com/a.js"></script>`, "")
+	expectURLs(t, `<script>let x = "<img src='a.png'>"</script><img src=b.png>`, "asset b.png b.png")
+}
+
+func TestLinks(t *testing.T) {
+	expectURLs(t, `<link rel="stylesheet" href="a.css">`, "stylesheet ./a.css a.css")
+	expectURLs(t, `<link href=" a.css " rel="Alternate Stylesheet">`, "stylesheet ./a.css a.css")
+	expectURLs(t, `<link rel="shortcut icon" href="a.ico">`, "asset a.ico a.ico")
+	expectURLs(t, `<link rel="preload" href="a.js">`, "")
+	expectURLs(t, `<link rel="stylesheet">`, "")
+}
+
+func TestAssets(t *testing.T) {
+	expectURLs(t, `<img src="a.png" alt="<b>">`, "asset a.png a.png")
+	expectURLs(t, `<img src="a.png?x=1&amp;y=2">`, "asset a.png?x=1&y=2 a.png?x=1&amp;y=2")
+	expectURLs(t, `<img src="data:image/png,xyz">`, "")
+	expectURLs(t, `<img srcset="a.png 1x, b.png 2x">`, "asset a.png a.png\nasset b.png b.png")
+	expectURLs(t, `<img srcset="a.png, b.png 100w,c.png">`, "asset a.png a.png\nasset b.png b.png\nasset c.png c.png")
+	expectURLs(t, `<video src="a.mp4" poster="a.jpg"></video>`, "asset a.mp4 a.mp4\nasset a.jpg a.jpg")
+	expectURLs(t, `<input type="image" src="a.png"><input src="b.png">`, "asset a.png a.png")
+}
+
+func TestIgnored(t *testing.T) {
+	expectURLs(t, `<!-- <img src="a.png"> --><img src="b.png">`, "asset b.png b.png")
+	expectURLs(t, `<!DOCTYPE html><?xml?></div><img src="b.png">`, "asset b.png b.png")
+	expectURLs(t, `<textarea><img src="a.png"></textarea>`, "")
+	expectURLs(t, `<a href="a.html">`, "")
+	expectURLs(t, `1 < 2 <img src="a.png"`, "asset a.png a.png")
+}
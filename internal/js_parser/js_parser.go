@@ -481,6 +481,7 @@ type optionsThatSupportStructuralEquality struct {
 	treeShaking            bool
 	dropDebugger           bool
 	mangleQuoted           bool
+	hmr                    bool
 
 	// This is an internal-only option used for the implementation of Yarn PnP
 	decodeHydrateRuntimeStateYarnPnP bool
@@ -525,6 +526,7 @@ func OptionsFromConfig(options *config.Options) Options {
 			treeShaking:                       options.TreeShaking,
 			dropDebugger:                      options.DropDebugger,
 			mangleQuoted:                      options.MangleQuoted,
+			hmr:                               options.HMR,
 		},
 	}
 }
@@ -582,6 +584,32 @@ func (a *Options) Equal(b *Options) bool {
 	return true
 }
 
+// The persistent cache can't use "Equal" since it compares options from
+// different processes. It serializes these values instead, which together
+// cover everything that "Equal" compares plus the defines (which "Equal" can
+// assume are the same within a single build). Regular expressions are
+// represented by their source text.
+func (a *Options) CacheKeyData() []interface{} {
+	return []interface{}{
+		a.optionsThatSupportStructuralEquality,
+		a.injectedFiles,
+		a.jsx,
+		a.tsAlwaysStrict,
+		regexpSourceForCacheKey(a.mangleProps),
+		regexpSourceForCacheKey(a.reserveProps),
+		a.dropLabels,
+		a.defines,
+	}
+}
+
+func regexpSourceForCacheKey(re *regexp.Regexp) *string {
+	if re == nil {
+		return nil
+	}
+	source := re.String()
+	return &source
+}
+
 func isSameRegexp(a *regexp.Regexp, b *regexp.Regexp) bool {
 	if a == nil {
 		return b == nil
@@ -6331,10 +6359,15 @@ func (p *parser) parsePath() (logger.Range, string, *ast.ImportAssertions, ast.I
 		p.lexer.Expect(js_lexer.TStringLiteral)
 	}
 
-	// See https://github.com/tc39/proposal-import-assertions for more info
+	// See https://github.com/tc39/proposal-import-attributes for more info
 	var assertions *ast.ImportAssertions
-	if !p.lexer.HasNewlineBefore && p.lexer.IsContextualKeyword("assert") {
+	if p.lexer.Token == js_lexer.TWith || (!p.lexer.HasNewlineBefore && p.lexer.IsContextualKeyword("assert")) {
 		// "import './foo.json' assert { type: 'json' }"
+		// "import './foo.json' with { type: 'json' }"
+		assertOrWith := ast.AssertKeyword
+		if p.lexer.Token == js_lexer.TWith {
+			assertOrWith = ast.WithKeyword
+		}
 		var entries []ast.AssertEntry
 		duplicates := make(map[string]logger.Range)
 		assertLoc := p.saveExprCommentsHere()
@@ -6397,6 +6430,7 @@ func (p *parser) parsePath() (logger.Range, string, *ast.ImportAssertions, ast.I
 			AssertLoc:          assertLoc,
 			InnerOpenBraceLoc:  openBraceLoc,
 			InnerCloseBraceLoc: closeBraceLoc,
+			AssertOrWith:       assertOrWith,
 		}
 	}
 
@@ -13322,6 +13356,16 @@ func (p *parser) visitExprInOut(expr js_ast.Expr, in exprIn) (js_ast.Expr, exprO
 			}
 		}
 
+		// Substitute "import.meta.hot" with this module's hot module replacement
+		// context. The runtime looks the context up using the module's path, which
+		// is the same key the linker uses when it registers the module's wrapper.
+		if p.options.hmr && e.Name == "hot" && in.assignTarget == js_ast.AssignTargetNone && !isDeleteTarget {
+			if _, ok := e.Target.Data.(*js_ast.EImportMeta); ok {
+				return p.callRuntime(expr.Loc, "__hmrHot", []js_ast.Expr{{Loc: expr.Loc,
+					Data: &js_ast.EString{Value: helpers.StringToUTF16(p.source.PrettyPath)}}}), exprOut{}
+			}
+		}
+
 		// Track ".then().catch()" chains
 		if isCallTarget && p.thenCatchChain.nextTarget == e {
 			if e.Name == "catch" {
@@ -13977,11 +14021,16 @@ func (p *parser) visitExprInOut(expr js_ast.Expr, in exprIn) (js_ast.Expr, exprO
 			whyLoc := e.OptionsOrNil.Loc
 
 			// However, make a special case for an additional argument that contains
-			// only an "assert" clause. In that case we can split this AST node.
+			// only an "assert" or "with" clause. In that case we can split this AST
+			// node.
 			if object, ok := e.OptionsOrNil.Data.(*js_ast.EObject); ok {
 				if len(object.Properties) == 1 {
 					if prop := object.Properties[0]; prop.Kind == js_ast.PropertyNormal && !prop.Flags.Has(js_ast.PropertyIsComputed) && !prop.Flags.Has(js_ast.PropertyIsMethod) {
-						if str, ok := prop.Key.Data.(*js_ast.EString); ok && helpers.UTF16EqualsString(str.Value, "assert") {
+						if str, ok := prop.Key.Data.(*js_ast.EString); ok && (helpers.UTF16EqualsString(str.Value, "assert") || helpers.UTF16EqualsString(str.Value, "with")) {
+							assertOrWith := ast.AssertKeyword
+							if helpers.UTF16EqualsString(str.Value, "with") {
+								assertOrWith = ast.WithKeyword
+							}
 							if value, ok := prop.ValueOrNil.Data.(*js_ast.EObject); ok {
 								entries := []ast.AssertEntry{}
 								for _, p := range value.Properties {
@@ -14023,15 +14072,16 @@ func (p *parser) visitExprInOut(expr js_ast.Expr, in exprIn) (js_ast.Expr, exprO
 										InnerCloseBraceLoc: value.CloseBraceLoc,
 										OuterOpenBraceLoc:  e.OptionsOrNil.Loc,
 										OuterCloseBraceLoc: object.CloseBraceLoc,
+										AssertOrWith:       assertOrWith,
 									}
 									why = ""
 								}
 							} else {
-								why = "the value for \"assert\" was not an object literal"
+								why = fmt.Sprintf("the value for %q was not an object literal", assertOrWith.String())
 								whyLoc = prop.ValueOrNil.Loc
 							}
 						} else {
-							why = "this property was not called \"assert\""
+							why = "this property was not called \"assert\" or \"with\""
 							whyLoc = prop.Key.Loc
 						}
 					} else {
@@ -14039,7 +14089,7 @@ func (p *parser) visitExprInOut(expr js_ast.Expr, in exprIn) (js_ast.Expr, exprO
 						whyLoc = prop.Key.Loc
 					}
 				} else {
-					why = "the second argument was not an object literal with a single property called \"assert\""
+					why = "the second argument was not an object literal with a single property called \"assert\" or \"with\""
 					whyLoc = e.OptionsOrNil.Loc
 				}
 			}
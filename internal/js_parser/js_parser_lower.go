@@ -42,10 +42,13 @@ func (p *parser) prettyPrintTargetEnvironment(feature compat.JSFeature) (where s
 func (p *parser) markSyntaxFeature(feature compat.JSFeature, r logger.Range) (didGenerateError bool) {
 	didGenerateError = true
 
-	if !p.options.unsupportedJSFeatures.Has(feature) {
-		if feature == compat.TopLevelAwait && !p.options.outputFormat.KeepESMImportExportSyntax() {
-			p.log.AddError(&p.tracker, r, fmt.Sprintf(
-				"Top-level await is currently not supported with the %q output format", p.options.outputFormat.String()))
+	// Output formats without ES module syntax run code that uses top-level
+	// await inside an async function instead, so that's what must be supported
+	if feature == compat.TopLevelAwait && !p.options.outputFormat.KeepESMImportExportSyntax() {
+		if p.options.unsupportedJSFeatures.Has(compat.AsyncAwait) {
+			where, notes := p.prettyPrintTargetEnvironment(compat.AsyncAwait)
+			p.log.AddErrorWithNotes(&p.tracker, r, fmt.Sprintf(
+				"Top-level await is not available in %s with the %q output format", where, p.options.outputFormat.String()), notes)
 			return
 		}
 
@@ -53,6 +56,11 @@ func (p *parser) markSyntaxFeature(feature compat.JSFeature, r logger.Range) (di
 		return
 	}
 
+	if !p.options.unsupportedJSFeatures.Has(feature) {
+		didGenerateError = false
+		return
+	}
+
 	var name string
 	where, notes := p.prettyPrintTargetEnvironment(feature)
 
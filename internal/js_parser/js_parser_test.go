@@ -5689,6 +5689,13 @@ func TestImportAssertions(t *testing.T) {
 	expectPrinted(t, "export {} from 'x' assert {x: 'y'}", "export {} from \"x\" assert { x: \"y\" };\n")
 	expectPrinted(t, "export * from 'x' assert {x: 'y'}", "export * from \"x\" assert { x: \"y\" };\n")
 
+	expectPrinted(t, "import 'x' with {type: 'json'}", "import \"x\" with { type: \"json\" };\n")
+	expectPrinted(t, "import 'x'\nwith {type: 'json'}", "import \"x\" with { type: \"json\" };\n")
+	expectPrinted(t, "import x from 'x' with {x: 'y'}", "import x from \"x\" with { x: \"y\" };\n")
+	expectPrinted(t, "export * from 'x' with {x: 'y'}", "export * from \"x\" with { x: \"y\" };\n")
+	expectParseError(t, "import 'x' with {x: 'y', x: 'y'}",
+		"<stdin>: ERROR: Duplicate import assertion \"x\"\n<stdin>: NOTE: The first \"x\" was here:\n")
+
 	expectPrinted(t, "import(x ? 'y' : 'z')", "x ? import(\"y\") : import(\"z\");\n")
 	expectPrinted(t, "import(x ? 'y' : 'z', {assert: {}})",
 		"x ? import(\"y\", { assert: {} }) : import(\"z\", { assert: {} });\n")
@@ -5701,12 +5708,16 @@ func TestImportAssertions(t *testing.T) {
 	expectPrintedMangle(t, "import(x ? 'y' : 'z', {assert: {'a a': 'b'}})",
 		"x ? import(\"y\", { assert: { \"a a\": \"b\" } }) : import(\"z\", { assert: { \"a a\": \"b\" } });\n")
 
+	expectPrinted(t, "import(x ? 'y' : 'z', {with: {a: 'b'}})",
+		"x ? import(\"y\", { with: { a: \"b\" } }) : import(\"z\", { with: { a: \"b\" } });\n")
+
 	expectPrinted(t, "import(x ? 'y' : 'z', {})", "import(x ? \"y\" : \"z\", {});\n")
 	expectPrinted(t, "import(x ? 'y' : 'z', {assert: []})", "import(x ? \"y\" : \"z\", { assert: [] });\n")
 	expectPrinted(t, "import(x ? 'y' : 'z', {asserts: {}})", "import(x ? \"y\" : \"z\", { asserts: {} });\n")
 	expectPrinted(t, "import(x ? 'y' : 'z', {assert: {x: 1}})", "import(x ? \"y\" : \"z\", { assert: { x: 1 } });\n")
 
 	expectPrintedTarget(t, 2015, "import 'x' assert {x: 'y'}", "import \"x\";\n")
+	expectPrintedTarget(t, 2015, "import 'x' with {x: 'y'}", "import \"x\";\n")
 	expectPrintedTarget(t, 2015, "import(x, {assert: {x: 'y'}})", "import(x);\n")
 	expectPrintedTarget(t, 2015, "import(x, {assert: {x: 1}})", "import(x);\n")
 	expectPrintedTarget(t, 2015, "import(x ? 'y' : 'z', {assert: {x: 'y'}})", "x ? import(\"y\") : import(\"z\");\n")
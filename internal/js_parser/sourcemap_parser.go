@@ -25,6 +25,10 @@ func ParseSourceMap(log logger.Log, source logger.Source) *sourcemap.SourceMap {
 		return nil
 	}
 
+	return parseSourceMapObject(log, source, &tracker, obj)
+}
+
+func parseSourceMapObject(log logger.Log, source logger.Source, tracker *logger.LineColumnTracker, obj *js_ast.EObject) *sourcemap.SourceMap {
 	var sources []string
 	var sourcesContent []sourcemap.SourceContent
 	var names []string
@@ -33,12 +37,9 @@ func ParseSourceMap(log logger.Log, source logger.Source) *sourcemap.SourceMap {
 	hasVersion := false
 
 	for _, prop := range obj.Properties {
-		keyRange := source.RangeOfString(prop.Key.Loc)
-
 		switch helpers.UTF16ToString(prop.Key.Data.(*js_ast.EString).Value) {
 		case "sections":
-			log.AddID(logger.MsgID_SourceMap_SectionsInSourceMap, logger.Warning, &tracker, keyRange, "Source maps with \"sections\" are not supported")
-			return nil
+			return parseSourceMapSections(log, source, tracker, prop.ValueOrNil)
 
 		case "version":
 			if value, ok := prop.ValueOrNil.Data.(*js_ast.ENumber); ok && value.Value == 3 {
@@ -243,7 +244,7 @@ func ParseSourceMap(log logger.Log, source logger.Source) *sourcemap.SourceMap {
 
 	if errorText != "" {
 		r := logger.Range{Loc: logger.Loc{Start: mappingsStart + int32(current)}, Len: int32(errorLen)}
-		log.AddID(logger.MsgID_SourceMap_InvalidSourceMappings, logger.Warning, &tracker, r,
+		log.AddID(logger.MsgID_SourceMap_InvalidSourceMappings, logger.Warning, tracker, r,
 			fmt.Sprintf("Bad \"mappings\" data in source map at character %d: %s", current, errorText))
 		return nil
 	}
@@ -264,6 +265,109 @@ func ParseSourceMap(log logger.Log, source logger.Source) *sourcemap.SourceMap {
 	}
 }
 
+// An index source map is flattened into a regular source map by parsing the
+// map for each section and then moving it to the offset of that section. The
+// sources and names of all sections are concatenated together.
+func parseSourceMapSections(log logger.Log, source logger.Source, tracker *logger.LineColumnTracker, value js_ast.Expr) *sourcemap.SourceMap {
+	array, ok := value.Data.(*js_ast.EArray)
+	if !ok {
+		return nil
+	}
+
+	var result sourcemap.SourceMap
+	var prevEnd sourcemap.Mapping
+	needSort := false
+
+	for _, item := range array.Items {
+		section, ok := item.Data.(*js_ast.EObject)
+		if !ok {
+			continue
+		}
+
+		var offsetLine int32
+		var offsetColumn int32
+		var sm *sourcemap.SourceMap
+		for _, prop := range section.Properties {
+			switch helpers.UTF16ToString(prop.Key.Data.(*js_ast.EString).Value) {
+			case "offset":
+				if offset, ok := prop.ValueOrNil.Data.(*js_ast.EObject); ok {
+					for _, field := range offset.Properties {
+						if number, ok := field.ValueOrNil.Data.(*js_ast.ENumber); ok && number.Value >= 0 {
+							switch helpers.UTF16ToString(field.Key.Data.(*js_ast.EString).Value) {
+							case "line":
+								offsetLine = int32(number.Value)
+							case "column":
+								offsetColumn = int32(number.Value)
+							}
+						}
+					}
+				}
+
+			case "map":
+				if obj, ok := prop.ValueOrNil.Data.(*js_ast.EObject); ok {
+					sm = parseSourceMapObject(log, source, tracker, obj)
+				}
+
+			case "url":
+				log.AddID(logger.MsgID_SourceMap_SectionsInSourceMap, logger.Warning, tracker, source.RangeOfString(prop.Key.Loc),
+					"Source map sections with \"url\" are not supported")
+				return nil
+			}
+		}
+
+		// Skip over sections that are empty or that failed to parse
+		if sm == nil {
+			continue
+		}
+
+		// Sources without contents are padded so the indices still line up
+		sourcesOffset := int32(len(result.Sources))
+		namesOffset := uint32(len(result.Names))
+		if len(sm.SourcesContent) > 0 || len(result.SourcesContent) > 0 {
+			for len(result.SourcesContent) < len(result.Sources) {
+				result.SourcesContent = append(result.SourcesContent, sourcemap.SourceContent{})
+			}
+			result.SourcesContent = append(result.SourcesContent, sm.SourcesContent...)
+			for len(result.SourcesContent) < len(result.Sources)+len(sm.Sources) {
+				result.SourcesContent = append(result.SourcesContent, sourcemap.SourceContent{})
+			}
+		}
+		result.Sources = append(result.Sources, sm.Sources...)
+		result.Names = append(result.Names, sm.Names...)
+
+		for _, mapping := range sm.Mappings {
+			// Only the first line of the section is offset by the column
+			if mapping.GeneratedLine == 0 {
+				mapping.GeneratedColumn += offsetColumn
+			}
+			mapping.GeneratedLine += offsetLine
+			mapping.SourceIndex += sourcesOffset
+			if mapping.OriginalName.IsValid() {
+				mapping.OriginalName = ast.MakeIndex32(mapping.OriginalName.GetIndex() + namesOffset)
+			}
+
+			// Sections are supposed to be in order, but check just in case
+			if mapping.GeneratedLine < prevEnd.GeneratedLine ||
+				(mapping.GeneratedLine == prevEnd.GeneratedLine && mapping.GeneratedColumn < prevEnd.GeneratedColumn) {
+				needSort = true
+			}
+			prevEnd = mapping
+			result.Mappings = append(result.Mappings, mapping)
+		}
+	}
+
+	// Silently fail if the source map is pointless (i.e. empty)
+	if len(result.Sources) == 0 || len(result.Mappings) == 0 {
+		return nil
+	}
+
+	if needSort {
+		sort.Stable(mappingArray(result.Mappings))
+	}
+
+	return &result
+}
+
 // This type is just so we can use Go's native sort function
 type mappingArray []sourcemap.Mapping
 
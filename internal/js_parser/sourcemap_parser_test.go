@@ -0,0 +1,80 @@
+package js_parser
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/evanw/esbuild/internal/logger"
+	"github.com/evanw/esbuild/internal/test"
+)
+
+func expectParseSourceMap(t *testing.T, contents string, expected string) {
+	t.Helper()
+	t.Run(contents, func(t *testing.T) {
+		t.Helper()
+		log := logger.NewDeferLog(logger.DeferLogNoVerboseOrDebug, nil)
+		sm := ParseSourceMap(log, test.SourceForTest(contents))
+		text := ""
+		for _, msg := range log.Done() {
+			text += msg.String(logger.OutputOptions{}, logger.TerminalInfo{})
+		}
+		if sm != nil {
+			var lines []string
+			for _, m := range sm.Mappings {
+				line := fmt.Sprintf("%d:%d => %s:%d:%d", m.GeneratedLine, m.GeneratedColumn,
+					sm.Sources[m.SourceIndex], m.OriginalLine, m.OriginalColumn)
+				if m.OriginalName.IsValid() {
+					line += " " + sm.Names[m.OriginalName.GetIndex()]
+				}
+				if int(m.SourceIndex) < len(sm.SourcesContent) {
+					line += fmt.Sprintf(" %q", sm.SourcesContent[m.SourceIndex].Quoted)
+				}
+				lines = append(lines, line)
+			}
+			text += strings.Join(lines, "\n")
+		}
+		test.AssertEqualWithDiff(t, text, expected)
+	})
+}
+
+func TestParseSourceMap(t *testing.T) {
+	expectParseSourceMap(t, `{"version": 3, "sources": ["a.js"], "names": ["x"], "mappings": "AAAA,EAAEA;AACA"}`,
+		"0:0 => a.js:0:0\n0:2 => a.js:0:2 x\n1:0 => a.js:1:2")
+	expectParseSourceMap(t, `{"version": 3, "sources": ["a.js"], "mappings": "AAAA,E"}`,
+		"0:0 => a.js:0:0")
+	expectParseSourceMap(t, `{"version": 3, "sources": ["a.js"], "mappings": "AAAA,AAAA!"}`,
+		"<stdin>: WARNING: Bad \"mappings\" data in source map at character 9: Invalid character after mapping: \"!\"\n")
+	expectParseSourceMap(t, `{"version": 2, "sources": ["a.js"], "mappings": "AAAA"}`, "")
+	expectParseSourceMap(t, `[]`, "<stdin>: ERROR: Invalid source map\n")
+}
+
+func TestParseSourceMapSections(t *testing.T) {
+	// Columns are only offset on the first line of each section, and source and
+	// name indices are offset by the sources and names of the previous sections
+	expectParseSourceMap(t, `{"version": 3, "sections": [
+		{"offset": {"line": 0, "column": 0}, "map": {"version": 3, "sources": ["a.js"], "names": ["x"], "mappings": "AAAA,EAAEA"}},
+		{"offset": {"line": 1, "column": 5}, "map": {"version": 3, "sources": ["b.js"], "names": ["y"], "mappings": "AAAAA;AACA"}}
+	]}`,
+		"0:0 => a.js:0:0\n0:2 => a.js:0:2 x\n1:5 => b.js:0:0 y\n2:0 => b.js:1:0")
+
+	// Sources content is padded for sections that don't have any
+	expectParseSourceMap(t, `{"version": 3, "sections": [
+		{"offset": {"line": 0, "column": 0}, "map": {"version": 3, "sources": ["a.js"], "mappings": "AAAA"}},
+		{"offset": {"line": 1, "column": 0}, "map": {"version": 3, "sources": ["b.js"], "sourcesContent": ["b"], "mappings": "AAAA"}}
+	]}`,
+		"0:0 => a.js:0:0 \"\"\n1:0 => b.js:0:0 \"\\\"b\\\"\"")
+
+	// Empty sections are skipped and sections that are out of order are sorted
+	expectParseSourceMap(t, `{"version": 3, "sections": [
+		{"offset": {"line": 3, "column": 0}, "map": {"version": 3, "sources": ["a.js"], "mappings": "AAAA"}},
+		{"offset": {"line": 2, "column": 0}, "map": {"version": 3, "sources": [], "mappings": ""}},
+		{"offset": {"line": 1, "column": 0}, "map": {"version": 3, "sources": ["b.js"], "mappings": "AAAA"}}
+	]}`,
+		"1:0 => b.js:0:0\n3:0 => a.js:0:0")
+
+	expectParseSourceMap(t, `{"version": 3, "sections": [
+		{"offset": {"line": 0, "column": 0}, "url": "a.js.map"}
+	]}`,
+		"<stdin>: WARNING: Source map sections with \"url\" are not supported\n")
+}
@@ -1215,7 +1215,7 @@ func (p *printer) printProperty(property js_ast.Property) {
 			if !p.options.UnsupportedFeatures.Has(compat.ObjectExtensions) && property.ValueOrNil.Data != nil && !p.willPrintExprCommentsAtLoc(property.ValueOrNil.Loc) {
 				switch e := property.ValueOrNil.Data.(type) {
 				case *js_ast.EIdentifier:
-					if name == p.renamer.NameForSymbol(e.Ref) {
+					if _, ok := p.crossChunkImport(e.Ref); !ok && name == p.renamer.NameForSymbol(e.Ref) {
 						if property.InitializerOrNil.Data != nil {
 							p.printSpace()
 							p.print("=")
@@ -1228,6 +1228,9 @@ func (p *printer) printProperty(property js_ast.Property) {
 				case *js_ast.EImportIdentifier:
 					// Make sure we're not using a property access instead of an identifier
 					ref := ast.FollowSymbols(p.symbols, e.Ref)
+					if _, ok := p.crossChunkImport(ref); ok {
+						break
+					}
 					if symbol := p.symbols.Get(ref); symbol.NamespaceAlias == nil && name == p.renamer.NameForSymbol(ref) &&
 						p.options.ConstValues[ref].Kind == js_ast.ConstValueNone {
 						if property.InitializerOrNil.Data != nil {
@@ -1253,7 +1256,7 @@ func (p *printer) printProperty(property js_ast.Property) {
 			if !p.options.UnsupportedFeatures.Has(compat.ObjectExtensions) && property.ValueOrNil.Data != nil && !p.willPrintExprCommentsAtLoc(property.ValueOrNil.Loc) {
 				switch e := property.ValueOrNil.Data.(type) {
 				case *js_ast.EIdentifier:
-					if helpers.UTF16EqualsString(key.Value, p.renamer.NameForSymbol(e.Ref)) {
+					if _, ok := p.crossChunkImport(e.Ref); !ok && helpers.UTF16EqualsString(key.Value, p.renamer.NameForSymbol(e.Ref)) {
 						if p.options.AddSourceMappings {
 							p.addSourceMappingForName(property.Key.Loc, helpers.UTF16ToString(key.Value), e.Ref)
 						}
@@ -1270,6 +1273,9 @@ func (p *printer) printProperty(property js_ast.Property) {
 				case *js_ast.EImportIdentifier:
 					// Make sure we're not using a property access instead of an identifier
 					ref := ast.FollowSymbols(p.symbols, e.Ref)
+					if _, ok := p.crossChunkImport(ref); ok {
+						break
+					}
 					if symbol := p.symbols.Get(ref); symbol.NamespaceAlias == nil && helpers.UTF16EqualsString(key.Value, p.renamer.NameForSymbol(ref)) &&
 						p.options.ConstValues[ref].Kind == js_ast.ConstValueNone {
 						if p.options.AddSourceMappings {
@@ -1371,6 +1377,62 @@ func (p *printer) printQuotedUTF16(data []uint16, flags printQuotedFlags) {
 	p.print(c)
 }
 
+func (p *printer) crossChunkImport(ref ast.Ref) (ast.NamespaceAlias, bool) {
+	if p.options.CrossChunkImports == nil {
+		return ast.NamespaceAlias{}, false
+	}
+	alias, ok := p.options.CrossChunkImports[ast.FollowSymbols(p.symbols, ref)]
+	return alias, ok
+}
+
+// This prints "ns.alias" (or "(0, ns.alias)" if it's the target of a call so
+// that "this" isn't bound to the namespace). The namespace itself may also be
+// imported from another chunk.
+func (p *printer) printNamespaceAlias(loc logger.Loc, ref ast.Ref, alias ast.NamespaceAlias, wrap bool, preferQuotedKey bool) {
+	if wrap {
+		if p.options.MinifyWhitespace {
+			p.print("(0,")
+		} else {
+			p.print("(0, ")
+		}
+	}
+	p.printSpaceBeforeIdentifier()
+	p.addSourceMapping(loc)
+	if other, ok := p.crossChunkImport(alias.NamespaceRef); ok {
+		p.printIdentifier(p.renamer.NameForSymbol(other.NamespaceRef))
+		p.printNamespaceAliasProperty(loc, alias.NamespaceRef, other.Alias, false)
+	} else {
+		p.printIdentifier(p.renamer.NameForSymbol(alias.NamespaceRef))
+	}
+	p.printNamespaceAliasProperty(loc, ref, alias.Alias, preferQuotedKey)
+	if wrap {
+		p.print(")")
+	}
+}
+
+// This is used for symbols that the printer references directly such as the
+// runtime helpers, which may be imported from another chunk
+func (p *printer) printSymbol(ref ast.Ref, isCallTarget bool) {
+	if alias, ok := p.crossChunkImport(ref); ok {
+		p.printNamespaceAlias(logger.Loc{}, ref, alias, isCallTarget, false)
+	} else {
+		p.printIdentifier(p.renamer.NameForSymbol(ref))
+	}
+}
+
+func (p *printer) printNamespaceAliasProperty(loc logger.Loc, ref ast.Ref, alias string, preferQuotedKey bool) {
+	if !preferQuotedKey && p.canPrintIdentifier(alias) {
+		p.print(".")
+		p.addSourceMappingForName(loc, alias, ref)
+		p.printIdentifier(alias)
+	} else {
+		p.print("[")
+		p.addSourceMappingForName(loc, alias, ref)
+		p.printQuotedUTF8(alias, printQuotedAllowBacktick)
+		p.print("]")
+	}
+}
+
 func (p *printer) printRequireOrImportExpr(importRecordIndex uint32, level js_ast.L, flags printExprFlags, closeParenLoc logger.Loc) {
 	record := &p.importRecords[importRecordIndex]
 
@@ -1387,14 +1449,14 @@ func (p *printer) printRequireOrImportExpr(importRecordIndex uint32, level js_as
 			wrapWithToESM := record.Flags.Has(ast.WrapWithToESM)
 			if wrapWithToESM {
 				p.printSpaceBeforeIdentifier()
-				p.printIdentifier(p.renamer.NameForSymbol(p.options.ToESMRef))
+				p.printSymbol(p.options.ToESMRef, true)
 				p.print("(")
 			}
 
 			// Potentially substitute our own "__require" stub for "require"
 			p.printSpaceBeforeIdentifier()
 			if record.Flags.Has(ast.CallRuntimeRequire) {
-				p.printIdentifier(p.renamer.NameForSymbol(p.options.RuntimeRequireRef))
+				p.printSymbol(p.options.RuntimeRequireRef, true)
 			} else {
 				p.print("require")
 			}
@@ -1433,7 +1495,15 @@ func (p *printer) printRequireOrImportExpr(importRecordIndex uint32, level js_as
 
 		// External "import()"
 		kind := ast.ImportDynamic
-		if !p.options.UnsupportedFeatures.Has(compat.DynamicImport) {
+		isImportCall := false
+		if record.Flags.Has(ast.ImportsChunk) && p.options.OutputFormat == config.FormatIIFE {
+			// Chunks in the "iife" format are loaded by the chunk loader
+			p.printSpaceBeforeIdentifier()
+			p.printIdentifier(p.renamer.NameForSymbol(p.options.ChunkLoaderRef))
+			p.print("(")
+		} else if !p.options.UnsupportedFeatures.Has(compat.DynamicImport) &&
+			!(record.Flags.Has(ast.ImportsChunk) && p.options.OutputFormat == config.FormatCommonJS) {
+			isImportCall = true
 			p.printSpaceBeforeIdentifier()
 			p.print("import(")
 		} else {
@@ -1444,9 +1514,9 @@ func (p *printer) printRequireOrImportExpr(importRecordIndex uint32, level js_as
 			defer p.printDotThenSuffix()
 
 			// Wrap this with a call to "__toESM()" if this is a CommonJS file
-			if record.Flags.Has(ast.WrapWithToESM) {
+			if record.Flags.Has(ast.WrapWithToESM) && !record.Flags.Has(ast.ImportsChunk) {
 				p.printSpaceBeforeIdentifier()
-				p.printIdentifier(p.renamer.NameForSymbol(p.options.ToESMRef))
+				p.printSymbol(p.options.ToESMRef, true)
 				p.print("(")
 				defer func() {
 					if p.moduleType.IsESM() {
@@ -1461,7 +1531,7 @@ func (p *printer) printRequireOrImportExpr(importRecordIndex uint32, level js_as
 			// Potentially substitute our own "__require" stub for "require"
 			p.printSpaceBeforeIdentifier()
 			if record.Flags.Has(ast.CallRuntimeRequire) {
-				p.printIdentifier(p.renamer.NameForSymbol(p.options.RuntimeRequireRef))
+				p.printSymbol(p.options.RuntimeRequireRef, true)
 			} else {
 				p.print("require")
 			}
@@ -1470,8 +1540,7 @@ func (p *printer) printRequireOrImportExpr(importRecordIndex uint32, level js_as
 		}
 		isMultiLine := p.willPrintExprCommentsAtLoc(record.Range.Loc) ||
 			p.willPrintExprCommentsAtLoc(closeParenLoc) ||
-			(record.Assertions != nil &&
-				!p.options.UnsupportedFeatures.Has(compat.DynamicImport) &&
+			(record.Assertions != nil && isImportCall &&
 				!p.options.UnsupportedFeatures.Has(compat.ImportAssertions) &&
 				p.willPrintExprCommentsAtLoc(record.Assertions.OuterOpenBraceLoc))
 		if isMultiLine {
@@ -1481,7 +1550,7 @@ func (p *printer) printRequireOrImportExpr(importRecordIndex uint32, level js_as
 		}
 		p.printExprCommentsAtLoc(record.Range.Loc)
 		p.printPath(importRecordIndex, kind)
-		if !p.options.UnsupportedFeatures.Has(compat.DynamicImport) {
+		if isImportCall {
 			p.printImportCallAssertions(record.Assertions, isMultiLine)
 		}
 		if isMultiLine {
@@ -1507,12 +1576,12 @@ func (p *printer) printRequireOrImportExpr(importRecordIndex uint32, level js_as
 	// Internal "import()" of async ESM
 	if record.Kind == ast.ImportDynamic && meta.IsWrapperAsync {
 		p.printSpaceBeforeIdentifier()
-		p.printIdentifier(p.renamer.NameForSymbol(meta.WrapperRef))
+		p.printSymbol(meta.WrapperRef, true)
 		p.print("()")
 		if meta.ExportsRef != ast.InvalidRef {
 			p.printDotThenPrefix()
 			p.printSpaceBeforeIdentifier()
-			p.printIdentifier(p.renamer.NameForSymbol(meta.ExportsRef))
+			p.printSymbol(meta.ExportsRef, false)
 			p.printDotThenSuffix()
 		}
 		return
@@ -1536,13 +1605,13 @@ func (p *printer) printRequireOrImportExpr(importRecordIndex uint32, level js_as
 	wrapWithToESM := record.Flags.Has(ast.WrapWithToESM)
 	if wrapWithToESM {
 		p.printSpaceBeforeIdentifier()
-		p.printIdentifier(p.renamer.NameForSymbol(p.options.ToESMRef))
+		p.printSymbol(p.options.ToESMRef, true)
 		p.print("(")
 	}
 
 	// Call the wrapper
 	p.printSpaceBeforeIdentifier()
-	p.printIdentifier(p.renamer.NameForSymbol(meta.WrapperRef))
+	p.printSymbol(meta.WrapperRef, true)
 	p.print("()")
 
 	// Return the namespace object if this is an ESM file
@@ -1553,10 +1622,10 @@ func (p *printer) printRequireOrImportExpr(importRecordIndex uint32, level js_as
 		// Wrap this with a call to "__toCommonJS()" if this is an ESM file
 		wrapWithTpCJS := record.Flags.Has(ast.WrapWithToCJS)
 		if wrapWithTpCJS {
-			p.printIdentifier(p.renamer.NameForSymbol(p.options.ToCommonJSRef))
+			p.printSymbol(p.options.ToCommonJSRef, true)
 			p.print("(")
 		}
-		p.printIdentifier(p.renamer.NameForSymbol(meta.ExportsRef))
+		p.printSymbol(meta.ExportsRef, false)
 		if wrapWithTpCJS {
 			p.print(")")
 		}
@@ -3001,6 +3070,11 @@ func (p *printer) printExpr(expr js_ast.Expr, level js_ast.L, flags printExprFla
 		p.printNumber(e.Value, level)
 
 	case *js_ast.EIdentifier:
+		if alias, ok := p.crossChunkImport(e.Ref); ok {
+			p.printNamespaceAlias(expr.Loc, e.Ref, alias, p.callTarget == e, false)
+			break
+		}
+
 		name := p.renamer.NameForSymbol(e.Ref)
 		wrap := len(p.js) == p.forOfInitStart && (name == "let" ||
 			((flags&isFollowedByOf) != 0 && (flags&isInsideForAwait) == 0 && name == "async"))
@@ -3024,32 +3098,10 @@ func (p *printer) printExpr(expr js_ast.Expr, level js_ast.L, flags printExprFla
 
 		if symbol.ImportItemStatus == ast.ImportItemMissing {
 			p.printUndefined(expr.Loc, level)
+		} else if alias, ok := p.crossChunkImport(ref); ok {
+			p.printNamespaceAlias(expr.Loc, ref, alias, p.callTarget == e && e.WasOriginallyIdentifier, e.PreferQuotedKey)
 		} else if symbol.NamespaceAlias != nil {
-			wrap := p.callTarget == e && e.WasOriginallyIdentifier
-			if wrap {
-				if p.options.MinifyWhitespace {
-					p.print("(0,")
-				} else {
-					p.print("(0, ")
-				}
-			}
-			p.printSpaceBeforeIdentifier()
-			p.addSourceMapping(expr.Loc)
-			p.printIdentifier(p.renamer.NameForSymbol(symbol.NamespaceAlias.NamespaceRef))
-			alias := symbol.NamespaceAlias.Alias
-			if !e.PreferQuotedKey && p.canPrintIdentifier(alias) {
-				p.print(".")
-				p.addSourceMappingForName(expr.Loc, alias, ref)
-				p.printIdentifier(alias)
-			} else {
-				p.print("[")
-				p.addSourceMappingForName(expr.Loc, alias, ref)
-				p.printQuotedUTF8(alias, printQuotedAllowBacktick)
-				p.print("]")
-			}
-			if wrap {
-				p.print(")")
-			}
+			p.printNamespaceAlias(expr.Loc, ref, *symbol.NamespaceAlias, p.callTarget == e && e.WasOriginallyIdentifier, e.PreferQuotedKey)
 		} else if value := p.options.ConstValues[ref]; value.Kind != js_ast.ConstValueNone {
 			// Handle inlined constants
 			p.printExpr(js_ast.ConstValueToExpr(expr.Loc, value), level, flags)
@@ -3814,7 +3866,7 @@ func (p *printer) printPath(importRecordIndex uint32, importKind ast.ImportKind)
 	if record.Assertions != nil && importKind == ast.ImportStmt {
 		p.printSpace()
 		p.addSourceMapping(record.Assertions.AssertLoc)
-		p.print("assert")
+		p.print(record.Assertions.AssertOrWith.String())
 		p.printSpace()
 		p.printImportAssertionsClause(*record.Assertions)
 	}
@@ -3855,7 +3907,8 @@ func (p *printer) printImportCallAssertions(assertions *ast.ImportAssertions, ou
 
 	p.printExprCommentsAtLoc(assertions.AssertLoc)
 	p.addSourceMapping(assertions.AssertLoc)
-	p.print("assert:")
+	p.print(assertions.AssertOrWith.String())
+	p.print(":")
 
 	if p.willPrintExprCommentsAtLoc(assertions.InnerOpenBraceLoc) {
 		p.printNewline()
@@ -4762,6 +4815,14 @@ type Options struct {
 	// Property mangling results go here
 	MangledProps map[ast.Ref]string
 
+	// Code splitting with output formats other than ESM doesn't have import
+	// bindings. Symbols imported from another chunk are property accesses off
+	// of that chunk's exports object instead, and "import()" of another chunk
+	// calls the chunk loader (for the "iife" format) or "require()" (for the
+	// "cjs" format).
+	CrossChunkImports map[ast.Ref]ast.NamespaceAlias
+	ChunkLoaderRef    ast.Ref
+
 	// This will be present if the input file had a source map. In that case we
 	// want to map all the way back to the original input file(s).
 	InputSourceMap *sourcemap.SourceMap
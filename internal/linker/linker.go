@@ -30,8 +30,10 @@ import (
 	"github.com/evanw/esbuild/internal/fs"
 	"github.com/evanw/esbuild/internal/graph"
 	"github.com/evanw/esbuild/internal/helpers"
+	"github.com/evanw/esbuild/internal/html_ast"
 	"github.com/evanw/esbuild/internal/js_ast"
 	"github.com/evanw/esbuild/internal/js_lexer"
+	"github.com/evanw/esbuild/internal/js_parser"
 	"github.com/evanw/esbuild/internal/js_printer"
 	"github.com/evanw/esbuild/internal/logger"
 	"github.com/evanw/esbuild/internal/renamer"
@@ -62,17 +64,74 @@ type linkerContext struct {
 	uniqueKeyPrefix      string
 	uniqueKeyPrefixBytes []byte // This is just "uniqueKeyPrefix" in byte form
 
+	// This is passed to us when linking HTML entry points. It contains the
+	// output files generated for the scripts and style sheets they reference.
+	entryPointOutputs map[uint32]graph.EntryPointOutput
+
+	// Output files from other linking operations that are referenced by HTML
+	// files. These are referenced using unique keys until the final paths of
+	// the HTML files are known.
+	otherOutputAbsPaths   []string
+	otherOutputKeyIndices map[string]uint32
+
 	// Property mangling results go here
 	mangledProps map[ast.Ref]string
 
 	// We may need to refer to the CommonJS "module" symbol for exports
 	unboundModuleRef ast.Ref
 
+	// Code splitting with the "iife" format passes a function to each chunk
+	// that loads other chunks. This is what "import()" of a chunk calls.
+	chunkLoaderRef ast.Ref
+
 	// We may need to refer to the "__esm" and/or "__commonJS" runtime symbols
 	cjsRuntimeRef ast.Ref
 	esmRuntimeRef ast.Ref
+
+	// When the metafile needs to say why things are included, this records why
+	// tree shaking marked each file and each part as live. These are indexed
+	// by source index (and then by part index).
+	whyFileIsLive []liveReason
+	whyPartIsLive [][]liveReason
+
+	// This is the JSON for the "includedBy" and "parts" properties of each input
+	// file in the metafile. It's generated serially because line and column
+	// lookups aren't thread-safe, and then used in parallel during printing.
+	includedByJSON []string
+}
+
+// This is the first reason that tree shaking found for including a file or a
+// part. Following these reasons from one to the next always ends at an entry
+// point because each one refers to something that was included earlier.
+type liveReason struct {
+	kind        liveReasonKind
+	sourceIndex uint32
+	index       uint32 // This is a part index or an import record index
 }
 
+type liveReasonKind uint8
+
+const (
+	liveReasonNone liveReasonKind = iota
+
+	// The file is an entry point
+	liveReasonEntryPoint
+
+	// The file is imported for its side effects by import record "index" in
+	// the file "sourceIndex"
+	liveReasonImport
+
+	// Something in the file or part is used by part "index" in the file
+	// "sourceIndex"
+	liveReasonUsedByPart
+
+	// The part has side effects and the file containing it was included
+	liveReasonSideEffects
+
+	// The part is in an entry point and tree shaking is disabled
+	liveReasonNoTreeShaking
+)
+
 type partRange struct {
 	sourceIndex    uint32
 	partIndexBegin uint32
@@ -101,6 +160,11 @@ type chunkInfo struct {
 	// is the substitution of the final hash into "finalTemplate".
 	finalRelPath string
 
+	// If non-empty, this is a UUID derived from the final hash of this chunk.
+	// It's written to both the output file and the source map so that tools
+	// can match them up without relying on the URL of the output file.
+	debugID string
+
 	// If non-empty, this chunk needs to generate an external legal comments file.
 	externalLegalComments []byte
 
@@ -120,6 +184,14 @@ type chunkInfo struct {
 	// omitted.
 	intermediateOutput intermediateOutput
 
+	// This maps the path of each module in this chunk to a hash of its code.
+	// It's only present when hot module replacement is enabled.
+	moduleHashesForHMR map[string]uint64
+
+	// If present, this chunk contains the files that matched the manual chunk
+	// with this name instead of the files for a specific set of entry points
+	manualChunkName string
+
 	// This information is only useful if "isEntryPoint" is true
 	entryPointBit uint   // An index into "c.graph.EntryPoints"
 	sourceIndex   uint32 // An index into "c.sources"
@@ -139,6 +211,8 @@ const (
 	outputPieceNone outputPieceIndexKind = iota
 	outputPieceAssetIndex
 	outputPieceChunkIndex
+	outputPieceOtherOutputIndex
+	outputPieceDebugIDIndex
 )
 
 // This is a chunk of source code followed by a reference to another chunk. For
@@ -170,8 +244,9 @@ type intermediateOutput struct {
 
 type chunkRepr interface{ isChunk() }
 
-func (*chunkReprJS) isChunk()  {}
-func (*chunkReprCSS) isChunk() {}
+func (*chunkReprJS) isChunk()   {}
+func (*chunkReprCSS) isChunk()  {}
+func (*chunkReprHTML) isChunk() {}
 
 type chunkReprJS struct {
 	filesInChunkInOrder []uint32
@@ -183,14 +258,30 @@ type chunkReprJS struct {
 	crossChunkPrefixStmts  []js_ast.Stmt
 	crossChunkSuffixStmts  []js_ast.Stmt
 
+	// Output formats other than ESM import each other chunk as a namespace
+	// object. Symbols imported from that chunk become property accesses off
+	// of the namespace.
+	crossChunkNamespaces    []crossChunkNamespace
+	crossChunkImportAliases map[ast.Ref]ast.NamespaceAlias
+
 	cssChunkIndex uint32
 	hasCSSChunk   bool
 }
 
+type crossChunkNamespace struct {
+	ref        ast.Ref
+	chunkIndex uint32
+}
+
 type chunkReprCSS struct {
 	importsInChunkInOrder []cssImportOrder
 }
 
+type chunkReprHTML struct {
+	// These are the files referenced by assets in this chunk (e.g. images)
+	assetSourceIndices []uint32
+}
+
 type externalImportCSS struct {
 	path                   logger.Path
 	conditions             []css_ast.ImportConditions
@@ -234,6 +325,7 @@ func Link(
 	uniqueKeyPrefix string,
 	reachableFiles []uint32,
 	dataForSourceMaps func() []bundler.DataForSourceMap,
+	entryPointOutputs map[uint32]graph.EntryPointOutput,
 ) []graph.OutputFile {
 	timer.Begin("Link")
 	defer timer.End("Link")
@@ -248,6 +340,7 @@ func Link(
 		fs:                   fs,
 		res:                  res,
 		dataForSourceMaps:    dataForSourceMaps,
+		entryPointOutputs:    entryPointOutputs,
 		uniqueKeyPrefix:      uniqueKeyPrefix,
 		uniqueKeyPrefixBytes: []byte(uniqueKeyPrefix),
 		graph: graph.CloneLinkerGraph(
@@ -259,9 +352,16 @@ func Link(
 	}
 	timer.End("Clone linker graph")
 
+	if entryPointOutputs != nil {
+		c.indexOtherOutputs()
+	}
+
 	// Use a smaller version of these functions if we don't need profiler names
 	runtimeRepr := c.graph.Files[runtime.SourceIndex].InputFile.Repr.(*graph.JSRepr)
-	if c.options.ProfilerNames {
+	if c.options.HMR {
+		c.cjsRuntimeRef = runtimeRepr.AST.NamedExports["__hmrModule"].Ref
+		c.esmRuntimeRef = runtimeRepr.AST.NamedExports["__esm"].Ref
+	} else if c.options.ProfilerNames {
 		c.cjsRuntimeRef = runtimeRepr.AST.NamedExports["__commonJS"].Ref
 		c.esmRuntimeRef = runtimeRepr.AST.NamedExports["__esm"].Ref
 	} else {
@@ -284,10 +384,10 @@ func Link(
 
 			// Entry points with ES6 exports must generate an exports object when
 			// targeting non-ES6 formats. Note that the IIFE format only needs this
-			// when the global name is present, since that's the only way the exports
-			// can actually be observed externally.
+			// when the global name is present or when code splitting, since those
+			// are the only ways the exports can actually be observed externally.
 			if repr.AST.ExportKeyword.Len > 0 && (options.OutputFormat == config.FormatCommonJS ||
-				(options.OutputFormat == config.FormatIIFE && len(options.GlobalName) > 0)) {
+				(options.OutputFormat == config.FormatIIFE && (len(options.GlobalName) > 0 || options.CodeSplitting))) {
 				repr.AST.UsesExportsRef = true
 				repr.Meta.ForceIncludeExportsForEntryPoint = true
 			}
@@ -301,12 +401,29 @@ func Link(
 		}
 	}
 
+	// Dynamically-imported entry points must also generate an exports object
+	// when code splitting with non-ES6 formats since "import()" observes it
+	if options.CodeSplitting && !options.OutputFormat.KeepESMImportExportSyntax() {
+		for _, entryPoint := range c.graph.EntryPoints() {
+			file := &c.graph.Files[entryPoint.SourceIndex]
+			if repr, ok := file.InputFile.Repr.(*graph.JSRepr); ok && !file.IsUserSpecifiedEntryPoint() && repr.AST.ExportKeyword.Len > 0 {
+				repr.AST.UsesExportsRef = true
+				repr.Meta.ForceIncludeExportsForEntryPoint = true
+			}
+		}
+	}
+
 	// Allocate a new unbound symbol called "module" in case we need it later
 	if c.options.OutputFormat == config.FormatCommonJS {
 		c.unboundModuleRef = c.graph.GenerateNewSymbol(runtime.SourceIndex, ast.SymbolUnbound, "module")
 	} else {
 		c.unboundModuleRef = ast.InvalidRef
 	}
+	if c.options.OutputFormat == config.FormatIIFE && c.options.CodeSplitting {
+		c.chunkLoaderRef = c.graph.GenerateNewSymbol(runtime.SourceIndex, ast.SymbolOther, "__import")
+	} else {
+		c.chunkLoaderRef = ast.InvalidRef
+	}
 
 	c.scanImportsAndExports()
 
@@ -327,6 +444,16 @@ func Link(
 	}
 
 	c.computeChunks()
+	c.validateTopLevelAwaitInChunks()
+
+	// Stop now if there were errors
+	if c.log.HasErrors() {
+		c.options.ExclusiveMangleCacheUpdate(func(map[string]interface{}, map[string]bool) {
+			// Always do this so that we don't cause other entry points when there are errors
+		})
+		return []graph.OutputFile{}
+	}
+
 	c.computeCrossChunkDependencies()
 
 	// Merge mangled properties before chunks are generated since the names must
@@ -617,6 +744,8 @@ func (c *linkerContext) generateChunksInParallel(additionalFiles []graph.OutputF
 			go c.generateChunkJS(chunkIndex, &generateWaitGroup)
 		case *chunkReprCSS:
 			go c.generateChunkCSS(chunkIndex, &generateWaitGroup)
+		case *chunkReprHTML:
+			go c.generateChunkHTML(chunkIndex, &generateWaitGroup)
 		}
 	}
 	c.enforceNoCyclicChunkImports()
@@ -630,15 +759,27 @@ func (c *linkerContext) generateChunksInParallel(additionalFiles []graph.OutputF
 	for chunkIndex := range c.chunks {
 		chunk := &c.chunks[chunkIndex]
 		var hashSubstitution *string
+		_, isHTML := chunk.chunkRepr.(*chunkReprHTML)
+		needsHash := config.HasPlaceholder(chunk.finalTemplate, config.HashPlaceholder)
+		needsDebugID := c.options.DebugIDs && !isHTML
 
 		// Only wait for the hash if necessary
-		if config.HasPlaceholder(chunk.finalTemplate, config.HashPlaceholder) {
+		if needsHash || needsDebugID {
 			// Compute the final hash using the isolated hashes of the dependencies
 			hash := xxhash.New()
 			c.appendIsolatedHashesForImportedChunks(hash, uint32(chunkIndex), visited, ^uint32(chunkIndex))
 			finalBytes = hash.Sum(finalBytes[:0])
-			finalString := bundler.HashForFileName(finalBytes)
-			hashSubstitution = &finalString
+			if needsHash {
+				finalString := bundler.HashForFileName(finalBytes)
+				hashSubstitution = &finalString
+			}
+
+			// A debug ID needs 128 bits but the hash only has 64 bits, so mix the
+			// hash back in to get another 64 bits. This keeps it deterministic.
+			if needsDebugID {
+				hash.Write(finalBytes)
+				chunk.debugID = debugIDForHash(hash.Sum(finalBytes))
+			}
 		}
 
 		// Render the last remaining placeholder in the template
@@ -677,6 +818,11 @@ func (c *linkerContext) generateChunksInParallel(additionalFiles []graph.OutputF
 				}
 				commentPrefix = "/*"
 				commentSuffix = " */"
+
+			case *chunkReprHTML:
+				for _, sourceIndex := range chunkRepr.assetSourceIndices {
+					outputFiles = append(outputFiles, c.graph.Files[sourceIndex].InputFile.AdditionalFiles...)
+				}
 			}
 
 			// Path substitution for the chunk itself
@@ -709,10 +855,23 @@ func (c *linkerContext) generateChunksInParallel(additionalFiles []graph.OutputF
 				})
 			}
 
+			// Write the optional debug ID comment for this chunk
+			if chunk.debugID != "" {
+				outputContentsJoiner.EnsureNewlineAtEnd()
+				outputContentsJoiner.AddString(commentPrefix)
+				outputContentsJoiner.AddString("# debugId=")
+				outputContentsJoiner.AddString(chunk.debugID)
+				outputContentsJoiner.AddString(commentSuffix)
+				outputContentsJoiner.AddString("\n")
+			}
+
 			// Generate the optional source map for this chunk
 			if c.options.SourceMap != config.SourceMapNone && chunk.outputSourceMap.HasContent() {
 				outputSourceMap := chunk.outputSourceMap.Finalize(outputSourceMapShifts)
 				finalRelPathForSourceMap := chunk.finalRelPath + ".map"
+				if chunk.debugID != "" {
+					outputSourceMap = appendDebugIDToSourceMap(outputSourceMap, chunk.debugID)
+				}
 
 				// Potentially write a trailing source map comment
 				switch c.options.SourceMap {
@@ -760,12 +919,30 @@ func (c *linkerContext) generateChunksInParallel(additionalFiles []graph.OutputF
 				jsonMetadataChunk = string(jsonMetadataChunkBytes.Done())
 			}
 
+			// Remember the output files for each entry point in case an HTML file
+			// references that entry point
+			var entryPoint *graph.EntryPointOutput
+			if chunk.isEntryPoint && c.graph.Files[chunk.sourceIndex].EntryPointChunkIndex == uint32(chunkIndex) {
+				entryPoint = &graph.EntryPointOutput{
+					AbsPath:     c.fs.Join(c.options.AbsOutputDir, chunk.finalRelPath),
+					SourceIndex: chunk.sourceIndex,
+				}
+				if chunkRepr, ok := chunk.chunkRepr.(*chunkReprJS); ok {
+					if chunkRepr.hasCSSChunk {
+						entryPoint.CSSAbsPath = c.fs.Join(c.options.AbsOutputDir, c.chunks[chunkRepr.cssChunkIndex].finalRelPath)
+					}
+					entryPoint.StaticImportAbsPaths = c.staticallyImportedChunkAbsPaths(uint32(chunkIndex))
+				}
+			}
+
 			// Generate the output file for this chunk
 			outputFiles = append(outputFiles, graph.OutputFile{
-				AbsPath:           c.fs.Join(c.options.AbsOutputDir, chunk.finalRelPath),
-				Contents:          outputContents,
-				JSONMetadataChunk: jsonMetadataChunk,
-				IsExecutable:      chunk.isExecutable,
+				AbsPath:            c.fs.Join(c.options.AbsOutputDir, chunk.finalRelPath),
+				Contents:           outputContents,
+				JSONMetadataChunk:  jsonMetadataChunk,
+				ModuleHashesForHMR: chunk.moduleHashesForHMR,
+				EntryPoint:         entryPoint,
+				IsExecutable:       chunk.isExecutable,
 			})
 
 			results[chunkIndex] = outputFiles
@@ -837,6 +1014,20 @@ func (c *linkerContext) substituteFinalPaths(
 			shift.Before.AdvanceString(chunk.uniqueKey)
 			shift.After.AdvanceString(importPath)
 			shifts = append(shifts, shift)
+
+		case outputPieceOtherOutputIndex:
+			importPath := modifyPath(c.otherOutputRelPath(piece.index))
+			j.AddString(importPath)
+			shift.Before.AdvanceString(c.otherOutputUniqueKey(piece.index))
+			shift.After.AdvanceString(importPath)
+			shifts = append(shifts, shift)
+
+		case outputPieceDebugIDIndex:
+			debugID := c.chunks[piece.index].debugID
+			j.AddString(debugID)
+			shift.Before.AdvanceString(c.debugIDUniqueKey(piece.index))
+			shift.After.AdvanceString(debugID)
+			shifts = append(shifts, shift)
 		}
 	}
 
@@ -868,6 +1059,13 @@ func (c *linkerContext) accurateFinalByteCount(output intermediateOutput, chunkF
 			chunk := c.chunks[piece.index]
 			importPath := c.pathBetweenChunks(chunkFinalRelDir, chunk.finalRelPath)
 			count += len(importPath)
+
+		case outputPieceOtherOutputIndex:
+			importPath := c.pathBetweenChunks(chunkFinalRelDir, c.otherOutputRelPath(piece.index))
+			count += len(importPath)
+
+		case outputPieceDebugIDIndex:
+			count += len(c.chunks[piece.index].debugID)
 		}
 	}
 
@@ -946,7 +1144,7 @@ func (c *linkerContext) computeCrossChunkDependencies() {
 								otherChunkIndex := c.graph.Files[record.SourceIndex.GetIndex()].EntryPointChunkIndex
 								record.Path.Text = c.chunks[otherChunkIndex].uniqueKey
 								record.SourceIndex = ast.Index32{}
-								record.Flags |= ast.ShouldNotBeExternalInMetafile | ast.ContainsUniqueKey
+								record.Flags |= ast.ShouldNotBeExternalInMetafile | ast.ContainsUniqueKey | ast.ImportsChunk
 
 								// Track this cross-chunk dynamic import so we make sure to
 								// include its hash when we're calculating the hashes of all
@@ -1122,24 +1320,62 @@ func (c *linkerContext) computeCrossChunkDependencies() {
 		}
 
 		chunkRepr.exportsToOtherChunks = make(map[ast.Ref]string)
+		r := renamer.ExportRenamer{}
+		sortedExports := c.sortedCrossChunkExportItems(chunkMetas[chunkIndex].exports)
+		for _, export := range sortedExports {
+			var alias string
+			if c.options.MinifyIdentifiers {
+				alias = r.NextMinifiedName()
+			} else {
+				alias = r.NextRenamedName(c.graph.Symbols.Get(export.Ref).OriginalName)
+			}
+			chunkRepr.exportsToOtherChunks[export.Ref] = alias
+		}
+		if len(sortedExports) == 0 {
+			continue
+		}
+
 		switch c.options.OutputFormat {
 		case config.FormatESModule:
-			r := renamer.ExportRenamer{}
-			var items []js_ast.ClauseItem
-			for _, export := range c.sortedCrossChunkExportItems(chunkMetas[chunkIndex].exports) {
-				var alias string
-				if c.options.MinifyIdentifiers {
-					alias = r.NextMinifiedName()
-				} else {
-					alias = r.NextRenamedName(c.graph.Symbols.Get(export.Ref).OriginalName)
-				}
-				items = append(items, js_ast.ClauseItem{Name: ast.LocRef{Ref: export.Ref}, Alias: alias})
-				chunkRepr.exportsToOtherChunks[export.Ref] = alias
+			items := make([]js_ast.ClauseItem, 0, len(sortedExports))
+			for _, export := range sortedExports {
+				items = append(items, js_ast.ClauseItem{Name: ast.LocRef{Ref: export.Ref}, Alias: chunkRepr.exportsToOtherChunks[export.Ref]})
 			}
-			if len(items) > 0 {
-				chunkRepr.crossChunkSuffixStmts = []js_ast.Stmt{{Data: &js_ast.SExportClause{
-					Items: items,
-				}}}
+			chunkRepr.crossChunkSuffixStmts = []js_ast.Stmt{{Data: &js_ast.SExportClause{
+				Items: items,
+			}}}
+
+		case config.FormatCommonJS, config.FormatIIFE:
+			// Use getters so that other chunks observe later assignments to these
+			// symbols just like they would with ESM import bindings
+			properties := make([]js_ast.Property, 0, len(sortedExports))
+			for _, export := range sortedExports {
+				properties = append(properties, js_ast.Property{
+					Kind:  js_ast.PropertyGet,
+					Flags: js_ast.PropertyIsMethod,
+					Key:   js_ast.Expr{Data: &js_ast.EString{Value: helpers.StringToUTF16(chunkRepr.exportsToOtherChunks[export.Ref])}},
+					ValueOrNil: js_ast.Expr{Data: &js_ast.EFunction{Fn: js_ast.Fn{
+						ArgumentsRef: ast.InvalidRef,
+						Body: js_ast.FnBody{Block: js_ast.SBlock{Stmts: []js_ast.Stmt{{Data: &js_ast.SReturn{
+							ValueOrNil: js_ast.Expr{Data: &js_ast.EIdentifier{Ref: export.Ref}},
+						}}}}},
+					}}},
+				})
+			}
+			value := js_ast.Expr{Data: &js_ast.EObject{Properties: properties}}
+
+			if c.options.OutputFormat == config.FormatCommonJS {
+				// "module.exports = { get foo() { return foo; } };"
+				chunkRepr.crossChunkSuffixStmts = []js_ast.Stmt{js_ast.AssignStmt(
+					js_ast.Expr{Data: &js_ast.EDot{
+						Target: js_ast.Expr{Data: &js_ast.EIdentifier{Ref: c.unboundModuleRef}},
+						Name:   "exports",
+					}},
+					value,
+				)}
+			} else {
+				// "return { get foo() { return foo; } };"
+				chunkRepr.crossChunkSuffixStmts = []js_ast.Stmt{{Data: &js_ast.SReturn{ValueOrNil: value}}}
 			}
 
 		default:
@@ -1184,6 +1420,47 @@ func (c *linkerContext) computeCrossChunkDependencies() {
 					}})
 				}
 
+			case config.FormatCommonJS, config.FormatIIFE:
+				namespaceRef := c.graph.GenerateNewSymbol(runtime.SourceIndex, ast.SymbolOther, "import_chunk")
+				chunkRepr.crossChunkNamespaces = append(chunkRepr.crossChunkNamespaces, crossChunkNamespace{
+					ref:        namespaceRef,
+					chunkIndex: crossChunkImport.chunkIndex,
+				})
+				if chunkRepr.crossChunkImportAliases == nil {
+					chunkRepr.crossChunkImportAliases = make(map[ast.Ref]ast.NamespaceAlias)
+				}
+				for _, item := range crossChunkImport.sortedImportItems {
+					chunkRepr.crossChunkImportAliases[item.ref] = ast.NamespaceAlias{NamespaceRef: namespaceRef, Alias: item.exportAlias}
+				}
+
+				// The "iife" format passes the namespace for each chunk to the chunk
+				// loader callback instead, so only "cjs" needs import statements
+				if c.options.OutputFormat == config.FormatIIFE {
+					chunk.crossChunkImports = append(chunk.crossChunkImports, chunkImport{
+						importKind: ast.ImportStmt,
+						chunkIndex: crossChunkImport.chunkIndex,
+					})
+					break
+				}
+				importRecordIndex := uint32(len(chunk.crossChunkImports))
+				chunk.crossChunkImports = append(chunk.crossChunkImports, chunkImport{
+					importKind: ast.ImportRequire,
+					chunkIndex: crossChunkImport.chunkIndex,
+				})
+				require := js_ast.Expr{Data: &js_ast.ERequireString{ImportRecordIndex: importRecordIndex}}
+				if len(crossChunkImport.sortedImportItems) > 0 {
+					// "var import_chunk = require('./chunk.js');"
+					crossChunkPrefixStmts = append(crossChunkPrefixStmts, js_ast.Stmt{Data: &js_ast.SLocal{
+						Decls: []js_ast.Decl{{
+							Binding:    js_ast.Binding{Data: &js_ast.BIdentifier{Ref: namespaceRef}},
+							ValueOrNil: require,
+						}},
+					}})
+				} else {
+					// "require('./chunk.js');"
+					crossChunkPrefixStmts = append(crossChunkPrefixStmts, js_ast.Stmt{Data: &js_ast.SExpr{Value: require}})
+				}
+
 			default:
 				panic("Internal error")
 			}
@@ -1439,6 +1716,29 @@ func (c *linkerContext) scanImportsAndExports() {
 				}
 			}
 
+			// Hot module replacement needs every module to be individually
+			// replaceable at run time. Module-level variables are normally hoisted
+			// into the scope of the chunk, which makes that impossible. Wrapping
+			// every module in a CommonJS closure means modules can only reference
+			// each other through their exports objects, so the runtime can swap out
+			// the closure for a single module and re-run it.
+			//
+			// Entry points with exports are left alone since their exports must
+			// remain statically analyzable, and so are modules with top-level await
+			// since a CommonJS closure can't be asynchronous. Editing these modules
+			// causes a full reload instead. CSS files without local names only have
+			// an empty JavaScript stub, which doesn't need to be replaceable.
+			if c.options.HMR && sourceIndex != runtime.SourceIndex && repr.AST.ExportsKind != js_ast.ExportsCommonJS &&
+				repr.AST.TopLevelAwaitKeyword.Len == 0 && (!file.IsEntryPoint() || repr.AST.ExportKeyword.Len == 0) &&
+				file.InputFile.Loader != config.LoaderCSS && file.InputFile.Loader != config.LoaderGlobalCSS {
+				if !repr.AST.HasLazyExport && (repr.AST.ExportsKind == js_ast.ExportsESM ||
+					repr.AST.ExportsKind == js_ast.ExportsESMWithDynamicFallback) {
+					repr.Meta.IsESMWrappedForHMR = true
+				}
+				repr.Meta.Wrap = graph.WrapCJS
+				repr.AST.ExportsKind = js_ast.ExportsCommonJS
+			}
+
 			// If the output format doesn't have an implicit CommonJS wrapper, any file
 			// that uses CommonJS features will need to be wrapped, even though the
 			// resulting wrapper won't be invoked by other files. An exception is made
@@ -1880,6 +2180,12 @@ func (c *linkerContext) scanImportsAndExports() {
 
 				// Don't follow external imports (this includes import() expressions)
 				if !record.SourceIndex.IsValid() || c.isExternalDynamicImport(record, sourceIndex) {
+					// An "import()" of another chunk doesn't need any runtime helpers
+					// in formats that load it with "require()" or the chunk loader
+					if record.SourceIndex.IsValid() && !c.options.OutputFormat.KeepESMImportExportSyntax() {
+						continue
+					}
+
 					// This is an external import. Check if it will be a "require()" call.
 					if record.Kind == ast.ImportRequire || !c.options.OutputFormat.KeepESMImportExportSyntax() ||
 						(record.Kind == ast.ImportDynamic && c.options.UnsupportedJSFeatures.Has(compat.DynamicImport)) {
@@ -1923,8 +2229,11 @@ func (c *linkerContext) scanImportsAndExports() {
 					c.graph.GenerateSymbolImportAndUse(sourceIndex, uint32(partIndex), wrapperRef, 1, otherSourceIndex)
 
 					// This is an ES6 import of a CommonJS module, so it needs the
-					// "__toESM" wrapper as long as it's not a bare "require()"
-					if record.Kind != ast.ImportRequire && otherRepr.AST.ExportsKind == js_ast.ExportsCommonJS {
+					// "__toESM" wrapper as long as it's not a bare "require()". This
+					// isn't needed for ECMAScript modules wrapped for hot module
+					// replacement since their exports object is already a namespace.
+					if record.Kind != ast.ImportRequire && otherRepr.AST.ExportsKind == js_ast.ExportsCommonJS &&
+						!otherRepr.Meta.IsESMWrappedForHMR {
 						record.Flags |= ast.WrapWithToESM
 						toESMUses++
 					}
@@ -2347,7 +2656,7 @@ func (c *linkerContext) createExportsForFile(sourceIndex uint32) {
 	// bundle (including the entry point module) may do "import * as" to get
 	// access to the exports object and should NOT see the "__esModule" flag.
 	if repr.Meta.ForceIncludeExportsForEntryPoint &&
-		c.options.OutputFormat == config.FormatCommonJS {
+		c.options.OutputFormat == config.FormatCommonJS && !c.isAsyncEntryPoint(sourceIndex) {
 
 		runtimeRepr := c.graph.Files[runtime.SourceIndex].InputFile.Repr.(*graph.JSRepr)
 		toCommonJSRef := runtimeRepr.AST.NamedExports["__toCommonJS"].Ref
@@ -2376,8 +2685,10 @@ func (c *linkerContext) createExportsForFile(sourceIndex uint32) {
 			Dependencies:    nsExportDependencies,
 			DeclaredSymbols: declaredSymbols,
 
-			// This can be removed if nothing uses it
-			CanBeRemovedIfUnused: true,
+			// This can be removed if nothing uses it. Modules wrapped for hot module
+			// replacement are only ever accessed through their exports object, so
+			// the exports must always be present.
+			CanBeRemovedIfUnused: !repr.Meta.IsESMWrappedForHMR,
 
 			// Make sure this is trimmed if unused even if tree shaking is disabled
 			ForceTreeShaking: true,
@@ -3107,8 +3418,20 @@ func (c *linkerContext) advanceImportTracker(tracker importTracker) (importTrack
 func (c *linkerContext) treeShakingAndCodeSplitting() {
 	// Tree shaking: Each entry point marks all files reachable from itself
 	c.timer.Begin("Tree shaking")
+	if c.options.NeedsMetafileIncludedBy {
+		c.whyFileIsLive = make([]liveReason, len(c.graph.Files))
+		c.whyPartIsLive = make([][]liveReason, len(c.graph.Files))
+		for _, sourceIndex := range c.graph.ReachableFiles {
+			if repr, ok := c.graph.Files[sourceIndex].InputFile.Repr.(*graph.JSRepr); ok {
+				c.whyPartIsLive[sourceIndex] = make([]liveReason, len(repr.AST.Parts))
+			}
+		}
+	}
 	for _, entryPoint := range c.graph.EntryPoints() {
-		c.markFileLiveForTreeShaking(entryPoint.SourceIndex)
+		c.markFileLiveForTreeShaking(entryPoint.SourceIndex, liveReason{kind: liveReasonEntryPoint})
+	}
+	if c.options.NeedsMetafileIncludedBy {
+		c.computeIncludedByJSON()
 	}
 	c.timer.End("Tree shaking")
 
@@ -3120,9 +3443,251 @@ func (c *linkerContext) treeShakingAndCodeSplitting() {
 	for i, entryPoint := range c.graph.EntryPoints() {
 		c.markFileReachableForCodeSplitting(entryPoint.SourceIndex, uint(i), 0)
 	}
+	if c.options.CodeSplitting && len(c.options.ManualChunks) > 0 {
+		c.assignFilesToManualChunks()
+	}
 	c.timer.End("Code splitting")
 }
 
+// Files that match a manual chunk are moved out of the chunk for their entry
+// bits and into the manual chunk instead. Entry points always stay in their
+// own chunk, and so does the runtime since it's shared by everything.
+//
+// Files that statically import an entry point (possibly indirectly) also stay
+// where they are. Otherwise the manual chunk would import the entry point's
+// chunk, which may itself import the manual chunk, and chunks must not import
+// each other in a cycle.
+func (c *linkerContext) assignFilesToManualChunks() {
+	importsEntryPoint := c.filesThatStaticallyImportEntryPoints()
+	var matched []uint32
+	for _, sourceIndex := range c.graph.ReachableFiles {
+		if !c.canMoveToManualChunk(sourceIndex) || importsEntryPoint[sourceIndex] ||
+			c.graph.Files[sourceIndex].InputFile.Source.KeyPath.Namespace != "file" {
+			continue
+		}
+		file := &c.graph.Files[sourceIndex]
+		path := file.InputFile.Source.KeyPath.Text
+		packageName, isInPackage := packageNameFromPath(path)
+		for i, manualChunk := range c.options.ManualChunks {
+			if matchesManualChunkPattern(manualChunk.Paths, path) || (isInPackage && matchesManualChunkPattern(manualChunk.Packages, packageName)) {
+				file.ManualChunkIndex = ast.MakeIndex32(uint32(i))
+				matched = append(matched, sourceIndex)
+				break
+			}
+		}
+	}
+
+	// Files that are statically imported by a manual chunk are pulled into that
+	// manual chunk too unless they already belong to one. Otherwise a manual
+	// chunk could import a file that stays in an entry point chunk which itself
+	// imports the manual chunk, and chunks must not import each other in a cycle.
+	for _, sourceIndex := range matched {
+		c.pullDependenciesIntoManualChunk(sourceIndex, c.graph.Files[sourceIndex].ManualChunkIndex)
+	}
+
+	c.reportCyclesBetweenManualChunks()
+}
+
+type manualChunkImport struct {
+	record      *ast.ImportRecord
+	sourceIndex uint32
+	other       uint32
+}
+
+// Files in different manual chunks may still import each other. There is no
+// valid order to evaluate manual chunks in if those imports form a cycle, so
+// this is reported as an error instead of generating chunks that import each
+// other. The files involved need to be put in the same manual chunk instead.
+func (c *linkerContext) reportCyclesBetweenManualChunks() {
+	imports := make([][]manualChunkImport, len(c.options.ManualChunks))
+	for _, sourceIndex := range c.graph.ReachableFiles {
+		file := &c.graph.Files[sourceIndex]
+		if !file.ManualChunkIndex.IsValid() {
+			continue
+		}
+		from := file.ManualChunkIndex.GetIndex()
+		repr := file.InputFile.Repr.(*graph.JSRepr)
+		for i := range repr.AST.ImportRecords {
+			record := &repr.AST.ImportRecords[i]
+			if !record.SourceIndex.IsValid() || record.Kind == ast.ImportDynamic {
+				continue
+			}
+			otherFile := &c.graph.Files[record.SourceIndex.GetIndex()]
+			if !otherFile.IsLive || !otherFile.ManualChunkIndex.IsValid() {
+				continue
+			}
+			to := otherFile.ManualChunkIndex.GetIndex()
+			isNew := to != from
+			for _, item := range imports[from] {
+				if item.other == to {
+					isNew = false
+					break
+				}
+			}
+			if isNew {
+				imports[from] = append(imports[from], manualChunkImport{record: record, sourceIndex: sourceIndex, other: to})
+			}
+		}
+	}
+
+	// Search for a cycle using a depth-first traversal
+	const (
+		unvisited = iota
+		visiting
+		visited
+	)
+	state := make([]uint8, len(imports))
+	var stack []manualChunkImport
+	var cycle []manualChunkImport
+	var visit func(chunk uint32) bool
+	visit = func(chunk uint32) bool {
+		state[chunk] = visiting
+		for _, item := range imports[chunk] {
+			stack = append(stack, item)
+			if state[item.other] == visiting {
+				for i := range stack {
+					if c.graph.Files[stack[i].sourceIndex].ManualChunkIndex.GetIndex() == item.other {
+						cycle = stack[i:]
+						return true
+					}
+				}
+			}
+			if state[item.other] == unvisited && visit(item.other) {
+				return true
+			}
+			stack = stack[:len(stack)-1]
+		}
+		state[chunk] = visited
+		return false
+	}
+	for chunk := range imports {
+		if state[chunk] == unvisited && visit(uint32(chunk)) {
+			break
+		}
+	}
+	if cycle == nil {
+		return
+	}
+
+	var names []string
+	notes := make([]logger.MsgData, 0, len(cycle))
+	for _, item := range cycle {
+		file := &c.graph.Files[item.sourceIndex]
+		from := c.options.ManualChunks[file.ManualChunkIndex.GetIndex()].Name
+		names = append(names, fmt.Sprintf("%q", from))
+		notes = append(notes, file.LineColumnTracker().MsgData(item.record.Range,
+			fmt.Sprintf("Manual chunk %q imports manual chunk %q here:", from, c.options.ManualChunks[item.other].Name)))
+	}
+	var text string
+	if len(names) == 2 {
+		text = fmt.Sprintf("Manual chunks %s and %s cannot import each other", names[0], names[1])
+	} else {
+		text = fmt.Sprintf("Manual chunks %s, and %s cannot import each other in a cycle",
+			strings.Join(names[:len(names)-1], ", "), names[len(names)-1])
+	}
+	c.log.AddErrorWithNotes(nil, logger.Range{}, text, append(notes, logger.MsgData{
+		Text: "Put these files in the same manual chunk to avoid this problem."}))
+}
+
+// This walks static imports backward from every JavaScript entry point. Files
+// reached this way can't leave their entry point's chunk. Dependencies of a
+// file that can move also can't import an entry point, so they can move too.
+func (c *linkerContext) filesThatStaticallyImportEntryPoints() []bool {
+	importers := make([][]uint32, len(c.graph.Files))
+	for _, sourceIndex := range c.graph.ReachableFiles {
+		repr, ok := c.graph.Files[sourceIndex].InputFile.Repr.(*graph.JSRepr)
+		if !ok {
+			continue
+		}
+		for _, record := range repr.AST.ImportRecords {
+			if record.SourceIndex.IsValid() && record.Kind != ast.ImportDynamic {
+				other := record.SourceIndex.GetIndex()
+				importers[other] = append(importers[other], sourceIndex)
+			}
+		}
+	}
+
+	result := make([]bool, len(c.graph.Files))
+	var stack []uint32
+	for _, entryPoint := range c.graph.EntryPoints() {
+		if _, ok := c.graph.Files[entryPoint.SourceIndex].InputFile.Repr.(*graph.JSRepr); ok {
+			stack = append(stack, entryPoint.SourceIndex)
+		}
+	}
+	for len(stack) > 0 {
+		sourceIndex := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		for _, importer := range importers[sourceIndex] {
+			if !result[importer] {
+				result[importer] = true
+				stack = append(stack, importer)
+			}
+		}
+	}
+	return result
+}
+
+func (c *linkerContext) canMoveToManualChunk(sourceIndex uint32) bool {
+	file := &c.graph.Files[sourceIndex]
+	if !file.IsLive || file.IsEntryPoint() || sourceIndex == runtime.SourceIndex {
+		return false
+	}
+	_, ok := file.InputFile.Repr.(*graph.JSRepr)
+	return ok
+}
+
+func (c *linkerContext) pullDependenciesIntoManualChunk(sourceIndex uint32, manualChunkIndex ast.Index32) {
+	repr := c.graph.Files[sourceIndex].InputFile.Repr.(*graph.JSRepr)
+	for _, record := range repr.AST.ImportRecords {
+		if !record.SourceIndex.IsValid() || record.Kind == ast.ImportDynamic {
+			continue
+		}
+		otherSourceIndex := record.SourceIndex.GetIndex()
+		if c.graph.Files[otherSourceIndex].ManualChunkIndex.IsValid() || !c.canMoveToManualChunk(otherSourceIndex) {
+			continue
+		}
+		c.graph.Files[otherSourceIndex].ManualChunkIndex = manualChunkIndex
+		c.pullDependenciesIntoManualChunk(otherSourceIndex, manualChunkIndex)
+	}
+}
+
+// This returns the name of the package containing the file for paths inside
+// a "node_modules" directory. Nested "node_modules" directories use the name
+// of the innermost package.
+func packageNameFromPath(path string) (string, bool) {
+	path = strings.ReplaceAll(path, "\\", "/")
+	i := strings.LastIndex(path, "/node_modules/")
+	if i == -1 {
+		return "", false
+	}
+	rest := path[i+len("/node_modules/"):]
+	slash := strings.IndexByte(rest, '/')
+	if slash == -1 {
+		return "", false
+	}
+	if strings.HasPrefix(rest, "@") {
+		next := strings.IndexByte(rest[slash+1:], '/')
+		if next == -1 {
+			return "", false
+		}
+		slash += 1 + next
+	}
+	return rest[:slash], true
+}
+
+func matchesManualChunkPattern(matchers config.ExternalMatchers, text string) bool {
+	if matchers.Exact[text] {
+		return true
+	}
+	for _, pattern := range matchers.Patterns {
+		if len(text) >= len(pattern.Prefix)+len(pattern.Suffix) &&
+			strings.HasPrefix(text, pattern.Prefix) && strings.HasSuffix(text, pattern.Suffix) {
+			return true
+		}
+	}
+	return false
+}
+
 func (c *linkerContext) markFileReachableForCodeSplitting(sourceIndex uint32, entryPointBit uint, distanceFromEntryPoint uint32) {
 	file := &c.graph.Files[sourceIndex]
 	if !file.IsLive {
@@ -3176,7 +3741,7 @@ func (c *linkerContext) markFileReachableForCodeSplitting(sourceIndex uint32, en
 	}
 }
 
-func (c *linkerContext) markFileLiveForTreeShaking(sourceIndex uint32) {
+func (c *linkerContext) markFileLiveForTreeShaking(sourceIndex uint32, reason liveReason) {
 	file := &c.graph.Files[sourceIndex]
 
 	// Don't mark this file more than once
@@ -3184,12 +3749,15 @@ func (c *linkerContext) markFileLiveForTreeShaking(sourceIndex uint32) {
 		return
 	}
 	file.IsLive = true
+	if c.whyFileIsLive != nil {
+		c.whyFileIsLive[sourceIndex] = reason
+	}
 
 	switch repr := file.InputFile.Repr.(type) {
 	case *graph.JSRepr:
 		// If the JavaScript stub for a CSS file is included, also include the CSS file
 		if repr.CSSSourceIndex.IsValid() {
-			c.markFileLiveForTreeShaking(repr.CSSSourceIndex.GetIndex())
+			c.markFileLiveForTreeShaking(repr.CSSSourceIndex.GetIndex(), reason)
 		}
 
 		for partIndex, part := range repr.AST.Parts {
@@ -3212,7 +3780,7 @@ func (c *linkerContext) markFileLiveForTreeShaking(sourceIndex uint32) {
 					}
 
 					// Otherwise, include this module for its side effects
-					c.markFileLiveForTreeShaking(otherSourceIndex)
+					c.markFileLiveForTreeShaking(otherSourceIndex, liveReason{kind: liveReasonImport, sourceIndex: sourceIndex, index: importRecordIndex})
 				} else if record.Flags.Has(ast.IsExternalWithoutSideEffects) {
 					// This can be removed if it's unused
 					continue
@@ -3226,21 +3794,36 @@ func (c *linkerContext) markFileLiveForTreeShaking(sourceIndex uint32) {
 			// Include all parts in this file with side effects, or just include
 			// everything if tree-shaking is disabled. Note that we still want to
 			// perform tree-shaking on the runtime even if tree-shaking is disabled.
-			if !canBeRemovedIfUnused || (!part.ForceTreeShaking && !c.options.TreeShaking && file.IsEntryPoint()) {
-				c.markPartLiveForTreeShaking(sourceIndex, uint32(partIndex))
+			if !canBeRemovedIfUnused {
+				c.markPartLiveForTreeShaking(sourceIndex, uint32(partIndex), liveReason{kind: liveReasonSideEffects, sourceIndex: sourceIndex})
+			} else if !part.ForceTreeShaking && !c.options.TreeShaking && file.IsEntryPoint() {
+				c.markPartLiveForTreeShaking(sourceIndex, uint32(partIndex), liveReason{kind: liveReasonNoTreeShaking, sourceIndex: sourceIndex})
 			}
 		}
 
 	case *graph.CSSRepr:
 		// Include all "@import" rules
-		for _, record := range repr.AST.ImportRecords {
+		for importRecordIndex, record := range repr.AST.ImportRecords {
 			if record.SourceIndex.IsValid() {
-				c.markFileLiveForTreeShaking(record.SourceIndex.GetIndex())
+				c.markFileLiveForTreeShaking(record.SourceIndex.GetIndex(), liveReason{kind: liveReasonImport, sourceIndex: sourceIndex, index: uint32(importRecordIndex)})
 			}
 		}
 	}
 }
 
+// The "cjs" and "iife" formats can't use top-level await directly. Instead,
+// entry points that use top-level await (either themselves or through one of
+// their imports) run the code in their chunk inside an async function, and the
+// entry point exposes a promise for its exports.
+func (c *linkerContext) isAsyncEntryPoint(sourceIndex uint32) bool {
+	if c.options.OutputFormat != config.FormatCommonJS && c.options.OutputFormat != config.FormatIIFE {
+		return false
+	}
+	file := &c.graph.Files[sourceIndex]
+	repr, ok := file.InputFile.Repr.(*graph.JSRepr)
+	return ok && file.IsEntryPoint() && repr.Meta.Wrap == graph.WrapNone && repr.Meta.IsAsyncOrHasAsyncDependency
+}
+
 func (c *linkerContext) isExternalDynamicImport(record *ast.ImportRecord, sourceIndex uint32) bool {
 	return c.options.CodeSplitting &&
 		record.Kind == ast.ImportDynamic &&
@@ -3248,32 +3831,154 @@ func (c *linkerContext) isExternalDynamicImport(record *ast.ImportRecord, source
 		record.SourceIndex.GetIndex() != sourceIndex
 }
 
-func (c *linkerContext) markPartLiveForTreeShaking(sourceIndex uint32, partIndex uint32) {
-	file := &c.graph.Files[sourceIndex]
-	repr := file.InputFile.Repr.(*graph.JSRepr)
-	part := &repr.AST.Parts[partIndex]
+func (c *linkerContext) computeIncludedByJSON() {
+	c.includedByJSON = make([]string, len(c.graph.Files))
 
-	// Don't mark this part more than once
-	if part.IsLive {
-		return
-	}
-	part.IsLive = true
+	for _, sourceIndex := range c.graph.ReachableFiles {
+		file := &c.graph.Files[sourceIndex]
+		if !file.IsLive || file.InputFile.OmitFromSourceMapsAndMetafile {
+			continue
+		}
+		sb := strings.Builder{}
+		sb.WriteString(",\n          \"includedBy\": ")
+		sb.WriteString(c.includedByChainJSON(sourceIndex, c.whyFileIsLive[sourceIndex], "          "))
 
-	// Include the file containing this part
-	c.markFileLiveForTreeShaking(sourceIndex)
+		// Also explain each top-level statement that survived tree shaking
+		if repr, ok := file.InputFile.Repr.(*graph.JSRepr); ok {
+			isFirst := true
+			for partIndex, part := range repr.AST.Parts {
+				if !part.IsLive || len(part.Stmts) == 0 || uint32(partIndex) == js_ast.NSExportPartIndex {
+					continue
+				}
+				if isFirst {
+					sb.WriteString(",\n          \"parts\": [")
+					isFirst = false
+				} else {
+					sb.WriteString(",")
+				}
+				line, column := c.lineAndColumnForLoc(sourceIndex, part.Stmts[0].Loc)
+				sb.WriteString(fmt.Sprintf("\n            {\n              \"line\": %d,\n              \"column\": %d,\n              \"includedBy\": %s\n            }",
+					line, column, c.includedByChainJSON(sourceIndex, c.whyPartIsLive[sourceIndex][partIndex], "              ")))
+			}
+			if !isFirst {
+				sb.WriteString("\n          ]")
+			}
+		}
 
-	// Also include any dependencies
-	for _, dep := range part.Dependencies {
-		c.markPartLiveForTreeShaking(dep.SourceIndex, dep.PartIndex)
+		c.includedByJSON[sourceIndex] = sb.String()
 	}
 }
 
-// JavaScript modules are traversed in depth-first postorder. This is the
-// order that JavaScript modules were evaluated in before the top-level await
-// feature was introduced.
-//
-//	  A
-//	 / \
+// This follows the reasons recorded during tree shaking back to an entry point
+// and returns them as a JSON array, starting with the most specific reason.
+func (c *linkerContext) includedByChainJSON(sourceIndex uint32, reason liveReason, indent string) string {
+	var steps []string
+
+	for {
+		path := helpers.QuoteForJSON(c.graph.Files[sourceIndex].InputFile.Source.PrettyPath, c.options.ASCIIOnly)
+
+		switch reason.kind {
+		case liveReasonEntryPoint:
+			steps = append(steps, fmt.Sprintf("{ \"kind\": \"entry-point\", \"path\": %s }", path))
+
+		case liveReasonImport:
+			var record *ast.ImportRecord
+			switch repr := c.graph.Files[reason.sourceIndex].InputFile.Repr.(type) {
+			case *graph.JSRepr:
+				record = &repr.AST.ImportRecords[reason.index]
+			case *graph.CSSRepr:
+				record = &repr.AST.ImportRecords[reason.index]
+			}
+			steps = append(steps, c.includedByStepJSON(record.Kind.StringForMetafile(), reason.sourceIndex, record.Range.Loc))
+			sourceIndex = reason.sourceIndex
+			reason = c.whyFileIsLive[sourceIndex]
+			continue
+
+		case liveReasonUsedByPart:
+			var loc logger.Loc
+			if stmts := c.graph.Files[reason.sourceIndex].InputFile.Repr.(*graph.JSRepr).AST.Parts[reason.index].Stmts; len(stmts) > 0 {
+				loc = stmts[0].Loc
+			}
+			steps = append(steps, c.includedByStepJSON("used-by", reason.sourceIndex, loc))
+			sourceIndex = reason.sourceIndex
+			reason = c.whyPartIsLive[sourceIndex][reason.index]
+			continue
+
+		case liveReasonSideEffects:
+			steps = append(steps, fmt.Sprintf("{ \"kind\": \"side-effects\", \"path\": %s }", path))
+			reason = c.whyFileIsLive[sourceIndex]
+			continue
+
+		case liveReasonNoTreeShaking:
+			steps = append(steps, fmt.Sprintf("{ \"kind\": \"no-tree-shaking\", \"path\": %s }", path))
+			reason = c.whyFileIsLive[sourceIndex]
+			continue
+		}
+		break
+	}
+
+	if len(steps) == 0 {
+		return "[]"
+	}
+	return fmt.Sprintf("[\n%s  %s\n%s]", indent, strings.Join(steps, ",\n"+indent+"  "), indent)
+}
+
+func (c *linkerContext) includedByStepJSON(kind string, sourceIndex uint32, loc logger.Loc) string {
+	file := &c.graph.Files[sourceIndex]
+	path := helpers.QuoteForJSON(file.InputFile.Source.PrettyPath, c.options.ASCIIOnly)
+
+	// Locations inside the runtime aren't meaningful to the user
+	if file.InputFile.OmitFromSourceMapsAndMetafile {
+		return fmt.Sprintf("{ \"kind\": %q, \"path\": %s }", kind, path)
+	}
+
+	line, column := c.lineAndColumnForLoc(sourceIndex, loc)
+	return fmt.Sprintf("{ \"kind\": %q, \"path\": %s, \"line\": %d, \"column\": %d }", kind, path, line, column)
+}
+
+func (c *linkerContext) includedByJSONForFile(sourceIndex uint32) string {
+	if c.includedByJSON == nil {
+		return ""
+	}
+	return c.includedByJSON[sourceIndex]
+}
+
+func (c *linkerContext) lineAndColumnForLoc(sourceIndex uint32, loc logger.Loc) (int, int) {
+	if location := c.graph.Files[sourceIndex].LineColumnTracker().MsgLocationOrNil(logger.Range{Loc: loc}); location != nil {
+		return location.Line, location.Column
+	}
+	return 0, 0
+}
+
+func (c *linkerContext) markPartLiveForTreeShaking(sourceIndex uint32, partIndex uint32, reason liveReason) {
+	file := &c.graph.Files[sourceIndex]
+	repr := file.InputFile.Repr.(*graph.JSRepr)
+	part := &repr.AST.Parts[partIndex]
+
+	// Don't mark this part more than once
+	if part.IsLive {
+		return
+	}
+	part.IsLive = true
+	if c.whyPartIsLive != nil {
+		c.whyPartIsLive[sourceIndex][partIndex] = reason
+	}
+
+	// Include the file containing this part
+	c.markFileLiveForTreeShaking(sourceIndex, reason)
+
+	// Also include any dependencies
+	for _, dep := range part.Dependencies {
+		c.markPartLiveForTreeShaking(dep.SourceIndex, dep.PartIndex, liveReason{kind: liveReasonUsedByPart, sourceIndex: sourceIndex, index: partIndex})
+	}
+}
+
+// JavaScript modules are traversed in depth-first postorder. This is the
+// order that JavaScript modules were evaluated in before the top-level await
+// feature was introduced.
+//
+//	  A
+//	 / \
 //	B   C
 //	 \ /
 //	  D
@@ -3831,12 +4536,37 @@ func isConditionalImportRedundant(earlier []css_ast.ImportConditions, later []cs
 	return true
 }
 
+// Only entry point chunks can run their code inside an async function in the
+// "cjs" and "iife" formats, since other chunks must provide their exports to
+// the chunks that import them synchronously.
+func (c *linkerContext) validateTopLevelAwaitInChunks() {
+	if !c.options.CodeSplitting || (c.options.OutputFormat != config.FormatCommonJS && c.options.OutputFormat != config.FormatIIFE) {
+		return
+	}
+	for _, chunk := range c.chunks {
+		chunkRepr, ok := chunk.chunkRepr.(*chunkReprJS)
+		if !ok || chunk.isEntryPoint {
+			continue
+		}
+		for _, sourceIndex := range chunkRepr.filesInChunkInOrder {
+			file := &c.graph.Files[sourceIndex]
+			if repr := file.InputFile.Repr.(*graph.JSRepr); repr.Meta.Wrap == graph.WrapNone && repr.AST.LiveTopLevelAwaitKeyword.Len > 0 {
+				c.log.AddError(file.LineColumnTracker(), repr.AST.LiveTopLevelAwaitKeyword, fmt.Sprintf(
+					"Top-level await is currently not supported in code shared between entry points with the %q output format",
+					c.options.OutputFormat.String()))
+			}
+		}
+	}
+}
+
 func (c *linkerContext) computeChunks() {
 	c.timer.Begin("Compute chunks")
 	defer c.timer.End("Compute chunks")
 
 	jsChunks := make(map[string]chunkInfo)
+	manualChunks := make(map[uint32]chunkInfo)
 	cssChunks := make(map[string]chunkInfo)
+	htmlChunks := make(map[string]chunkInfo)
 
 	// Create chunks for entry points
 	for i, entryPoint := range c.graph.EntryPoints() {
@@ -3900,6 +4630,10 @@ func (c *linkerContext) computeChunks() {
 				importsInChunkInOrder: order,
 			}
 			cssChunks[key] = chunk
+
+		case *graph.HTMLRepr:
+			chunk.chunkRepr = &chunkReprHTML{}
+			htmlChunks[key] = chunk
 		}
 	}
 
@@ -3907,6 +4641,29 @@ func (c *linkerContext) computeChunks() {
 	for _, sourceIndex := range c.graph.ReachableFiles {
 		if file := &c.graph.Files[sourceIndex]; file.IsLive {
 			if _, ok := file.InputFile.Repr.(*graph.JSRepr); ok {
+				// Files in a manual chunk are grouped by name instead of by entry bits.
+				// The entry bits of the chunk are the union of the entry bits of its
+				// files so that every entry point that needs the chunk imports it.
+				if file.ManualChunkIndex.IsValid() {
+					manualChunkIndex := file.ManualChunkIndex.GetIndex()
+					chunk, ok := manualChunks[manualChunkIndex]
+					if !ok {
+						chunk.entryBits = helpers.NewBitSet(uint(len(c.graph.EntryPoints())))
+						chunk.filesWithPartsInChunk = make(map[uint32]bool)
+						chunk.chunkRepr = &chunkReprJS{}
+						chunk.manualChunkName = c.options.ManualChunks[manualChunkIndex].Name
+						manualChunks[manualChunkIndex] = chunk
+					}
+					chunk.entryBits.Union(file.EntryBits)
+					chunk.filesWithPartsInChunk[uint32(sourceIndex)] = true
+					continue
+				}
+
+				// The runtime is handled below once we know if there are manual chunks
+				if sourceIndex == runtime.SourceIndex && len(c.options.ManualChunks) > 0 {
+					continue
+				}
+
 				key := file.EntryBits.String()
 				chunk, ok := jsChunks[key]
 				if !ok {
@@ -3920,10 +4677,34 @@ func (c *linkerContext) computeChunks() {
 		}
 	}
 
+	// Files in manual chunks may use the runtime too. If the runtime stayed in
+	// the chunk for its entry bits, that chunk could import a manual chunk that
+	// imports the runtime back. Give the runtime its own chunk in that case
+	// since the runtime doesn't import anything itself.
+	var runtimeChunk *chunkInfo
+	if runtimeFile := &c.graph.Files[runtime.SourceIndex]; runtimeFile.IsLive && len(c.options.ManualChunks) > 0 {
+		key := runtimeFile.EntryBits.String()
+		if len(manualChunks) > 0 {
+			runtimeChunk = &chunkInfo{
+				entryBits:             runtimeFile.EntryBits,
+				filesWithPartsInChunk: map[uint32]bool{runtime.SourceIndex: true},
+				chunkRepr:             &chunkReprJS{},
+			}
+		} else if chunk, ok := jsChunks[key]; ok {
+			chunk.filesWithPartsInChunk[runtime.SourceIndex] = true
+		} else {
+			jsChunks[key] = chunkInfo{
+				entryBits:             runtimeFile.EntryBits,
+				filesWithPartsInChunk: map[uint32]bool{runtime.SourceIndex: true},
+				chunkRepr:             &chunkReprJS{},
+			}
+		}
+	}
+
 	// Sort the chunks for determinism. This matters because we use chunk indices
 	// as sorting keys in a few places.
-	sortedChunks := make([]chunkInfo, 0, len(jsChunks)+len(cssChunks))
-	sortedKeys := make([]string, 0, len(jsChunks)+len(cssChunks))
+	sortedChunks := make([]chunkInfo, 0, len(jsChunks)+len(manualChunks)+len(cssChunks)+len(htmlChunks)+1)
+	sortedKeys := make([]string, 0, len(jsChunks)+len(cssChunks)+len(htmlChunks))
 	for key := range jsChunks {
 		sortedKeys = append(sortedKeys, key)
 	}
@@ -3936,6 +4717,14 @@ func (c *linkerContext) computeChunks() {
 		}
 		sortedChunks = append(sortedChunks, chunk)
 	}
+	if runtimeChunk != nil {
+		sortedChunks = append(sortedChunks, *runtimeChunk)
+	}
+	for i := range c.options.ManualChunks {
+		if chunk, ok := manualChunks[uint32(i)]; ok {
+			sortedChunks = append(sortedChunks, chunk)
+		}
+	}
 	sortedKeys = sortedKeys[:0]
 	for key := range cssChunks {
 		sortedKeys = append(sortedKeys, key)
@@ -3948,6 +4737,14 @@ func (c *linkerContext) computeChunks() {
 		}
 		sortedChunks = append(sortedChunks, chunk)
 	}
+	sortedKeys = sortedKeys[:0]
+	for key := range htmlChunks {
+		sortedKeys = append(sortedKeys, key)
+	}
+	sort.Strings(sortedKeys)
+	for _, key := range sortedKeys {
+		sortedChunks = append(sortedChunks, htmlChunks[key])
+	}
 
 	// Map from the entry point file to its chunk. We will need this later if
 	// a file contains a dynamic import to this entry point, since we'll need
@@ -3992,6 +4789,8 @@ func (c *linkerContext) computeChunks() {
 			stdExt = c.options.OutputExtensionJS
 		case *chunkReprCSS:
 			stdExt = c.options.OutputExtensionCSS
+		case *chunkReprHTML:
+			stdExt = ".html"
 		}
 
 		// Compute the template substitutions
@@ -4035,6 +4834,9 @@ func (c *linkerContext) computeChunks() {
 		} else {
 			dir = "/"
 			base = "chunk"
+			if chunk.manualChunkName != "" {
+				base = chunk.manualChunkName
+			}
 			ext = stdExt
 			template = c.options.ChunkPathTemplate
 		}
@@ -4133,7 +4935,7 @@ func (c *linkerContext) findImportedPartsInJSOrder(chunk *chunkInfo) (js []uint3
 		file := &c.graph.Files[sourceIndex]
 
 		if repr, ok := file.InputFile.Repr.(*graph.JSRepr); ok {
-			isFileInThisChunk := chunk.entryBits.Equals(file.EntryBits)
+			isFileInThisChunk := chunk.filesWithPartsInChunk[sourceIndex]
 
 			// Wrapped files can't be split because they are all inside the wrapper
 			canFileBeSplit := repr.Meta.Wrap == graph.WrapNone
@@ -4595,6 +5397,8 @@ func (c *linkerContext) generateCodeForFileInChunkJS(
 	waitGroup *sync.WaitGroup,
 	partRange partRange,
 	entryBits helpers.BitSet,
+	crossChunkImports map[ast.Ref]ast.NamespaceAlias,
+	isAsync bool,
 	chunkAbsDir string,
 	toCommonJSRef ast.Ref,
 	toESMRef ast.Ref,
@@ -4756,7 +5560,24 @@ func (c *linkerContext) generateCodeForFileInChunkJS(
 			}
 
 			var cjsArgs []js_ast.Expr
-			if c.options.ProfilerNames {
+			if c.options.HMR {
+				// "__hmrModule({ 'file.js'(exports, module) { ... } }, 1)"
+				//
+				// The runtime uses the property name as the module's identity, so
+				// this form is always used when hot module replacement is enabled
+				var flags js_ast.PropertyFlags
+				if !c.options.UnsupportedJSFeatures.Has(compat.ObjectExtensions) {
+					flags |= js_ast.PropertyIsMethod
+				}
+				cjsArgs = []js_ast.Expr{{Data: &js_ast.EObject{Properties: []js_ast.Property{{
+					Flags:      flags,
+					Key:        js_ast.Expr{Data: &js_ast.EString{Value: helpers.StringToUTF16(file.InputFile.Source.PrettyPath)}},
+					ValueOrNil: js_ast.Expr{Data: &js_ast.EFunction{Fn: js_ast.Fn{Args: args, Body: js_ast.FnBody{Block: js_ast.SBlock{Stmts: stmts}}}}},
+				}}}}}
+				if repr.Meta.IsESMWrappedForHMR {
+					cjsArgs = append(cjsArgs, js_ast.Expr{Data: &js_ast.ENumber{Value: 1}})
+				}
+			} else if c.options.ProfilerNames {
 				// "__commonJS({ 'file.js'(exports, module) { ... } })"
 				var flags js_ast.PropertyFlags
 				if !c.options.UnsupportedJSFeatures.Has(compat.ObjectExtensions) {
@@ -4879,9 +5700,9 @@ func (c *linkerContext) generateCodeForFileInChunkJS(
 		lineOffsetTables = dataForSourceMaps[partRange.sourceIndex].LineOffsetTables
 	}
 
-	// Indent the file if everything is wrapped in an IIFE
+	// Indent the file if everything is wrapped in an IIFE or an async function
 	indent := 0
-	if c.options.OutputFormat == config.FormatIIFE {
+	if c.options.OutputFormat == config.FormatIIFE || isAsync {
 		indent++
 	}
 
@@ -4908,6 +5729,8 @@ func (c *linkerContext) generateCodeForFileInChunkJS(
 		RequireOrImportMetaForSource: c.requireOrImportMetaForSource,
 		MangledProps:                 c.mangledProps,
 		NeedsMetafile:                c.options.NeedsMetafile,
+		CrossChunkImports:            crossChunkImports,
+		ChunkLoaderRef:               c.chunkLoaderRef,
 	}
 	tree := repr.AST
 	tree.Directives = nil // This is handled elsewhere
@@ -4917,7 +5740,7 @@ func (c *linkerContext) generateCodeForFileInChunkJS(
 		sourceIndex: partRange.sourceIndex,
 	}
 
-	if file.InputFile.Loader == config.LoaderFile {
+	if file.InputFile.Loader == config.LoaderFile || (file.InputFile.Loader == config.LoaderWasm && file.InputFile.UniqueKeyForAdditionalFile != "") {
 		result.JSONMetadataImports = append(result.JSONMetadataImports, fmt.Sprintf("\n        {\n          \"path\": %s,\n          \"kind\": \"file-loader\"\n        }",
 			helpers.QuoteForJSON(file.InputFile.UniqueKeyForAdditionalFile, c.options.ASCIIOnly)))
 	}
@@ -4930,6 +5753,8 @@ func (c *linkerContext) generateEntryPointTailJS(
 	toCommonJSRef ast.Ref,
 	toESMRef ast.Ref,
 	sourceIndex uint32,
+	crossChunkImports map[ast.Ref]ast.NamespaceAlias,
+	isAsync bool,
 ) (result compileResultJS) {
 	file := &c.graph.Files[sourceIndex]
 	repr := file.InputFile.Repr.(*graph.JSRepr)
@@ -4947,7 +5772,7 @@ func (c *linkerContext) generateEntryPointTailJS(
 
 	case config.FormatIIFE:
 		if repr.Meta.Wrap == graph.WrapCJS {
-			if len(c.options.GlobalName) > 0 {
+			if len(c.options.GlobalName) > 0 || c.options.CodeSplitting {
 				// "return require_foo();"
 				stmts = append(stmts, js_ast.Stmt{Data: &js_ast.SReturn{ValueOrNil: js_ast.Expr{Data: &js_ast.ECall{
 					Target: js_ast.Expr{Data: &js_ast.EIdentifier{Ref: repr.AST.WrapperRef}},
@@ -4978,6 +5803,19 @@ func (c *linkerContext) generateEntryPointTailJS(
 		}
 
 	case config.FormatCommonJS:
+		if isAsync {
+			if repr.Meta.ForceIncludeExportsForEntryPoint {
+				// "return __toCommonJS(exports);"
+				stmts = append(stmts, js_ast.Stmt{Data: &js_ast.SReturn{
+					ValueOrNil: js_ast.Expr{Data: &js_ast.ECall{
+						Target: js_ast.Expr{Data: &js_ast.EIdentifier{Ref: toCommonJSRef}},
+						Args:   []js_ast.Expr{{Data: &js_ast.EIdentifier{Ref: repr.AST.ExportsRef}}},
+					}},
+				}})
+			}
+			break
+		}
+
 		if repr.Meta.Wrap == graph.WrapCJS {
 			// "module.exports = require_foo();"
 			stmts = append(stmts, js_ast.AssignStmt(
@@ -5084,7 +5922,12 @@ func (c *linkerContext) generateEntryPointTailJS(
 		}
 
 	case config.FormatESModule:
-		if repr.Meta.Wrap == graph.WrapCJS {
+		if repr.Meta.IsESMWrappedForHMR {
+			// "require_foo();"
+			stmts = append(stmts, js_ast.Stmt{Data: &js_ast.SExpr{Value: js_ast.Expr{Data: &js_ast.ECall{
+				Target: js_ast.Expr{Data: &js_ast.EIdentifier{Ref: repr.AST.WrapperRef}},
+			}}}})
+		} else if repr.Meta.Wrap == graph.WrapCJS {
 			// "export default require_foo();"
 			stmts = append(stmts, js_ast.Stmt{
 				Data: &js_ast.SExportDefault{Value: js_ast.Stmt{
@@ -5222,9 +6065,9 @@ func (c *linkerContext) generateEntryPointTailJS(
 	tree.Directives = nil
 	tree.Parts = []js_ast.Part{{Stmts: stmts}}
 
-	// Indent the file if everything is wrapped in an IIFE
+	// Indent the file if everything is wrapped in an IIFE or an async function
 	indent := 0
-	if c.options.OutputFormat == config.FormatIIFE {
+	if c.options.OutputFormat == config.FormatIIFE || isAsync {
 		indent++
 	}
 
@@ -5243,6 +6086,8 @@ func (c *linkerContext) generateEntryPointTailJS(
 		UnsupportedFeatures:          c.options.UnsupportedJSFeatures,
 		RequireOrImportMetaForSource: c.requireOrImportMetaForSource,
 		MangledProps:                 c.mangledProps,
+		CrossChunkImports:            crossChunkImports,
+		ChunkLoaderRef:               c.chunkLoaderRef,
 	}
 	result.PrintResult = js_printer.Print(tree, c.graph.Symbols, r, printOptions)
 	return
@@ -5274,11 +6119,28 @@ func (c *linkerContext) renameSymbolsInChunk(chunk *chunkInfo, filesInOrder []ui
 
 	// Make sure imports get a chance to be renamed too
 	var sortedImportsFromOtherChunks stableRefArray
-	for _, imports := range chunk.chunkRepr.(*chunkReprJS).importsFromOtherChunks {
-		for _, item := range imports {
+	chunkRepr := chunk.chunkRepr.(*chunkReprJS)
+	if c.options.OutputFormat.KeepESMImportExportSyntax() {
+		for _, imports := range chunkRepr.importsFromOtherChunks {
+			for _, item := range imports {
+				sortedImportsFromOtherChunks = append(sortedImportsFromOtherChunks, stableRef{
+					StableSourceIndex: c.graph.StableSourceIndices[item.ref.SourceIndex],
+					Ref:               item.ref,
+				})
+			}
+		}
+	} else {
+		// Other formats access imports through the namespace for each chunk
+		for _, namespace := range chunkRepr.crossChunkNamespaces {
+			sortedImportsFromOtherChunks = append(sortedImportsFromOtherChunks, stableRef{
+				StableSourceIndex: c.graph.StableSourceIndices[namespace.ref.SourceIndex],
+				Ref:               namespace.ref,
+			})
+		}
+		if c.chunkLoaderRef != ast.InvalidRef {
 			sortedImportsFromOtherChunks = append(sortedImportsFromOtherChunks, stableRef{
-				StableSourceIndex: c.graph.StableSourceIndices[item.ref.SourceIndex],
-				Ref:               item.ref,
+				StableSourceIndex: c.graph.StableSourceIndices[c.chunkLoaderRef.SourceIndex],
+				Ref:               c.chunkLoaderRef,
 			})
 		}
 	}
@@ -5504,6 +6366,7 @@ func (c *linkerContext) generateChunkJS(chunkIndex int, chunkWaitGroup *sync.Wai
 	runtimeRequireRef := ast.FollowSymbols(c.graph.Symbols, runtimeMembers["__require"].Ref)
 	r := c.renameSymbolsInChunk(chunk, chunkRepr.filesInChunkInOrder, timer)
 	dataForSourceMaps := c.dataForSourceMaps()
+	isAsync := chunk.isEntryPoint && c.isAsyncEntryPoint(chunk.sourceIndex)
 
 	// Note: This contains placeholders instead of what the placeholders are
 	// substituted with. That should be fine though because this should only
@@ -5532,6 +6395,8 @@ func (c *linkerContext) generateChunkJS(chunkIndex int, chunkWaitGroup *sync.Wai
 			&waitGroup,
 			partRange,
 			chunk.entryBits,
+			chunkRepr.crossChunkImportAliases,
+			isAsync,
 			chunkAbsDir,
 			toCommonJSRef,
 			toESMRef,
@@ -5546,9 +6411,9 @@ func (c *linkerContext) generateChunkJS(chunkIndex int, chunkWaitGroup *sync.Wai
 	var crossChunkSuffix []byte
 	var jsonMetadataImports []string
 	{
-		// Indent the file if everything is wrapped in an IIFE
+		// Indent the file if everything is wrapped in an IIFE or an async function
 		indent := 0
-		if c.options.OutputFormat == config.FormatIIFE {
+		if c.options.OutputFormat == config.FormatIIFE || isAsync {
 			indent++
 		}
 		printOptions := js_printer.Options{
@@ -5559,6 +6424,7 @@ func (c *linkerContext) generateChunkJS(chunkIndex int, chunkWaitGroup *sync.Wai
 			MinifySyntax:      c.options.MinifySyntax,
 			LineLimit:         c.options.LineLimit,
 			NeedsMetafile:     c.options.NeedsMetafile,
+			CrossChunkImports: chunkRepr.crossChunkImportAliases,
 		}
 		crossChunkImportRecords := make([]ast.ImportRecord, len(chunk.crossChunkImports))
 		for i, chunkImport := range chunk.crossChunkImports {
@@ -5574,6 +6440,16 @@ func (c *linkerContext) generateChunkJS(chunkIndex int, chunkWaitGroup *sync.Wai
 		}, c.graph.Symbols, r, printOptions)
 		crossChunkPrefix = crossChunkResult.JS
 		jsonMetadataImports = crossChunkResult.JSONMetadataImports
+
+		// The "iife" format imports other chunks using the chunk loader instead
+		// of with statements, so the metafile entries must be generated here
+		if c.options.OutputFormat == config.FormatIIFE && c.options.NeedsMetafile {
+			for _, namespace := range chunkRepr.crossChunkNamespaces {
+				jsonMetadataImports = append(jsonMetadataImports, fmt.Sprintf("\n        {\n          \"path\": %s,\n          \"kind\": %s\n        }",
+					helpers.QuoteForJSON(c.chunks[namespace.chunkIndex].uniqueKey, c.options.ASCIIOnly),
+					helpers.QuoteForJSON(ast.ImportStmt.StringForMetafile(), c.options.ASCIIOnly)))
+			}
+		}
 		crossChunkSuffix = js_printer.Print(js_ast.AST{
 			Parts: []js_ast.Part{{Stmts: chunkRepr.crossChunkSuffixStmts}},
 		}, c.graph.Symbols, r, printOptions).JS
@@ -5587,11 +6463,31 @@ func (c *linkerContext) generateChunkJS(chunkIndex int, chunkWaitGroup *sync.Wai
 			toCommonJSRef,
 			toESMRef,
 			chunk.sourceIndex,
+			chunkRepr.crossChunkImportAliases,
+			isAsync,
 		)
 	}
 
 	waitGroup.Wait()
 	timer.End("Print JavaScript files")
+
+	// Hash the code for each module so the development server can tell which
+	// modules changed between builds when hot module replacement is enabled.
+	// Code that can't be replaced individually is hashed together instead.
+	if c.options.HMR {
+		chunk.moduleHashesForHMR = make(map[string]uint64, len(compileResults))
+		other := xxhash.New()
+		for _, compileResult := range compileResults {
+			if repr := c.graph.Files[compileResult.sourceIndex].InputFile.Repr.(*graph.JSRepr); repr.Meta.Wrap == graph.WrapCJS {
+				path := c.graph.Files[compileResult.sourceIndex].InputFile.Source.PrettyPath
+				chunk.moduleHashesForHMR[path] = xxhash.Sum64(compileResult.JS)
+			} else {
+				other.Write(compileResult.JS)
+			}
+		}
+		chunk.moduleHashesForHMR[""] = other.Sum64()
+	}
+
 	timer.Begin("Join JavaScript files")
 
 	j := helpers.Joiner{}
@@ -5645,17 +6541,71 @@ func (c *linkerContext) generateChunkJS(chunkIndex int, chunkWaitGroup *sync.Wai
 		}
 	}
 
+	// Register the debug ID before any other code runs so that it's available
+	// even if an error is thrown while this chunk is being evaluated
+	if c.options.DebugIDs {
+		text := debugIDRegistrationJS(c.debugIDUniqueKey(uint32(chunkIndex)))
+		prevOffset.AdvanceString(text)
+		j.AddString(text)
+		newlineBeforeComment = true
+	}
+
+	// Code that uses top-level await is run inside an async function
+	async := ""
+	if isAsync {
+		async = "async "
+		if c.options.MinifyWhitespace && !c.options.UnsupportedJSFeatures.Has(compat.Arrow) {
+			async = "async"
+		}
+	}
+
 	// Optionally wrap with an IIFE
 	if c.options.OutputFormat == config.FormatIIFE {
 		var text string
 		indent = "  "
-		if len(c.options.GlobalName) > 0 {
-			text = c.generateGlobalNamePrefix()
+		if c.options.CodeSplitting {
+			// Code splitting uses the chunk loader instead of an IIFE. Its callback
+			// is passed the function for "import()" and the exports of each chunk
+			// that this chunk imports, in that order:
+			//
+			//   LOADER(["./chunk.js"], (__import, import_chunk) => {
+			//     ...
+			//   });
+			//
+			deps := make([]string, 0, len(chunkRepr.crossChunkNamespaces))
+			args := make([]string, 0, 1+len(chunkRepr.crossChunkNamespaces))
+			args = append(args, r.NameForSymbol(c.chunkLoaderRef))
+			for _, namespace := range chunkRepr.crossChunkNamespaces {
+				deps = append(deps, string(helpers.QuoteForJSON(c.chunks[namespace.chunkIndex].uniqueKey, c.options.ASCIIOnly)))
+				args = append(args, r.NameForSymbol(namespace.ref))
+			}
+			text = runtime.ChunkLoaderIIFE + "([" + strings.Join(deps, ","+space) + "]," + space
+			if c.options.UnsupportedJSFeatures.Has(compat.Arrow) {
+				text += async + "function(" + strings.Join(args, ","+space) + ")" + space + "{" + newline
+			} else {
+				text += async + "(" + strings.Join(args, ","+space) + ")" + space + "=>" + space + "{" + newline
+			}
+		} else {
+			if len(c.options.GlobalName) > 0 {
+				text = c.generateGlobalNamePrefix()
+			}
+			if c.options.UnsupportedJSFeatures.Has(compat.Arrow) {
+				text += "(" + async + "function()" + space + "{" + newline
+			} else {
+				text += "(" + async + "()" + space + "=>" + space + "{" + newline
+			}
 		}
+		prevOffset.AdvanceString(text)
+		j.AddString(text)
+		newlineBeforeComment = false
+	} else if isAsync {
+		// "module.exports = (async () => {"
+		text := "module.exports" + space + "=" + space
+		indent = "  "
 		if c.options.UnsupportedJSFeatures.Has(compat.Arrow) {
-			text += "(function()" + space + "{" + newline
+			text += "(" + async + "function()" + space + "{" + newline
 		} else {
-			text += "(()" + space + "=>" + space + "{" + newline
+			text += "(" + async + "()" + space + "=>" + space + "{" + newline
 		}
 		prevOffset.AdvanceString(text)
 		j.AddString(text)
@@ -5737,6 +6687,9 @@ func (c *linkerContext) generateChunkJS(chunkIndex int, chunkWaitGroup *sync.Wai
 			entryPoint := c.graph.Files[chunk.sourceIndex].InputFile.Source.PrettyPath
 			jMeta.AddString(fmt.Sprintf("      \"entryPoint\": %s,\n", helpers.QuoteForJSON(entryPoint, c.options.ASCIIOnly)))
 		}
+		if chunk.manualChunkName != "" {
+			jMeta.AddString(fmt.Sprintf("      \"manualChunk\": %s,\n", helpers.QuoteForJSON(chunk.manualChunkName, c.options.ASCIIOnly)))
+		}
 		if chunkRepr.hasCSSChunk {
 			jMeta.AddString(fmt.Sprintf("      \"cssBundle\": %s,\n", helpers.QuoteForJSON(c.chunks[chunkRepr.cssChunkIndex].uniqueKey, c.options.ASCIIOnly)))
 		}
@@ -5842,6 +6795,12 @@ func (c *linkerContext) generateChunkJS(chunkIndex int, chunkWaitGroup *sync.Wai
 
 	// Optionally wrap with an IIFE
 	if c.options.OutputFormat == config.FormatIIFE {
+		if c.options.CodeSplitting {
+			j.AddString("});" + newline)
+		} else {
+			j.AddString("})();" + newline)
+		}
+	} else if isAsync {
 		j.AddString("})();" + newline)
 	}
 
@@ -5859,13 +6818,23 @@ func (c *linkerContext) generateChunkJS(chunkIndex int, chunkWaitGroup *sync.Wai
 	}
 
 	// The JavaScript contents are done now that the source map comment is in
-	chunk.intermediateOutput = c.breakJoinerIntoPieces(j)
+	var renderSourceMap *sourcemap.SourceMap
+	chunk.intermediateOutput, renderSourceMap = c.runOnRenderChunkPlugins(chunk, j)
 	timer.End("Join JavaScript files")
 
 	if c.options.SourceMap != config.SourceMapNone {
 		timer.Begin("Generate source map")
-		canHaveShifts := chunk.intermediateOutput.pieces != nil
-		chunk.outputSourceMap = c.generateSourceMapForChunk(compileResultsForSourceMap, chunkAbsDir, dataForSourceMaps, canHaveShifts)
+		if c.options.SourcemapSections && renderSourceMap == nil {
+			// Composing with the source map from plugins produces a flat source
+			// map, so sections are only used when there's nothing to compose with
+			chunk.outputSourceMap = c.generateSectionedSourceMapForChunk(compileResultsForSourceMap, chunkAbsDir, dataForSourceMaps)
+		} else {
+			canHaveShifts := chunk.intermediateOutput.pieces != nil
+			chunk.outputSourceMap = c.generateSourceMapForChunk(compileResultsForSourceMap, chunkAbsDir, dataForSourceMaps, canHaveShifts)
+			if renderSourceMap != nil {
+				chunk.outputSourceMap = c.composeRenderChunkSourceMap(chunk.outputSourceMap, renderSourceMap)
+			}
+		}
 		timer.End("Generate source map")
 	}
 
@@ -5891,9 +6860,9 @@ func (c *linkerContext) generateChunkJS(chunkIndex int, chunkWaitGroup *sync.Wai
 				for _, output := range pieces[i] {
 					count += c.accurateFinalByteCount(output, finalRelDir)
 				}
-				jMeta.AddString(fmt.Sprintf("\n        %s: {\n          \"bytesInOutput\": %d\n        }",
+				jMeta.AddString(fmt.Sprintf("\n        %s: {\n          \"bytesInOutput\": %d%s\n        }",
 					helpers.QuoteForJSON(c.graph.Files[sourceIndex].InputFile.Source.PrettyPath, c.options.ASCIIOnly),
-					count))
+					count, c.includedByJSONForFile(sourceIndex)))
 			}
 			if len(metaOrder) > 0 {
 				jMeta.AddString("\n      ")
@@ -6306,13 +7275,23 @@ func (c *linkerContext) generateChunkCSS(chunkIndex int, chunkWaitGroup *sync.Wa
 	}
 
 	// The CSS contents are done now that the source map comment is in
-	chunk.intermediateOutput = c.breakJoinerIntoPieces(j)
+	var renderSourceMap *sourcemap.SourceMap
+	chunk.intermediateOutput, renderSourceMap = c.runOnRenderChunkPlugins(chunk, j)
 	timer.End("Join CSS files")
 
 	if c.options.SourceMap != config.SourceMapNone {
 		timer.Begin("Generate source map")
-		canHaveShifts := chunk.intermediateOutput.pieces != nil
-		chunk.outputSourceMap = c.generateSourceMapForChunk(compileResultsForSourceMap, chunkAbsDir, dataForSourceMaps, canHaveShifts)
+		if c.options.SourcemapSections && renderSourceMap == nil {
+			// Composing with the source map from plugins produces a flat source
+			// map, so sections are only used when there's nothing to compose with
+			chunk.outputSourceMap = c.generateSectionedSourceMapForChunk(compileResultsForSourceMap, chunkAbsDir, dataForSourceMaps)
+		} else {
+			canHaveShifts := chunk.intermediateOutput.pieces != nil
+			chunk.outputSourceMap = c.generateSourceMapForChunk(compileResultsForSourceMap, chunkAbsDir, dataForSourceMaps, canHaveShifts)
+			if renderSourceMap != nil {
+				chunk.outputSourceMap = c.composeRenderChunkSourceMap(chunk.outputSourceMap, renderSourceMap)
+			}
+		}
 		timer.End("Generate source map")
 	}
 
@@ -6335,9 +7314,9 @@ func (c *linkerContext) generateChunkCSS(chunkIndex int, chunkWaitGroup *sync.Wa
 				} else {
 					jMeta.AddString(",")
 				}
-				jMeta.AddString(fmt.Sprintf("\n        %s: {\n          \"bytesInOutput\": %d\n        }",
+				jMeta.AddString(fmt.Sprintf("\n        %s: {\n          \"bytesInOutput\": %d%s\n        }",
 					helpers.QuoteForJSON(c.graph.Files[compileResult.sourceIndex.GetIndex()].InputFile.Source.PrettyPath, c.options.ASCIIOnly),
-					c.accurateFinalByteCount(pieces[i], finalRelDir)))
+					c.accurateFinalByteCount(pieces[i], finalRelDir), c.includedByJSONForFile(compileResult.sourceIndex.GetIndex())))
 			}
 			if len(compileResults) > 0 {
 				jMeta.AddString("\n      ")
@@ -6351,6 +7330,221 @@ func (c *linkerContext) generateChunkCSS(chunkIndex int, chunkWaitGroup *sync.Wa
 	chunkWaitGroup.Done()
 }
 
+// HTML files reference output files from other linking operations (the ones
+// for the scripts and style sheets that they reference). Give each of these
+// an index so that it can be referenced using a unique key.
+func (c *linkerContext) indexOtherOutputs() {
+	sourceIndices := make([]int, 0, len(c.entryPointOutputs))
+	for sourceIndex := range c.entryPointOutputs {
+		sourceIndices = append(sourceIndices, int(sourceIndex))
+	}
+	sort.Ints(sourceIndices)
+
+	c.otherOutputKeyIndices = make(map[string]uint32)
+	add := func(absPath string) {
+		if _, ok := c.otherOutputKeyIndices[absPath]; !ok && absPath != "" {
+			c.otherOutputKeyIndices[absPath] = uint32(len(c.otherOutputAbsPaths))
+			c.otherOutputAbsPaths = append(c.otherOutputAbsPaths, absPath)
+		}
+	}
+	for _, sourceIndex := range sourceIndices {
+		output := c.entryPointOutputs[uint32(sourceIndex)]
+		add(output.AbsPath)
+		add(output.CSSAbsPath)
+		for _, absPath := range output.StaticImportAbsPaths {
+			add(absPath)
+		}
+	}
+}
+
+func (c *linkerContext) otherOutputUniqueKey(index uint32) string {
+	return fmt.Sprintf("%sO%08d", c.uniqueKeyPrefix, index)
+}
+
+// This stands in for the debug ID of a chunk until its final hash is known
+func (c *linkerContext) debugIDUniqueKey(chunkIndex uint32) string {
+	return fmt.Sprintf("%sD%08d", c.uniqueKeyPrefix, chunkIndex)
+}
+
+func (c *linkerContext) otherOutputRelPath(index uint32) string {
+	relPath, _ := c.fs.Rel(c.options.AbsOutputDir, c.otherOutputAbsPaths[index])
+
+	// Make sure to always use forward slashes, even on Windows
+	return strings.ReplaceAll(relPath, "\\", "/")
+}
+
+// Returns the paths of all chunks that must be loaded before the given chunk
+// can be evaluated because they are imported using static import statements
+func (c *linkerContext) staticallyImportedChunkAbsPaths(chunkIndex uint32) (absPaths []string) {
+	visited := map[uint32]bool{chunkIndex: true}
+	var visit func(uint32)
+	visit = func(chunkIndex uint32) {
+		for _, chunkImport := range c.chunks[chunkIndex].crossChunkImports {
+			if chunkImport.importKind == ast.ImportStmt && !visited[chunkImport.chunkIndex] {
+				visited[chunkImport.chunkIndex] = true
+				absPaths = append(absPaths, c.fs.Join(c.options.AbsOutputDir, c.chunks[chunkImport.chunkIndex].finalRelPath))
+				visit(chunkImport.chunkIndex)
+			}
+		}
+	}
+	visit(chunkIndex)
+	return
+}
+
+// Data URLs may contain characters that end the attribute value
+var htmlAttributeEscaper = strings.NewReplacer("&", "&amp;", "\"", "&quot;", "'", "&#39;")
+
+func (c *linkerContext) generateChunkHTML(chunkIndex int, chunkWaitGroup *sync.WaitGroup) {
+	defer c.recoverInternalError(chunkWaitGroup, runtime.SourceIndex)
+
+	chunk := &c.chunks[chunkIndex]
+	chunkRepr := chunk.chunkRepr.(*chunkReprHTML)
+	file := &c.graph.Files[chunk.sourceIndex].InputFile
+	repr := file.Repr.(*graph.HTMLRepr)
+	text := file.Source.Contents
+
+	timer := c.timer.Fork()
+	if timer != nil {
+		timeName := fmt.Sprintf("Generate chunk %q", path.Clean(config.TemplateToString(chunk.finalTemplate)))
+		timer.Begin(timeName)
+		defer c.timer.Join(timer)
+		defer timer.End(timeName)
+	}
+
+	type metafileImport struct {
+		path string
+		kind ast.ImportKind
+	}
+	var metafileImports []metafileImport
+	alreadyLinked := make(map[string]bool)
+	j := helpers.Joiner{}
+	end := 0
+
+	for _, url := range repr.AST.URLs {
+		record := &repr.AST.ImportRecords[url.ImportRecordIndex]
+		var replacement string
+		var tags []string
+
+		if url.Kind.IsEntryPoint() {
+			// Point to the output file generated for the referenced entry point
+			if !record.SourceIndex.IsValid() {
+				continue
+			}
+			output, ok := c.entryPointOutputs[record.SourceIndex.GetIndex()]
+			if !ok {
+				continue
+			}
+			replacement = c.otherOutputUniqueKey(c.otherOutputKeyIndices[output.AbsPath])
+			metafileImports = append(metafileImports, metafileImport{path: replacement, kind: ast.ImportEntryPoint})
+
+			// CSS imported by a script must be loaded using a separate tag
+			if output.CSSAbsPath != "" && !alreadyLinked[output.CSSAbsPath] {
+				alreadyLinked[output.CSSAbsPath] = true
+				key := c.otherOutputUniqueKey(c.otherOutputKeyIndices[output.CSSAbsPath])
+				tags = append(tags, fmt.Sprintf("<link rel=\"stylesheet\" href=\"%s\">", key))
+				metafileImports = append(metafileImports, metafileImport{path: key, kind: ast.ImportEntryPoint})
+			}
+
+			// Let the browser start downloading the chunks that a module script
+			// imports without having to wait for the module script to download
+			if url.Kind == html_ast.URLModuleScript {
+				for _, absPath := range output.StaticImportAbsPaths {
+					if !alreadyLinked[absPath] {
+						alreadyLinked[absPath] = true
+						key := c.otherOutputUniqueKey(c.otherOutputKeyIndices[absPath])
+						tags = append(tags, fmt.Sprintf("<link rel=\"modulepreload\" href=\"%s\">", key))
+						metafileImports = append(metafileImports, metafileImport{path: key, kind: ast.ImportStmt})
+					}
+				}
+			}
+		} else {
+			// Point to the output path for the asset
+			sourceIndex := record.SourceIndex
+			if !sourceIndex.IsValid() {
+				sourceIndex = record.CopySourceIndex
+			}
+			if !sourceIndex.IsValid() {
+				continue
+			}
+			otherFile := &c.graph.Files[sourceIndex.GetIndex()].InputFile
+			switch otherRepr := otherFile.Repr.(type) {
+			case *graph.JSRepr:
+				replacement = otherRepr.AST.URLForCSS
+			case *graph.CopyRepr:
+				replacement = otherRepr.URLForCode
+			}
+			if replacement == "" {
+				continue
+			}
+			replacement = htmlAttributeEscaper.Replace(replacement)
+			if len(otherFile.AdditionalFiles) > 0 {
+				chunkRepr.assetSourceIndices = append(chunkRepr.assetSourceIndices, sourceIndex.GetIndex())
+				metafileImports = append(metafileImports, metafileImport{path: replacement, kind: ast.ImportURL})
+			}
+		}
+
+		// Insert any additional tags before the tag containing this URL
+		if len(tags) > 0 {
+			tagStart := int(url.TagStart.Start)
+			j.AddString(text[end:tagStart])
+			end = tagStart
+
+			// Match the indentation of the original tag
+			separator := ""
+			if !c.options.MinifyWhitespace {
+				lineStart := strings.LastIndexByte(text[:tagStart], '\n') + 1
+				indent := text[lineStart:tagStart]
+				if strings.TrimLeft(indent, " \t") == "" {
+					separator = "\n" + indent
+				} else {
+					separator = "\n"
+				}
+			}
+			for _, tag := range tags {
+				j.AddString(tag)
+				j.AddString(separator)
+			}
+		}
+
+		j.AddString(text[end:url.Range.Loc.Start])
+		j.AddString(replacement)
+		end = int(url.Range.End())
+	}
+
+	j.AddString(text[end:])
+	chunk.intermediateOutput = c.breakJoinerIntoPieces(j)
+
+	// End the metadata lazily. The final output size is not known until the
+	// final import paths are substituted into the output pieces generated above.
+	if c.options.NeedsMetafile {
+		chunk.jsonMetadataChunkCallback = func(finalOutputSize int) helpers.Joiner {
+			jMeta := helpers.Joiner{}
+			jMeta.AddString("{\n      \"imports\": [")
+			for i, item := range metafileImports {
+				if i > 0 {
+					jMeta.AddString(",")
+				}
+				jMeta.AddString(fmt.Sprintf("\n        {\n          \"path\": %s,\n          \"kind\": %s\n        }",
+					helpers.QuoteForJSON(item.path, c.options.ASCIIOnly),
+					helpers.QuoteForJSON(item.kind.StringForMetafile(), c.options.ASCIIOnly)))
+			}
+			if len(metafileImports) > 0 {
+				jMeta.AddString("\n      ")
+			}
+			jMeta.AddString(fmt.Sprintf("],\n      \"entryPoint\": %s,\n      \"inputs\": {\n        %s: {\n          \"bytesInOutput\": %d%s\n        }\n      },\n      \"bytes\": %d\n    }",
+				helpers.QuoteForJSON(file.Source.PrettyPath, c.options.ASCIIOnly),
+				helpers.QuoteForJSON(file.Source.PrettyPath, c.options.ASCIIOnly),
+				finalOutputSize,
+				c.includedByJSONForFile(chunk.sourceIndex),
+				finalOutputSize))
+			return jMeta
+		}
+	}
+
+	c.generateIsolatedHashInParallel(chunk)
+	chunkWaitGroup.Done()
+}
+
 func wrapRulesWithConditions(
 	rules []css_ast.Rule, importRecords []ast.ImportRecord,
 	conditions []css_ast.ImportConditions, conditionImportRecords []ast.ImportRecord,
@@ -6567,7 +7761,11 @@ func (c *linkerContext) appendIsolatedHashesForImportedChunks(
 
 	// Mix in hashes for referenced asset paths (i.e. the "file" loader)
 	for _, piece := range chunk.intermediateOutput.pieces {
-		if piece.kind == outputPieceAssetIndex {
+		if piece.kind == outputPieceOtherOutputIndex {
+			// Output files from other linking operations already have their final
+			// paths, so mix those in directly
+			hashWriteLengthPrefixed(hash, []byte(c.otherOutputRelPath(piece.index)))
+		} else if piece.kind == outputPieceAssetIndex {
 			file := c.graph.Files[piece.index]
 			if len(file.InputFile.AdditionalFiles) != 1 {
 				panic("Internal error")
@@ -6615,6 +7813,10 @@ func (c *linkerContext) breakOutputIntoPieces(output []byte) intermediateOutput
 					kind = outputPieceAssetIndex
 				case 'C':
 					kind = outputPieceChunkIndex
+				case 'O':
+					kind = outputPieceOtherOutputIndex
+				case 'D':
+					kind = outputPieceDebugIDIndex
 				}
 				for j := 1; j < 9; j++ {
 					c := output[start+j]
@@ -6634,11 +7836,16 @@ func (c *linkerContext) breakOutputIntoPieces(output []byte) intermediateOutput
 				boundary = -1
 			}
 
-		case outputPieceChunkIndex:
+		case outputPieceChunkIndex, outputPieceDebugIDIndex:
 			if index >= uint32(len(c.chunks)) {
 				boundary = -1
 			}
 
+		case outputPieceOtherOutputIndex:
+			if index >= uint32(len(c.otherOutputAbsPaths)) {
+				boundary = -1
+			}
+
 		default:
 			boundary = -1
 		}
@@ -6662,6 +7869,118 @@ func (c *linkerContext) breakOutputIntoPieces(output []byte) intermediateOutput
 	return intermediateOutput{pieces: pieces}
 }
 
+// Plugins can modify the contents of each chunk here before the hash is
+// computed, so the hash and the source map take their changes into account.
+// The contents still contain the unique keys for the paths of other chunks
+// and assets at this point, and plugins are expected to preserve them.
+func (c *linkerContext) runOnRenderChunkPlugins(chunk *chunkInfo, j helpers.Joiner) (intermediateOutput, *sourcemap.SourceMap) {
+	hasPlugins := false
+	for _, plugin := range c.options.Plugins {
+		if len(plugin.OnRenderChunk) > 0 {
+			hasPlugins = true
+			break
+		}
+	}
+	if !hasPlugins {
+		return c.breakJoinerIntoPieces(j), nil
+	}
+
+	var entryPoint string
+	if chunk.isEntryPoint {
+		entryPoint = c.graph.Files[chunk.sourceIndex].InputFile.Source.PrettyPath
+	}
+	pathTemplate := path.Clean(config.TemplateToString(chunk.finalTemplate))
+	contents := string(j.Done())
+
+	// Source maps returned by plugins are chained together. The composed source
+	// map maps the final contents back to the contents generated by esbuild.
+	var composed *sourcemap.SourceMap
+	for _, plugin := range c.options.Plugins {
+		for _, onRenderChunk := range plugin.OnRenderChunk {
+			result := onRenderChunk.Callback(config.OnRenderChunkArgs{
+				PathTemplate: pathTemplate,
+				EntryPoint:   entryPoint,
+				Contents:     contents,
+			})
+			pluginName := result.PluginName
+			if pluginName == "" {
+				pluginName = plugin.Name
+			}
+			if bundler.LogPluginMessages(c.fs, c.log, pluginName, result.Msgs, result.ThrownError, nil, logger.Range{}) {
+				continue
+			}
+
+			// Leaving the contents alone passes them through unchanged
+			if result.Contents == nil || *result.Contents == contents {
+				continue
+			}
+			contents = *result.Contents
+
+			if result.SourceMap == nil {
+				if c.options.SourceMap != config.SourceMapNone {
+					c.log.AddID(logger.MsgID_SourceMap_MissingSourceMap, logger.Warning, nil, logger.Range{},
+						fmt.Sprintf("The plugin %q changed the contents of %q without returning a source map, so the source map for it may be incorrect",
+							pluginName, pathTemplate))
+				}
+				continue
+			}
+
+			log := logger.NewDeferLog(logger.DeferLogNoVerboseOrDebug, c.log.Overrides)
+			sourceMap := js_parser.ParseSourceMap(log, logger.Source{
+				KeyPath:    logger.Path{Text: pathTemplate},
+				PrettyPath: pathTemplate,
+				Contents:   *result.SourceMap,
+			})
+			for _, msg := range log.Done() {
+				msg.Notes = append(msg.Notes, logger.MsgData{
+					Text: fmt.Sprintf("This source map came from the plugin %q", pluginName)})
+				c.log.AddMsg(msg)
+			}
+			if sourceMap == nil {
+				continue
+			}
+			if composed == nil {
+				composed = sourceMap
+			} else {
+				composed = sourcemap.Compose(sourceMap, composed)
+			}
+		}
+	}
+
+	return c.breakOutputIntoPieces([]byte(contents)), composed
+}
+
+// Only the "mappings" and "names" fields change when the source map from the
+// plugins is applied, so the prefix with the sources is reused as-is.
+func (c *linkerContext) composeRenderChunkSourceMap(pieces sourcemap.SourceMapPieces, renderSourceMap *sourcemap.SourceMap) sourcemap.SourceMapPieces {
+	j := helpers.Joiner{}
+	j.AddBytes(pieces.Prefix)
+	j.AddBytes(pieces.Mappings)
+	j.AddBytes(pieces.Suffix)
+	log := logger.NewDeferLog(logger.DeferLogNoVerboseOrDebug, nil)
+	chunkSourceMap := js_parser.ParseSourceMap(log, logger.Source{Contents: string(j.Done())})
+	if chunkSourceMap == nil {
+		return pieces
+	}
+	composed := sourcemap.Compose(renderSourceMap, chunkSourceMap)
+
+	suffix := helpers.Joiner{}
+	suffix.AddString("\",\n  \"names\": [")
+	for i, name := range composed.Names {
+		if i != 0 {
+			suffix.AddString(", ")
+		}
+		suffix.AddBytes(helpers.QuoteForJSON(name, c.options.ASCIIOnly))
+	}
+	suffix.AddString("]\n}\n")
+
+	return sourcemap.SourceMapPieces{
+		Prefix:   pieces.Prefix,
+		Mappings: composed.EncodeMappings(),
+		Suffix:   suffix.Done(),
+	}
+}
+
 func (c *linkerContext) generateIsolatedHashInParallel(chunk *chunkInfo) {
 	// Compute the hash in parallel. This is a speedup when it turns out the hash
 	// isn't needed (well, as long as there are threads to spare).
@@ -6756,6 +8075,13 @@ func (c *linkerContext) generateIsolatedHash(chunk *chunkInfo, channel chan []by
 	hashWriteLengthPrefixed(hash, chunk.outputSourceMap.Prefix)
 	hashWriteLengthPrefixed(hash, chunk.outputSourceMap.Mappings)
 	hashWriteLengthPrefixed(hash, chunk.outputSourceMap.Suffix)
+	for _, section := range chunk.outputSourceMap.Sections {
+		hashWriteUint32(hash, uint32(section.Offset.Lines))
+		hashWriteUint32(hash, uint32(section.Offset.Columns))
+		hashWriteLengthPrefixed(hash, section.Prefix)
+		hashWriteLengthPrefixed(hash, section.Mappings)
+		hashWriteLengthPrefixed(hash, section.Suffix)
+	}
 
 	// Store the hash so far. All other chunks that import this chunk will mix
 	// this hash into their final hash to ensure that the import path changes
@@ -6846,83 +8172,63 @@ func (c *linkerContext) preventExportsFromBeingRenamed(sourceIndex uint32) {
 	}
 }
 
-type compileResultForSourceMap struct {
-	sourceMapChunk  sourcemap.Chunk
-	generatedOffset sourcemap.LineColumnOffset
-	sourceIndex     uint32
+type sourceMapItem struct {
+	path           logger.Path
+	prettyPath     string
+	quotedContents []byte
 }
 
-func (c *linkerContext) generateSourceMapForChunk(
-	results []compileResultForSourceMap,
-	chunkAbsDir string,
-	dataForSourceMaps []bundler.DataForSourceMap,
-	canHaveShifts bool,
-) (pieces sourcemap.SourceMapPieces) {
-	j := helpers.Joiner{}
-	j.AddString("{\n  \"version\": 3")
-
-	// Only write out the sources for a given source index once
-	sourceIndexToSourcesIndex := make(map[uint32]int)
+// A file has more than one source if it has a nested source map
+func (c *linkerContext) sourceMapItemsForFile(sourceIndex uint32, dataForSourceMaps []bundler.DataForSourceMap) []sourceMapItem {
+	file := &c.graph.Files[sourceIndex]
 
-	// Generate the "sources" and "sourcesContent" arrays
-	type item struct {
-		path           logger.Path
-		prettyPath     string
-		quotedContents []byte
-	}
-	items := make([]item, 0, len(results))
-	nextSourcesIndex := 0
-	for _, result := range results {
-		if _, ok := sourceIndexToSourcesIndex[result.sourceIndex]; ok {
-			continue
-		}
-		sourceIndexToSourcesIndex[result.sourceIndex] = nextSourcesIndex
-		file := &c.graph.Files[result.sourceIndex]
-
-		// Simple case: no nested source map
-		if file.InputFile.InputSourceMap == nil {
-			var quotedContents []byte
-			if !c.options.ExcludeSourcesContent {
-				quotedContents = dataForSourceMaps[result.sourceIndex].QuotedContents[0]
-			}
-			items = append(items, item{
-				path:           file.InputFile.Source.KeyPath,
-				prettyPath:     file.InputFile.Source.PrettyPath,
-				quotedContents: quotedContents,
-			})
-			nextSourcesIndex++
-			continue
+	// Simple case: no nested source map
+	if file.InputFile.InputSourceMap == nil {
+		var quotedContents []byte
+		if !c.options.ExcludeSourcesContent {
+			quotedContents = dataForSourceMaps[sourceIndex].QuotedContents[0]
 		}
+		return []sourceMapItem{{
+			path:           file.InputFile.Source.KeyPath,
+			prettyPath:     file.InputFile.Source.PrettyPath,
+			quotedContents: quotedContents,
+		}}
+	}
 
-		// Complex case: nested source map
-		sm := file.InputFile.InputSourceMap
-		for i, source := range sm.Sources {
-			path := logger.Path{
-				Namespace: file.InputFile.Source.KeyPath.Namespace,
-				Text:      source,
-			}
+	// Complex case: nested source map
+	sm := file.InputFile.InputSourceMap
+	items := make([]sourceMapItem, 0, len(sm.Sources))
+	for i, source := range sm.Sources {
+		path := logger.Path{
+			Namespace: file.InputFile.Source.KeyPath.Namespace,
+			Text:      source,
+		}
 
-			// If this file is in the "file" namespace, change the relative path in
-			// the source map into an absolute path using the directory of this file
-			if path.Namespace == "file" {
-				path.Text = c.fs.Join(c.fs.Dir(file.InputFile.Source.KeyPath.Text), source)
-			}
+		// If this file is in the "file" namespace, change the relative path in
+		// the source map into an absolute path using the directory of this file
+		if path.Namespace == "file" {
+			path.Text = c.fs.Join(c.fs.Dir(file.InputFile.Source.KeyPath.Text), source)
+		}
 
-			var quotedContents []byte
-			if !c.options.ExcludeSourcesContent {
-				quotedContents = dataForSourceMaps[result.sourceIndex].QuotedContents[i]
-			}
-			items = append(items, item{
-				path:           path,
-				prettyPath:     source,
-				quotedContents: quotedContents,
-			})
+		var quotedContents []byte
+		if !c.options.ExcludeSourcesContent {
+			quotedContents = dataForSourceMaps[sourceIndex].QuotedContents[i]
 		}
-		nextSourcesIndex += len(sm.Sources)
+		items = append(items, sourceMapItem{
+			path:           path,
+			prettyPath:     source,
+			quotedContents: quotedContents,
+		})
 	}
+	return items
+}
 
+// This writes the fields of a source map that come before "mappings". Each
+// field is preceded by the separator.
+func (c *linkerContext) appendSourceMapSources(j *helpers.Joiner, items []sourceMapItem, chunkAbsDir string, separator string) {
 	// Write the sources
-	j.AddString(",\n  \"sources\": [")
+	j.AddString(separator)
+	j.AddString("\"sources\": [")
 	for i, item := range items {
 		if i != 0 {
 			j.AddString(", ")
@@ -6942,13 +8248,34 @@ func (c *linkerContext) generateSourceMapForChunk(
 	j.AddString("]")
 
 	if c.options.SourceRoot != "" {
-		j.AddString(",\n  \"sourceRoot\": ")
+		j.AddString(separator)
+		j.AddString("\"sourceRoot\": ")
 		j.AddBytes(helpers.QuoteForJSON(c.options.SourceRoot, c.options.ASCIIOnly))
 	}
 
+	// Write the ignore list. This is written under both the standard name and
+	// the name that older versions of Chrome use.
+	if len(c.options.SourcemapIgnoreList) > 0 {
+		var ignoreList []string
+		for i, item := range items {
+			path := strings.ReplaceAll(item.path.Text, "\\", "/")
+			for _, pattern := range c.options.SourcemapIgnoreList {
+				if pattern.MatchString(path) {
+					ignoreList = append(ignoreList, strconv.Itoa(i))
+					break
+				}
+			}
+		}
+		if len(ignoreList) > 0 {
+			text := strings.Join(ignoreList, ", ")
+			j.AddString(fmt.Sprintf("%s\"ignoreList\": [%s]%s\"x_google_ignoreList\": [%s]", separator, text, separator, text))
+		}
+	}
+
 	// Write the sourcesContent
 	if !c.options.ExcludeSourcesContent {
-		j.AddString(",\n  \"sourcesContent\": [")
+		j.AddString(separator)
+		j.AddString("\"sourcesContent\": [")
 		for i, item := range items {
 			if i != 0 {
 				j.AddString(", ")
@@ -6957,6 +8284,39 @@ func (c *linkerContext) generateSourceMapForChunk(
 		}
 		j.AddString("]")
 	}
+}
+
+type compileResultForSourceMap struct {
+	sourceMapChunk  sourcemap.Chunk
+	generatedOffset sourcemap.LineColumnOffset
+	sourceIndex     uint32
+}
+
+func (c *linkerContext) generateSourceMapForChunk(
+	results []compileResultForSourceMap,
+	chunkAbsDir string,
+	dataForSourceMaps []bundler.DataForSourceMap,
+	canHaveShifts bool,
+) (pieces sourcemap.SourceMapPieces) {
+	j := helpers.Joiner{}
+	j.AddString("{\n  \"version\": 3")
+
+	// Only write out the sources for a given source index once
+	sourceIndexToSourcesIndex := make(map[uint32]int)
+
+	// Generate the "sources" and "sourcesContent" arrays
+	items := make([]sourceMapItem, 0, len(results))
+	nextSourcesIndex := 0
+	for _, result := range results {
+		if _, ok := sourceIndexToSourcesIndex[result.sourceIndex]; ok {
+			continue
+		}
+		sourceIndexToSourcesIndex[result.sourceIndex] = nextSourcesIndex
+		fileItems := c.sourceMapItemsForFile(result.sourceIndex, dataForSourceMaps)
+		items = append(items, fileItems...)
+		nextSourcesIndex += len(fileItems)
+	}
+	c.appendSourceMapSources(&j, items, chunkAbsDir, ",\n  ")
 
 	j.AddString(",\n  \"mappings\": \"")
 
@@ -7059,6 +8419,109 @@ func (c *linkerContext) generateSourceMapForChunk(
 	return
 }
 
+// This generates an index source map with one section per file instead of
+// joining the mappings for all files together. Each file's source map chunk
+// was generated as if it started at the beginning of the output, so it can be
+// used as the mappings for its section without being re-encoded. The only
+// thing that needs to be computed is where each section starts.
+func (c *linkerContext) generateSectionedSourceMapForChunk(
+	results []compileResultForSourceMap,
+	chunkAbsDir string,
+	dataForSourceMaps []bundler.DataForSourceMap,
+) (pieces sourcemap.SourceMapPieces) {
+	pieces.Prefix = []byte("{\n  \"version\": 3,\n  \"sections\": [")
+	pieces.Suffix = []byte("\n  ]\n}\n")
+	pieces.Sections = make([]sourcemap.SourceMapSection, 0, len(results))
+
+	// A file may be split into more than one section if its parts aren't all
+	// next to each other, in which case the sections share the same prefix
+	prefixes := make(map[uint32][]byte)
+
+	var offset sourcemap.LineColumnOffset
+	for _, result := range results {
+		chunk := result.sourceMapChunk
+
+		// This should have already been checked earlier
+		if chunk.ShouldIgnore {
+			panic("Internal error")
+		}
+
+		// The generated offset is relative to the end of the previous file
+		offset.Add(result.generatedOffset)
+
+		prefix, ok := prefixes[result.sourceIndex]
+		if !ok {
+			j := helpers.Joiner{}
+			j.AddString("{\"version\": 3")
+			c.appendSourceMapSources(&j, c.sourceMapItemsForFile(result.sourceIndex, dataForSourceMaps), chunkAbsDir, ", ")
+			j.AddString(", \"mappings\": \"")
+			prefix = j.Done()
+			prefixes[result.sourceIndex] = prefix
+		}
+
+		suffix := helpers.Joiner{}
+		suffix.AddString("\", \"names\": [")
+		for i, quotedName := range chunk.QuotedNames {
+			if i != 0 {
+				suffix.AddString(", ")
+			}
+			suffix.AddBytes(quotedName)
+		}
+		suffix.AddString("]}")
+
+		pieces.Sections = append(pieces.Sections, sourcemap.SourceMapSection{
+			Offset:   offset,
+			Prefix:   prefix,
+			Mappings: chunk.Buffer.Data,
+			Suffix:   suffix.Done(),
+		})
+
+		// Advance to the end of this file
+		offset.Add(sourcemap.LineColumnOffset{Lines: chunk.EndState.GeneratedLine, Columns: chunk.FinalGeneratedColumn})
+	}
+	return
+}
+
+// This formats the hash as a version 4 UUID, which is what the debug ID
+// proposal for source maps requires. The first 16 bytes of the hash are used.
+func debugIDForHash(hashBytes []byte) string {
+	var uuid [16]byte
+	copy(uuid[:], hashBytes)
+	uuid[6] = (uuid[6] & 0x0F) | 0x40
+	uuid[8] = (uuid[8] & 0x3F) | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", uuid[0:4], uuid[4:6], uuid[6:8], uuid[8:10], uuid[10:16])
+}
+
+// This snippet stores the debug ID in a global map keyed by the stack trace of
+// an error created in this file. Error reporting tools read this map to find
+// the debug ID for the file that a given stack frame came from. It must never
+// throw, and it must work in all environments and for all language targets.
+func debugIDRegistrationJS(debugID string) string {
+	return "!function(){try{var e=\"undefined\"!=typeof globalThis?globalThis:\"undefined\"!=typeof window?window:" +
+		"\"undefined\"!=typeof global?global:\"undefined\"!=typeof self?self:{},n=new e.Error().stack;" +
+		"n&&(e._debugIds=e._debugIds||{},e._debugIds[n]=\"" + debugID + "\")}catch(e){}}();\n"
+}
+
+// The debug ID can only be added to the source map once the final hash is
+// known, which is after the source map has been generated. So it's spliced
+// in right before the closing brace of the top-level object.
+func appendDebugIDToSourceMap(sourceMap []byte, debugID string) []byte {
+	end := bytes.LastIndexByte(sourceMap, '}')
+	if end == -1 {
+		return sourceMap
+	}
+	for end > 0 && sourceMap[end-1] == '\n' {
+		end--
+	}
+	result := make([]byte, 0, len(sourceMap)+64)
+	result = append(result, sourceMap[:end]...)
+	result = append(result, ",\n  \"debugId\": \""...)
+	result = append(result, debugID...)
+	result = append(result, '"')
+	result = append(result, sourceMap[end:]...)
+	return result
+}
+
 // Recover from a panic by logging it as an internal error instead of crashing
 func (c *linkerContext) recoverInternalError(waitGroup *sync.WaitGroup, sourceIndex uint32) {
 	if r := recover(); r != nil {
@@ -11,6 +11,7 @@ package logger
 // default.
 
 import (
+	"encoding/binary"
 	"fmt"
 	"os"
 	"runtime"
@@ -248,9 +249,71 @@ type Path struct {
 	// the output. This is supported by other bundlers, so we also support this.
 	IgnoredSuffix string
 
+	// Import attributes (e.g. "with { type: 'json' }") are part of the identity
+	// of a module, so the same path imported with different attributes results
+	// in two different modules. This lets plugins load them differently.
+	ImportAttributes ImportAttributes
+
 	Flags PathFlags
 }
 
+// This is stored in a packed form instead of as a "map[string]string" so that
+// "Path" can still be compared using "==" and used as a map key
+type ImportAttributes struct {
+	packedData string
+}
+
+type ImportAttribute struct {
+	Key   string
+	Value string
+}
+
+// The keys are sorted so that the order of the attributes doesn't matter
+func EncodeImportAttributes(value map[string]string) ImportAttributes {
+	if len(value) == 0 {
+		return ImportAttributes{}
+	}
+	keys := make([]string, 0, len(value))
+	for k := range value {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	var sb strings.Builder
+	var length [4]byte
+	for _, k := range keys {
+		v := value[k]
+		binary.LittleEndian.PutUint32(length[:], uint32(len(k)))
+		sb.Write(length[:])
+		sb.WriteString(k)
+		binary.LittleEndian.PutUint32(length[:], uint32(len(v)))
+		sb.Write(length[:])
+		sb.WriteString(v)
+	}
+	return ImportAttributes{packedData: sb.String()}
+}
+
+func (attrs ImportAttributes) DecodeIntoArray() (result []ImportAttribute) {
+	data := attrs.packedData
+	for len(data) > 0 {
+		kn := binary.LittleEndian.Uint32([]byte(data[:4]))
+		k := data[4 : 4+kn]
+		data = data[4+kn:]
+		vn := binary.LittleEndian.Uint32([]byte(data[:4]))
+		v := data[4 : 4+vn]
+		data = data[4+vn:]
+		result = append(result, ImportAttribute{Key: k, Value: v})
+	}
+	return
+}
+
+func (attrs ImportAttributes) DecodeIntoMap() map[string]string {
+	result := make(map[string]string)
+	for _, attr := range attrs.DecodeIntoArray() {
+		result[attr.Key] = attr.Value
+	}
+	return result
+}
+
 type PathFlags uint8
 
 const (
@@ -266,7 +329,8 @@ func (a Path) ComesBeforeInSortedOrder(b Path) bool {
 	return a.Namespace > b.Namespace ||
 		(a.Namespace == b.Namespace && (a.Text < b.Text ||
 			(a.Text == b.Text && (a.Flags < b.Flags ||
-				(a.Flags == b.Flags && a.IgnoredSuffix < b.IgnoredSuffix)))))
+				(a.Flags == b.Flags && (a.IgnoredSuffix < b.IgnoredSuffix ||
+					(a.IgnoredSuffix == b.IgnoredSuffix && a.ImportAttributes.packedData < b.ImportAttributes.packedData)))))))
 }
 
 var noColorResult bool
@@ -185,6 +185,111 @@ func Source(unsupportedJSFeatures compat.JSFeature) logger.Source {
 		}
 		export var __commonJSMin = (cb, mod) => () => (mod || cb((mod = {exports: {}}).exports, mod), mod.exports)
 
+		// Hot module replacement. Every module is wrapped in a CommonJS closure and
+		// registered in a page-wide registry that outlives any single copy of the
+		// bundle. Loading a rebuilt copy of the bundle replaces the closures in the
+		// registry without running them. The changed modules and their importers
+		// up to the nearest module that accepts its own updates are then re-run.
+		var __hmrRegistry = () => globalThis.__esbuild_hmr__ || __hmrConnect(globalThis.__esbuild_hmr__ = {
+			modules: {},
+			stack: [],
+
+			// Classic scripts set "document.currentScript" while they run and module
+			// scripts don't. Rebuilt bundles must be loaded the same way.
+			isModule: typeof document === 'object' && !document.currentScript,
+		})
+		var __hmrRecord = (hmr, id) => hmr.modules[id] || (hmr.modules[id] = { importers: {}, dispose: [], data: {} })
+
+		// The development server describes where it's mounted in the HTML pages it
+		// serves. Paths in updates don't include the prefix it's mounted under.
+		var __hmrConnect = hmr => {
+			var server = globalThis.__esbuild_hmr_server__ || {}
+			hmr.prefix = server.prefix || ''
+			if (typeof EventSource === 'function')
+				new EventSource(server.eventStream || '/esbuild').addEventListener('hmr', e => __hmrUpdate(hmr, JSON.parse(e.data)))
+			return hmr
+		}
+		var __hmrUpdate = (hmr, update) => {
+			var pathname = url => new URL(url, location.href).pathname
+			var served = url => pathname(hmr.prefix + url)
+			var links = document.querySelectorAll('link[rel=stylesheet]'), scripts = document.scripts, loaded = {}, modules = [], i
+			for (i = 0; i < links.length; i++)
+				if (update.css.map(served).indexOf(pathname(links[i].href)) >= 0)
+					links[i].href = pathname(links[i].href) + '?hmr=' + Date.now()
+
+			// Only reload the bundles that were loaded into this page
+			for (i = 0; i < scripts.length; i++)
+				if (scripts[i].src) loaded[pathname(scripts[i].src)] = 1
+			update = update.updates.filter(item => loaded[served(item.url)])
+			for (i = 0; i < update.length; i++)
+				if (update[i].reload) return location.reload()
+				else modules = modules.concat(update[i].modules)
+			if (update.length)
+				Promise.all(update.map(item => new Promise((resolve, reject) => {
+					var script = document.createElement('script')
+					if (hmr.isModule) script.type = 'module'
+					script.src = served(item.url) + '?hmr=' + Date.now()
+					script.onload = resolve
+					script.onerror = reject
+					document.head.appendChild(script)
+				}))).then(() => __hmrApply(hmr, modules) || location.reload(), () => location.reload())
+		}
+		var __hmrApply = (hmr, ids) => {
+			var stale = {}, accepted = [], queue = ids.slice(), id, record, importers, i
+			while (queue.length) {
+				if (stale[id = queue.pop()] || !(record = hmr.modules[id]) || !record.mod) continue
+				if (record.declined) return false
+				stale[id] = 1
+				if (record.accept) accepted.push([record, record.accept])
+				else if ((importers = __getOwnPropNames(record.importers)).length) queue = queue.concat(importers)
+				else return false
+			}
+			for (id in stale) {
+				record = hmr.modules[id]
+				record.data = {}
+				for (i = 0; i < record.dispose.length; i++) record.dispose[i](record.data)
+				record.mod = record.hot = record.accept = 0
+				record.dispose = []
+			}
+			for (i = 0; i < accepted.length; i++)
+				accepted[i][1](accepted[i][0].require())
+			return true
+		}
+		export var __hmrModule = (cb, isESM) => {
+			var hmr = __hmrRegistry(), id = __getOwnPropNames(cb)[0], record = __hmrRecord(hmr, id)
+			record.fn = cb[id]
+			return record.require = function __require() {
+				var mod = record.mod, parent = hmr.stack[hmr.stack.length - 1]
+				if (parent !== void 0) record.importers[parent] = 1
+				if (!mod) {
+					mod = record.mod = { exports: {} }
+					if (isESM) __defProp(mod.exports, '__esModule', { value: true })
+					hmr.stack.push(id)
+					try {
+						(0, record.fn)(mod.exports, mod)
+					} catch (e) {
+						record.mod = 0
+						throw e
+					} finally {
+						hmr.stack.pop()
+					}
+				}
+				return mod.exports
+			}
+		}
+
+		// This implements "import.meta.hot" when hot module replacement is enabled
+		export var __hmrHot = id => {
+			var record = __hmrRecord(__hmrRegistry(), id)
+			return record.hot || (record.hot = {
+				data: record.data,
+				accept: callback => { record.accept = callback || (() => {}) },
+				dispose: callback => { record.dispose.push(callback) },
+				decline: () => { record.declined = 1 },
+				invalidate: () => location.reload(),
+			})
+		}
+
 		// Used to implement ESM exports both for "require()" and "import * as"
 		export var __export = (target, all) => {
 			for (var name in all)
@@ -556,3 +661,55 @@ func Source(unsupportedJSFeatures compat.JSFeature) logger.Source {
 //                                      __decorateClass([
 //                                        dec
 //                                      ], C.prototype, 'foo', 2);
+
+// Code splitting with the "iife" format wraps each chunk in a call to this
+// function instead of in an IIFE. It's passed the relative paths of the chunks
+// that this chunk imports and a callback that runs the code in this chunk. That
+// callback is passed a function that implements "import()" of another chunk
+// followed by the exports of each imported chunk, and returns the exports of
+// this chunk. Chunks share a registry on the global object keyed by URL so that
+// each chunk is only evaluated once.
+//
+// Running a chunk only registers it. The chunks it imports start loading right
+// away and in parallel, but each chunk is only evaluated after the chunks it
+// imports have been evaluated one after another in import order, which matches
+// how ES modules are evaluated. Only a chunk that wasn't loaded by another chunk
+// (i.e. an entry point) starts evaluating itself. Chunks are loaded using
+// "<script>" tags when there is a "document" and using "importScripts()"
+// otherwise, which makes this work in classic Web Workers too. The URL of the
+// chunk being loaded by "importScripts()" is passed along in the registry
+// because workers don't have "document.currentScript". Chunk paths are resolved
+// against the URL of the chunk that imports them with string operations instead
+// of "URL", which older browsers don't have.
+//
+// This is deliberately written in ES5 and pre-minified because it's pasted
+// into every chunk and isn't run through the parser or the lowering passes. The
+// only thing it needs that ES5 doesn't have is "Promise", and only when a chunk
+// calls "import()".
+const ChunkLoaderIIFE = `(function(d,f){` +
+	`var g=typeof globalThis!="undefined"?globalThis:self,` +
+	`r=g.__esbuildChunks||(g.__esbuildChunks={}),` +
+	`o=g.document,s=o&&o.currentScript,u=s?s.src:r.$||location.href,m=e(u),i;` +
+	`function e(k){return r[k]||(r[k]={c:[]})}` +
+	`function b(p,k){var s,t=[],i,x;` +
+	`if(/^[a-z][\w+.-]*:/i.test(p))return p;k=k.replace(/[?#].*/,"");` +
+	`if(p.slice(0,2)=="//")return k.slice(0,k.indexOf(":")+1)+p;` +
+	`x=/^[a-z][\w+.-]*:(\/\/[^\/]*)?/i.exec(k);x=x?x[0]:"";` +
+	`s=(p.charAt(0)=="/"?p:(k.slice(x.length)||"/").replace(/[^\/]*$/,"")+p).split("/");` +
+	`for(i=0;i<s.length;i++)if(s[i]==".."){if(t.length>1)t.pop()}else if(s[i]!=".")t.push(s[i]);` +
+	`return x+t.join("/")}` +
+	`function w(c,h,x){var q=c.c;c.c=[];for(var i=0;i<q.length;i++)q[i][h](x)}` +
+	`function j(c,k,x){delete r[k];w(c,1,x)}` +
+	`function l(k){var c=e(k),p,t;` +
+	`if(!c.l&&!c.f){c.l=1;` +
+	`if(o){t=o.createElement("script");t.src=k;t.onerror=function(){j(c,k,Error("Failed to load chunk "+k))};o.head.appendChild(t)}` +
+	`else{p=r.$;r.$=k;try{importScripts(k)}catch(x){if(c.f)throw x;j(c,k,x)}finally{r.$=p}}}` +
+	`return c}` +
+	`function v(k,y,n){var c=l(k);c.v?y(c.e):(c.c.push([y,n]),c.f&&!c.x&&z(c))}` +
+	`function z(c){var a=[function(p){return new Promise(function(y,n){v(b(p,c.u),y,n)})}],i=0;c.x=1;` +
+	`(function t(){i<c.d.length?v(c.d[i],function(x){a[++i]=x;t()},function(x){c.x=0;w(c,1,x)}):` +
+	`(c.e=c.f.apply(void 0,a),c.v=1,w(c,0,c.e))})()}` +
+	`if(!m.f){m.u=u;m.f=f;m.d=[];` +
+	`for(i=0;i<d.length;i++)l(m.d[i]=b(d[i],u));` +
+	`m.l||m.c.push([function(){},function(x){setTimeout(function(){throw x})}]);` +
+	`m.c.length&&z(m)}})`
@@ -2,6 +2,7 @@ package sourcemap
 
 import (
 	"bytes"
+	"fmt"
 	"unicode/utf8"
 
 	"github.com/evanw/esbuild/internal/ast"
@@ -68,6 +69,79 @@ func (sm *SourceMap) Find(line int32, column int32) *Mapping {
 	return nil
 }
 
+// This returns a source map from the generated code of "outer" to the original
+// code of "inner", where the original code of "outer" is the generated code of
+// "inner". Mappings in "outer" that "inner" doesn't cover are dropped.
+func Compose(outer *SourceMap, inner *SourceMap) *SourceMap {
+	result := &SourceMap{
+		Sources:        inner.Sources,
+		SourcesContent: inner.SourcesContent,
+		Names:          append([]string{}, inner.Names...),
+		Mappings:       make([]Mapping, 0, len(outer.Mappings)),
+	}
+
+	for _, mapping := range outer.Mappings {
+		found := inner.Find(mapping.OriginalLine, mapping.OriginalColumn)
+		if found == nil {
+			continue
+		}
+
+		// Prefer the name from the original code if there is one
+		name := found.OriginalName
+		if !name.IsValid() && mapping.OriginalName.IsValid() {
+			name = ast.MakeIndex32(uint32(len(result.Names)))
+			result.Names = append(result.Names, outer.Names[mapping.OriginalName.GetIndex()])
+		}
+
+		result.Mappings = append(result.Mappings, Mapping{
+			GeneratedLine:   mapping.GeneratedLine,
+			GeneratedColumn: mapping.GeneratedColumn,
+			SourceIndex:     found.SourceIndex,
+			OriginalLine:    found.OriginalLine,
+			OriginalColumn:  found.OriginalColumn,
+			OriginalName:    name,
+		})
+	}
+
+	return result
+}
+
+// This is the inverse of parsing the "mappings" field. The mappings must be
+// sorted by generated position.
+func (sm *SourceMap) EncodeMappings() []byte {
+	var buffer []byte
+	var lastByte byte
+	prevState := SourceMapState{}
+	generatedLine := int32(0)
+
+	for _, mapping := range sm.Mappings {
+		for generatedLine < mapping.GeneratedLine {
+			buffer = append(buffer, ';')
+			lastByte = ';'
+			prevState.GeneratedColumn = 0
+			generatedLine++
+		}
+
+		currentState := SourceMapState{
+			GeneratedColumn: int(mapping.GeneratedColumn),
+			SourceIndex:     int(mapping.SourceIndex),
+			OriginalLine:    int(mapping.OriginalLine),
+			OriginalColumn:  int(mapping.OriginalColumn),
+			OriginalName:    prevState.OriginalName,
+		}
+		if mapping.OriginalName.IsValid() {
+			currentState.OriginalName = int(mapping.OriginalName.GetIndex())
+			currentState.HasOriginalName = true
+		}
+
+		buffer, _ = appendMappingToBuffer(buffer, lastByte, prevState, currentState)
+		lastByte = buffer[len(buffer)-1]
+		prevState = currentState
+	}
+
+	return buffer
+}
+
 var base64 = []byte("ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz0123456789+/")
 
 // A single base 64 digit can contain 6 bits of data. For the base 64 variable
@@ -260,10 +334,29 @@ type SourceMapPieces struct {
 	Prefix   []byte
 	Mappings []byte
 	Suffix   []byte
+
+	// If this is present, this is an index source map and "Mappings" is empty.
+	// The sections are written in order in between the prefix and the suffix.
+	Sections []SourceMapSection
+}
+
+// Each section of an index source map contains the mappings for one file. The
+// mappings are relative to the start of the section, so they can be copied
+// from each file's source map chunk as-is instead of being re-encoded.
+type SourceMapSection struct {
+	// This is the generated position of the start of the section before any
+	// shifts are applied
+	Offset LineColumnOffset
+
+	// These are the same as the fields in "SourceMapPieces" but for the map of
+	// this section, which is a complete source map on its own
+	Prefix   []byte
+	Mappings []byte
+	Suffix   []byte
 }
 
 func (pieces SourceMapPieces) HasContent() bool {
-	return len(pieces.Prefix)+len(pieces.Mappings)+len(pieces.Suffix) > 0
+	return len(pieces.Prefix)+len(pieces.Mappings)+len(pieces.Suffix)+len(pieces.Sections) > 0
 }
 
 type SourceMapShift struct {
@@ -272,6 +365,10 @@ type SourceMapShift struct {
 }
 
 func (pieces SourceMapPieces) Finalize(shifts []SourceMapShift) []byte {
+	if pieces.Sections != nil {
+		return pieces.finalizeSections(shifts)
+	}
+
 	// An optimized path for when there are no shifts
 	if len(shifts) == 1 {
 		bytes := pieces.Prefix
@@ -284,21 +381,70 @@ func (pieces SourceMapPieces) Finalize(shifts []SourceMapShift) []byte {
 		return bytes
 	}
 
-	startOfRun := 0
-	current := 0
-	generated := LineColumnOffset{}
-	prevShiftColumnDelta := 0
 	j := helpers.Joiner{}
+	j.AddBytes(pieces.Prefix)
+	appendShiftedMappings(&j, pieces.Mappings, LineColumnOffset{}, 0, shifts)
+	j.AddBytes(pieces.Suffix)
+	return j.Done()
+}
 
-	// Start the source map
+func (pieces SourceMapPieces) finalizeSections(shifts []SourceMapShift) []byte {
+	j := helpers.Joiner{}
 	j.AddBytes(pieces.Prefix)
 
+	for i, section := range pieces.Sections {
+		// The start of the section moves over if any paths that come before it on
+		// the same line are different lengths after being substituted
+		for len(shifts) > 1 && !section.Offset.ComesBefore(shifts[1].Before) {
+			shifts = shifts[1:]
+		}
+		shiftColumnDelta := 0
+		if shift := shifts[0]; shift.Before.Lines == section.Offset.Lines {
+			shiftColumnDelta = shift.After.Columns - shift.Before.Columns
+		}
+
+		if i != 0 {
+			j.AddString(",")
+		}
+		j.AddString(fmt.Sprintf("\n    {\"offset\": {\"line\": %d, \"column\": %d}, \"map\": ",
+			section.Offset.Lines, section.Offset.Columns+shiftColumnDelta))
+		j.AddBytes(section.Prefix)
+
+		// Mappings only need to be decoded if there are shifts left to apply
+		if len(shifts) > 1 {
+			shifts = appendShiftedMappings(&j, section.Mappings, section.Offset, shiftColumnDelta, shifts)
+		} else {
+			j.AddBytes(section.Mappings)
+		}
+
+		j.AddBytes(section.Suffix)
+		j.AddString("}")
+	}
+
+	j.AddBytes(pieces.Suffix)
+	return j.Done()
+}
+
+// This copies the mappings over while adjusting the generated columns of any
+// mappings that come after a shift on the same line. The generated position
+// and column delta are those of the start of the mappings. The shifts that
+// haven't been reached yet are returned so they can be used for what follows.
+func appendShiftedMappings(
+	j *helpers.Joiner,
+	mappings []byte,
+	generated LineColumnOffset,
+	prevShiftColumnDelta int,
+	shifts []SourceMapShift,
+) []SourceMapShift {
+	startOfRun := 0
+	current := 0
+
 	// This assumes that a) all mappings are valid and b) all mappings are ordered
 	// by increasing generated position. This should be the case for all mappings
 	// generated by esbuild, which should be the only mappings we process here.
-	for current < len(pieces.Mappings) {
+	for current < len(mappings) {
 		// Handle a line break
-		if pieces.Mappings[current] == ';' {
+		if mappings[current] == ';' {
 			generated.Lines++
 			generated.Columns = 0
 			prevShiftColumnDelta = 0
@@ -309,26 +455,26 @@ func (pieces SourceMapPieces) Finalize(shifts []SourceMapShift) []byte {
 		potentialEndOfRun := current
 
 		// Read the generated column
-		generatedColumnDelta, next := DecodeVLQ(pieces.Mappings, current)
+		generatedColumnDelta, next := DecodeVLQ(mappings, current)
 		generated.Columns += generatedColumnDelta
 		current = next
 
 		potentialStartOfRun := current
 
 		// Skip over the original position information
-		_, current = DecodeVLQ(pieces.Mappings, current) // The original source
-		_, current = DecodeVLQ(pieces.Mappings, current) // The original line
-		_, current = DecodeVLQ(pieces.Mappings, current) // The original column
+		_, current = DecodeVLQ(mappings, current) // The original source
+		_, current = DecodeVLQ(mappings, current) // The original line
+		_, current = DecodeVLQ(mappings, current) // The original column
 
 		// Skip over the original name
-		if current < len(pieces.Mappings) {
-			if c := pieces.Mappings[current]; c != ',' && c != ';' {
-				_, current = DecodeVLQ(pieces.Mappings, current)
+		if current < len(mappings) {
+			if c := mappings[current]; c != ',' && c != ';' {
+				_, current = DecodeVLQ(mappings, current)
 			}
 		}
 
 		// Skip a trailing comma
-		if current < len(pieces.Mappings) && pieces.Mappings[current] == ',' {
+		if current < len(mappings) && mappings[current] == ',' {
 			current++
 		}
 
@@ -351,7 +497,7 @@ func (pieces SourceMapPieces) Finalize(shifts []SourceMapShift) []byte {
 
 		// Add all previous mappings in a single run for efficiency. Since source
 		// mappings are relative, no data needs to be modified inside this run.
-		j.AddBytes(pieces.Mappings[startOfRun:potentialEndOfRun])
+		j.AddBytes(mappings[startOfRun:potentialEndOfRun])
 
 		// Then modify the first mapping across the shift boundary with the updated
 		// generated column value. It's simplest to only support column shifts. This
@@ -367,10 +513,8 @@ func (pieces SourceMapPieces) Finalize(shifts []SourceMapShift) []byte {
 		startOfRun = potentialStartOfRun
 	}
 
-	// Finish the source map
-	j.AddBytes(pieces.Mappings[startOfRun:])
-	j.AddBytes(pieces.Suffix)
-	return j.Done()
+	j.AddBytes(mappings[startOfRun:])
+	return shifts
 }
 
 // Coordinates in source maps are stored using relative offsets for size
@@ -648,8 +792,10 @@ func MakeChunkBuilder(inputSourceMap *SourceMap, lineOffsetTables []LineOffsetTa
 }
 
 func (b *ChunkBuilder) AddSourceMapping(originalLoc logger.Loc, originalName string, output []byte) {
-	// Avoid generating duplicate mappings
-	if originalLoc == b.prevOriginalLoc && (b.prevGeneratedLen == len(output) || b.prevOriginalName == originalName) {
+	// Avoid generating duplicate mappings. A mapping with a name is never a
+	// duplicate if it starts somewhere else, since the name is only correct for
+	// the identifier that it starts (e.g. "b" in "{a:b}" from "{ shorthand }").
+	if originalLoc == b.prevOriginalLoc && (b.prevGeneratedLen == len(output) || (originalName == "" && b.prevOriginalName == "")) {
 		return
 	}
 
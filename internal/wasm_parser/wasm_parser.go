@@ -0,0 +1,267 @@
+package wasm_parser
+
+// This is not a full WebAssembly parser. It only reads the parts of the binary
+// format that matter for linking a module into a JavaScript bundle, which are
+// the names in the import and export sections. Everything else (code, data,
+// types, etc.) is skipped over without being validated.
+
+import (
+	"errors"
+	"fmt"
+	"unicode/utf8"
+)
+
+type Module struct {
+	// These are in the order that they appear in the binary
+	Imports []Import
+	Exports []Export
+}
+
+type ExternalKind uint8
+
+const (
+	ExternalFunction ExternalKind = iota
+	ExternalTable
+	ExternalMemory
+	ExternalGlobal
+	ExternalTag
+)
+
+type Import struct {
+	Module string
+	Name   string
+	Kind   ExternalKind
+}
+
+type Export struct {
+	Name string
+	Kind ExternalKind
+}
+
+const (
+	sectionImport = 2
+	sectionExport = 7
+)
+
+type reader struct {
+	contents string
+	offset   int
+}
+
+var errUnexpectedEnd = errors.New("Unexpected end of file")
+
+func Parse(contents string) (Module, error) {
+	var module Module
+	r := reader{contents: contents}
+
+	if len(contents) < 8 || contents[:4] != "\x00asm" {
+		return Module{}, errors.New("Missing the \"\\0asm\" header")
+	}
+	if version := contents[4:8]; version != "\x01\x00\x00\x00" {
+		return Module{}, fmt.Errorf("Unsupported version %d", uint32(version[0])|uint32(version[1])<<8|uint32(version[2])<<16|uint32(version[3])<<24)
+	}
+	r.offset = 8
+
+	for r.offset < len(contents) {
+		id, err := r.byte()
+		if err != nil {
+			return Module{}, err
+		}
+		size, err := r.u32()
+		if err != nil {
+			return Module{}, err
+		}
+		if uint64(size) > uint64(len(contents)-r.offset) {
+			return Module{}, errUnexpectedEnd
+		}
+		section := reader{contents: contents[:r.offset+int(size)], offset: r.offset}
+		r.offset += int(size)
+
+		switch id {
+		case sectionImport:
+			if module.Imports, err = section.imports(); err != nil {
+				return Module{}, err
+			}
+
+		case sectionExport:
+			if module.Exports, err = section.exports(); err != nil {
+				return Module{}, err
+			}
+
+		default:
+			continue
+		}
+
+		if section.offset != len(section.contents) {
+			return Module{}, fmt.Errorf("Unexpected data at the end of section %d", id)
+		}
+	}
+
+	return module, nil
+}
+
+func (r *reader) imports() ([]Import, error) {
+	count, err := r.u32()
+	if err != nil {
+		return nil, err
+	}
+	var imports []Import
+	for i := uint32(0); i < count; i++ {
+		module, err := r.name()
+		if err != nil {
+			return nil, err
+		}
+		name, err := r.name()
+		if err != nil {
+			return nil, err
+		}
+		kind, err := r.byte()
+		if err != nil {
+			return nil, err
+		}
+
+		// Skip over the type of the import
+		switch ExternalKind(kind) {
+		case ExternalFunction:
+			_, err = r.u32()
+
+		case ExternalTable:
+			if err = r.valueType(); err == nil {
+				err = r.limits()
+			}
+
+		case ExternalMemory:
+			err = r.limits()
+
+		case ExternalGlobal:
+			if err = r.valueType(); err == nil {
+				_, err = r.byte()
+			}
+
+		case ExternalTag:
+			if _, err = r.byte(); err == nil {
+				_, err = r.u32()
+			}
+
+		default:
+			return nil, fmt.Errorf("Unknown import kind %d for %q", kind, name)
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		imports = append(imports, Import{Module: module, Name: name, Kind: ExternalKind(kind)})
+	}
+	return imports, nil
+}
+
+func (r *reader) exports() ([]Export, error) {
+	count, err := r.u32()
+	if err != nil {
+		return nil, err
+	}
+	var exports []Export
+	for i := uint32(0); i < count; i++ {
+		name, err := r.name()
+		if err != nil {
+			return nil, err
+		}
+		kind, err := r.byte()
+		if err != nil {
+			return nil, err
+		}
+		if kind > uint8(ExternalTag) {
+			return nil, fmt.Errorf("Unknown export kind %d for %q", kind, name)
+		}
+		if _, err := r.u32(); err != nil {
+			return nil, err
+		}
+		exports = append(exports, Export{Name: name, Kind: ExternalKind(kind)})
+	}
+	return exports, nil
+}
+
+func (r *reader) byte() (uint8, error) {
+	if r.offset >= len(r.contents) {
+		return 0, errUnexpectedEnd
+	}
+	c := r.contents[r.offset]
+	r.offset++
+	return c, nil
+}
+
+// This reads an unsigned LEB128 integer. Memory limits can be 64-bit with the
+// "memory64" proposal, so everything is read as 64-bit and narrowed after.
+func (r *reader) u64() (uint64, error) {
+	var value uint64
+	for shift := uint(0); shift < 70; shift += 7 {
+		c, err := r.byte()
+		if err != nil {
+			return 0, err
+		}
+		value |= uint64(c&0x7F) << shift
+		if c&0x80 == 0 {
+			return value, nil
+		}
+	}
+	return 0, errors.New("Invalid integer")
+}
+
+func (r *reader) u32() (uint32, error) {
+	value, err := r.u64()
+	if err != nil {
+		return 0, err
+	}
+	if value > 0xFFFFFFFF {
+		return 0, errors.New("Invalid integer")
+	}
+	return uint32(value), nil
+}
+
+func (r *reader) name() (string, error) {
+	length, err := r.u32()
+	if err != nil {
+		return "", err
+	}
+	if uint64(length) > uint64(len(r.contents)-r.offset) {
+		return "", errUnexpectedEnd
+	}
+	name := r.contents[r.offset : r.offset+int(length)]
+	r.offset += int(length)
+	if !utf8.ValidString(name) {
+		return "", errors.New("Invalid UTF-8 in name")
+	}
+	return name, nil
+}
+
+func (r *reader) limits() error {
+	flags, err := r.byte()
+	if err != nil {
+		return err
+	}
+	if flags > 0x07 {
+		return fmt.Errorf("Invalid limits flags %d", flags)
+	}
+	if _, err := r.u64(); err != nil {
+		return err
+	}
+	if flags&0x01 != 0 {
+		if _, err := r.u64(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Most value types are a single byte. Reference types from the "GC" proposal
+// are followed by a signed LEB128 heap type, which is skipped here.
+func (r *reader) valueType() error {
+	c, err := r.byte()
+	if err != nil {
+		return err
+	}
+	if c == 0x63 || c == 0x64 {
+		_, err = r.u64()
+	}
+	return err
+}
@@ -0,0 +1,67 @@
+package wasm_parser
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/evanw/esbuild/internal/test"
+)
+
+const header = "\x00asm\x01\x00\x00\x00"
+
+func expectParse(t *testing.T, name string, contents string, expected string) {
+	t.Helper()
+	t.Run(name, func(t *testing.T) {
+		t.Helper()
+		module, err := Parse(contents)
+		if err != nil {
+			test.AssertEqualWithDiff(t, "error: "+err.Error(), expected)
+			return
+		}
+		var lines []string
+		for _, imp := range module.Imports {
+			lines = append(lines, fmt.Sprintf("import %d %q %q", imp.Kind, imp.Module, imp.Name))
+		}
+		for _, exp := range module.Exports {
+			lines = append(lines, fmt.Sprintf("export %d %q", exp.Kind, exp.Name))
+		}
+		test.AssertEqualWithDiff(t, strings.Join(lines, "\n"), expected)
+	})
+}
+
+func TestEmpty(t *testing.T) {
+	expectParse(t, "empty", header, "")
+	expectParse(t, "custom", header+"\x00\x05\x04name", "")
+}
+
+func TestImports(t *testing.T) {
+	expectParse(t, "function", header+"\x02\x0b\x01\x03env\x03log\x00\x00", `import 0 "env" "log"`)
+	expectParse(t, "table", header+"\x02\x0d\x01\x03env\x03tbl\x01\x70\x00\x01", `import 1 "env" "tbl"`)
+	expectParse(t, "memory", header+"\x02\x0d\x01\x03env\x03mem\x02\x01\x01\x02", `import 2 "env" "mem"`)
+	expectParse(t, "memory64", header+"\x02\x0d\x01\x03env\x03mem\x02\x05\x01\x02", `import 2 "env" "mem"`)
+	expectParse(t, "global", header+"\x02\x0c\x01\x03env\x03val\x03\x7f\x01", `import 3 "env" "val"`)
+	expectParse(t, "ref global", header+"\x02\x0d\x01\x03env\x03val\x03\x64\x70\x00", `import 3 "env" "val"`)
+	expectParse(t, "tag", header+"\x02\x0c\x01\x03env\x03err\x04\x00\x00", `import 4 "env" "err"`)
+	expectParse(t, "multiple", header+"\x02\x0f\x02\x01a\x01b\x00\x00\x03c d\x01e\x00\x00",
+		"import 0 \"a\" \"b\"\nimport 0 \"c d\" \"e\"")
+}
+
+func TestExports(t *testing.T) {
+	expectParse(t, "exports", header+"\x07\x15\x03\x03add\x00\x00\x06memory\x02\x00\x02\xc3\xa9\x03\x00",
+		"export 0 \"add\"\nexport 2 \"memory\"\nexport 3 \"é\"")
+}
+
+func TestErrors(t *testing.T) {
+	expectParse(t, "no header", "", `error: Missing the "\0asm" header`)
+	expectParse(t, "bad magic", "\x00wasm\x01\x00\x00", `error: Missing the "\0asm" header`)
+	expectParse(t, "bad version", "\x00asm\x0d\x00\x01\x00", "error: Unsupported version 65549")
+	expectParse(t, "truncated section", header+"\x07\x05\x01", "error: Unexpected end of file")
+	expectParse(t, "truncated name", header+"\x07\x03\x01\x05a", "error: Unexpected end of file")
+	expectParse(t, "bad utf-8", header+"\x07\x05\x01\x01\xff\x00\x00", "error: Invalid UTF-8 in name")
+	expectParse(t, "bad import kind", header+"\x02\x06\x01\x01a\x01b\x05", `error: Unknown import kind 5 for "b"`)
+	expectParse(t, "bad export kind", header+"\x07\x05\x01\x01a\x05\x00", `error: Unknown export kind 5 for "a"`)
+	expectParse(t, "bad limits", header+"\x02\x08\x01\x01a\x01b\x02\x08\x00", "error: Invalid limits flags 8")
+	expectParse(t, "extra data", header+"\x07\x02\x00\x00", "error: Unexpected data at the end of section 7")
+	expectParse(t, "bad integer", header+"\x07\x06\xff\xff\xff\xff\x7f\x00", "error: Invalid integer")
+}
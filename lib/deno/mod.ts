@@ -19,6 +19,10 @@ export const transform: typeof types.transform = (input: string | Uint8Array, op
   ensureServiceIsRunning().then(service =>
     service.transform(input, options))
 
+export const transformMany: typeof types.transformMany = (inputs: types.TransformManyInput[]) =>
+  ensureServiceIsRunning().then(service =>
+    service.transformMany(inputs))
+
 export const formatMessages: typeof types.formatMessages = (messages, options) =>
   ensureServiceIsRunning().then(service =>
     service.formatMessages(messages, options))
@@ -172,6 +176,7 @@ interface Service {
   build: typeof types.build
   context: typeof types.context
   transform: typeof types.transform
+  transformMany: typeof types.transformMany
   formatMessages: typeof types.formatMessages
   analyzeMetafile: typeof types.analyzeMetafile
 }
@@ -304,6 +309,16 @@ let ensureServiceIsRunning = (): Promise<Service> => {
               callback: (err, res) => err ? reject(err) : resolve(res!),
             })),
 
+        transformMany: (inputs: types.TransformManyInput[]) =>
+          new Promise<(types.TransformResult | types.TransformFailure)[]>((resolve, reject) =>
+            service.transformMany({
+              callName: 'transformMany',
+              refs: null,
+              inputs,
+              isTTY,
+              callback: (err, res) => err ? reject(err) : resolve(res!),
+            })),
+
         formatMessages: (messages, options) =>
           new Promise((resolve, reject) =>
             service.formatMessages({
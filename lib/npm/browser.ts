@@ -17,6 +17,9 @@ export let context: typeof types.context = (options: types.BuildOptions) =>
 export const transform: typeof types.transform = (input: string | Uint8Array, options?: types.TransformOptions) =>
   ensureServiceIsRunning().transform(input, options)
 
+export const transformMany: typeof types.transformMany = (inputs: types.TransformManyInput[]) =>
+  ensureServiceIsRunning().transformMany(inputs)
+
 export const formatMessages: typeof types.formatMessages = (messages, options) =>
   ensureServiceIsRunning().formatMessages(messages, options)
 
@@ -43,6 +46,7 @@ interface Service {
   build: typeof types.build
   context: typeof types.context
   transform: typeof types.transform
+  transformMany: typeof types.transformMany
   formatMessages: typeof types.formatMessages
   analyzeMetafile: typeof types.analyzeMetafile
 }
@@ -159,6 +163,16 @@ const startRunningService = async (wasmURL: string | URL, wasmModule: WebAssembl
           callback: (err, res) => err ? reject(err) : resolve(res!),
         })),
 
+    transformMany: (inputs: types.TransformManyInput[]) =>
+      new Promise<(types.TransformResult | types.TransformFailure)[]>((resolve, reject) =>
+        service.transformMany({
+          callName: 'transformMany',
+          refs: null,
+          inputs,
+          isTTY: false,
+          callback: (err, res) => err ? reject(err) : resolve(res!),
+        })),
+
     formatMessages: (messages, options) =>
       new Promise((resolve, reject) =>
         service.formatMessages({
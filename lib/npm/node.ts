@@ -139,6 +139,9 @@ export let context: typeof types.context = (buildOptions: types.BuildOptions) =>
 export let transform: typeof types.transform = (input: string | Uint8Array, options?: types.TransformOptions) =>
   ensureServiceIsRunning().transform(input, options)
 
+export let transformMany: typeof types.transformMany = (inputs: types.TransformManyInput[]) =>
+  ensureServiceIsRunning().transformMany(inputs)
+
 export let formatMessages: typeof types.formatMessages = (messages, options) =>
   ensureServiceIsRunning().formatMessages(messages, options)
 
@@ -237,6 +240,7 @@ interface Service {
   build: typeof types.build
   context: typeof types.context
   transform: typeof types.transform
+  transformMany: typeof types.transformMany
   formatMessages: typeof types.formatMessages
   analyzeMetafile: typeof types.analyzeMetafile
 }
@@ -328,6 +332,16 @@ let ensureServiceIsRunning = (): Service => {
           callback: (err, res) => err ? reject(err) : resolve(res!),
         })),
 
+    transformMany: (inputs: types.TransformManyInput[]) =>
+      new Promise<(types.TransformResult | types.TransformFailure)[]>((resolve, reject) =>
+        service.transformMany({
+          callName: 'transformMany',
+          refs,
+          inputs,
+          isTTY: isTTY(),
+          callback: (err, res) => err ? reject(err) : resolve(res!),
+        })),
+
     formatMessages: (messages, options) =>
       new Promise((resolve, reject) =>
         service.formatMessages({
@@ -135,6 +135,7 @@ function pushCommonFlags(flags: string[], options: CommonOptions, keys: OptionKe
   let legalComments = getFlag(options, keys, 'legalComments', mustBeString)
   let sourceRoot = getFlag(options, keys, 'sourceRoot', mustBeString)
   let sourcesContent = getFlag(options, keys, 'sourcesContent', mustBeBoolean)
+  let sourcemapIgnoreList = getFlag(options, keys, 'sourcemapIgnoreList', mustBeArray)
   let target = getFlag(options, keys, 'target', mustBeStringOrArray)
   let format = getFlag(options, keys, 'format', mustBeString)
   let globalName = getFlag(options, keys, 'globalName', mustBeString)
@@ -168,6 +169,15 @@ function pushCommonFlags(flags: string[], options: CommonOptions, keys: OptionKe
   if (legalComments) flags.push(`--legal-comments=${legalComments}`)
   if (sourceRoot !== void 0) flags.push(`--source-root=${sourceRoot}`)
   if (sourcesContent !== void 0) flags.push(`--sources-content=${sourcesContent}`)
+  if (sourcemapIgnoreList) {
+    let values: string[] = []
+    for (let value of sourcemapIgnoreList) {
+      validateStringValue(value, 'sourcemap ignore list')
+      if (value.indexOf(',') >= 0) throw new Error(`Invalid sourcemap ignore list pattern: ${value}`)
+      values.push(value)
+    }
+    flags.push(`--sourcemap-ignore-list=${values.join(',')}`)
+  }
   if (target) {
     if (Array.isArray(target)) flags.push(`--target=${Array.from(target).map(validateTarget).join(',')}`)
     else flags.push(`--target=${validateTarget(target)}`)
@@ -249,8 +259,12 @@ function flagsForBuildOptions(
   let sourcemap = getFlag(options, keys, 'sourcemap', mustBeStringOrBoolean)
   let bundle = getFlag(options, keys, 'bundle', mustBeBoolean)
   let splitting = getFlag(options, keys, 'splitting', mustBeBoolean)
+  let hmr = getFlag(options, keys, 'hmr', mustBeBoolean)
   let preserveSymlinks = getFlag(options, keys, 'preserveSymlinks', mustBeBoolean)
   let metafile = getFlag(options, keys, 'metafile', mustBeBoolean)
+  let metafileIncludedBy = getFlag(options, keys, 'metafileIncludedBy', mustBeBoolean)
+  let debugIds = getFlag(options, keys, 'debugIds', mustBeBoolean)
+  let sourcemapSections = getFlag(options, keys, 'sourcemapSections', mustBeBoolean)
   let outfile = getFlag(options, keys, 'outfile', mustBeString)
   let outdir = getFlag(options, keys, 'outdir', mustBeString)
   let outbase = getFlag(options, keys, 'outbase', mustBeString)
@@ -261,6 +275,7 @@ function flagsForBuildOptions(
   let conditions = getFlag(options, keys, 'conditions', mustBeArray)
   let external = getFlag(options, keys, 'external', mustBeArray)
   let packages = getFlag(options, keys, 'packages', mustBeString)
+  let wasmBytes = getFlag(options, keys, 'wasmBytes', mustBeString)
   let alias = getFlag(options, keys, 'alias', mustBeObject)
   let loader = getFlag(options, keys, 'loader', mustBeObject)
   let outExtension = getFlag(options, keys, 'outExtension', mustBeObject)
@@ -268,11 +283,14 @@ function flagsForBuildOptions(
   let entryNames = getFlag(options, keys, 'entryNames', mustBeString)
   let chunkNames = getFlag(options, keys, 'chunkNames', mustBeString)
   let assetNames = getFlag(options, keys, 'assetNames', mustBeString)
+  let manualChunks = getFlag(options, keys, 'manualChunks', mustBeObject)
+  let budgets = getFlag(options, keys, 'budgets', mustBeArray)
   let inject = getFlag(options, keys, 'inject', mustBeArray)
   let banner = getFlag(options, keys, 'banner', mustBeObject)
   let footer = getFlag(options, keys, 'footer', mustBeObject)
   let entryPoints = getFlag(options, keys, 'entryPoints', mustBeEntryPoints)
   let absWorkingDir = getFlag(options, keys, 'absWorkingDir', mustBeString)
+  let cacheDir = getFlag(options, keys, 'cacheDir', mustBeString)
   let stdin = getFlag(options, keys, 'stdin', mustBeObject)
   let write = getFlag(options, keys, 'write', mustBeBoolean) ?? writeDefault; // Default to true if not specified
   let allowOverwrite = getFlag(options, keys, 'allowOverwrite', mustBeBoolean)
@@ -284,13 +302,19 @@ function flagsForBuildOptions(
   if (bundle) flags.push('--bundle')
   if (allowOverwrite) flags.push('--allow-overwrite')
   if (splitting) flags.push('--splitting')
+  if (hmr) flags.push('--hmr')
   if (preserveSymlinks) flags.push('--preserve-symlinks')
   if (metafile) flags.push(`--metafile`)
+  if (metafileIncludedBy) flags.push(`--metafile-included-by`)
+  if (debugIds) flags.push(`--debug-ids`)
+  if (sourcemapSections) flags.push(`--sourcemap-sections`)
   if (outfile) flags.push(`--outfile=${outfile}`)
   if (outdir) flags.push(`--outdir=${outdir}`)
   if (outbase) flags.push(`--outbase=${outbase}`)
   if (tsconfig) flags.push(`--tsconfig=${tsconfig}`)
+  if (cacheDir) flags.push(`--cache-dir=${cacheDir}`)
   if (packages) flags.push(`--packages=${packages}`)
+  if (wasmBytes) flags.push(`--wasm-bytes=${wasmBytes}`)
   if (resolveExtensions) {
     let values: string[] = []
     for (let value of resolveExtensions) {
@@ -323,6 +347,21 @@ function flagsForBuildOptions(
     flags.push(`--conditions=${values.join(',')}`)
   }
   if (external) for (let name of external) flags.push(`--external:${validateStringValue(name, 'external')}`)
+  if (budgets) budgets.forEach((budget, index) => flags.push(`--budget:${budgetFlagValue(budget, index)}`))
+  if (manualChunks) {
+    for (let name in manualChunks) {
+      if (name.indexOf('=') >= 0) throw new Error(`Invalid manual chunk name: ${name}`)
+      let paths = manualChunks[name]
+      if (!Array.isArray(paths)) throw new Error(`Expected value for manual chunk ${quote(name)} to be an array`)
+      let values: string[] = []
+      for (let value of paths) {
+        validateStringValue(value, 'manual chunk', name)
+        if (value.indexOf(',') >= 0) throw new Error(`Invalid manual chunk path: ${value}`)
+        values.push(value)
+      }
+      flags.push(`--manual-chunk:${name}=${values.join(',')}`)
+    }
+  }
   if (alias) {
     for (let old in alias) {
       if (old.indexOf('=') >= 0) throw new Error(`Invalid package name in alias: ${old}`)
@@ -491,6 +530,14 @@ export interface StreamService {
     callback: (err: Error | null, res: types.TransformResult | null) => void,
   }): void
 
+  transformMany(args: {
+    callName: string,
+    refs: Refs | null,
+    inputs: types.TransformManyInput[],
+    isTTY: boolean,
+    callback: (err: Error | null, res: (types.TransformResult | types.TransformFailure)[] | null) => void,
+  }): void
+
   formatMessages(args: {
     callName: string,
     refs: Refs | null,
@@ -794,6 +841,57 @@ export function createChannel(streamIn: StreamIn): StreamOut {
     start(null)
   }
 
+  let transformMany: StreamService['transformMany'] = ({ callName, refs, inputs, isTTY, callback }) => {
+    const details = createObjectStash()
+    let request: protocol.TransformManyRequest
+    try {
+      if (!Array.isArray(inputs)) throw new Error(`The first argument to "${callName}" must be an array`)
+      request = {
+        command: 'transform-many',
+        inputs: inputs.map(item => {
+          let keys: OptionKeys = {}
+          let input = getFlag(item, keys, 'input', mustBeStringOrUint8Array)
+          let options = getFlag(item, keys, 'options', mustBeObject)
+          checkForInvalidFlags(item, keys, `in ${callName}() call`)
+          if (input === void 0) throw new Error(`Missing "input" in ${callName}() call`)
+          let {
+            flags,
+            mangleCache,
+          } = flagsForTransformOptions(callName, options || {}, isTTY, transformLogLevelDefault)
+          let requestInput: protocol.TransformManyRequestInput = {
+            flags,
+            input: typeof input === 'string' ? protocol.encodeUTF8(input) : input,
+          }
+          if (mangleCache) requestInput.mangleCache = mangleCache
+          return requestInput
+        }),
+      }
+    } catch (e) {
+      return callback(e as Error, null)
+    }
+    sendRequest<protocol.TransformManyRequest, protocol.TransformManyResponse>(refs, request, (error, response) => {
+      if (error) return callback(new Error(error), null)
+
+      // Each input either succeeds or fails by itself, so failures are returned
+      // in the array instead of rejecting the whole call
+      callback(null, response!.results.map(response => {
+        let errors = replaceDetailsInMessages(response.errors, details)
+        let warnings = replaceDetailsInMessages(response.warnings, details)
+        if (errors.length > 0) return failureErrorWithLog('Transform failed', errors, warnings)
+        let result: types.TransformResult = {
+          warnings,
+          code: response.code,
+          map: response.map,
+          mangleCache: undefined,
+          legalComments: undefined,
+        }
+        if ('legalComments' in response) result.legalComments = response.legalComments
+        if (response.mangleCache) result.mangleCache = response.mangleCache
+        return result
+      }))
+    })
+  }
+
   let formatMessages: StreamService['formatMessages'] = ({ callName, refs, messages, options, callback }) => {
     let result = sanitizeMessages(messages, 'messages', null, '')
     if (!options) throw new Error(`Missing second argument in ${callName}() call`)
@@ -822,6 +920,9 @@ export function createChannel(streamIn: StreamIn): StreamOut {
     let keys: OptionKeys = {}
     let color = getFlag(options, keys, 'color', mustBeBoolean)
     let verbose = getFlag(options, keys, 'verbose', mustBeBoolean)
+    let why = getFlag(options, keys, 'why', mustBeString)
+    let html = getFlag(options, keys, 'html', mustBeBoolean)
+    let baseline = getFlag(options, keys, 'baseline', mustBeStringOrObject)
     checkForInvalidFlags(options, keys, `in ${callName}() call`)
     let request: protocol.AnalyzeMetafileRequest = {
       command: 'analyze-metafile',
@@ -829,6 +930,9 @@ export function createChannel(streamIn: StreamIn): StreamOut {
     }
     if (color !== void 0) request.color = color
     if (verbose !== void 0) request.verbose = verbose
+    if (why !== void 0) request.why = why
+    if (html !== void 0) request.html = html
+    if (baseline !== void 0) request.baseline = typeof baseline === 'string' ? baseline : JSON.stringify(baseline)
     sendRequest<protocol.AnalyzeMetafileRequest, protocol.AnalyzeMetafileResponse>(refs, request, (error, response) => {
       if (error) return callback(new Error(error), null)
       callback(null, response!.result)
@@ -841,6 +945,7 @@ export function createChannel(streamIn: StreamIn): StreamOut {
     service: {
       buildOrContext,
       transform,
+      transformMany,
       formatMessages,
       analyzeMetafile,
     },
@@ -1073,11 +1178,22 @@ function buildOrContextImpl(
         watch: (options = {}) => new Promise((resolve, reject) => {
           if (!streamIn.hasFS) throw new Error(`Cannot use the "watch" API in this environment`)
           const keys: OptionKeys = {}
+          const backend = getFlag(options, keys, 'backend', mustBeString)
+          const pollInterval = getFlag(options, keys, 'pollInterval', mustBeInteger)
+          const debounce = getFlag(options, keys, 'debounce', mustBeInteger)
+          const ignore = getFlag(options, keys, 'ignore', mustBeArray)
           checkForInvalidFlags(options, keys, `in watch() call`)
           const request: protocol.WatchRequest = {
             command: 'watch',
             key: buildKey,
           }
+          if (backend !== void 0) {
+            if (backend !== 'polling' && backend !== 'native') throw new Error(`Invalid watch backend: ${backend}`)
+            request.backend = backend
+          }
+          if (pollInterval !== void 0) request.pollInterval = pollInterval
+          if (debounce !== void 0) request.debounce = debounce
+          if (ignore !== void 0) request.ignore = ignore.map(pattern => validateStringValue(pattern, 'ignore pattern'))
           sendRequest<protocol.WatchRequest, null>(refs, request, error => {
             if (error) reject(new Error(error))
             else resolve(undefined)
@@ -1093,6 +1209,10 @@ function buildOrContextImpl(
           const keyfile = getFlag(options, keys, 'keyfile', mustBeString)
           const certfile = getFlag(options, keys, 'certfile', mustBeString)
           const fallback = getFlag(options, keys, 'fallback', mustBeString)
+          const errorOverlay = getFlag(options, keys, 'errorOverlay', mustBeBoolean)
+          const proxy = getFlag(options, keys, 'proxy', mustBeArray)
+          const compress = getFlag(options, keys, 'compress', mustBeBoolean)
+          const cacheControl = getFlag(options, keys, 'cacheControl', mustBeArray)
           const onRequest = getFlag(options, keys, 'onRequest', mustBeFunction)
           checkForInvalidFlags(options, keys, `in serve() call`)
 
@@ -1107,6 +1227,10 @@ function buildOrContextImpl(
           if (keyfile !== void 0) request.keyfile = keyfile
           if (certfile !== void 0) request.certfile = certfile
           if (fallback !== void 0) request.fallback = fallback
+          if (errorOverlay !== void 0) request.errorOverlay = errorOverlay
+          if (proxy !== void 0) request.proxy = proxy.map(sanitizeServeProxy)
+          if (compress !== void 0) request.compress = compress
+          if (cacheControl !== void 0) request.cacheControl = cacheControl.map(sanitizeServeCacheControl)
 
           sendRequest<protocol.ServeRequest, protocol.ServeResponse>(refs, request, (error, response) => {
             if (error) return reject(new Error(error))
@@ -1207,6 +1331,24 @@ let handlePlugins = async (
     },
   } = {}
 
+  let onTransformCallbacks: {
+    [id: number]: {
+      name: string,
+      note: () => types.Note | undefined,
+      callback: (args: types.OnTransformArgs) =>
+        (types.OnTransformResult | null | undefined | Promise<types.OnTransformResult | null | undefined>),
+    },
+  } = {}
+
+  let onRenderChunkCallbacks: {
+    [id: number]: {
+      name: string,
+      note: () => types.Note | undefined,
+      callback: (args: types.OnRenderChunkArgs) =>
+        (types.OnRenderChunkResult | null | undefined | Promise<types.OnRenderChunkResult | null | undefined>),
+    },
+  } = {}
+
   let onDisposeCallbacks: (() => void)[] = []
   let nextCallbackID = 0
   let i = 0
@@ -1232,6 +1374,8 @@ let handlePlugins = async (
         onEnd: false,
         onResolve: [],
         onLoad: [],
+        onTransform: [],
+        onRenderChunk: [],
       }
       i++
 
@@ -1245,6 +1389,7 @@ let handlePlugins = async (
         let resolveDir = getFlag(options, keys, 'resolveDir', mustBeString)
         let kind = getFlag(options, keys, 'kind', mustBeString)
         let pluginData = getFlag(options, keys, 'pluginData', canBeAnything)
+        let importAttributes = getFlag(options, keys, 'with', mustBeObject)
         checkForInvalidFlags(options, keys, 'in resolve() call')
 
         return new Promise((resolve, reject) => {
@@ -1261,6 +1406,10 @@ let handlePlugins = async (
           if (kind != null) request.kind = kind
           else throw new Error(`Must specify "kind" when calling "resolve"`)
           if (pluginData != null) request.pluginData = details.store(pluginData)
+          if (importAttributes != null) {
+            request.with = Object.create(null)
+            for (let key in importAttributes) request.with![key] = validateStringValue(importAttributes[key], 'import attribute', key)
+          }
 
           sendRequest<protocol.ResolveRequest, protocol.ResolveResponse>(refs, request, (error, response) => {
             if (error !== null) reject(new Error(error))
@@ -1323,6 +1472,27 @@ let handlePlugins = async (
           plugin.onLoad.push({ id, filter: filter.source, namespace: namespace || '' })
         },
 
+        onTransform(options, callback) {
+          let registeredText = `This error came from the "onTransform" callback registered here:`
+          let registeredNote = extractCallerV8(new Error(registeredText), streamIn, 'onTransform')
+          let keys: OptionKeys = {}
+          let filter = getFlag(options, keys, 'filter', mustBeRegExp)
+          let namespace = getFlag(options, keys, 'namespace', mustBeString)
+          checkForInvalidFlags(options, keys, `in onTransform() call for plugin ${quote(name)}`)
+          if (filter == null) throw new Error(`onTransform() call is missing a filter`)
+          let id = nextCallbackID++
+          onTransformCallbacks[id] = { name: name!, callback, note: registeredNote }
+          plugin.onTransform.push({ id, filter: filter.source, namespace: namespace || '' })
+        },
+
+        onRenderChunk(callback) {
+          let registeredText = `This error came from the "onRenderChunk" callback registered here:`
+          let registeredNote = extractCallerV8(new Error(registeredText), streamIn, 'onRenderChunk')
+          let id = nextCallbackID++
+          onRenderChunkCallbacks[id] = { name: name!, callback, note: registeredNote }
+          plugin.onRenderChunk.push(id)
+        },
+
         onDispose(callback) {
           onDisposeCallbacks.push(callback)
         },
@@ -1378,6 +1548,7 @@ let handlePlugins = async (
           resolveDir: request.resolveDir,
           kind: request.kind,
           pluginData: details.load(request.pluginData),
+          with: request.with,
         })
 
         if (result != null) {
@@ -1428,6 +1599,7 @@ let handlePlugins = async (
           namespace: request.namespace,
           suffix: request.suffix,
           pluginData: details.load(request.pluginData),
+          with: request.with,
         })
 
         if (result != null) {
@@ -1435,6 +1607,7 @@ let handlePlugins = async (
           let keys: OptionKeys = {}
           let pluginName = getFlag(result, keys, 'pluginName', mustBeString)
           let contents = getFlag(result, keys, 'contents', mustBeStringOrUint8Array)
+          let sourceMap = getFlag(result, keys, 'sourceMap', mustBeStringOrObject)
           let resolveDir = getFlag(result, keys, 'resolveDir', mustBeString)
           let pluginData = getFlag(result, keys, 'pluginData', canBeAnything)
           let loader = getFlag(result, keys, 'loader', mustBeString)
@@ -1448,6 +1621,7 @@ let handlePlugins = async (
           if (pluginName != null) response.pluginName = pluginName
           if (contents instanceof Uint8Array) response.contents = contents
           else if (contents != null) response.contents = protocol.encodeUTF8(contents)
+          if (sourceMap != null) response.sourceMap = protocol.encodeUTF8(typeof sourceMap === 'string' ? sourceMap : JSON.stringify(sourceMap))
           if (resolveDir != null) response.resolveDir = resolveDir
           if (pluginData != null) response.pluginData = details.store(pluginData)
           if (loader != null) response.loader = loader
@@ -1465,6 +1639,82 @@ let handlePlugins = async (
     sendResponse(id, response as any)
   }
 
+  requestCallbacks['on-transform'] = async (id, request: protocol.OnTransformRequest) => {
+    let response: protocol.OnTransformResponse = {}, { name, callback, note } = onTransformCallbacks[request.id]
+    try {
+      let result = await callback({
+        path: request.path,
+        namespace: request.namespace,
+        suffix: request.suffix,
+        contents: protocol.decodeUTF8(request.contents),
+        loader: request.loader as types.Loader,
+        pluginData: details.load(request.pluginData),
+        with: request.with,
+      })
+
+      if (result != null) {
+        if (typeof result !== 'object') throw new Error(`Expected onTransform() callback in plugin ${quote(name)} to return an object`)
+        let keys: OptionKeys = {}
+        let pluginName = getFlag(result, keys, 'pluginName', mustBeString)
+        let contents = getFlag(result, keys, 'contents', mustBeStringOrUint8Array)
+        let sourceMap = getFlag(result, keys, 'sourceMap', mustBeStringOrObject)
+        let pluginData = getFlag(result, keys, 'pluginData', canBeAnything)
+        let loader = getFlag(result, keys, 'loader', mustBeString)
+        let errors = getFlag(result, keys, 'errors', mustBeArray)
+        let warnings = getFlag(result, keys, 'warnings', mustBeArray)
+        let watchFiles = getFlag(result, keys, 'watchFiles', mustBeArray)
+        let watchDirs = getFlag(result, keys, 'watchDirs', mustBeArray)
+        checkForInvalidFlags(result, keys, `from onTransform() callback in plugin ${quote(name)}`)
+
+        if (pluginName != null) response.pluginName = pluginName
+        if (contents instanceof Uint8Array) response.contents = contents
+        else if (contents != null) response.contents = protocol.encodeUTF8(contents)
+        if (sourceMap != null) response.sourceMap = protocol.encodeUTF8(typeof sourceMap === 'string' ? sourceMap : JSON.stringify(sourceMap))
+        if (pluginData != null) response.pluginData = details.store(pluginData)
+        if (loader != null) response.loader = loader
+        if (errors != null) response.errors = sanitizeMessages(errors, 'errors', details, name)
+        if (warnings != null) response.warnings = sanitizeMessages(warnings, 'warnings', details, name)
+        if (watchFiles != null) response.watchFiles = sanitizeStringArray(watchFiles, 'watchFiles')
+        if (watchDirs != null) response.watchDirs = sanitizeStringArray(watchDirs, 'watchDirs')
+      }
+    } catch (e) {
+      response = { errors: [extractErrorMessageV8(e, streamIn, details, note && note(), name)] }
+    }
+    sendResponse(id, response as any)
+  }
+
+  requestCallbacks['on-render-chunk'] = async (id, request: protocol.OnRenderChunkRequest) => {
+    let response: protocol.OnRenderChunkResponse = {}, { name, callback, note } = onRenderChunkCallbacks[request.id]
+    try {
+      let result = await callback({
+        pathTemplate: request.pathTemplate,
+        entryPoint: request.entryPoint,
+        contents: protocol.decodeUTF8(request.contents),
+      })
+
+      if (result != null) {
+        if (typeof result !== 'object') throw new Error(`Expected onRenderChunk() callback in plugin ${quote(name)} to return an object`)
+        let keys: OptionKeys = {}
+        let pluginName = getFlag(result, keys, 'pluginName', mustBeString)
+        let contents = getFlag(result, keys, 'contents', mustBeStringOrUint8Array)
+        let sourceMap = getFlag(result, keys, 'sourceMap', mustBeStringOrObject)
+        let errors = getFlag(result, keys, 'errors', mustBeArray)
+        let warnings = getFlag(result, keys, 'warnings', mustBeArray)
+        checkForInvalidFlags(result, keys, `from onRenderChunk() callback in plugin ${quote(name)}`)
+
+        if (pluginName != null) response.pluginName = pluginName
+        if (contents instanceof Uint8Array) response.contents = contents
+        else if (contents != null) response.contents = protocol.encodeUTF8(contents)
+        if (sourceMap != null) response.sourceMap = protocol.encodeUTF8(typeof sourceMap === 'string' ? sourceMap : JSON.stringify(sourceMap))
+        if (errors != null) response.errors = sanitizeMessages(errors, 'errors', details, name)
+        if (warnings != null) response.warnings = sanitizeMessages(warnings, 'warnings', details, name)
+      }
+    } catch (e) {
+      response = { errors: [extractErrorMessageV8(e, streamIn, details, note && note(), name)] }
+    }
+    sendResponse(id, response as any)
+  }
+
   let runOnEndCallbacks: RunOnEndCallbacks = (result, done) => done([], [])
 
   if (onEndCallbacks.length > 0) {
@@ -1767,6 +2017,54 @@ function sanitizeStringArray(values: any[], property: string): string[] {
   return result
 }
 
+function budgetFlagValue(budget: types.Budget, index: number): string {
+  let keys: OptionKeys = {}
+  let where = `in element ${index} of "budgets"`
+  let path = getFlag(budget, keys, 'path', mustBeString)
+  let maxBytes = getFlag(budget, keys, 'maxBytes', mustBeInteger)
+  let maxGzipBytes = getFlag(budget, keys, 'maxGzipBytes', mustBeInteger)
+  let warn = getFlag(budget, keys, 'warn', mustBeBoolean)
+  checkForInvalidFlags(budget, keys, where)
+  if (path === void 0) throw new Error(`Missing "path" ${where}`)
+  if (path.indexOf('=') >= 0) throw new Error(`Invalid budget path: ${path}`)
+  let values: string[] = []
+  if (maxBytes !== void 0) values.push(`${maxBytes}`)
+  if (maxGzipBytes !== void 0) values.push(`gzip=${maxGzipBytes}`)
+  if (warn) values.push('warn')
+  return `${path}=${values.join(',')}`
+}
+
+function sanitizeServeCacheControl(rule: types.ServeCacheControl, index: number): protocol.ServeCacheControl {
+  let keys: OptionKeys = {}
+  let where = `in element ${index} of "cacheControl"`
+  let path = getFlag(rule, keys, 'path', mustBeString)
+  let value = getFlag(rule, keys, 'value', mustBeString)
+  checkForInvalidFlags(rule, keys, where)
+  if (path === void 0) throw new Error(`Missing "path" ${where}`)
+  if (value === void 0) throw new Error(`Missing "value" ${where}`)
+  return { path, value }
+}
+
+function sanitizeServeProxy(rule: types.ServeProxy, index: number): protocol.ServeProxy {
+  let keys: OptionKeys = {}
+  let where = `in element ${index} of "proxy"`
+  let path = getFlag(rule, keys, 'path', mustBeString)
+  let target = getFlag(rule, keys, 'target', mustBeString)
+  let rewrite = getFlag(rule, keys, 'rewrite', mustBeString)
+  let headers = getFlag(rule, keys, 'headers', mustBeObject)
+  checkForInvalidFlags(rule, keys, where)
+  if (path === void 0) throw new Error(`Missing "path" ${where}`)
+  if (target === void 0) throw new Error(`Missing "target" ${where}`)
+
+  let result: protocol.ServeProxy = { path, target }
+  if (rewrite !== void 0) result.rewrite = rewrite
+  if (headers !== void 0) {
+    result.headers = Object.create(null)
+    for (let key in headers) result.headers![key] = validateStringValue(headers[key], 'header', key)
+  }
+  return result
+}
+
 function convertOutputFiles({ path, contents, hash }: protocol.BuildOutputFile): types.OutputFile {
   // The text is lazily-generated for performance reasons. If no one asks for
   // it, then it never needs to be generated.
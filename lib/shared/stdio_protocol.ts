@@ -31,6 +31,22 @@ export interface ServeRequest {
   keyfile?: string
   certfile?: string
   fallback?: string
+  errorOverlay?: boolean
+  proxy?: ServeProxy[]
+  compress?: boolean
+  cacheControl?: ServeCacheControl[]
+}
+
+export interface ServeCacheControl {
+  path: string
+  value: string
+}
+
+export interface ServeProxy {
+  path: string
+  target: string
+  rewrite?: string
+  headers?: Record<string, string>
 }
 
 export interface ServeResponse {
@@ -44,6 +60,8 @@ export interface BuildPlugin {
   onEnd: boolean
   onResolve: { id: number, filter: string, namespace: string }[]
   onLoad: { id: number, filter: string, namespace: string }[]
+  onTransform: { id: number, filter: string, namespace: string }[]
+  onRenderChunk: number[]
 }
 
 export interface BuildResponse {
@@ -97,6 +115,10 @@ export interface CancelRequest {
 export interface WatchRequest {
   command: 'watch'
   key: number
+  backend?: 'polling' | 'native'
+  pollInterval?: number
+  debounce?: number
+  ignore?: string[]
 }
 
 export interface OnServeRequest {
@@ -127,6 +149,30 @@ export interface TransformResponse {
   mangleCache?: Record<string, string | false>
 }
 
+export interface TransformManyRequest {
+  command: 'transform-many'
+  inputs: TransformManyRequestInput[]
+}
+
+export interface TransformManyRequestInput {
+  flags: string[]
+  input: Uint8Array
+  mangleCache?: Record<string, string | false>
+}
+
+export interface TransformManyResponse {
+  results: TransformManyResponseResult[]
+}
+
+export interface TransformManyResponseResult {
+  errors: types.Message[]
+  warnings: types.Message[]
+  code: string
+  map: string
+  legalComments?: string
+  mangleCache?: Record<string, string | false>
+}
+
 export interface FormatMsgsRequest {
   command: 'format-msgs'
   messages: types.Message[]
@@ -144,6 +190,9 @@ export interface AnalyzeMetafileRequest {
   metafile: string
   color?: boolean
   verbose?: boolean
+  why?: string
+  html?: boolean
+  baseline?: string
 }
 
 export interface AnalyzeMetafileResponse {
@@ -170,6 +219,7 @@ export interface ResolveRequest {
   resolveDir?: string
   kind?: string
   pluginData?: number
+  with?: Record<string, string>
 }
 
 export interface ResolveResponse {
@@ -194,6 +244,7 @@ export interface OnResolveRequest {
   resolveDir: string
   kind: types.ImportKind
   pluginData: number
+  with: Record<string, string>
 }
 
 export interface OnResolveResponse {
@@ -222,6 +273,7 @@ export interface OnLoadRequest {
   namespace: string
   suffix: string
   pluginData: number
+  with: Record<string, string>
 }
 
 export interface OnLoadResponse {
@@ -232,6 +284,7 @@ export interface OnLoadResponse {
   warnings?: types.PartialMessage[]
 
   contents?: Uint8Array
+  sourceMap?: Uint8Array
   resolveDir?: string
   loader?: string
   pluginData?: number
@@ -240,6 +293,53 @@ export interface OnLoadResponse {
   watchDirs?: string[]
 }
 
+export interface OnTransformRequest {
+  command: 'on-transform'
+  key: number
+  id: number
+  path: string
+  namespace: string
+  suffix: string
+  contents: Uint8Array
+  loader: string
+  pluginData: number
+  with: Record<string, string>
+}
+
+export interface OnTransformResponse {
+  pluginName?: string
+
+  errors?: types.PartialMessage[]
+  warnings?: types.PartialMessage[]
+
+  contents?: Uint8Array
+  sourceMap?: Uint8Array
+  loader?: string
+  pluginData?: number
+
+  watchFiles?: string[]
+  watchDirs?: string[]
+}
+
+export interface OnRenderChunkRequest {
+  command: 'on-render-chunk'
+  key: number
+  id: number
+  pathTemplate: string
+  entryPoint: string
+  contents: Uint8Array
+}
+
+export interface OnRenderChunkResponse {
+  pluginName?: string
+
+  errors?: types.PartialMessage[]
+  warnings?: types.PartialMessage[]
+
+  contents?: Uint8Array
+  sourceMap?: Uint8Array
+}
+
 ////////////////////////////////////////////////////////////////////////////////
 
 export interface Packet {
@@ -1,6 +1,6 @@
 export type Platform = 'browser' | 'node' | 'neutral'
 export type Format = 'iife' | 'cjs' | 'esm'
-export type Loader = 'base64' | 'binary' | 'copy' | 'css' | 'dataurl' | 'default' | 'empty' | 'file' | 'js' | 'json' | 'jsx' | 'local-css' | 'text' | 'ts' | 'tsx'
+export type Loader = 'base64' | 'binary' | 'copy' | 'css' | 'dataurl' | 'default' | 'empty' | 'file' | 'html' | 'js' | 'json' | 'jsx' | 'local-css' | 'text' | 'ts' | 'tsx' | 'wasm'
 export type LogLevel = 'verbose' | 'debug' | 'info' | 'warning' | 'error' | 'silent'
 export type Charset = 'ascii' | 'utf8'
 export type Drop = 'console' | 'debugger'
@@ -14,6 +14,8 @@ interface CommonOptions {
   sourceRoot?: string
   /** Documentation: https://esbuild.github.io/api/#sources-content */
   sourcesContent?: boolean
+  /** Glob patterns for sources that debuggers should skip, which are listed in the "ignoreList" source map field (default ["**\/node_modules/**"]) */
+  sourcemapIgnoreList?: string[]
 
   /** Documentation: https://esbuild.github.io/api/#format */
   format?: Format
@@ -112,12 +114,22 @@ export interface BuildOptions extends CommonOptions {
   bundle?: boolean
   /** Documentation: https://esbuild.github.io/api/#splitting */
   splitting?: boolean
+  /** Makes modules individually replaceable at run time via "import.meta.hot" */
+  hmr?: boolean
   /** Documentation: https://esbuild.github.io/api/#preserve-symlinks */
   preserveSymlinks?: boolean
   /** Documentation: https://esbuild.github.io/api/#outfile */
   outfile?: string
   /** Documentation: https://esbuild.github.io/api/#metafile */
   metafile?: boolean
+  /** Records in the metafile why each input file and top-level statement survived tree shaking (requires "metafile") */
+  metafileIncludedBy?: boolean
+  /** Fails the build (or warns) when output files are larger than these limits */
+  budgets?: Budget[]
+  /** Adds a "debugId" to each output file and its source map so error reporting tools can match them up without URLs */
+  debugIds?: boolean
+  /** Generates index source maps with one section per file, which is faster for large bundles */
+  sourcemapSections?: boolean
   /** Documentation: https://esbuild.github.io/api/#outdir */
   outdir?: string
   /** Documentation: https://esbuild.github.io/api/#outbase */
@@ -146,12 +158,16 @@ export interface BuildOptions extends CommonOptions {
   outExtension?: { [ext: string]: string }
   /** Documentation: https://esbuild.github.io/api/#public-path */
   publicPath?: string
+  /** Whether the "wasm" loader embeds the module's bytes or emits them as a separate file (default "inline") */
+  wasmBytes?: 'inline' | 'file'
   /** Documentation: https://esbuild.github.io/api/#entry-names */
   entryNames?: string
   /** Documentation: https://esbuild.github.io/api/#chunk-names */
   chunkNames?: string
   /** Documentation: https://esbuild.github.io/api/#asset-names */
   assetNames?: string
+  /** Forces modules matching these package names or paths into a chunk with the given name (requires "splitting") */
+  manualChunks?: Record<string, string[]>
   /** Documentation: https://esbuild.github.io/api/#inject */
   inject?: string[]
   /** Documentation: https://esbuild.github.io/api/#banner */
@@ -166,6 +182,8 @@ export interface BuildOptions extends CommonOptions {
   plugins?: Plugin[]
   /** Documentation: https://esbuild.github.io/api/#working-directory */
   absWorkingDir?: string
+  /** Persists parse results in this directory so separate processes can reuse them */
+  cacheDir?: string
   /** Documentation: https://esbuild.github.io/api/#node-paths */
   nodePaths?: string[]; // The "NODE_PATH" variable from Node.js
 }
@@ -241,9 +259,42 @@ export interface ServeOptions {
   keyfile?: string
   certfile?: string
   fallback?: string
+  errorOverlay?: boolean
+  proxy?: ServeProxy[]
+  compress?: boolean
+  cacheControl?: ServeCacheControl[]
   onRequest?: (args: ServeOnRequestArgs) => void
 }
 
+export interface Budget {
+  /** A glob pattern such as "assets/*.js" matched against output paths relative to the output directory (patterns without "/" match the file name) */
+  path: string
+  /** The maximum size of each matching output file in bytes */
+  maxBytes?: number
+  /** The maximum size of each matching output file in bytes after gzip compression */
+  maxGzipBytes?: number
+  /** Report a warning instead of an error when this budget is exceeded */
+  warn?: boolean
+}
+
+export interface ServeCacheControl {
+  /** A glob pattern such as "/assets/**" (patterns without "/" match the file name) */
+  path: string
+  /** The value of the "Cache-Control" header */
+  value: string
+}
+
+export interface ServeProxy {
+  /** Matches this path and everything below it (e.g. "/api") */
+  path: string
+  /** The upstream server (e.g. "http://localhost:3000") */
+  target: string
+  /** Replaces the matched path prefix before forwarding ("/" removes it) */
+  rewrite?: string
+  /** Extra request headers to send to the upstream server */
+  headers?: Record<string, string>
+}
+
 export interface ServeOnRequestArgs {
   remoteAddress: string
   method: string
@@ -285,6 +336,11 @@ export interface TransformFailure extends Error {
   warnings: Message[]
 }
 
+export interface TransformManyInput {
+  input: string | Uint8Array
+  options?: TransformOptions
+}
+
 export interface Plugin {
   name: string
   setup: (build: PluginBuild) => (void | Promise<void>)
@@ -313,6 +369,23 @@ export interface PluginBuild {
   onLoad(options: OnLoadOptions, callback: (args: OnLoadArgs) =>
     (OnLoadResult | null | undefined | Promise<OnLoadResult | null | undefined>)): void
 
+  /**
+   * Runs after a file has been loaded. Every matching callback runs in the
+   * order it was registered, and each one receives the output of the previous
+   * one. A returned source map must map the new contents to the old ones.
+   */
+  onTransform(options: OnTransformOptions, callback: (args: OnTransformArgs) =>
+    (OnTransformResult | null | undefined | Promise<OnTransformResult | null | undefined>)): void
+
+  /**
+   * Runs on the contents of each output chunk before its hash is computed.
+   * The contents contain placeholders for the paths of other output files
+   * which must be preserved. A returned source map must map the new contents
+   * to the old ones.
+   */
+  onRenderChunk(callback: (args: OnRenderChunkArgs) =>
+    (OnRenderChunkResult | null | undefined | Promise<OnRenderChunkResult | null | undefined>)): void
+
   /** Documentation: https://esbuild.github.io/plugins/#on-dispose */
   onDispose(callback: () => void): void
 
@@ -322,6 +395,7 @@ export interface PluginBuild {
     build: typeof build,
     buildSync: typeof buildSync,
     transform: typeof transform,
+    transformMany: typeof transformMany,
     transformSync: typeof transformSync,
     formatMessages: typeof formatMessages,
     formatMessagesSync: typeof formatMessagesSync,
@@ -340,6 +414,7 @@ export interface ResolveOptions {
   resolveDir?: string
   kind?: ImportKind
   pluginData?: any
+  with?: Record<string, string>
 }
 
 /** Documentation: https://esbuild.github.io/plugins/#resolve-results */
@@ -379,6 +454,7 @@ export interface OnResolveArgs {
   resolveDir: string
   kind: ImportKind
   pluginData: any
+  with: Record<string, string>
 }
 
 export type ImportKind =
@@ -425,6 +501,7 @@ export interface OnLoadArgs {
   namespace: string
   suffix: string
   pluginData: any
+  with: Record<string, string>
 }
 
 /** Documentation: https://esbuild.github.io/plugins/#on-load-results */
@@ -435,6 +512,8 @@ export interface OnLoadResult {
   warnings?: PartialMessage[]
 
   contents?: string | Uint8Array
+  /** Maps the contents to the original files they were compiled from (used instead of any "sourceMappingURL" comment) */
+  sourceMap?: string | object
   resolveDir?: string
   loader?: Loader
   pluginData?: any
@@ -443,6 +522,53 @@ export interface OnLoadResult {
   watchDirs?: string[]
 }
 
+export interface OnTransformOptions {
+  filter: RegExp
+  namespace?: string
+}
+
+export interface OnTransformArgs {
+  path: string
+  namespace: string
+  suffix: string
+  contents: string
+  loader: Loader
+  pluginData: any
+  with: Record<string, string>
+}
+
+export interface OnTransformResult {
+  pluginName?: string
+
+  errors?: PartialMessage[]
+  warnings?: PartialMessage[]
+
+  contents?: string | Uint8Array
+  sourceMap?: string | object
+  loader?: Loader
+  pluginData?: any
+
+  watchFiles?: string[]
+  watchDirs?: string[]
+}
+
+export interface OnRenderChunkArgs {
+  /** Relative to the output directory, with any "[hash]" placeholder intact */
+  pathTemplate: string
+  entryPoint: string
+  contents: string
+}
+
+export interface OnRenderChunkResult {
+  pluginName?: string
+
+  errors?: PartialMessage[]
+  warnings?: PartialMessage[]
+
+  contents?: string | Uint8Array
+  sourceMap?: string | object
+}
+
 export interface PartialMessage {
   id?: string
   pluginName?: string
@@ -477,6 +603,14 @@ export interface Metafile {
       inputs: {
         [path: string]: {
           bytesInOutput: number
+          /** Only present with "metafileIncludedBy" */
+          includedBy?: MetafileIncludedByStep[]
+          /** Only present with "metafileIncludedBy" */
+          parts?: {
+            line: number
+            column: number
+            includedBy: MetafileIncludedByStep[]
+          }[]
         }
       }
       imports: {
@@ -486,11 +620,20 @@ export interface Metafile {
       }[]
       exports: string[]
       entryPoint?: string
+      manualChunk?: string
       cssBundle?: string
     }
   }
 }
 
+/** The first step is the most specific reason and the last step is an entry point */
+export interface MetafileIncludedByStep {
+  kind: ImportKind | 'entry-point' | 'used-by' | 'side-effects' | 'no-tree-shaking'
+  path: string
+  line?: number
+  column?: number
+}
+
 export interface FormatMessagesOptions {
   kind: 'error' | 'warning'
   color?: boolean
@@ -500,9 +643,22 @@ export interface FormatMessagesOptions {
 export interface AnalyzeMetafileOptions {
   color?: boolean
   verbose?: boolean
+  /** Explains why each input whose path contains this text is in each output file */
+  why?: string
+  /** Returns a self-contained HTML page with a treemap of the output files instead of a text table */
+  html?: boolean
+  /** Another metafile to compare against, which reports the size changes of each output file and package */
+  baseline?: Metafile | string
 }
 
 export interface WatchOptions {
+  backend?: 'polling' | 'native'
+  /** In milliseconds */
+  pollInterval?: number
+  /** In milliseconds */
+  debounce?: number
+  /** Glob patterns relative to the working directory */
+  ignore?: string[]
 }
 
 export interface BuildContext<ProvidedOptions extends BuildOptions = BuildOptions> {
@@ -560,6 +716,18 @@ export declare function context<T extends BuildOptions>(options: SameShape<Build
  */
 export declare function transform<T extends TransformOptions>(input: string | Uint8Array, options?: SameShape<TransformOptions, T>): Promise<TransformResult<T>>
 
+/**
+ * This function transforms many files in parallel with a single request. It's
+ * the same as calling "transform" for each input, except that it avoids the
+ * per-call overhead and only validates shared options once. It returns a
+ * promise for an array with either a "TransformResult" object or a
+ * "TransformFailure" object for each input, in the same order as the inputs.
+ *
+ * - Works in node: yes
+ * - Works in browser: yes
+ */
+export declare function transformMany(inputs: TransformManyInput[]): Promise<(TransformResult | TransformFailure)[]>
+
 /**
  * Converts log messages to formatted message strings suitable for printing in
  * the terminal. This allows you to reuse the built-in behavior of esbuild's
@@ -0,0 +1,527 @@
+package api
+
+// This file implements the "HTML" and "Baseline" modes of the
+// "AnalyzeMetafile" API. The default text table is in "api_impl.go".
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/evanw/esbuild/internal/helpers"
+	"github.com/evanw/esbuild/internal/js_ast"
+	"github.com/evanw/esbuild/internal/js_parser"
+	"github.com/evanw/esbuild/internal/logger"
+)
+
+type metafileOutput struct {
+	path   string
+	bytes  int
+	inputs []metafileEntry
+
+	// Output paths often contain content hashes that change from build to build,
+	// so outputs are matched using the entry point or manual chunk name instead
+	// when there is one
+	key string
+}
+
+func parseMetafileOutputs(metafile string) ([]metafileOutput, bool) {
+	log := logger.NewDeferLog(logger.DeferLogNoVerboseOrDebug, nil)
+	source := logger.Source{Contents: metafile}
+	result, ok := js_parser.ParseJSON(log, source, js_parser.JSONOptions{})
+	if !ok {
+		return nil, false
+	}
+	outputs := getObjectPropertyObject(result, "outputs")
+	if outputs == nil {
+		return nil, false
+	}
+
+	var list []metafileOutput
+	for _, output := range outputs.Properties {
+		path := helpers.UTF16ToString(output.Key.Data.(*js_ast.EString).Value)
+		if strings.HasSuffix(path, ".map") {
+			continue
+		}
+		bytes := getObjectPropertyNumber(output.ValueOrNil, "bytes")
+		if bytes == nil {
+			continue
+		}
+		item := metafileOutput{path: path, key: path, bytes: int(bytes.Value)}
+
+		// Include the extension in the key since an entry point can have both a
+		// ".js" output file and a ".css" output file
+		ext := ""
+		if dot := strings.LastIndexByte(path, '.'); dot > strings.LastIndexByte(path, '/') {
+			ext = path[dot:]
+		}
+		if entryPoint := getObjectPropertyString(output.ValueOrNil, "entryPoint"); entryPoint != nil {
+			item.key = "entry " + helpers.UTF16ToString(entryPoint.Value) + ext
+		} else if manualChunk := getObjectPropertyString(output.ValueOrNil, "manualChunk"); manualChunk != nil {
+			item.key = "chunk " + helpers.UTF16ToString(manualChunk.Value) + ext
+		}
+
+		if inputs := getObjectPropertyObject(output.ValueOrNil, "inputs"); inputs != nil {
+			for _, input := range inputs.Properties {
+				if bytesInOutput := getObjectPropertyNumber(input.ValueOrNil, "bytesInOutput"); bytesInOutput != nil && bytesInOutput.Value > 0 {
+					item.inputs = append(item.inputs, metafileEntry{
+						name: helpers.UTF16ToString(input.Key.Data.(*js_ast.EString).Value),
+						size: int(bytesInOutput.Value),
+					})
+				}
+			}
+		}
+		list = append(list, item)
+	}
+	return list, true
+}
+
+// Inputs are grouped by the package that contains them. Inputs that aren't
+// inside a "node_modules" directory belong to the project itself.
+func packageNameForInput(path string) string {
+	i := strings.LastIndex(path, "node_modules/")
+	if i < 0 {
+		return "(project)"
+	}
+	rest := path[i+len("node_modules/"):]
+	parts := strings.SplitN(rest, "/", 3)
+	if strings.HasPrefix(rest, "@") && len(parts) >= 2 {
+		return parts[0] + "/" + parts[1]
+	}
+	return parts[0]
+}
+
+type metafileSizeDiff struct {
+	name     string
+	before   int
+	after    int
+	inBefore bool
+	inAfter  bool
+}
+
+func analyzeMetafileDiff(baseline string, metafile string, opts AnalyzeMetafileOptions) string {
+	beforeOutputs, ok := parseMetafileOutputs(baseline)
+	if !ok {
+		return ""
+	}
+	afterOutputs, ok := parseMetafileOutputs(metafile)
+	if !ok {
+		return ""
+	}
+
+	// Match up output files using their keys
+	var outputs []*metafileSizeDiff
+	outputsByKey := make(map[string]*metafileSizeDiff)
+	for _, output := range afterOutputs {
+		diff := &metafileSizeDiff{name: output.path, after: output.bytes, inAfter: true}
+		outputsByKey[output.key] = diff
+		outputs = append(outputs, diff)
+	}
+	for _, output := range beforeOutputs {
+		diff, ok := outputsByKey[output.key]
+		if !ok {
+			diff = &metafileSizeDiff{name: output.path}
+			outputsByKey[output.key] = diff
+			outputs = append(outputs, diff)
+		}
+		diff.before += output.bytes
+		diff.inBefore = true
+	}
+
+	// Sum up the bytes of each package over all output files
+	var packages []*metafileSizeDiff
+	packagesByName := make(map[string]*metafileSizeDiff)
+	addPackages := func(outputs []metafileOutput, isAfter bool) {
+		for _, output := range outputs {
+			for _, input := range output.inputs {
+				name := packageNameForInput(input.name)
+				diff, ok := packagesByName[name]
+				if !ok {
+					diff = &metafileSizeDiff{name: name}
+					packagesByName[name] = diff
+					packages = append(packages, diff)
+				}
+				if isAfter {
+					diff.after += input.size
+					diff.inAfter = true
+				} else {
+					diff.before += input.size
+					diff.inBefore = true
+				}
+			}
+		}
+	}
+	addPackages(afterOutputs, true)
+	addPackages(beforeOutputs, false)
+
+	total := &metafileSizeDiff{name: "(total)", inBefore: true, inAfter: true}
+	for _, output := range outputs {
+		total.before += output.before
+		total.after += output.after
+	}
+
+	var colors logger.Colors
+	if opts.Color {
+		colors = logger.TerminalColors
+	}
+
+	sb := strings.Builder{}
+	writeTable := func(title string, rows []*metafileSizeDiff) {
+		// Only show rows that changed unless we're in verbose mode
+		var shown []*metafileSizeDiff
+		for _, row := range rows {
+			if opts.Verbose || row.before != row.after || row.inBefore != row.inAfter {
+				shown = append(shown, row)
+			}
+		}
+		sort.SliceStable(shown, func(i int, j int) bool {
+			a, b := absInt(shown[i].after-shown[i].before), absInt(shown[j].after-shown[j].before)
+			return a > b || (a == b && shown[i].name < shown[j].name)
+		})
+		if title == "Outputs" {
+			shown = append(shown, total)
+		} else if len(shown) == 0 {
+			return
+		}
+
+		type tableRow struct {
+			cells  [4]string
+			colors [4]string
+		}
+		table := []tableRow{{cells: [4]string{title, "Before", "After", "Change"}, colors: [4]string{colors.Bold, colors.Bold, colors.Bold, colors.Bold}}}
+		for _, row := range shown {
+			before, after := "-", "-"
+			if row.inBefore {
+				before = strings.TrimRight(prettyPrintByteCount(row.before), " ")
+			}
+			if row.inAfter {
+				after = strings.TrimRight(prettyPrintByteCount(row.after), " ")
+			}
+
+			var change string
+			delta := row.after - row.before
+			switch {
+			case !row.inBefore:
+				change = "+" + strings.TrimRight(prettyPrintByteCount(delta), " ") + " (added)"
+			case !row.inAfter:
+				change = "-" + strings.TrimRight(prettyPrintByteCount(-delta), " ") + " (removed)"
+			case delta == 0:
+				change = "0b"
+			default:
+				sign := "+"
+				if delta < 0 {
+					sign = "-"
+				}
+				change = sign + strings.TrimRight(prettyPrintByteCount(absInt(delta)), " ")
+				if row.before > 0 {
+					change += fmt.Sprintf(" (%s%.1f%%)", sign, 100*float64(absInt(delta))/float64(row.before))
+				}
+			}
+
+			// Size increases are red and size decreases are green
+			changeColor := colors.Dim
+			if delta > 0 {
+				changeColor = colors.Red
+			} else if delta < 0 {
+				changeColor = colors.Green
+			}
+			table = append(table, tableRow{cells: [4]string{row.name, before, after, change}, colors: [4]string{"", "", "", changeColor}})
+		}
+
+		// Calculate column widths
+		var widths [4]int
+		for _, row := range table {
+			for i, cell := range row.cells {
+				if n := len([]rune(cell)); n > widths[i] {
+					widths[i] = n
+				}
+			}
+		}
+
+		sb.WriteString("\n")
+		for _, row := range table {
+			sb.WriteString(" ")
+			for i, cell := range row.cells {
+				padding := strings.Repeat(" ", widths[i]-len([]rune(cell)))
+				sb.WriteString(" ")
+				if row.colors[i] != "" {
+					sb.WriteString(row.colors[i])
+				}
+				if i == 0 || i == 3 {
+					// Names and changes are left-aligned
+					sb.WriteString(cell)
+					if row.colors[i] != "" {
+						sb.WriteString(colors.Reset)
+					}
+					if i == 0 {
+						sb.WriteString(padding)
+					}
+				} else {
+					// Sizes are right-aligned
+					sb.WriteString(padding)
+					sb.WriteString(cell)
+					if row.colors[i] != "" {
+						sb.WriteString(colors.Reset)
+					}
+				}
+				if i < 3 {
+					sb.WriteString(" ")
+				}
+			}
+			sb.WriteString("\n")
+		}
+	}
+
+	writeTable("Outputs", outputs)
+	writeTable("Packages", packages)
+	return sb.String()
+}
+
+func absInt(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
+
+type treemapNode struct {
+	name     string
+	size     int
+	children []*treemapNode
+	byName   map[string]*treemapNode
+}
+
+func (node *treemapNode) child(name string) *treemapNode {
+	if child, ok := node.byName[name]; ok {
+		return child
+	}
+	child := &treemapNode{name: name, byName: make(map[string]*treemapNode)}
+	node.byName[name] = child
+	node.children = append(node.children, child)
+	return child
+}
+
+// Directories with only a single child directory are merged into one node so
+// that paths like "node_modules/lodash" take up a single level in the treemap
+func (node *treemapNode) collapse() {
+	for len(node.children) == 1 && len(node.children[0].children) > 0 && node.name != "" {
+		only := node.children[0]
+		node.name += "/" + only.name
+		node.children = only.children
+	}
+	for _, child := range node.children {
+		child.collapse()
+	}
+	sort.SliceStable(node.children, func(i int, j int) bool {
+		return node.children[i].size > node.children[j].size
+	})
+}
+
+func (node *treemapNode) writeJSON(sb *strings.Builder) {
+	sb.WriteString(fmt.Sprintf("{\"n\":%s,\"s\":%d", helpers.QuoteForJSON(node.name, false), node.size))
+	if len(node.children) > 0 {
+		sb.WriteString(",\"c\":[")
+		for i, child := range node.children {
+			if i > 0 {
+				sb.WriteByte(',')
+			}
+			child.writeJSON(sb)
+		}
+		sb.WriteByte(']')
+	}
+	sb.WriteByte('}')
+}
+
+func analyzeMetafileHTML(metafile string) string {
+	outputs, ok := parseMetafileOutputs(metafile)
+	if !ok {
+		return ""
+	}
+
+	// Each output file is a top-level node and each input file is placed in
+	// the tree according to its path
+	root := &treemapNode{byName: make(map[string]*treemapNode)}
+	for _, output := range outputs {
+		outputNode := &treemapNode{name: output.path, byName: make(map[string]*treemapNode)}
+		for _, input := range output.inputs {
+			node := outputNode
+			node.size += input.size
+			for _, part := range strings.Split(input.name, "/") {
+				node = node.child(part)
+				node.size += input.size
+			}
+		}
+
+		// Account for code that isn't attributed to any input file
+		if output.bytes > outputNode.size {
+			other := outputNode.child("(other)")
+			other.size = output.bytes - outputNode.size
+			outputNode.size = output.bytes
+		}
+		if outputNode.size > 0 {
+			root.children = append(root.children, outputNode)
+			root.size += outputNode.size
+		}
+	}
+	for _, child := range root.children {
+		for _, grandchild := range child.children {
+			grandchild.collapse()
+		}
+		sort.SliceStable(child.children, func(i int, j int) bool {
+			return child.children[i].size > child.children[j].size
+		})
+	}
+	sort.SliceStable(root.children, func(i int, j int) bool {
+		return root.children[i].size > root.children[j].size
+	})
+
+	data := strings.Builder{}
+	root.writeJSON(&data)
+
+	// Make sure the JSON can't end the script tag early
+	json := strings.ReplaceAll(data.String(), "</", "<\\/")
+	return strings.Replace(treemapHTML, "/*DATA*/", json, 1)
+}
+
+// This page has no external dependencies so that it can be saved to a file,
+// attached to a CI run, and opened later
+const treemapHTML = `<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>Bundle analysis</title>
+<style>
+  body { margin: 0; font: 12px sans-serif; background: #222; color: #fff; }
+  #bar { height: 24px; line-height: 24px; padding: 0 8px; background: #111; white-space: nowrap; overflow: hidden; }
+  #bar a { color: #8cf; cursor: pointer; }
+  #map { position: absolute; left: 0; top: 24px; right: 0; bottom: 0; overflow: hidden; }
+  .node { position: absolute; box-sizing: border-box; border: 1px solid rgba(0, 0, 0, 0.4); overflow: hidden; cursor: pointer; }
+  .node:hover { filter: brightness(1.2); }
+  .label { padding: 1px 3px; height: 14px; line-height: 14px; white-space: nowrap; overflow: hidden; text-overflow: ellipsis; color: #000; }
+</style>
+</head>
+<body>
+<div id="bar"></div>
+<div id="map"></div>
+<script>
+(function() {
+  var data = /*DATA*/;
+  var bar = document.getElementById('bar');
+  var map = document.getElementById('map');
+  var stack = [data];
+
+  function bytes(n) {
+    if (n < 1024) return n + 'b';
+    if (n < 1024 * 1024) return (n / 1024).toFixed(1) + 'kb';
+    return (n / (1024 * 1024)).toFixed(1) + 'mb';
+  }
+
+  function worst(row, sum, side) {
+    var max = 0, min = Infinity;
+    for (var i = 0; i < row.length; i++) {
+      max = Math.max(max, row[i].a);
+      min = Math.min(min, row[i].a);
+    }
+    return Math.max(side * side * max / (sum * sum), sum * sum / (side * side * min));
+  }
+
+  // This is the "squarified" treemap layout algorithm
+  function squarify(nodes, x, y, w, h) {
+    var total = 0, rects = [], row = [], rowSum = 0, i = 0;
+    for (var j = 0; j < nodes.length; j++) total += nodes[j].s;
+    if (!total || w <= 0 || h <= 0) return rects;
+    var items = nodes.filter(function(n) { return n.s > 0; }).map(function(n) { return { n: n, a: n.s * w * h / total }; });
+
+    function flush() {
+      if (w >= h) {
+        var cw = rowSum / h, cy = y;
+        for (var k = 0; k < row.length; k++) {
+          var rh = row[k].a / cw;
+          rects.push({ n: row[k].n, x: x, y: cy, w: cw, h: rh });
+          cy += rh;
+        }
+        x += cw; w -= cw;
+      } else {
+        var rh2 = rowSum / w, cx = x;
+        for (var k2 = 0; k2 < row.length; k2++) {
+          var rw = row[k2].a / rh2;
+          rects.push({ n: row[k2].n, x: cx, y: y, w: rw, h: rh2 });
+          cx += rw;
+        }
+        y += rh2; h -= rh2;
+      }
+      row = []; rowSum = 0;
+    }
+
+    while (i < items.length) {
+      var side = Math.min(w, h), item = items[i];
+      if (!row.length || worst(row.concat(item), rowSum + item.a, side) <= worst(row, rowSum, side)) {
+        row.push(item); rowSum += item.a; i++;
+      } else {
+        flush();
+      }
+    }
+    if (row.length) flush();
+    return rects;
+  }
+
+  function render(parent, node, x, y, w, h, depth, path) {
+    var div = document.createElement('div');
+    div.className = 'node';
+    div.style.left = x + 'px';
+    div.style.top = y + 'px';
+    div.style.width = w + 'px';
+    div.style.height = h + 'px';
+    div.style.background = 'hsl(' + ((path.length * 47 + depth * 13) % 360) + ', 50%, ' + (70 - depth * 6) + '%)';
+    div.title = path.concat(node.n).join('/') + ' (' + bytes(node.s) + ')';
+    parent.appendChild(div);
+    if (w > 30 && h > 14) {
+      var label = document.createElement('div');
+      label.className = 'label';
+      label.textContent = node.n + ' ' + bytes(node.s);
+      div.appendChild(label);
+    }
+    if (node.c && w > 30 && h > 30 && depth < 12) {
+      var rects = squarify(node.c, 0, 16, w - 2, h - 18);
+      for (var i = 0; i < rects.length; i++) {
+        var r = rects[i];
+        render(div, r.n, r.x, r.y, r.w, r.h, depth + 1, path.concat(node.n));
+      }
+    }
+    div.onclick = function(e) {
+      e.stopPropagation();
+      if (node.c) {
+        stack.push(node);
+        draw();
+      }
+    };
+  }
+
+  function draw() {
+    var top = stack[stack.length - 1];
+    map.textContent = '';
+    bar.textContent = '';
+    for (var i = 0; i < stack.length; i++) {
+      (function(i) {
+        if (i > 0) bar.appendChild(document.createTextNode(' / '));
+        var a = document.createElement('a');
+        a.textContent = i ? stack[i].n : 'all outputs';
+        a.onclick = function() { stack.length = i + 1; draw(); };
+        bar.appendChild(a);
+      })(i);
+    }
+    bar.appendChild(document.createTextNode(' (' + bytes(top.s) + ')'));
+    var rects = squarify(top.c || [], 0, 0, map.clientWidth, map.clientHeight);
+    for (var j = 0; j < rects.length; j++) {
+      var r = rects[j];
+      render(map, r.n, r.x, r.y, r.w, r.h, 0, []);
+    }
+  }
+
+  window.onresize = draw;
+  draw();
+})();
+</script>
+</body>
+</html>
+`
@@ -0,0 +1,108 @@
+package api
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/evanw/esbuild/internal/test"
+)
+
+func TestPackageNameForInput(t *testing.T) {
+	test.AssertEqual(t, packageNameForInput("src/index.js"), "(project)")
+	test.AssertEqual(t, packageNameForInput("node_modules/lodash/cloneDeep.js"), "lodash")
+	test.AssertEqual(t, packageNameForInput("node_modules/@scope/pkg/lib/index.js"), "@scope/pkg")
+	test.AssertEqual(t, packageNameForInput("node_modules/a/node_modules/b/index.js"), "b")
+	test.AssertEqual(t, packageNameForInput("../node_modules/react/index.js"), "react")
+}
+
+const analyzeBaselineMetafile = `{
+  "outputs": {
+    "out/entry-AAAAAAAA.js": {
+      "entryPoint": "src/entry.js",
+      "inputs": {
+        "src/entry.js": { "bytesInOutput": 100 },
+        "node_modules/lodash/cloneDeep.js": { "bytesInOutput": 2000 }
+      },
+      "bytes": 2200
+    },
+    "out/entry-AAAAAAAA.js.map": {
+      "inputs": {},
+      "bytes": 5000
+    },
+    "out/chunk-BBBBBBBB.js": {
+      "inputs": {
+        "src/shared.js": { "bytesInOutput": 300 }
+      },
+      "bytes": 300
+    }
+  }
+}`
+
+const analyzeCurrentMetafile = `{
+  "outputs": {
+    "out/entry-CCCCCCCC.js": {
+      "entryPoint": "src/entry.js",
+      "inputs": {
+        "src/entry.js": { "bytesInOutput": 150 },
+        "node_modules/@scope/pkg/index.js": { "bytesInOutput": 50 }
+      },
+      "bytes": 250
+    },
+    "out/vendor-DDDDDDDD.js": {
+      "manualChunk": "vendor",
+      "inputs": {
+        "node_modules/lodash/cloneDeep.js": { "bytesInOutput": 2000 }
+      },
+      "bytes": 2000
+    }
+  }
+}`
+
+func TestAnalyzeMetafileBaseline(t *testing.T) {
+	test.AssertEqualWithDiff(t, AnalyzeMetafile(analyzeCurrentMetafile, AnalyzeMetafileOptions{Baseline: analyzeBaselineMetafile}), `
+  Outputs                 Before  After  Change
+  out/vendor-DDDDDDDD.js       -  2.0kb  +2.0kb (added)
+  out/entry-CCCCCCCC.js    2.1kb   250b  -1.9kb (-88.6%)
+  out/chunk-BBBBBBBB.js     300b      -  -300b (removed)
+  (total)                  2.4kb  2.2kb  -250b (-10.0%)
+
+  Packages    Before  After  Change
+  (project)     400b   150b  -250b (-62.5%)
+  @scope/pkg       -    50b  +50b (added)
+`)
+
+	// Verbose mode also shows the things that didn't change
+	test.AssertEqualWithDiff(t, AnalyzeMetafile(analyzeCurrentMetafile, AnalyzeMetafileOptions{Baseline: analyzeBaselineMetafile, Verbose: true}), `
+  Outputs                 Before  After  Change
+  out/vendor-DDDDDDDD.js       -  2.0kb  +2.0kb (added)
+  out/entry-CCCCCCCC.js    2.1kb   250b  -1.9kb (-88.6%)
+  out/chunk-BBBBBBBB.js     300b      -  -300b (removed)
+  (total)                  2.4kb  2.2kb  -250b (-10.0%)
+
+  Packages    Before  After  Change
+  (project)     400b   150b  -250b (-62.5%)
+  @scope/pkg       -    50b  +50b (added)
+  lodash       2.0kb  2.0kb  0b
+`)
+
+	// Comparing a metafile against itself doesn't report any changes
+	test.AssertEqualWithDiff(t, AnalyzeMetafile(analyzeCurrentMetafile, AnalyzeMetafileOptions{Baseline: analyzeCurrentMetafile}), `
+  Outputs  Before  After  Change
+  (total)   2.2kb  2.2kb  0b
+`)
+
+	test.AssertEqual(t, AnalyzeMetafile(analyzeCurrentMetafile, AnalyzeMetafileOptions{Baseline: "{"}), "")
+}
+
+func TestAnalyzeMetafileHTML(t *testing.T) {
+	html := AnalyzeMetafile(analyzeCurrentMetafile, AnalyzeMetafileOptions{HTML: true})
+	test.AssertEqual(t, strings.HasPrefix(html, "<!DOCTYPE html>"), true)
+	test.AssertEqual(t, strings.Contains(html, `var data = {"n":"","s":2250,"c":[`+
+		`{"n":"out/vendor-DDDDDDDD.js","s":2000,"c":[{"n":"node_modules/lodash","s":2000,"c":[{"n":"cloneDeep.js","s":2000}]}]},`+
+		`{"n":"out/entry-CCCCCCCC.js","s":250,"c":[{"n":"src","s":150,"c":[{"n":"entry.js","s":150}]},`+
+		`{"n":"node_modules/@scope/pkg","s":50,"c":[{"n":"index.js","s":50}]},{"n":"(other)","s":50}]}]};`), true)
+
+	// Paths can't end the script tag early
+	html = AnalyzeMetafile(`{"outputs":{"</script>.js":{"inputs":{},"bytes":1}}}`, AnalyzeMetafileOptions{HTML: true})
+	test.AssertEqual(t, strings.Contains(html, `{"n":"<\/script>.js","s":1,`), true)
+}
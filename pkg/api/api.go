@@ -147,6 +147,7 @@ const (
 	LoaderEmpty
 	LoaderFile
 	LoaderGlobalCSS
+	LoaderHTML
 	LoaderJS
 	LoaderJSON
 	LoaderJSX
@@ -154,6 +155,7 @@ const (
 	LoaderText
 	LoaderTS
 	LoaderTSX
+	LoaderWasm
 )
 
 type Platform uint8
@@ -181,6 +183,14 @@ const (
 	PackagesExternal
 )
 
+type WasmBytes uint8
+
+const (
+	WasmBytesDefault WasmBytes = iota
+	WasmBytesInline
+	WasmBytesFile
+)
+
 type Engine struct {
 	Name    EngineName
 	Version string
@@ -271,9 +281,12 @@ type BuildOptions struct {
 	LogLimit    int                 // Documentation: https://esbuild.github.io/api/#log-limit
 	LogOverride map[string]LogLevel // Documentation: https://esbuild.github.io/api/#log-override
 
-	Sourcemap      SourceMap      // Documentation: https://esbuild.github.io/api/#sourcemap
-	SourceRoot     string         // Documentation: https://esbuild.github.io/api/#source-root
-	SourcesContent SourcesContent // Documentation: https://esbuild.github.io/api/#sources-content
+	Sourcemap           SourceMap      // Documentation: https://esbuild.github.io/api/#sourcemap
+	SourceRoot          string         // Documentation: https://esbuild.github.io/api/#source-root
+	SourcesContent      SourcesContent // Documentation: https://esbuild.github.io/api/#sources-content
+	SourcemapIgnoreList []string       // Glob patterns for sources that debuggers should skip (nil means "**/node_modules/**")
+	SourcemapSections   bool           // Generates index source maps with one section per file, which is faster for large bundles
+	DebugIDs            bool           // Adds a "debugId" to each output file and its source map so they can be matched without URLs
 
 	Target    Target          // Documentation: https://esbuild.github.io/api/#target
 	Engines   []Engine        // Documentation: https://esbuild.github.io/api/#target
@@ -309,11 +322,14 @@ type BuildOptions struct {
 	Bundle            bool              // Documentation: https://esbuild.github.io/api/#bundle
 	PreserveSymlinks  bool              // Documentation: https://esbuild.github.io/api/#preserve-symlinks
 	Splitting         bool              // Documentation: https://esbuild.github.io/api/#splitting
+	HMR               bool              // Makes modules individually replaceable at run time via "import.meta.hot" (requires "Bundle")
 	Outfile           string            // Documentation: https://esbuild.github.io/api/#outfile
 	Metafile          bool              // Documentation: https://esbuild.github.io/api/#metafile
 	Outdir            string            // Documentation: https://esbuild.github.io/api/#outdir
 	Outbase           string            // Documentation: https://esbuild.github.io/api/#outbase
 	AbsWorkingDir     string            // Documentation: https://esbuild.github.io/api/#working-directory
+	CacheDir          string            // Persists parse results in this directory so they can be reused across processes
+	FS                FS                // Reads input files from this file system instead of from the real one
 	Platform          Platform          // Documentation: https://esbuild.github.io/api/#platform
 	Format            Format            // Documentation: https://esbuild.github.io/api/#format
 	External          []string          // Documentation: https://esbuild.github.io/api/#external
@@ -327,6 +343,7 @@ type BuildOptions struct {
 	TsconfigRaw       string            // Documentation: https://esbuild.github.io/api/#tsconfig-raw
 	OutExtension      map[string]string // Documentation: https://esbuild.github.io/api/#out-extension
 	PublicPath        string            // Documentation: https://esbuild.github.io/api/#public-path
+	WasmBytes         WasmBytes         // Whether the "wasm" loader embeds the module's bytes or emits them as a separate file (default inline)
 	Inject            []string          // Documentation: https://esbuild.github.io/api/#inject
 	Banner            map[string]string // Documentation: https://esbuild.github.io/api/#banner
 	Footer            map[string]string // Documentation: https://esbuild.github.io/api/#footer
@@ -336,6 +353,12 @@ type BuildOptions struct {
 	ChunkNames string // Documentation: https://esbuild.github.io/api/#chunk-names
 	AssetNames string // Documentation: https://esbuild.github.io/api/#asset-names
 
+	ManualChunks map[string][]string // Forces modules matching these package names or paths into a chunk with the given name (requires "Splitting")
+
+	MetafileIncludedBy bool // Records in the metafile why each input file and top-level statement survived tree shaking (requires "Metafile")
+
+	Budgets []Budget // Fails the build (or warns) when output files are larger than these limits
+
 	EntryPoints         []string     // Documentation: https://esbuild.github.io/api/#entry-points
 	EntryPointsAdvanced []EntryPoint // Documentation: https://esbuild.github.io/api/#entry-points
 
@@ -357,6 +380,36 @@ type StdinOptions struct {
 	Loader     Loader
 }
 
+// This lets a build read its input files from somewhere other than the real
+// file system (e.g. from memory or from a database). Paths passed to these
+// methods are always absolute and always use Unix-style forward slashes
+// regardless of the host operating system. Relative paths in the build options
+// are resolved relative to "AbsWorkingDir", which defaults to "/". This only
+// affects reading. Output files are still written to the real file system
+// when "Write" is enabled.
+type FS interface {
+	// This should return an error for which "errors.Is(err, os.ErrNotExist)"
+	// is true if the directory doesn't exist. Entry names are just the base
+	// name of each entry, not the full path.
+	ReadDirectory(path string) ([]FSEntry, error)
+
+	// This should return an error for which "errors.Is(err, os.ErrNotExist)"
+	// is true if the file doesn't exist.
+	ReadFile(path string) ([]byte, error)
+
+	// This should return a string that changes whenever the contents of the
+	// file change (e.g. a modification time or a version number). It's used to
+	// avoid re-parsing unchanged files when rebuilding and to detect changes in
+	// watch mode. Return an empty string if this isn't available, in which case
+	// the file contents are compared instead.
+	ModKey(path string) (string, error)
+}
+
+type FSEntry struct {
+	Name  string
+	IsDir bool
+}
+
 type BuildResult struct {
 	Errors   []Message
 	Warnings []Message
@@ -402,9 +455,10 @@ type TransformOptions struct {
 	LogLimit    int                 // Documentation: https://esbuild.github.io/api/#log-limit
 	LogOverride map[string]LogLevel // Documentation: https://esbuild.github.io/api/#log-override
 
-	Sourcemap      SourceMap      // Documentation: https://esbuild.github.io/api/#sourcemap
-	SourceRoot     string         // Documentation: https://esbuild.github.io/api/#source-root
-	SourcesContent SourcesContent // Documentation: https://esbuild.github.io/api/#sources-content
+	Sourcemap           SourceMap      // Documentation: https://esbuild.github.io/api/#sourcemap
+	SourceRoot          string         // Documentation: https://esbuild.github.io/api/#source-root
+	SourcesContent      SourcesContent // Documentation: https://esbuild.github.io/api/#sources-content
+	SourcemapIgnoreList []string       // Glob patterns for sources that debuggers should skip (nil means "**/node_modules/**")
 
 	Target    Target          // Documentation: https://esbuild.github.io/api/#target
 	Engines   []Engine        // Documentation: https://esbuild.github.io/api/#target
@@ -464,6 +518,24 @@ func Transform(input string, options TransformOptions) TransformResult {
 	return transformImpl(input, options)
 }
 
+type TransformInput struct {
+	Contents string
+	Options  TransformOptions
+}
+
+// This is the same as calling "Transform" on each input except that the inputs
+// are transformed in parallel, and options that are shared between inputs are
+// only validated once. The results are returned in the same order as the
+// inputs. Errors in one input don't affect the results of the other inputs.
+//
+// Unlike "Build", parsed files aren't cached between inputs. A shared source
+// index cache would give every distinct file name its own source index, and
+// later inputs would then allocate arrays sized by the largest index. The
+// runtime code is already parsed only once per process regardless.
+func TransformMany(inputs []TransformInput) []TransformResult {
+	return transformManyImpl(inputs)
+}
+
 ////////////////////////////////////////////////////////////////////////////////
 // Context API
 
@@ -476,6 +548,61 @@ type ServeOptions struct {
 	Certfile  string
 	Fallback  string
 	OnRequest func(ServeOnRequestArgs)
+
+	// Inject a script into served HTML pages that shows build errors on top of
+	// the page. HTML pages are still served while the build has errors.
+	ErrorOverlay bool
+
+	// Requests matching one of these rules are forwarded to another server
+	// instead of being served from the build output. The first match wins.
+	Proxy []ServeProxy
+
+	// Compress responses with brotli or gzip if the "Accept-Encoding" header
+	// allows it. Only text-based formats are compressed.
+	Compress bool
+
+	// Set the "Cache-Control" header for files whose path matches one of these
+	// rules. The first match wins. No header is sent by default.
+	CacheControl []ServeCacheControl
+}
+
+type Budget struct {
+	// A glob pattern such as "assets/*.js" that is matched against the paths of
+	// output files relative to the output directory. Patterns without a "/" only
+	// match the file name (e.g. "*.css").
+	Path string
+
+	MaxBytes     int // The maximum size of each matching output file
+	MaxGzipBytes int // The maximum size of each matching output file after gzip compression
+
+	// Report a warning instead of an error when this budget is exceeded
+	Warn bool
+}
+
+type ServeCacheControl struct {
+	// A glob pattern such as "/assets/**". Patterns without a "/" only match
+	// the file name (e.g. "*.html").
+	Path string
+
+	// The header value (e.g. "max-age=31536000, immutable")
+	Value string
+}
+
+type ServeProxy struct {
+	// Matches this path and everything below it (e.g. "/api")
+	Path string
+
+	// The upstream server (e.g. "http://localhost:3000"). Any path in this URL
+	// is prepended to the forwarded path.
+	Target string
+
+	// If present, this replaces the matched path prefix before forwarding. Use
+	// "/" to remove the prefix entirely.
+	Rewrite string
+
+	// Extra request headers to send to the upstream server. Setting "Host"
+	// overrides the host header, which is otherwise passed through unchanged.
+	Headers map[string]string
 }
 
 type ServeOnRequestArgs struct {
@@ -492,7 +619,19 @@ type ServeResult struct {
 	Host string
 }
 
+type WatchBackend uint8
+
+const (
+	WatchBackendDefault WatchBackend = iota
+	WatchBackendPolling
+	WatchBackendNative // Currently inotify on Linux, falls back to polling elsewhere
+)
+
 type WatchOptions struct {
+	Backend      WatchBackend
+	PollInterval int      // In milliseconds, defaults to 100
+	Debounce     int      // In milliseconds, defaults to 0
+	Ignore       []string // Glob patterns relative to the working directory
 }
 
 type BuildContext interface {
@@ -563,6 +702,17 @@ type PluginBuild struct {
 	// Documentation: https://esbuild.github.io/plugins/#on-load
 	OnLoad func(options OnLoadOptions, callback func(OnLoadArgs) (OnLoadResult, error))
 
+	// Transform callbacks run on the contents of each matching file after it
+	// has been loaded. Unlike "OnLoad", every matching callback runs, in the
+	// order that they were registered, with each one receiving the output of
+	// the previous one.
+	OnTransform func(options OnTransformOptions, callback func(OnTransformArgs) (OnTransformResult, error))
+
+	// Render chunk callbacks can modify the contents of each output chunk
+	// before its hash is computed. They run in the order that they were
+	// registered, with each one receiving the output of the previous one.
+	OnRenderChunk func(callback func(OnRenderChunkArgs) (OnRenderChunkResult, error))
+
 	// Documentation: https://esbuild.github.io/plugins/#on-dispose
 	OnDispose func(callback func())
 }
@@ -575,6 +725,7 @@ type ResolveOptions struct {
 	ResolveDir string
 	Kind       ResolveKind
 	PluginData interface{}
+	With       map[string]string
 }
 
 // Documentation: https://esbuild.github.io/plugins/#resolve-results
@@ -614,6 +765,10 @@ type OnResolveArgs struct {
 	ResolveDir string
 	Kind       ResolveKind
 	PluginData interface{}
+
+	// These are the import attributes (e.g. "with { type: 'json' }") of the
+	// import statement that is being resolved
+	With map[string]string
 }
 
 // Documentation: https://esbuild.github.io/plugins/#on-resolve-results
@@ -646,6 +801,10 @@ type OnLoadArgs struct {
 	Namespace  string
 	Suffix     string
 	PluginData interface{}
+
+	// The same path imported with different import attributes results in
+	// separate modules, so this is loaded once for each set of attributes
+	With map[string]string
 }
 
 // Documentation: https://esbuild.github.io/plugins/#on-load-results
@@ -655,7 +814,10 @@ type OnLoadResult struct {
 	Errors   []Message
 	Warnings []Message
 
+	// The source map is optional and maps the contents to the original files
+	// that they were compiled from. It's used instead of any source map comment.
 	Contents   *string
+	SourceMap  *string
 	ResolveDir string
 	Loader     Loader
 	PluginData interface{}
@@ -664,6 +826,61 @@ type OnLoadResult struct {
 	WatchDirs  []string
 }
 
+type OnTransformOptions struct {
+	Filter    string
+	Namespace string
+}
+
+type OnTransformArgs struct {
+	Path       string
+	Namespace  string
+	Suffix     string
+	Contents   string
+	Loader     Loader
+	PluginData interface{}
+	With       map[string]string
+}
+
+type OnTransformResult struct {
+	PluginName string
+
+	Errors   []Message
+	Warnings []Message
+
+	// Leave this nil to pass the contents through unchanged. The source map
+	// is optional and must map the new contents to the ones passed in.
+	Contents   *string
+	SourceMap  *string
+	Loader     Loader
+	PluginData interface{}
+
+	WatchFiles []string
+	WatchDirs  []string
+}
+
+type OnRenderChunkArgs struct {
+	// This is relative to the output directory. It still contains the "[hash]"
+	// placeholder if there is one because the hash isn't known yet.
+	PathTemplate string
+	EntryPoint   string
+
+	// The contents contain unique placeholder strings for the paths of other
+	// output files. These must be left intact.
+	Contents string
+}
+
+type OnRenderChunkResult struct {
+	PluginName string
+
+	Errors   []Message
+	Warnings []Message
+
+	// Leave this nil to pass the contents through unchanged. The source map
+	// is optional and must map the new contents to the ones passed in.
+	Contents  *string
+	SourceMap *string
+}
+
 type ResolveKind uint8
 
 const (
@@ -704,6 +921,10 @@ func FormatMessages(msgs []Message, opts FormatMessagesOptions) []string {
 type AnalyzeMetafileOptions struct {
 	Color   bool
 	Verbose bool
+	Why     string // Explains why each input whose path contains this text is in each output file
+
+	HTML     bool   // Returns a self-contained HTML page with a treemap of the output files instead of a text table
+	Baseline string // Another metafile to compare against, which reports the size changes of each output file and package
 }
 
 // Documentation: https://esbuild.github.io/api/#analyze
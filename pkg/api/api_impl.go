@@ -14,6 +14,7 @@ import (
 	"os"
 	"path"
 	"regexp"
+	"runtime"
 	"sort"
 	"strconv"
 	"strings"
@@ -255,6 +256,8 @@ func validateLoader(value Loader) config.Loader {
 		return config.LoaderFile
 	case LoaderGlobalCSS:
 		return config.LoaderGlobalCSS
+	case LoaderHTML:
+		return config.LoaderHTML
 	case LoaderJS:
 		return config.LoaderJS
 	case LoaderJSON:
@@ -271,11 +274,56 @@ func validateLoader(value Loader) config.Loader {
 		return config.LoaderTS
 	case LoaderTSX:
 		return config.LoaderTSX
+	case LoaderWasm:
+		return config.LoaderWasm
 	default:
 		panic("Invalid loader")
 	}
 }
 
+func loaderToAPI(value config.Loader) Loader {
+	switch value {
+	case config.LoaderBase64:
+		return LoaderBase64
+	case config.LoaderBinary:
+		return LoaderBinary
+	case config.LoaderCopy:
+		return LoaderCopy
+	case config.LoaderCSS:
+		return LoaderCSS
+	case config.LoaderDataURL:
+		return LoaderDataURL
+	case config.LoaderDefault:
+		return LoaderDefault
+	case config.LoaderEmpty:
+		return LoaderEmpty
+	case config.LoaderFile:
+		return LoaderFile
+	case config.LoaderGlobalCSS:
+		return LoaderGlobalCSS
+	case config.LoaderHTML:
+		return LoaderHTML
+	case config.LoaderJS:
+		return LoaderJS
+	case config.LoaderJSON:
+		return LoaderJSON
+	case config.LoaderJSX:
+		return LoaderJSX
+	case config.LoaderLocalCSS:
+		return LoaderLocalCSS
+	case config.LoaderText:
+		return LoaderText
+	case config.LoaderTS, config.LoaderTSNoAmbiguousLessThan:
+		return LoaderTS
+	case config.LoaderTSX:
+		return LoaderTSX
+	case config.LoaderWasm:
+		return LoaderWasm
+	default:
+		return LoaderNone
+	}
+}
+
 func validateEngine(value EngineName) compat.Engine {
 	switch value {
 	case EngineChrome:
@@ -467,6 +515,72 @@ func validateExternals(log logger.Log, fs fs.FS, paths []string) config.External
 	return result
 }
 
+// Patterns without a "/" only match the file name, like in ".gitignore"
+func validateSourcemapIgnoreList(patterns []string) []*regexp.Regexp {
+	if patterns == nil {
+		patterns = []string{"**/node_modules/**"}
+	}
+	var result []*regexp.Regexp
+	for _, pattern := range patterns {
+		if pattern == "" {
+			continue
+		}
+		if !strings.ContainsAny(pattern, "/\\") {
+			pattern = "**/" + pattern
+		}
+		result = append(result, helpers.GlobPatternToRegexp(helpers.ParseGlobPattern(pattern)))
+	}
+	return result
+}
+
+func validateManualChunks(log logger.Log, fs fs.FS, manualChunks map[string][]string) []config.ManualChunk {
+	if len(manualChunks) == 0 {
+		return nil
+	}
+
+	// Sort by name so that the first match is deterministic
+	names := make([]string, 0, len(manualChunks))
+	for name := range manualChunks {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	result := make([]config.ManualChunk, 0, len(names))
+	for _, name := range names {
+		if name == "" || strings.ContainsAny(name, "/\\") {
+			log.AddError(nil, logger.Range{}, fmt.Sprintf("Invalid manual chunk name: %q", name))
+			continue
+		}
+		chunk := config.ManualChunk{
+			Name:     name,
+			Packages: config.ExternalMatchers{Exact: make(map[string]bool)},
+			Paths:    config.ExternalMatchers{Exact: make(map[string]bool)},
+		}
+		for _, path := range manualChunks[name] {
+			index := strings.IndexByte(path, '*')
+			if index != -1 && strings.ContainsRune(path[index+1:], '*') {
+				log.AddError(nil, logger.Range{}, fmt.Sprintf("Manual chunk path %q cannot have more than one \"*\" wildcard", path))
+				continue
+			}
+			if resolver.IsPackagePath(path) {
+				if index != -1 {
+					chunk.Packages.Patterns = append(chunk.Packages.Patterns, config.WildcardPattern{Prefix: path[:index], Suffix: path[index+1:]})
+				} else {
+					chunk.Packages.Exact[path] = true
+				}
+			} else if absPath := validatePath(log, fs, path, "manual chunk path"); absPath != "" {
+				if absIndex := strings.IndexByte(absPath, '*'); absIndex != -1 {
+					chunk.Paths.Patterns = append(chunk.Paths.Patterns, config.WildcardPattern{Prefix: absPath[:absIndex], Suffix: absPath[absIndex+1:]})
+				} else {
+					chunk.Paths.Exact[absPath] = true
+				}
+			}
+		}
+		result = append(result, chunk)
+	}
+	return result
+}
+
 func esmParsePackageName(packageSpecifier string) (packageName string, packageSubpath string, ok bool) {
 	if packageSpecifier == "" {
 		return
@@ -910,16 +1024,25 @@ func contextImpl(buildOpts BuildOptions) (*internalContext, []Message) {
 		Overrides:     validateLogOverrides(buildOpts.LogOverride),
 	}
 
-	// Validate that the current working directory is an absolute path
+	// Validate that the current working directory is an absolute path. The
+	// working directory belongs to the custom file system if there is one.
 	absWorkingDir := buildOpts.AbsWorkingDir
+	realWorkingDir := absWorkingDir
+	if buildOpts.FS != nil {
+		realWorkingDir = ""
+	}
 	realFS, err := fs.RealFS(fs.RealFSOptions{
-		AbsWorkingDir: absWorkingDir,
+		AbsWorkingDir: realWorkingDir,
 
 		// This is a long-lived file system object so do not cache calls to
 		// ReadDirectory() (they are normally cached for the duration of a build
 		// for performance).
 		DoNotCache: true,
 	})
+	buildFS := realFS
+	if err == nil && buildOpts.FS != nil {
+		buildFS, err = makeCustomFS(buildOpts.FS, absWorkingDir, false, true)
+	}
 	if err != nil {
 		log := logger.NewStderrLog(logOptions)
 		log.AddError(nil, logger.Range{}, err.Error())
@@ -931,9 +1054,17 @@ func contextImpl(buildOpts BuildOptions) (*internalContext, []Message) {
 	// validation that we just did above.
 	caches := cache.MakeCacheSet()
 	log := logger.NewDeferLog(logger.DeferLogNoVerboseOrDebug, logOptions.Overrides)
-	onEndCallbacks, onDisposeCallbacks, finalizeBuildOptions := loadPlugins(&buildOpts, realFS, log, caches)
-	options, entryPoints := validateBuildOptions(buildOpts, log, realFS)
+	onEndCallbacks, onDisposeCallbacks, finalizeBuildOptions := loadPlugins(&buildOpts, buildFS, log, caches)
+	options, entryPoints := validateBuildOptions(buildOpts, log, buildFS)
 	finalizeBuildOptions(&options)
+	budgets := validateBudgets(log, buildOpts.Budgets)
+	if len(budgets) > 0 {
+		// The metafile is used to explain which inputs go over budget
+		options.NeedsMetafile = true
+	}
+	if cacheDir := validatePath(log, realFS, buildOpts.CacheDir, "cache directory"); cacheDir != "" {
+		caches.EnableDiskCache(cache.MakeDiskCache(realFS, cacheDir))
+	}
 	if buildOpts.AbsWorkingDir != absWorkingDir {
 		panic("Mutating \"AbsWorkingDir\" is not allowed")
 	}
@@ -966,16 +1097,43 @@ func contextImpl(buildOpts BuildOptions) (*internalContext, []Message) {
 		options:            options,
 		mangleCache:        buildOpts.MangleCache,
 		absWorkingDir:      absWorkingDir,
+		customFS:           buildOpts.FS,
 		write:              buildOpts.Write,
+		budgets:            budgets,
+		returnMetafile:     buildOpts.Metafile,
 	}
 
 	return &internalContext{
 		args:          args,
 		realFS:        realFS,
+		buildFS:       buildFS,
 		absWorkingDir: absWorkingDir,
 	}, nil
 }
 
+// The build reads from this instead of from the real file system when the
+// API user provides their own file system
+func makeCustomFS(customFS FS, absWorkingDir string, wantWatchData bool, doNotCache bool) (fs.FS, error) {
+	return fs.CustomFS(fs.CustomFSOptions{
+		AbsWorkingDir: absWorkingDir,
+		WantWatchData: wantWatchData,
+		DoNotCache:    doNotCache,
+		ReadDirectory: func(path string) ([]fs.CustomEntry, error) {
+			entries, err := customFS.ReadDirectory(path)
+			if err != nil {
+				return nil, err
+			}
+			result := make([]fs.CustomEntry, len(entries))
+			for i, entry := range entries {
+				result[i] = fs.CustomEntry{Name: entry.Name, IsDir: entry.IsDir}
+			}
+			return result, nil
+		},
+		ReadFile: customFS.ReadFile,
+		ModKey:   customFS.ModKey,
+	})
+}
+
 type buildInProgress struct {
 	state     rebuildState
 	waitGroup sync.WaitGroup
@@ -988,6 +1146,7 @@ type internalContext struct {
 	activeBuild   *buildInProgress
 	recentBuild   *BuildResult
 	realFS        fs.FS
+	buildFS       fs.FS
 	absWorkingDir string
 	watcher       *watcher
 	handler       *apiHandler
@@ -1030,7 +1189,7 @@ func (ctx *internalContext) rebuild() rebuildState {
 	var newHashes map[string]string
 	build.state, newHashes = rebuildImpl(args, oldHashes)
 	if handler != nil {
-		handler.broadcastBuildResult(build.state.result, newHashes)
+		handler.broadcastBuildResult(build.state.result, newHashes, build.state.moduleHashesForHMR)
 	}
 	if watcher != nil {
 		watcher.setWatchData(build.state.watchData)
@@ -1106,12 +1265,20 @@ func (ctx *internalContext) Watch(options WatchOptions) error {
 		return errors.New("Watch mode has already been enabled")
 	}
 
-	ctx.watcher = &watcher{
-		fs: ctx.realFS,
-		rebuild: func() fs.WatchData {
-			return ctx.rebuild().watchData
-		},
+	// The operating system can't notify us about changes to a custom file system
+	if ctx.args.customFS != nil && options.Backend == WatchBackendNative {
+		options.Backend = WatchBackendPolling
+	}
+
+	watcher, err := validateWatchOptions(options, ctx.buildFS)
+	if err != nil {
+		return err
+	}
+	watcher.rebuild = func() fs.WatchData {
+		return ctx.rebuild().watchData
 	}
+	watcher.logOptions = ctx.args.logOptions
+	ctx.watcher = watcher
 
 	// All subsequent builds will be watch mode builds
 	ctx.args.options.WatchMode = true
@@ -1288,6 +1455,7 @@ func validateBuildOptions(
 		LegalComments:         validateLegalComments(buildOpts.LegalComments, buildOpts.Bundle),
 		SourceRoot:            buildOpts.SourceRoot,
 		ExcludeSourcesContent: buildOpts.SourcesContent == SourcesContentExclude,
+		SourcemapIgnoreList:   validateSourcemapIgnoreList(buildOpts.SourcemapIgnoreList),
 		MinifySyntax:          buildOpts.MinifySyntax,
 		MinifyWhitespace:      buildOpts.MinifyWhitespace,
 		MinifyIdentifiers:     buildOpts.MinifyIdentifiers,
@@ -1303,6 +1471,7 @@ func validateBuildOptions(
 		TreeShaking:           validateTreeShaking(buildOpts.TreeShaking, buildOpts.Bundle, buildOpts.Format),
 		GlobalName:            validateGlobalName(log, buildOpts.GlobalName),
 		CodeSplitting:         buildOpts.Splitting,
+		HMR:                   buildOpts.HMR,
 		OutputFormat:          validateFormat(buildOpts.Format),
 		AbsOutputFile:         validatePath(log, realFS, buildOpts.Outfile, "outfile path"),
 		AbsOutputDir:          validatePath(log, realFS, buildOpts.Outdir, "outdir path"),
@@ -1311,6 +1480,7 @@ func validateBuildOptions(
 		EntryPathTemplate:     validatePathTemplate(buildOpts.EntryNames),
 		ChunkPathTemplate:     validatePathTemplate(buildOpts.ChunkNames),
 		AssetPathTemplate:     validatePathTemplate(buildOpts.AssetNames),
+		ManualChunks:          validateManualChunks(log, realFS, buildOpts.ManualChunks),
 		OutputExtensionJS:     outJS,
 		OutputExtensionCSS:    outCSS,
 		ExtensionToLoader:     validateLoaders(log, buildOpts.Loader),
@@ -1322,6 +1492,7 @@ func validateBuildOptions(
 		TSConfigRaw:           buildOpts.TsconfigRaw,
 		MainFields:            buildOpts.MainFields,
 		PublicPath:            buildOpts.PublicPath,
+		WasmBytesAsFile:       buildOpts.WasmBytes == WasmBytesFile,
 		KeepNames:             buildOpts.KeepNames,
 		InjectPaths:           append([]string{}, buildOpts.Inject...),
 		AbsNodePaths:          make([]string, len(buildOpts.NodePaths)),
@@ -1331,6 +1502,9 @@ func validateBuildOptions(
 		CSSFooter:             footerCSS,
 		PreserveSymlinks:      buildOpts.PreserveSymlinks,
 	}
+	options.NeedsMetafileIncludedBy = buildOpts.MetafileIncludedBy
+	options.DebugIDs = buildOpts.DebugIDs
+	options.SourcemapSections = buildOpts.SourcemapSections
 	if buildOpts.Conditions != nil {
 		options.Conditions = append([]string{}, buildOpts.Conditions...)
 	}
@@ -1434,9 +1608,38 @@ func validateBuildOptions(
 		options.Conditions = []string{"module"}
 	}
 
-	// Code splitting is experimental and currently only enabled for ES6 modules
-	if options.CodeSplitting && options.OutputFormat != config.FormatESModule {
-		log.AddError(nil, logger.Range{}, "Splitting currently only works with the \"esm\" format")
+	// Code splitting is experimental and currently only enabled for some formats
+	if options.CodeSplitting {
+		switch options.OutputFormat {
+		case config.FormatESModule, config.FormatCommonJS:
+		case config.FormatIIFE:
+			// Each chunk returns its exports to the chunk loader instead
+			if len(options.GlobalName) > 0 {
+				log.AddError(nil, logger.Range{}, "Cannot use \"globalName\" with \"splitting\"")
+			}
+		default:
+			log.AddError(nil, logger.Range{}, "Splitting currently only works with the \"esm\", \"cjs\", and \"iife\" formats")
+		}
+	}
+
+	if len(options.ManualChunks) > 0 && !options.CodeSplitting {
+		log.AddError(nil, logger.Range{}, "Cannot use \"manualChunks\" without \"splitting\"")
+	}
+
+	if options.NeedsMetafileIncludedBy && !options.NeedsMetafile {
+		log.AddError(nil, logger.Range{}, "Cannot use \"metafileIncludedBy\" without \"metafile\"")
+	}
+
+	// Hot module replacement relies on the bundler wrapping every module, and
+	// the runtime can only re-run a module that lives in a single chunk
+	if options.HMR {
+		if !buildOpts.Bundle {
+			log.AddError(nil, logger.Range{}, "Cannot use \"hmr\" without \"bundle\"")
+		} else if options.CodeSplitting {
+			log.AddError(nil, logger.Range{}, "Cannot use \"hmr\" with \"splitting\"")
+		} else if options.OutputFormat != config.FormatESModule && options.OutputFormat != config.FormatIIFE {
+			log.AddError(nil, logger.Range{}, "Hot module replacement currently only works with the \"esm\" and \"iife\" formats")
+		}
 	}
 
 	// Code splitting is experimental and currently only enabled for ES6 modules
@@ -1468,13 +1671,20 @@ type rebuildArgs struct {
 	options            config.Options
 	mangleCache        map[string]interface{}
 	absWorkingDir      string
+	customFS           FS
 	write              bool
+	budgets            []outputBudget
+	returnMetafile     bool
 }
 
 type rebuildState struct {
 	result    BuildResult
 	watchData fs.WatchData
 	options   config.Options
+
+	// This maps each output file to the hashes of the modules inside it. It's
+	// only present when hot module replacement is enabled.
+	moduleHashesForHMR map[string]map[string]uint64
 }
 
 func rebuildImpl(args rebuildArgs, oldHashes map[string]string) (rebuildState, map[string]string) {
@@ -1486,10 +1696,16 @@ func rebuildImpl(args rebuildArgs, oldHashes map[string]string) (rebuildState, m
 	}
 
 	// Convert and validate the buildOpts
-	realFS, err := fs.RealFS(fs.RealFSOptions{
-		AbsWorkingDir: args.absWorkingDir,
-		WantWatchData: args.options.WatchMode,
-	})
+	var buildFS fs.FS
+	var err error
+	if args.customFS != nil {
+		buildFS, err = makeCustomFS(args.customFS, args.absWorkingDir, args.options.WatchMode, false)
+	} else {
+		buildFS, err = fs.RealFS(fs.RealFSOptions{
+			AbsWorkingDir: args.absWorkingDir,
+			WantWatchData: args.options.WatchMode,
+		})
+	}
 	if err != nil {
 		// This should already have been checked by the caller
 		panic(err.Error())
@@ -1498,6 +1714,7 @@ func rebuildImpl(args rebuildArgs, oldHashes map[string]string) (rebuildState, m
 	var result BuildResult
 	var watchData fs.WatchData
 	var toWriteToStdout []byte
+	var moduleHashesForHMR map[string]map[string]uint64
 
 	var timer *helpers.Timer
 	if api_helpers.UseTimer {
@@ -1505,8 +1722,8 @@ func rebuildImpl(args rebuildArgs, oldHashes map[string]string) (rebuildState, m
 	}
 
 	// Scan over the bundle
-	bundle := bundler.ScanBundle(config.BuildCall, log, realFS, args.caches, args.entryPoints, args.options, timer)
-	watchData = realFS.WatchData()
+	bundle := bundler.ScanBundle(config.BuildCall, log, buildFS, args.caches, args.entryPoints, args.options, timer)
+	watchData = buildFS.WatchData()
 
 	// The new build summary remains the same as the old one when there are
 	// errors. A failed build shouldn't erase the previous successful build.
@@ -1523,9 +1740,16 @@ func rebuildImpl(args rebuildArgs, oldHashes map[string]string) (rebuildState, m
 			log.AddError(nil, logger.Range{}, "The build was canceled")
 		}
 
+		// Check the sizes of the output files before anything is written
+		if len(args.budgets) > 0 && !log.HasErrors() {
+			checkBudgets(log, args.budgets, buildFS, args.options.AbsOutputDir, results, metafile)
+		}
+
 		// Stop now if there were errors
 		if !log.HasErrors() {
-			result.Metafile = metafile
+			if args.returnMetafile {
+				result.Metafile = metafile
+			}
 
 			// Populate the results to return
 			var hashBytes [8]byte
@@ -1545,6 +1769,12 @@ func rebuildImpl(args rebuildArgs, oldHashes map[string]string) (rebuildState, m
 					Hash:     hash,
 				}
 				newHashes[item.AbsPath] = hash
+				if item.ModuleHashesForHMR != nil {
+					if moduleHashesForHMR == nil {
+						moduleHashesForHMR = make(map[string]map[string]uint64)
+					}
+					moduleHashesForHMR[item.AbsPath] = item.ModuleHashesForHMR
+				}
 			}
 
 			// Write output files before "OnEnd" callbacks run so they can expect
@@ -1584,7 +1814,7 @@ func rebuildImpl(args rebuildArgs, oldHashes map[string]string) (rebuildState, m
 									return
 								}
 							}
-							if err := fs.MkdirAll(realFS, realFS.Dir(result.AbsPath), 0755); err != nil {
+							if err := fs.MkdirAll(buildFS, buildFS.Dir(result.AbsPath), 0755); err != nil {
 								log.AddError(nil, logger.Range{}, fmt.Sprintf(
 									"Failed to create output directory: %s", err.Error()))
 							} else {
@@ -1684,9 +1914,10 @@ func rebuildImpl(args rebuildArgs, oldHashes map[string]string) (rebuildState, m
 	}
 
 	return rebuildState{
-		result:    result,
-		options:   args.options,
-		watchData: watchData,
+		result:             result,
+		options:            args.options,
+		watchData:          watchData,
+		moduleHashesForHMR: moduleHashesForHMR,
 	}, newHashes
 }
 
@@ -1694,29 +1925,92 @@ func rebuildImpl(args rebuildArgs, oldHashes map[string]string) (rebuildState, m
 // Transform API
 
 func transformImpl(input string, transformOpts TransformOptions) TransformResult {
-	log := logger.NewStderrLog(logger.OutputOptions{
-		IncludeSource: true,
-		MessageLimit:  transformOpts.LogLimit,
-		Color:         validateColor(transformOpts.Color),
-		LogLevel:      validateLogLevel(transformOpts.LogLevel),
-		Overrides:     validateLogOverrides(transformOpts.LogOverride),
-	})
-	caches := cache.MakeCacheSet()
+	applyTransformDefaults(&transformOpts)
+	return transformWithValidatedOptions(input, transformOpts, validateTransformOptions(transformOpts))
+}
+
+func transformManyImpl(inputs []TransformInput) []TransformResult {
+	results := make([]TransformResult, len(inputs))
+	transformOpts := make([]TransformOptions, len(inputs))
+	validated := make([]validatedTransformOptions, len(inputs))
+
+	// Validate each distinct set of options once. Inputs usually only differ
+	// in their file name and loader, which are applied to each input separately.
+	distinct := make(map[string]int)
+	for i, input := range inputs {
+		transformOpts[i] = input.Options
+		applyTransformDefaults(&transformOpts[i])
+		key := sharedTransformOptionsKey(transformOpts[i])
+		if j, ok := distinct[key]; ok {
+			validated[i] = validated[j]
+		} else {
+			validated[i] = validateTransformOptions(transformOpts[i])
+			distinct[key] = i
+		}
+	}
 
-	// Apply default values
+	// Transform the inputs in parallel using one worker per available thread
+	workerCount := runtime.GOMAXPROCS(0)
+	if workerCount > len(inputs) {
+		workerCount = len(inputs)
+	}
+	indices := make(chan int, len(inputs))
+	for i := range inputs {
+		indices <- i
+	}
+	close(indices)
+	waitGroup := sync.WaitGroup{}
+	waitGroup.Add(workerCount)
+	for w := 0; w < workerCount; w++ {
+		go func() {
+			for i := range indices {
+				results[i] = transformWithValidatedOptions(inputs[i].Contents, transformOpts[i], validated[i])
+			}
+			waitGroup.Done()
+		}()
+	}
+	waitGroup.Wait()
+	return results
+}
+
+func applyTransformDefaults(transformOpts *TransformOptions) {
 	if transformOpts.Sourcefile == "" {
 		transformOpts.Sourcefile = "<stdin>"
 	}
 	if transformOpts.Loader == LoaderNone {
 		transformOpts.Loader = LoaderJS
 	}
+}
+
+// Options are considered the same if they only differ in the settings that
+// are applied to each input separately. The options don't contain pointers
+// and "fmt" prints map entries in sorted order, so equal options always print
+// the same way. Note that this keeps nil and empty slices apart on purpose
+// since some options treat nil as "use the default".
+func sharedTransformOptionsKey(options TransformOptions) string {
+	options.Sourcefile = ""
+	options.Loader = LoaderNone
+	options.Banner = ""
+	options.Footer = ""
+	options.MangleCache = nil
+	return fmt.Sprintf("%#v", options)
+}
+
+// These are the parts of the transform options that don't depend on the input.
+// The messages are replayed into the log for each input that uses them.
+type validatedTransformOptions struct {
+	options config.Options
+	msgs    []logger.Msg
+}
+
+func validateTransformOptions(transformOpts TransformOptions) validatedTransformOptions {
+	log := logger.NewDeferLog(logger.DeferLogAll, validateLogOverrides(transformOpts.LogOverride))
 
 	// Convert and validate the transformOpts
 	jsFeatures, cssFeatures, cssPrefixData, targetEnv := validateFeatures(log, transformOpts.Target, transformOpts.Engines)
 	jsOverrides, jsMask, cssOverrides, cssMask := validateSupported(log, transformOpts.Supported)
 	platform := validatePlatform(transformOpts.Platform)
 	defines, injectedDefines := validateDefines(log, transformOpts.Define, transformOpts.Pure, platform, false /* isBuildAPI */, false /* minify */, transformOpts.Drop)
-	mangleCache := cloneMangleCache(log, transformOpts.MangleCache)
 	options := config.Options{
 		CSSPrefixData:                      cssPrefixData,
 		UnsupportedJSFeatures:              jsFeatures.ApplyOverrides(jsOverrides, jsMask),
@@ -1743,6 +2037,7 @@ func transformImpl(input string, transformOpts TransformOptions) TransformResult
 		LegalComments:         validateLegalComments(transformOpts.LegalComments, false /* bundle */),
 		SourceRoot:            transformOpts.SourceRoot,
 		ExcludeSourcesContent: transformOpts.SourcesContent == SourcesContentExclude,
+		SourcemapIgnoreList:   validateSourcemapIgnoreList(transformOpts.SourcemapIgnoreList),
 		OutputFormat:          validateFormat(transformOpts.Format),
 		GlobalName:            validateGlobalName(log, transformOpts.GlobalName),
 		MinifySyntax:          transformOpts.MinifySyntax,
@@ -1757,29 +2052,12 @@ func transformImpl(input string, transformOpts TransformOptions) TransformResult
 		ASCIIOnly:             validateASCIIOnly(transformOpts.Charset),
 		IgnoreDCEAnnotations:  transformOpts.IgnoreAnnotations,
 		TreeShaking:           validateTreeShaking(transformOpts.TreeShaking, false /* bundle */, transformOpts.Format),
-		AbsOutputFile:         transformOpts.Sourcefile + "-out",
 		KeepNames:             transformOpts.KeepNames,
-		Stdin: &config.StdinInfo{
-			Loader:     validateLoader(transformOpts.Loader),
-			Contents:   input,
-			SourceFile: transformOpts.Sourcefile,
-		},
-	}
-	if options.Stdin.Loader == config.LoaderCSS {
-		options.CSSBanner = transformOpts.Banner
-		options.CSSFooter = transformOpts.Footer
-	} else {
-		options.JSBanner = transformOpts.Banner
-		options.JSFooter = transformOpts.Footer
 	}
 	if options.SourceMap == config.SourceMapLinkedWithComment {
 		// Linked source maps don't make sense because there's no output file name
 		log.AddError(nil, logger.Range{}, "Cannot transform with linked source maps")
 	}
-	if options.SourceMap != config.SourceMapNone && options.Stdin.SourceFile == "" {
-		log.AddError(nil, logger.Range{},
-			"Must use \"sourcefile\" with \"sourcemap\" to set the original file name")
-	}
 	if logger.API == logger.CLIAPI {
 		if options.LegalComments.HasExternalFile() {
 			log.AddError(nil, logger.Range{}, "Cannot transform with linked or external legal comments")
@@ -1793,6 +2071,46 @@ func transformImpl(input string, transformOpts TransformOptions) TransformResult
 		options.Mode = config.ModeConvertFormat
 	}
 
+	return validatedTransformOptions{
+		options: options,
+		msgs:    log.Done(),
+	}
+}
+
+func transformWithValidatedOptions(input string, transformOpts TransformOptions, validated validatedTransformOptions) TransformResult {
+	log := logger.NewStderrLog(logger.OutputOptions{
+		IncludeSource: true,
+		MessageLimit:  transformOpts.LogLimit,
+		Color:         validateColor(transformOpts.Color),
+		LogLevel:      validateLogLevel(transformOpts.LogLevel),
+		Overrides:     validateLogOverrides(transformOpts.LogOverride),
+	})
+	caches := cache.MakeCacheSet()
+	for _, msg := range validated.msgs {
+		log.AddMsg(msg)
+	}
+
+	// Apply the options that depend on the input
+	mangleCache := cloneMangleCache(log, transformOpts.MangleCache)
+	options := validated.options
+	options.AbsOutputFile = transformOpts.Sourcefile + "-out"
+	options.Stdin = &config.StdinInfo{
+		Loader:     validateLoader(transformOpts.Loader),
+		Contents:   input,
+		SourceFile: transformOpts.Sourcefile,
+	}
+	if options.Stdin.Loader == config.LoaderCSS {
+		options.CSSBanner = transformOpts.Banner
+		options.CSSFooter = transformOpts.Footer
+	} else {
+		options.JSBanner = transformOpts.Banner
+		options.JSFooter = transformOpts.Footer
+	}
+	if options.SourceMap != config.SourceMapNone && options.Stdin.SourceFile == "" {
+		log.AddError(nil, logger.Range{},
+			"Must use \"sourcefile\" with \"sourcemap\" to set the original file name")
+	}
+
 	var results []graph.OutputFile
 
 	// Stop now if there were errors
@@ -1882,6 +2200,32 @@ func (impl *pluginImpl) onStart(callback func() (OnStartResult, error)) {
 	})
 }
 
+func (impl *pluginImpl) onRenderChunk(callback func(OnRenderChunkArgs) (OnRenderChunkResult, error)) {
+	impl.plugin.OnRenderChunk = append(impl.plugin.OnRenderChunk, config.OnRenderChunk{
+		Name: impl.plugin.Name,
+		Callback: func(args config.OnRenderChunkArgs) (result config.OnRenderChunkResult) {
+			response, err := callback(OnRenderChunkArgs{
+				PathTemplate: args.PathTemplate,
+				EntryPoint:   args.EntryPoint,
+				Contents:     args.Contents,
+			})
+			result.PluginName = response.PluginName
+
+			if err != nil {
+				result.ThrownError = err
+				return
+			}
+
+			result.Contents = response.Contents
+			result.SourceMap = response.SourceMap
+
+			// Convert log messages
+			result.Msgs = convertErrorsAndWarningsToInternal(response.Errors, response.Warnings)
+			return
+		},
+	})
+}
+
 func importKindToResolveKind(kind ast.ImportKind) ResolveKind {
 	switch kind {
 	case ast.ImportEntryPoint:
@@ -1947,6 +2291,7 @@ func (impl *pluginImpl) onResolve(options OnResolveOptions, callback func(OnReso
 				ResolveDir: args.ResolveDir,
 				Kind:       importKindToResolveKind(args.Kind),
 				PluginData: args.PluginData,
+				With:       args.With.DecodeIntoMap(),
 			})
 			result.PluginName = response.PluginName
 			result.AbsWatchFiles = impl.validatePathsArray(response.WatchFiles, "watch file")
@@ -1994,6 +2339,7 @@ func (impl *pluginImpl) onLoad(options OnLoadOptions, callback func(OnLoadArgs)
 				Namespace:  args.Path.Namespace,
 				PluginData: args.PluginData,
 				Suffix:     args.Path.IgnoredSuffix,
+				With:       args.Path.ImportAttributes.DecodeIntoMap(),
 			})
 			result.PluginName = response.PluginName
 			result.AbsWatchFiles = impl.validatePathsArray(response.WatchFiles, "watch file")
@@ -2005,6 +2351,7 @@ func (impl *pluginImpl) onLoad(options OnLoadOptions, callback func(OnLoadArgs)
 			}
 
 			result.Contents = response.Contents
+			result.SourceMap = response.SourceMap
 			result.Loader = validateLoader(response.Loader)
 			result.PluginData = response.PluginData
 			pathKind := fmt.Sprintf("resolve directory path for plugin %q", impl.plugin.Name)
@@ -2019,6 +2366,51 @@ func (impl *pluginImpl) onLoad(options OnLoadOptions, callback func(OnLoadArgs)
 	})
 }
 
+func (impl *pluginImpl) onTransform(options OnTransformOptions, callback func(OnTransformArgs) (OnTransformResult, error)) {
+	filter, err := config.CompileFilterForPlugin(impl.plugin.Name, "OnTransform", options.Filter)
+	if filter == nil {
+		impl.log.AddError(nil, logger.Range{}, err.Error())
+		return
+	}
+
+	impl.plugin.OnTransform = append(impl.plugin.OnTransform, config.OnTransform{
+		Filter:    filter,
+		Namespace: options.Namespace,
+		Callback: func(args config.OnTransformArgs) (result config.OnTransformResult) {
+			response, err := callback(OnTransformArgs{
+				Path:       args.Path.Text,
+				Namespace:  args.Path.Namespace,
+				Suffix:     args.Path.IgnoredSuffix,
+				Contents:   args.Contents,
+				Loader:     loaderToAPI(args.Loader),
+				PluginData: args.PluginData,
+				With:       args.Path.ImportAttributes.DecodeIntoMap(),
+			})
+			result.PluginName = response.PluginName
+			result.AbsWatchFiles = impl.validatePathsArray(response.WatchFiles, "watch file")
+			result.AbsWatchDirs = impl.validatePathsArray(response.WatchDirs, "watch directory")
+
+			if err != nil {
+				result.ThrownError = err
+				return
+			}
+
+			result.Contents = response.Contents
+			result.SourceMap = response.SourceMap
+			result.PluginData = response.PluginData
+
+			// Returning the same loader keeps variants such as the one for ".mts"
+			if apiLoader := loaderToAPI(args.Loader); response.Loader != apiLoader {
+				result.Loader = validateLoader(response.Loader)
+			}
+
+			// Convert log messages
+			result.Msgs = convertErrorsAndWarningsToInternal(response.Errors, response.Warnings)
+			return
+		},
+	})
+}
+
 func (impl *pluginImpl) validatePathsArray(pathsIn []string, name string) (pathsOut []string) {
 	if len(pathsIn) > 0 {
 		pathKind := fmt.Sprintf("%s path for plugin %q", name, impl.plugin.Name)
@@ -2099,6 +2491,7 @@ func loadPlugins(initialOptions *BuildOptions, fs fs.FS, log logger.Log, caches
 				logger.Path{Text: options.Importer, Namespace: options.Namespace},
 				path,
 				kind,
+				logger.EncodeImportAttributes(options.With),
 				absResolveDir,
 				options.PluginData,
 			)
@@ -2148,6 +2541,8 @@ func loadPlugins(initialOptions *BuildOptions, fs fs.FS, log logger.Log, caches
 			OnDispose:      onDispose,
 			OnResolve:      impl.onResolve,
 			OnLoad:         impl.onLoad,
+			OnTransform:    impl.onTransform,
+			OnRenderChunk:  impl.onRenderChunk,
 		})
 
 		plugins = append(plugins, impl.plugin)
@@ -2188,6 +2583,36 @@ type metafileEntry struct {
 	entryPoint string
 	entries    []metafileEntry
 	size       int
+
+	// These come from the optional "includedBy" and "parts" properties
+	includedBy []string
+	parts      []metafilePart
+}
+
+type metafilePart struct {
+	loc        string
+	includedBy []string
+}
+
+// Each step is formatted like "import-statement src/app.js:2:7"
+func formatIncludedBy(steps *js_ast.EArray) []string {
+	if steps == nil {
+		return nil
+	}
+	texts := make([]string, 0, len(steps.Items))
+	for _, step := range steps.Items {
+		kind := getObjectPropertyString(step, "kind")
+		path := getObjectPropertyString(step, "path")
+		if kind == nil || path == nil {
+			continue
+		}
+		text := fmt.Sprintf("%s %s", helpers.UTF16ToString(kind.Value), helpers.UTF16ToString(path.Value))
+		if line, column := getObjectPropertyNumber(step, "line"), getObjectPropertyNumber(step, "column"); line != nil && column != nil {
+			text += fmt.Sprintf(":%d:%d", int(line.Value), int(column.Value))
+		}
+		texts = append(texts, text)
+	}
+	return texts
 }
 
 // This type is just so we can use Go's native sort function
@@ -2234,6 +2659,13 @@ func getObjectPropertyArray(expr js_ast.Expr, key string) *js_ast.EArray {
 }
 
 func analyzeMetafileImpl(metafile string, opts AnalyzeMetafileOptions) string {
+	if opts.Baseline != "" {
+		return analyzeMetafileDiff(opts.Baseline, metafile, opts)
+	}
+	if opts.HTML {
+		return analyzeMetafileHTML(metafile)
+	}
+
 	log := logger.NewDeferLog(logger.DeferLogNoVerboseOrDebug, nil)
 	source := logger.Source{Contents: metafile}
 
@@ -2257,13 +2689,37 @@ func analyzeMetafileImpl(metafile string, opts AnalyzeMetafileOptions) string {
 
 							for _, input := range inputs.Properties {
 								if bytesInOutput := getObjectPropertyNumber(input.ValueOrNil, "bytesInOutput"); bytesInOutput != nil && bytesInOutput.Value > 0 {
-									children = append(children, metafileEntry{
-										name: helpers.UTF16ToString(input.Key.Data.(*js_ast.EString).Value),
-										size: int(bytesInOutput.Value),
-									})
+									name := helpers.UTF16ToString(input.Key.Data.(*js_ast.EString).Value)
+									if opts.Why != "" && !strings.Contains(name, opts.Why) {
+										continue
+									}
+									child := metafileEntry{
+										name:       name,
+										size:       int(bytesInOutput.Value),
+										includedBy: formatIncludedBy(getObjectPropertyArray(input.ValueOrNil, "includedBy")),
+									}
+									if parts := getObjectPropertyArray(input.ValueOrNil, "parts"); parts != nil {
+										for _, part := range parts.Items {
+											line := getObjectPropertyNumber(part, "line")
+											column := getObjectPropertyNumber(part, "column")
+											if line != nil && column != nil {
+												child.parts = append(child.parts, metafilePart{
+													loc:        fmt.Sprintf("%d:%d", int(line.Value), int(column.Value)),
+													includedBy: formatIncludedBy(getObjectPropertyArray(part, "includedBy")),
+												})
+											}
+										}
+									}
+									children = append(children, child)
 								}
 							}
 
+							// When explaining why certain inputs are included, leave out
+							// the output files that don't contain any of them
+							if opts.Why != "" && len(children) == 0 {
+								continue
+							}
+
 							sort.Sort(children)
 
 							entries = append(entries, metafileEntry{
@@ -2309,7 +2765,7 @@ func analyzeMetafileImpl(metafile string, opts AnalyzeMetafileOptions) string {
 
 			// Returns a graph with links pointing from imports to importers
 			graphForEntryPoints := func(worklist []string) map[string]graphData {
-				if !opts.Verbose {
+				if !opts.Verbose && opts.Why == "" {
 					return nil
 				}
 
@@ -2407,20 +2863,39 @@ func analyzeMetafileImpl(metafile string, opts AnalyzeMetafileOptions) string {
 
 					// If we're in verbose mode, also print the import chain from this file
 					// up toward an entry point to show why this file is in the bundle
-					if opts.Verbose {
+					if opts.Verbose || opts.Why != "" {
 						indent = " │ "
 						if j+1 == len(entry.entries) {
 							indent = "   "
 						}
-						data := graph[child.name]
-						depth := 0
+						addChain := func(steps []string, depth int) {
+							for _, step := range steps {
+								table = append(table, tableEntry{
+									first: fmt.Sprintf("%s%s%s └ %s%s", indent, colors.Dim, strings.Repeat(" ", depth), step, colors.Reset),
+								})
+								depth += 3
+							}
+						}
 
-						for data.depth != 0 {
-							table = append(table, tableEntry{
-								first: fmt.Sprintf("%s%s%s └ %s%s", indent, colors.Dim, strings.Repeat(" ", depth), data.parent, colors.Reset),
-							})
-							data = graph[data.parent]
-							depth += 3
+						// Prefer the reasons recorded by the bundler if they are present
+						if child.includedBy != nil {
+							addChain(child.includedBy, 0)
+							if opts.Verbose {
+								for _, part := range child.parts {
+									addChain(append([]string{"statement at " + part.loc}, part.includedBy...), 0)
+								}
+							}
+						} else {
+							data := graph[child.name]
+							depth := 0
+
+							for data.depth != 0 {
+								table = append(table, tableEntry{
+									first: fmt.Sprintf("%s%s%s └ %s%s", indent, colors.Dim, strings.Repeat(" ", depth), data.parent, colors.Reset),
+								})
+								data = graph[data.parent]
+								depth += 3
+							}
 						}
 					}
 				}
@@ -2,8 +2,16 @@ package api
 
 import (
 	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 
+	"github.com/evanw/esbuild/internal/helpers"
+	"github.com/evanw/esbuild/internal/js_ast"
+	"github.com/evanw/esbuild/internal/js_parser"
+	"github.com/evanw/esbuild/internal/logger"
 	"github.com/evanw/esbuild/internal/test"
 )
 
@@ -55,3 +63,505 @@ func TestStripDirPrefix(t *testing.T) {
 	expectFailure(`C:\foo\bar`, `C:\fo`, `\/`)
 	expectFailure(`C:/foo/bar`, `C:\foo`, `\/`)
 }
+
+type mapFS map[string]string
+
+func (m mapFS) ReadDirectory(dir string) ([]FSEntry, error) {
+	var entries []FSEntry
+	seen := make(map[string]bool)
+	prefix := strings.TrimSuffix(dir, "/") + "/"
+	for file := range m {
+		if strings.HasPrefix(file, prefix) {
+			name := file[len(prefix):]
+			isDir := false
+			if slash := strings.IndexByte(name, '/'); slash != -1 {
+				name = name[:slash]
+				isDir = true
+			}
+			if !seen[name] {
+				seen[name] = true
+				entries = append(entries, FSEntry{Name: name, IsDir: isDir})
+			}
+		}
+	}
+	if entries == nil {
+		return nil, os.ErrNotExist
+	}
+	return entries, nil
+}
+
+func (m mapFS) ReadFile(path string) ([]byte, error) {
+	if contents, ok := m[path]; ok {
+		return []byte(contents), nil
+	}
+	return nil, &os.PathError{Op: "open", Path: path, Err: os.ErrNotExist}
+}
+
+func (m mapFS) ModKey(path string) (string, error) {
+	return "", nil
+}
+
+func TestCustomFS(t *testing.T) {
+	files := mapFS{
+		"/project/src/entry.ts":                       "import { greet } from 'pkg'\nimport data from './data.json'\nconsole.log(greet(data.name) as string)\n",
+		"/project/src/data.json":                      `{ "name": "world" }`,
+		"/project/node_modules/pkg/package.json":      `{ "main": "lib/index.js" }`,
+		"/project/node_modules/pkg/lib/index.js":      "export let greet = name => 'hello ' + name\n",
+		"/project/node_modules/other/package.json":    `{}`,
+		"/project/node_modules/other/index.js":        "export default 123\n",
+		"/project/src/missing.js":                     "import './does-not-exist'\n",
+		"/project/src/uses-other.js":                  "import x from 'other'\nconsole.log(x)\n",
+		"/project/src/nested/dir/uses-relative.js":    "import x from '../../data.json'\nconsole.log(x)\n",
+		"/project/src/nested/dir/uses-absolute.js":    "import '/project/src/uses-other.js'\n",
+		"/project/src/nested/dir/uses-nonexistent.js": "import '/nope'\n",
+	}
+
+	result := Build(BuildOptions{
+		EntryPoints:   []string{"src/entry.ts", "src/nested/dir/uses-relative.js", "src/nested/dir/uses-absolute.js"},
+		Outdir:        "out",
+		AbsWorkingDir: "/project",
+		FS:            files,
+		Bundle:        true,
+		Format:        FormatESModule,
+		LogLevel:      LogLevelSilent,
+	})
+	test.AssertEqual(t, len(result.Errors), 0)
+	test.AssertEqual(t, len(result.OutputFiles), 3)
+	test.AssertEqual(t, result.OutputFiles[0].Path, "/project/out/entry.js")
+	test.AssertEqualWithDiff(t, string(result.OutputFiles[0].Contents), `// node_modules/pkg/lib/index.js
+var greet = (name) => "hello " + name;
+
+// src/data.json
+var data_default = { name: "world" };
+
+// src/entry.ts
+console.log(greet(data_default.name));
+`)
+	test.AssertEqual(t, strings.Contains(string(result.OutputFiles[2].Contents), "// node_modules/other/index.js"), true)
+
+	// Missing files are reported as resolve errors, not as file system errors
+	result = Build(BuildOptions{
+		EntryPoints:   []string{"/project/src/missing.js", "/project/src/nested/dir/uses-nonexistent.js"},
+		Outdir:        "/project/out",
+		AbsWorkingDir: "/project",
+		FS:            files,
+		Bundle:        true,
+		LogLevel:      LogLevelSilent,
+	})
+	test.AssertEqual(t, len(result.Errors), 2)
+	test.AssertEqual(t, result.Errors[0].Text, "Could not resolve \"./does-not-exist\"")
+	test.AssertEqual(t, result.Errors[1].Text, "Could not resolve \"/nope\"")
+
+	// The working directory belongs to the custom file system
+	result = Build(BuildOptions{
+		EntryPoints:   []string{"entry.ts"},
+		AbsWorkingDir: "project",
+		FS:            files,
+		LogLevel:      LogLevelSilent,
+	})
+	test.AssertEqual(t, len(result.Errors), 1)
+	test.AssertEqual(t, result.Errors[0].Text, "The working directory \"project\" is not an absolute path")
+}
+
+func TestAnalyzeMetafileWhy(t *testing.T) {
+	files := mapFS{
+		"/project/src/entry.js":                     "import { copy } from './util'\nconsole.log(copy({}))\n",
+		"/project/src/util.js":                      "import cloneDeep from 'lodash/cloneDeep'\nexport function copy(x) { return cloneDeep(x) }\n",
+		"/project/node_modules/lodash/cloneDeep.js": "export default function cloneDeep(x) { return x }\n",
+		"/project/node_modules/lodash/package.json": `{}`,
+	}
+
+	result := Build(BuildOptions{
+		EntryPoints:        []string{"src/entry.js"},
+		Outdir:             "out",
+		AbsWorkingDir:      "/project",
+		FS:                 files,
+		Bundle:             true,
+		Metafile:           true,
+		MetafileIncludedBy: true,
+		LogLevel:           LogLevelSilent,
+	})
+	test.AssertEqual(t, len(result.Errors), 0)
+
+	test.AssertEqualWithDiff(t, AnalyzeMetafile(result.Metafile, AnalyzeMetafileOptions{Why: "lodash/cloneDeep"}), `
+  out/entry.js                         209b   100.0%
+   └ node_modules/lodash/cloneDeep.js   44b    21.1%
+      └ import-statement src/util.js:1:22
+         └ import-statement src/entry.js:1:21
+            └ entry-point src/entry.js
+`)
+
+	test.AssertEqualWithDiff(t, AnalyzeMetafile(result.Metafile, AnalyzeMetafileOptions{Why: "lodash/cloneDeep", Verbose: true}), `
+  out/entry.js ──────────────────────── 209b ── 100.0%
+   └ node_modules/lodash/cloneDeep.js ── 44b ─── 21.1%
+      └ import-statement src/util.js:1:22
+         └ import-statement src/entry.js:1:21
+            └ entry-point src/entry.js
+      └ statement at 1:0
+         └ used-by src/util.js:2:7
+            └ used-by src/entry.js:2:0
+               └ side-effects src/entry.js
+                  └ entry-point src/entry.js
+`)
+
+	// Without recorded reasons, the import chain is used instead
+	result = Build(BuildOptions{
+		EntryPoints:   []string{"src/entry.js"},
+		Outdir:        "out",
+		AbsWorkingDir: "/project",
+		FS:            files,
+		Bundle:        true,
+		Metafile:      true,
+		LogLevel:      LogLevelSilent,
+	})
+	test.AssertEqual(t, len(result.Errors), 0)
+	test.AssertEqualWithDiff(t, AnalyzeMetafile(result.Metafile, AnalyzeMetafileOptions{Why: "cloneDeep"}), `
+  out/entry.js                         209b   100.0%
+   └ node_modules/lodash/cloneDeep.js   44b    21.1%
+      └ src/util.js
+         └ src/entry.js
+`)
+
+	// Output files without matching inputs are left out
+	test.AssertEqualWithDiff(t, AnalyzeMetafile(result.Metafile, AnalyzeMetafileOptions{Why: "unrelated"}), "")
+}
+
+func TestValidateSourcemapIgnoreList(t *testing.T) {
+	matches := func(patterns []string, path string) bool {
+		for _, re := range validateSourcemapIgnoreList(patterns) {
+			if re.MatchString(path) {
+				return true
+			}
+		}
+		return false
+	}
+
+	// The default is to ignore everything in "node_modules"
+	test.AssertEqual(t, matches(nil, "/project/node_modules/pkg/index.js"), true)
+	test.AssertEqual(t, matches(nil, "C:/project/node_modules/@scope/pkg/index.js"), true)
+	test.AssertEqual(t, matches(nil, "/project/src/index.js"), false)
+	test.AssertEqual(t, matches([]string{}, "/project/node_modules/pkg/index.js"), false)
+
+	// Patterns without a slash match the file name
+	test.AssertEqual(t, matches([]string{"*.min.js"}, "/project/src/vendor/jquery.min.js"), true)
+	test.AssertEqual(t, matches([]string{"*.min.js"}, "/project/src/index.js"), false)
+	test.AssertEqual(t, matches([]string{"/project/src/vendor/**"}, "/project/src/vendor/a/b.js"), true)
+	test.AssertEqual(t, matches([]string{"/project/src/vendor/**"}, "/project/src/index.js"), false)
+}
+
+func TestSourcemapSections(t *testing.T) {
+	files := mapFS{
+		"/project/src/entry.js":  "import { a } from './shared'\nimport './style.css'\nexport let load = () => import('./lazy')\nconsole.log(a, load)\n",
+		"/project/src/other.js":  "import { a } from './shared'\nexport let b = () => [a, import('./lazy'), import('./other')]\n",
+		"/project/src/shared.js": "export let a = function named() { return 'shared' }\n",
+		"/project/src/lazy.js":   "export default function lazy(x) {\n  return x * 2\n}\n",
+		"/project/src/style.css": "a { background: url(./image.png) }\nb { color: red }\n",
+		"/project/src/image.png": "...",
+	}
+
+	// Decode each mapping into something that doesn't depend on the order of
+	// the sources and names, since those are deduplicated for flat maps
+	decode := func(contents []byte) []string {
+		log := logger.NewDeferLog(logger.DeferLogNoVerboseOrDebug, nil)
+		sm := js_parser.ParseSourceMap(log, logger.Source{Contents: string(contents)})
+		test.AssertEqual(t, len(log.Done()), 0)
+		var result []string
+		for _, m := range sm.Mappings {
+			text := fmt.Sprintf("%d:%d => %s:%d:%d", m.GeneratedLine, m.GeneratedColumn,
+				sm.Sources[m.SourceIndex], m.OriginalLine, m.OriginalColumn)
+			if m.OriginalName.IsValid() {
+				text += " " + sm.Names[m.OriginalName.GetIndex()]
+			}
+			result = append(result, text)
+		}
+		return result
+	}
+
+	for _, minify := range []bool{false, true} {
+		build := func(sections bool) BuildResult {
+			return Build(BuildOptions{
+				EntryPoints:       []string{"src/entry.js", "src/other.js"},
+				Outdir:            "out",
+				AbsWorkingDir:     "/project",
+				FS:                files,
+				Bundle:            true,
+				Splitting:         true,
+				Format:            FormatESModule,
+				ChunkNames:        "[name]",
+				Loader:            map[string]Loader{".png": LoaderFile},
+				MinifyWhitespace:  minify,
+				MinifyIdentifiers: minify,
+				Sourcemap:         SourceMapExternal,
+				SourcemapSections: sections,
+				LogLevel:          LogLevelSilent,
+			})
+		}
+
+		flat := build(false)
+		sectioned := build(true)
+		test.AssertEqual(t, len(flat.Errors), 0)
+		test.AssertEqual(t, len(sectioned.Errors), 0)
+		test.AssertEqual(t, len(sectioned.OutputFiles), len(flat.OutputFiles))
+
+		// Both kinds of source maps must describe the same mappings, including
+		// after the paths of other chunks and assets have been substituted
+		maps := 0
+		for i, file := range sectioned.OutputFiles {
+			test.AssertEqual(t, file.Path, flat.OutputFiles[i].Path)
+			if strings.HasSuffix(file.Path, ".map") {
+				test.AssertEqual(t, strings.Contains(string(file.Contents), "\"sections\": ["), true)
+				test.AssertEqualWithDiff(t, strings.Join(decode(file.Contents), "\n"), strings.Join(decode(flat.OutputFiles[i].Contents), "\n"))
+				maps++
+			} else {
+				test.AssertEqualWithDiff(t, string(file.Contents), string(flat.OutputFiles[i].Contents))
+			}
+		}
+		test.AssertEqual(t, maps, 5)
+	}
+}
+
+func TestCSSInputSourceMap(t *testing.T) {
+	files := mapFS{
+		"/project/src/entry.css":        "@import './compiled.css';\n@import './plugin.scss';\n",
+		"/project/src/compiled.css":     "a {\n  color: red;\n}\n/*# sourceMappingURL=compiled.css.map */\n",
+		"/project/src/compiled.css.map": `{"version": 3, "sources": ["compiled.scss"], "mappings": "AAAA;EACE"}`,
+		"/project/src/compiled.scss":    "a {\n  color: red;\n}\n",
+		"/project/src/plugin.scss":      "$c: blue;\nb {\n  color: $c;\n}\n",
+	}
+
+	build := func(sourceMap string) BuildResult {
+		return Build(BuildOptions{
+			EntryPoints:   []string{"src/entry.css"},
+			Outdir:        "out",
+			AbsWorkingDir: "/project",
+			FS:            files,
+			Bundle:        true,
+			Sourcemap:     SourceMapExternal,
+			LogLevel:      LogLevelSilent,
+			Plugins: []Plugin{{
+				Name: "scss",
+				Setup: func(build PluginBuild) {
+					build.OnLoad(OnLoadOptions{Filter: `plugin\.scss$`}, func(args OnLoadArgs) (OnLoadResult, error) {
+						contents := "b {\n  color: blue;\n}\n/*# sourceMappingURL=ignored.map */\n"
+						return OnLoadResult{Contents: &contents, SourceMap: &sourceMap, Loader: LoaderCSS}, nil
+					})
+				},
+			}},
+		})
+	}
+
+	// Both the source map comment and the source map from the plugin should be
+	// used to map the output back to the original files
+	result := build(`{"version": 3, "sources": ["plugin.scss"], "mappings": "AACA;EACE"}`)
+	test.AssertEqual(t, len(result.Errors), 0)
+	test.AssertEqual(t, len(result.Warnings), 0)
+	test.AssertEqual(t, len(result.OutputFiles), 2)
+	log := logger.NewDeferLog(logger.DeferLogNoVerboseOrDebug, nil)
+	sm := js_parser.ParseSourceMap(log, logger.Source{Contents: string(result.OutputFiles[0].Contents)})
+	test.AssertEqual(t, len(log.Done()), 0)
+	test.AssertEqualWithDiff(t, strings.Join(sm.Sources, ","), "../src/compiled.scss,../src/plugin.scss")
+	test.AssertEqual(t, helpers.UTF16ToString(sm.SourcesContent[0].Value), files["/project/src/compiled.scss"])
+	test.AssertEqual(t, helpers.UTF16ToString(sm.SourcesContent[1].Value), files["/project/src/plugin.scss"])
+	var mappings []string
+	for _, m := range sm.Mappings {
+		mappings = append(mappings, fmt.Sprintf("%d:%d => %s:%d:%d", m.GeneratedLine, m.GeneratedColumn,
+			sm.Sources[m.SourceIndex], m.OriginalLine, m.OriginalColumn))
+	}
+	test.AssertEqualWithDiff(t, strings.Join(mappings, "\n"), `1:0 => ../src/compiled.scss:0:0
+2:0 => ../src/compiled.scss:1:2
+2:9 => ../src/compiled.scss:1:2
+6:0 => ../src/plugin.scss:1:0
+7:0 => ../src/plugin.scss:2:2
+7:9 => ../src/plugin.scss:2:2`)
+
+	// Problems with the source map from the plugin mention the plugin
+	result = build(`{"version": 3, "sources": ["plugin.scss"], "mappings": "AAAA!"}`)
+	test.AssertEqual(t, len(result.Errors), 0)
+	test.AssertEqual(t, len(result.Warnings), 1)
+	test.AssertEqual(t, len(result.Warnings[0].Notes), 1)
+	test.AssertEqual(t, result.Warnings[0].Notes[0].Text, "This source map came from plugin \"scss\"")
+}
+
+func TestSourcemapNames(t *testing.T) {
+	files := mapFS{
+		"/project/entry.js": "let shorthand_ = 1\nlet value = { shorthand_ }\nlet { shorthand_: local } = value\nexport default function named(param) {\n  return [value.shorthand_, local, param]\n}\n",
+	}
+
+	build := func(minify bool) (string, []string, int) {
+		result := Build(BuildOptions{
+			EntryPoints:       []string{"entry.js"},
+			Outfile:           "out.js",
+			AbsWorkingDir:     "/project",
+			FS:                files,
+			Bundle:            true,
+			Format:            FormatESModule,
+			MinifyWhitespace:  true,
+			MinifyIdentifiers: minify,
+			MangleProps:       "_$",
+			Sourcemap:         SourceMapExternal,
+			LogLevel:          LogLevelSilent,
+		})
+		test.AssertEqual(t, len(result.Errors), 0)
+		test.AssertEqual(t, len(result.OutputFiles), 2)
+
+		// Pair each generated identifier that starts a mapping with its name
+		log := logger.NewDeferLog(logger.DeferLogNoVerboseOrDebug, nil)
+		sm := js_parser.ParseSourceMap(log, logger.Source{Contents: string(result.OutputFiles[0].Contents)})
+		test.AssertEqual(t, len(log.Done()), 0)
+		code := strings.Split(string(result.OutputFiles[1].Contents), "\n")
+		var names []string
+		repeated := 0
+		for i, m := range sm.Mappings {
+			if i > 0 && m.OriginalName.IsValid() && m.OriginalName == sm.Mappings[i-1].OriginalName &&
+				m.OriginalLine == sm.Mappings[i-1].OriginalLine && m.OriginalColumn == sm.Mappings[i-1].OriginalColumn {
+				repeated++
+			}
+			if m.OriginalName.IsValid() {
+				line := code[m.GeneratedLine]
+				end := m.GeneratedColumn
+				for end < int32(len(line)) && js_ast.IsIdentifierContinue(rune(line[end])) {
+					end++
+				}
+				names = append(names, line[m.GeneratedColumn:end]+"="+sm.Names[m.OriginalName.GetIndex()])
+			}
+		}
+		return string(result.OutputFiles[1].Contents), names, repeated
+	}
+
+	// Every renamed identifier should have a mapping with its original name,
+	// including the value in a shorthand property when only the key is mangled
+	code, names, repeated := build(true)
+	test.AssertEqualWithDiff(t, code, "var t=1;var e={e:t};var{e:a}=e;function n(l){return[e.e,a,l]}export{n as default};\n")
+	test.AssertEqualWithDiff(t, strings.Join(names, " "),
+		"t=shorthand_ e=value e=shorthand_ t=shorthand_ e=shorthand_ a=local e=value n=named l=param e=value e=shorthand_ a=local l=param")
+
+	// The only extra mapping is the one for "t" in "{e:t}", which repeats the
+	// original location and name of the mapping for "e" before it. This guards
+	// against source maps growing by more than one mapping per shorthand.
+	test.AssertEqual(t, repeated, 1)
+
+	// Names are only added for identifiers that were renamed, so source maps
+	// for code without renamed identifiers don't get any bigger
+	_, names, repeated = build(false)
+	test.AssertEqualWithDiff(t, strings.Join(names, " "), "a=shorthand_ a=shorthand_ a=shorthand_")
+	test.AssertEqual(t, repeated, 0)
+}
+
+func TestTransformMany(t *testing.T) {
+	shared := TransformOptions{
+		Define:            map[string]string{"DEBUG": "false"},
+		Target:            ES2015,
+		MinifyWhitespace:  true,
+		MinifyIdentifiers: true,
+		Sourcemap:         SourceMapExternal,
+		LogLevel:          LogLevelSilent,
+	}
+	withLoader := func(options TransformOptions, loader Loader, sourcefile string) TransformOptions {
+		options.Loader = loader
+		options.Sourcefile = sourcefile
+		return options
+	}
+	invalid := shared
+	invalid.Define = map[string]string{"DEBUG": "+"}
+
+	inputs := []TransformInput{
+		{Contents: "let x: number = DEBUG ? 1 : 2; export { x }", Options: withLoader(shared, LoaderTS, "a.ts")},
+		{Contents: "a { color: red }", Options: withLoader(shared, LoaderCSS, "b.css")},
+		{Contents: "let y = x ?? <div/>", Options: withLoader(shared, LoaderJSX, "c.jsx")},
+		{Contents: "let z = (", Options: withLoader(shared, LoaderJS, "d.js")},
+		{Contents: "let w = 1", Options: invalid},
+		{Contents: "let v = DEBUG", Options: withLoader(invalid, LoaderJS, "e.js")},
+	}
+	results := TransformMany(inputs)
+	test.AssertEqual(t, len(results), len(inputs))
+
+	// Each result should be the same as transforming that input by itself
+	for i, result := range results {
+		expected := Transform(inputs[i].Contents, inputs[i].Options)
+		test.AssertEqualWithDiff(t, string(result.Code), string(expected.Code))
+		test.AssertEqualWithDiff(t, string(result.Map), string(expected.Map))
+		test.AssertEqual(t, len(result.Errors), len(expected.Errors))
+		for j, msg := range result.Errors {
+			test.AssertEqual(t, msg.Text, expected.Errors[j].Text)
+		}
+	}
+	test.AssertEqualWithDiff(t, string(results[0].Code), "let e=false?1:2;export{e as x};\n")
+	test.AssertEqualWithDiff(t, string(results[1].Code), "a{color:red}\n")
+	test.AssertEqualWithDiff(t, string(results[2].Code), "let y=x!=null?x:React.createElement(\"div\",null);\n")
+	test.AssertEqual(t, strings.Contains(string(results[2].Map), "\"sources\": [\"c.jsx\"]"), true)
+
+	// Errors in one input don't affect the other inputs, but errors from shared
+	// options are reported for every input that uses them
+	test.AssertEqual(t, len(results[3].Errors), 1)
+	test.AssertEqual(t, results[3].Errors[0].Text, "Unexpected end of file")
+	test.AssertEqual(t, len(results[4].Errors), 1)
+	test.AssertEqual(t, results[4].Errors[0].Text, "Invalid define value (must be an entity name or valid JSON syntax): +")
+	test.AssertEqual(t, len(results[5].Errors), 1)
+	test.AssertEqual(t, results[5].Errors[0].Text, results[4].Errors[0].Text)
+
+	// The options passed in shouldn't be modified
+	test.AssertEqual(t, inputs[4].Options.Sourcefile, "")
+	test.AssertEqual(t, inputs[4].Options.Loader, LoaderNone)
+
+	// Options that only differ in per-input settings or map order share a key
+	a := withLoader(shared, LoaderTS, "a.ts")
+	a.Supported = map[string]bool{"bigint": true, "arrow": false, "class": true}
+	b := withLoader(shared, LoaderCSS, "b.css")
+	b.Supported = map[string]bool{"class": true, "arrow": false, "bigint": true}
+	b.Banner = "/* banner */"
+	test.AssertEqual(t, sharedTransformOptionsKey(a), sharedTransformOptionsKey(b))
+	test.AssertEqual(t, sharedTransformOptionsKey(shared) == sharedTransformOptionsKey(invalid), false)
+	c := shared
+	c.SourcemapIgnoreList = []string{}
+	test.AssertEqual(t, sharedTransformOptionsKey(shared) == sharedTransformOptionsKey(c), false)
+}
+
+func TestCacheDir(t *testing.T) {
+	dir, err := ioutil.TempDir("", "esbuild-cache-dir-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	files := map[string]string{
+		"entry.ts":  "import './style.css'\nimport data from './data.json'\nexport let greet = (name: string) => 'hello ' + name + data.suffix\n",
+		"style.css": "@import './reset.css';\nbody { color: red }\n",
+		"reset.css": "* { margin: 0 }\n",
+		"data.json": `{ "suffix": "!" }`,
+	}
+	for name, contents := range files {
+		if err := ioutil.WriteFile(filepath.Join(dir, name), []byte(contents), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Each build uses a new in-memory cache, so the second build can only
+	// reuse parse results from the first build through the cache directory
+	build := func() BuildResult {
+		result := Build(BuildOptions{
+			EntryPoints:   []string{"entry.ts"},
+			Outdir:        "out",
+			AbsWorkingDir: dir,
+			CacheDir:      "cache",
+			Bundle:        true,
+			Format:        FormatESModule,
+			LogLevel:      LogLevelSilent,
+		})
+		test.AssertEqual(t, len(result.Errors), 0)
+		test.AssertEqual(t, len(result.OutputFiles), 2)
+		return result
+	}
+	first := build()
+	for _, kind := range []string{"css", "js", "json"} {
+		matches, err := filepath.Glob(filepath.Join(dir, "cache", "esbuild-*", kind, "*", "*"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(matches) == 0 {
+			t.Fatalf("Expected %s files to be in the cache directory", kind)
+		}
+	}
+	second := build()
+	for i, file := range first.OutputFiles {
+		test.AssertEqualWithDiff(t, string(second.OutputFiles[i].Contents), string(file.Contents))
+	}
+}
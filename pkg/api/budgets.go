@@ -0,0 +1,160 @@
+package api
+
+// This implements size budgets for output files. Budgets are checked after
+// the bundle is compiled but before anything is written, so a build that goes
+// over budget fails the same way as a build with any other error.
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/evanw/esbuild/internal/fs"
+	"github.com/evanw/esbuild/internal/graph"
+	"github.com/evanw/esbuild/internal/helpers"
+	"github.com/evanw/esbuild/internal/logger"
+)
+
+type outputBudget struct {
+	path         string
+	pattern      *regexp.Regexp
+	matchesBase  bool
+	maxBytes     int
+	maxGzipBytes int
+	kind         logger.MsgKind
+}
+
+func validateBudgets(log logger.Log, budgets []Budget) []outputBudget {
+	var result []outputBudget
+	for _, budget := range budgets {
+		if budget.Path == "" {
+			log.AddError(nil, logger.Range{}, "Missing path pattern for budget")
+			continue
+		}
+		if budget.MaxBytes < 0 || budget.MaxGzipBytes < 0 {
+			log.AddError(nil, logger.Range{}, fmt.Sprintf("Invalid negative size for budget %q", budget.Path))
+			continue
+		}
+		if budget.MaxBytes == 0 && budget.MaxGzipBytes == 0 {
+			log.AddError(nil, logger.Range{}, fmt.Sprintf("Missing maximum size for budget %q", budget.Path))
+			continue
+		}
+		kind := logger.Error
+		if budget.Warn {
+			kind = logger.Warning
+		}
+		result = append(result, outputBudget{
+			path:         budget.Path,
+			pattern:      helpers.GlobPatternToRegexp(helpers.ParseGlobPattern(budget.Path)),
+			matchesBase:  !strings.Contains(budget.Path, "/"),
+			maxBytes:     budget.MaxBytes,
+			maxGzipBytes: budget.MaxGzipBytes,
+			kind:         kind,
+		})
+	}
+	return result
+}
+
+func gzipSize(contents []byte) int {
+	var buffer bytes.Buffer
+	writer, _ := gzip.NewWriterLevel(&buffer, gzip.BestCompression)
+	writer.Write(contents)
+	writer.Close()
+	return buffer.Len()
+}
+
+// Patterns are matched against output paths relative to the output directory.
+// Like in ".gitignore", patterns without a slash only match the file name.
+func checkBudgets(log logger.Log, budgets []outputBudget, fs fs.FS, absOutputDir string, results []graph.OutputFile, metafile string) {
+	var metafileOutputs []metafileOutput
+	didParseMetafile := false
+
+	for _, item := range results {
+		relPath, ok := fs.Rel(absOutputDir, item.AbsPath)
+		if !ok {
+			continue
+		}
+		relPath = strings.ReplaceAll(relPath, "\\", "/")
+		prettyPath := item.AbsPath
+		if rel, ok := fs.Rel(fs.Cwd(), item.AbsPath); ok {
+			prettyPath = strings.ReplaceAll(rel, "\\", "/")
+		}
+		compressedSize := -1
+
+		for _, budget := range budgets {
+			subject := relPath
+			if budget.matchesBase {
+				subject = fs.Base(relPath)
+			}
+			if !budget.pattern.MatchString(subject) {
+				continue
+			}
+
+			var text string
+			isGzip := false
+			if budget.maxBytes > 0 && len(item.Contents) > budget.maxBytes {
+				text = fmt.Sprintf("Output file %q is %d bytes, which exceeds the budget of %d bytes for %q",
+					prettyPath, len(item.Contents), budget.maxBytes, budget.path)
+			} else if budget.maxGzipBytes > 0 {
+				if compressedSize == -1 {
+					compressedSize = gzipSize(item.Contents)
+				}
+				if compressedSize > budget.maxGzipBytes {
+					isGzip = true
+					text = fmt.Sprintf("Output file %q is %d bytes after gzip compression, which exceeds the budget of %d bytes for %q",
+						prettyPath, compressedSize, budget.maxGzipBytes, budget.path)
+				}
+			}
+			if text == "" {
+				continue
+			}
+
+			// Use the metafile to explain which inputs take up the most space
+			if !didParseMetafile {
+				metafileOutputs, _ = parseMetafileOutputs(metafile)
+				didParseMetafile = true
+			}
+			log.AddMsg(logger.Msg{
+				Kind:  budget.kind,
+				Data:  logger.MsgData{Text: text},
+				Notes: largestInputsForBudget(metafileOutputs, prettyPath, len(item.Contents), isGzip),
+			})
+		}
+	}
+}
+
+const maxBudgetNotes = 5
+
+// Sizes in the metafile are always uncompressed since inputs can't be compressed
+// separately, so they are shown as a share of the uncompressed output file even
+// when it's the gzip budget that was exceeded.
+func largestInputsForBudget(outputs []metafileOutput, path string, size int, isGzip bool) (notes []logger.MsgData) {
+	for _, output := range outputs {
+		if output.path != path {
+			continue
+		}
+		inputs := append([]metafileEntry{}, output.inputs...)
+		sort.SliceStable(inputs, func(i int, j int) bool {
+			return inputs[i].size > inputs[j].size
+		})
+		for i, input := range inputs {
+			if i == maxBudgetNotes {
+				notes = append(notes, logger.MsgData{Text: fmt.Sprintf("%d more inputs are not shown", len(inputs)-i)})
+				break
+			}
+			percent := 100 * float64(input.size) / float64(size)
+			if isGzip {
+				notes = append(notes, logger.MsgData{Text: fmt.Sprintf("The input %q is %d bytes of this output file before compression (%.1f%% of the uncompressed size)",
+					input.name, input.size, percent)})
+			} else {
+				notes = append(notes, logger.MsgData{Text: fmt.Sprintf("The input %q is %d bytes of this output file (%.1f%%)",
+					input.name, input.size, percent)})
+			}
+		}
+		break
+	}
+	return
+}
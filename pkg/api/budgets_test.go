@@ -0,0 +1,74 @@
+package api
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/evanw/esbuild/internal/test"
+)
+
+func TestBudgets(t *testing.T) {
+	files := mapFS{
+		"/project/src/entry.js":                    "import big from './big'\nimport small from 'small'\nconsole.log(big, small)\n",
+		"/project/src/big.js":                      "export default '" + strings.Repeat("x", 1000) + "'\n",
+		"/project/node_modules/small/package.json": `{}`,
+		"/project/node_modules/small/index.js":     "export default 'small'\n",
+		"/project/src/style.css":                   "a { color: red }\n",
+	}
+
+	build := func(budgets []Budget) BuildResult {
+		return Build(BuildOptions{
+			EntryPoints:   []string{"src/entry.js", "src/style.css"},
+			Outdir:        "out",
+			AbsWorkingDir: "/project",
+			FS:            files,
+			Bundle:        true,
+			LogLevel:      LogLevelSilent,
+			Budgets:       budgets,
+		})
+	}
+
+	// Output files that are under budget don't generate any messages
+	result := build([]Budget{{Path: "*.js", MaxBytes: 2000}, {Path: "*.css", MaxBytes: 100, MaxGzipBytes: 100}})
+	test.AssertEqual(t, len(result.Errors), 0)
+	test.AssertEqual(t, len(result.Warnings), 0)
+	test.AssertEqual(t, len(result.OutputFiles), 2)
+
+	// The metafile is only returned if it was requested
+	test.AssertEqual(t, result.Metafile, "")
+
+	// Going over budget is an error that lists the largest inputs
+	result = build([]Budget{{Path: "*.js", MaxBytes: 500}})
+	test.AssertEqual(t, len(result.Errors), 1)
+	test.AssertEqual(t, len(result.OutputFiles), 0)
+	test.AssertEqual(t, result.Errors[0].Text, "Output file \"out/entry.js\" is 1182 bytes, which exceeds the budget of 500 bytes for \"*.js\"")
+	test.AssertEqual(t, len(result.Errors[0].Notes), 3)
+	test.AssertEqual(t, result.Errors[0].Notes[0].Text, "The input \"src/big.js\" is 1024 bytes of this output file (86.6%)")
+	test.AssertEqual(t, result.Errors[0].Notes[1].Text, "The input \"src/entry.js\" is 43 bytes of this output file (3.6%)")
+	test.AssertEqual(t, result.Errors[0].Notes[2].Text, "The input \"node_modules/small/index.js\" is 31 bytes of this output file (2.6%)")
+
+	// Budgets can also limit the compressed size and can be warnings instead
+	result = build([]Budget{{Path: "*.js", MaxGzipBytes: 10, Warn: true}})
+	test.AssertEqual(t, len(result.Errors), 0)
+	test.AssertEqual(t, len(result.Warnings), 1)
+	test.AssertEqual(t, len(result.OutputFiles), 2)
+	test.AssertEqual(t, strings.HasPrefix(result.Warnings[0].Text, "Output file \"out/entry.js\" is "), true)
+	test.AssertEqual(t, strings.HasSuffix(result.Warnings[0].Text, " bytes after gzip compression, which exceeds the budget of 10 bytes for \"*.js\""), true)
+
+	// Input sizes are only known before compression, which the notes make clear
+	test.AssertEqual(t, len(result.Warnings[0].Notes), 3)
+	test.AssertEqual(t, result.Warnings[0].Notes[0].Text,
+		"The input \"src/big.js\" is 1024 bytes of this output file before compression (86.6% of the uncompressed size)")
+
+	// Patterns with a slash are matched against the path in the output directory
+	result = build([]Budget{{Path: "**/*.css", MaxBytes: 1}, {Path: "src/*.js", MaxBytes: 1}})
+	test.AssertEqual(t, len(result.Errors), 1)
+	test.AssertEqual(t, result.Errors[0].Text, "Output file \"out/style.css\" is 40 bytes, which exceeds the budget of 1 bytes for \"**/*.css\"")
+
+	// Invalid budgets
+	result = build([]Budget{{MaxBytes: 1}, {Path: "*.js"}, {Path: "*.css", MaxBytes: -1}})
+	test.AssertEqual(t, len(result.Errors), 3)
+	test.AssertEqual(t, result.Errors[0].Text, "Missing path pattern for budget")
+	test.AssertEqual(t, result.Errors[1].Text, "Missing maximum size for budget \"*.js\"")
+	test.AssertEqual(t, result.Errors[2].Text, "Invalid negative size for budget \"*.css\"")
+}
@@ -0,0 +1,161 @@
+//go:build !js || !wasm
+// +build !js !wasm
+
+package api
+
+// This implements the optional response headers that make the development
+// server behave more like a production server: compression based on the
+// "Accept-Encoding" header, strong ETags with "If-None-Match" support, and
+// "Cache-Control" headers for certain paths. This is useful for testing how
+// a site behaves with caching before it's deployed.
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/base64"
+	"encoding/binary"
+	"fmt"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/evanw/esbuild/internal/brotli"
+	"github.com/evanw/esbuild/internal/helpers"
+	"github.com/evanw/esbuild/internal/xxhash"
+)
+
+type cacheControlRule struct {
+	pattern      *regexp.Regexp
+	matchesBase  bool
+	cacheControl string
+}
+
+func parseCacheControlRules(options []ServeCacheControl) ([]cacheControlRule, error) {
+	var rules []cacheControlRule
+	for _, option := range options {
+		if option.Path == "" {
+			return nil, fmt.Errorf("Missing path pattern for cache control value: %s", option.Value)
+		}
+		rules = append(rules, cacheControlRule{
+			pattern:      helpers.GlobPatternToRegexp(helpers.ParseGlobPattern(option.Path)),
+			matchesBase:  !strings.Contains(option.Path, "/"),
+			cacheControl: option.Value,
+		})
+	}
+	return rules, nil
+}
+
+// Patterns without a slash only match the file name, like in ".gitignore"
+func (h *apiHandler) cacheControlForPath(urlPath string) string {
+	urlPath = path.Clean(urlPath)
+	for _, rule := range h.cacheControlRules {
+		subject := urlPath
+		if rule.matchesBase {
+			subject = path.Base(urlPath)
+		}
+		if rule.pattern.MatchString(subject) {
+			return rule.cacheControl
+		}
+	}
+	return ""
+}
+
+// This uses the same hash as the "hash" property of output files
+func hashForETag(contents []byte) string {
+	var hashBytes [8]byte
+	hasher := xxhash.New()
+	hasher.Write(contents)
+	binary.LittleEndian.PutUint64(hashBytes[:], hasher.Sum64())
+	return base64.RawStdEncoding.EncodeToString(hashBytes[:])
+}
+
+// The "If-None-Match" header uses the weak comparison function, so "W/" is
+// ignored: https://httpwg.org/specs/rfc9110.html#field.if-none-match
+func etagMatches(ifNoneMatch string, etag string) bool {
+	for _, item := range strings.Split(ifNoneMatch, ",") {
+		item = strings.TrimSpace(item)
+		if item == "*" || strings.TrimPrefix(item, "W/") == etag {
+			return true
+		}
+	}
+	return false
+}
+
+// Brotli is preferred over gzip when the client accepts both equally
+func negotiateContentEncoding(acceptEncoding string) string {
+	best := ""
+	bestQuality := 0.0
+	for _, item := range strings.Split(acceptEncoding, ",") {
+		name := item
+		quality := 1.0
+		if semicolon := strings.IndexByte(item, ';'); semicolon != -1 {
+			name = item[:semicolon]
+			param := strings.TrimSpace(item[semicolon+1:])
+			if strings.HasPrefix(param, "q=") {
+				if value, err := strconv.ParseFloat(param[2:], 64); err == nil {
+					quality = value
+				}
+			}
+		}
+		name = strings.ToLower(strings.TrimSpace(name))
+		if (name == "br" || name == "gzip") && quality > 0 &&
+			(quality > bestQuality || (quality == bestQuality && name == "br")) {
+			best = name
+			bestQuality = quality
+		}
+	}
+	return best
+}
+
+// Already-compressed formats such as images and fonts aren't compressed again
+func isCompressibleContentType(contentType string) bool {
+	if semicolon := strings.IndexByte(contentType, ';'); semicolon != -1 {
+		contentType = contentType[:semicolon]
+	}
+	return strings.HasPrefix(contentType, "text/") ||
+		strings.HasSuffix(contentType, "+json") ||
+		strings.HasSuffix(contentType, "+xml") ||
+		contentType == "application/javascript" ||
+		contentType == "application/json" ||
+		contentType == "application/wasm" ||
+		contentType == "application/xml"
+}
+
+// Compressed contents are cached by ETag since the same files are typically
+// requested many times between rebuilds. The cache is cleared when it gets
+// too big instead of tracking which entries are still needed.
+const maxCompressedCacheSize = 64 * 1024 * 1024
+
+func (h *apiHandler) compressContents(encoding string, etag string, contents []byte) []byte {
+	h.mutex.Lock()
+	compressed, ok := h.compressedCache[etag]
+	h.mutex.Unlock()
+	if ok {
+		return compressed
+	}
+
+	switch encoding {
+	case "br":
+		compressed = brotli.Compress(contents)
+
+	case "gzip":
+		buffer := bytes.Buffer{}
+		writer := gzip.NewWriter(&buffer)
+		writer.Write(contents)
+		writer.Close()
+		compressed = buffer.Bytes()
+	}
+
+	if etag != "" {
+		h.mutex.Lock()
+		if h.compressedCache == nil || h.compressedCacheSize+len(compressed) > maxCompressedCacheSize {
+			h.compressedCache = make(map[string][]byte)
+			h.compressedCacheSize = 0
+		}
+		h.compressedCache[etag] = compressed
+		h.compressedCacheSize += len(compressed)
+		h.mutex.Unlock()
+	}
+	return compressed
+}
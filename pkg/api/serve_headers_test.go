@@ -0,0 +1,110 @@
+//go:build !js || !wasm
+// +build !js !wasm
+
+package api
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/evanw/esbuild/internal/fs"
+	"github.com/evanw/esbuild/internal/test"
+)
+
+func TestNegotiateContentEncoding(t *testing.T) {
+	test.AssertEqual(t, negotiateContentEncoding(""), "")
+	test.AssertEqual(t, negotiateContentEncoding("identity"), "")
+	test.AssertEqual(t, negotiateContentEncoding("gzip, deflate"), "gzip")
+	test.AssertEqual(t, negotiateContentEncoding("gzip, deflate, br"), "br")
+	test.AssertEqual(t, negotiateContentEncoding("br;q=0.5, gzip"), "gzip")
+	test.AssertEqual(t, negotiateContentEncoding("BR, gzip;q=0"), "br")
+	test.AssertEqual(t, negotiateContentEncoding("br;q=0, gzip;q=0"), "")
+}
+
+func TestETagMatches(t *testing.T) {
+	test.AssertEqual(t, etagMatches("", "\"a\""), false)
+	test.AssertEqual(t, etagMatches("\"a\"", "\"a\""), true)
+	test.AssertEqual(t, etagMatches("W/\"a\"", "\"a\""), true)
+	test.AssertEqual(t, etagMatches("\"b\", \"a\"", "\"a\""), true)
+	test.AssertEqual(t, etagMatches("\"a-br\"", "\"a\""), false)
+	test.AssertEqual(t, etagMatches("*", "\"a\""), true)
+}
+
+func TestCacheControlForPath(t *testing.T) {
+	rules, err := parseCacheControlRules([]ServeCacheControl{
+		{Path: "/assets/**", Value: "max-age=31536000, immutable"},
+		{Path: "*.html", Value: "no-cache"},
+	})
+	test.AssertEqual(t, err, nil)
+
+	h := &apiHandler{cacheControlRules: rules}
+	test.AssertEqual(t, h.cacheControlForPath("/assets/app.js"), "max-age=31536000, immutable")
+	test.AssertEqual(t, h.cacheControlForPath("/assets/img/logo.png"), "max-age=31536000, immutable")
+	test.AssertEqual(t, h.cacheControlForPath("/index.html"), "no-cache")
+	test.AssertEqual(t, h.cacheControlForPath("/docs/page.html"), "no-cache")
+	test.AssertEqual(t, h.cacheControlForPath("/app.js"), "")
+}
+
+func TestServeCompressionAndETags(t *testing.T) {
+	contents := strings.Repeat("console.log('hello')\n", 100)
+	rules, _ := parseCacheControlRules([]ServeCacheControl{{Path: "*.js", Value: "no-cache"}})
+	h := &apiHandler{
+		fs:                fs.MockFS(nil, fs.MockUnix, "/"),
+		absOutputDir:      "/out",
+		compress:          true,
+		cacheControlRules: rules,
+		rebuild: func() BuildResult {
+			return BuildResult{OutputFiles: []OutputFile{{Path: "/out/app.js", Contents: []byte(contents), Hash: "HASH"}}}
+		},
+	}
+
+	get := func(headers map[string]string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", "/app.js", nil)
+		for key, value := range headers {
+			req.Header.Set(key, value)
+		}
+		res := httptest.NewRecorder()
+		h.ServeHTTP(res, req)
+		return res
+	}
+
+	res := get(nil)
+	test.AssertEqual(t, res.Code, http.StatusOK)
+	test.AssertEqual(t, res.Header().Get("ETag"), "\"HASH\"")
+	test.AssertEqual(t, res.Header().Get("Content-Encoding"), "")
+	test.AssertEqual(t, res.Header().Get("Cache-Control"), "no-cache")
+	test.AssertEqual(t, res.Body.String(), contents)
+
+	res = get(map[string]string{"Accept-Encoding": "gzip"})
+	test.AssertEqual(t, res.Code, http.StatusOK)
+	test.AssertEqual(t, res.Header().Get("ETag"), "\"HASH-gzip\"")
+	test.AssertEqual(t, res.Header().Get("Content-Encoding"), "gzip")
+	test.AssertEqual(t, res.Header().Get("Vary"), "Accept-Encoding")
+	reader, err := gzip.NewReader(bytes.NewReader(res.Body.Bytes()))
+	test.AssertEqual(t, err, nil)
+	decompressed, _ := ioutil.ReadAll(reader)
+	test.AssertEqual(t, string(decompressed), contents)
+
+	res = get(map[string]string{"Accept-Encoding": "gzip, br"})
+	test.AssertEqual(t, res.Header().Get("ETag"), "\"HASH-br\"")
+	test.AssertEqual(t, res.Header().Get("Content-Encoding"), "br")
+	test.AssertEqual(t, res.Body.Len() < len(contents), true)
+
+	res = get(map[string]string{"Accept-Encoding": "br", "If-None-Match": "\"HASH-br\""})
+	test.AssertEqual(t, res.Code, http.StatusNotModified)
+	test.AssertEqual(t, res.Body.Len(), 0)
+
+	res = get(map[string]string{"If-None-Match": "\"HASH-br\""})
+	test.AssertEqual(t, res.Code, http.StatusOK)
+
+	// Range requests are never compressed
+	res = get(map[string]string{"Accept-Encoding": "br", "Range": "bytes=0-6"})
+	test.AssertEqual(t, res.Code, http.StatusPartialContent)
+	test.AssertEqual(t, res.Header().Get("ETag"), "\"HASH\"")
+	test.AssertEqual(t, res.Body.String(), "console")
+}
@@ -18,6 +18,7 @@ import (
 	"fmt"
 	"net"
 	"net/http"
+	"net/url"
 	"os"
 	"path"
 	"sort"
@@ -37,21 +38,31 @@ import (
 // Serve API
 
 type apiHandler struct {
-	onRequest        func(ServeOnRequestArgs)
-	rebuild          func() BuildResult
-	stop             func()
-	fs               fs.FS
-	absOutputDir     string
-	outdirPathPrefix string
-	publicPath       string
-	servedir         string
-	keyfileToLower   string
-	certfileToLower  string
-	fallback         string
-	serveWaitGroup   sync.WaitGroup
-	activeStreams    []chan serverSentEvent
-	currentHashes    map[string]string
-	mutex            sync.Mutex
+	onRequest           func(ServeOnRequestArgs)
+	rebuild             func() BuildResult
+	stop                func()
+	fs                  fs.FS
+	absOutputDir        string
+	outdirPathPrefix    string
+	publicPath          string
+	servedir            string
+	keyfileToLower      string
+	certfileToLower     string
+	fallback            string
+	proxyRules          []proxyRule
+	cacheControlRules   []cacheControlRule
+	compressedCache     map[string][]byte
+	compressedCacheSize int
+	serveWaitGroup      sync.WaitGroup
+	activeStreams       []chan serverSentEvent
+	currentHashes       map[string]string
+	currentModules      map[string]map[string]uint64
+	currentErrors       string
+	mutex               sync.Mutex
+	shouldStop          int32
+	errorOverlay        bool
+	hmr                 bool
+	compress            bool
 }
 
 type serverSentEvent struct {
@@ -100,6 +111,34 @@ func errorsToString(errors []Message) string {
 	return sb.String()
 }
 
+// When the handler is mounted under a prefix using "http.StripPrefix", the
+// request path no longer includes that prefix but the original request URI
+// still does. The returned prefix never ends with a slash.
+func mountPrefixForRequest(req *http.Request) string {
+	if uri, err := url.ParseRequestURI(req.RequestURI); err == nil && strings.HasSuffix(uri.Path, req.URL.Path) {
+		return strings.TrimSuffix(uri.Path[:len(uri.Path)-len(req.URL.Path)], "/")
+	}
+	return ""
+}
+
+// The event stream is at "/esbuild" relative to where the handler is mounted
+func eventStreamPathForRequest(req *http.Request) string {
+	return mountPrefixForRequest(req) + "/esbuild"
+}
+
+// Hot module replacement clients need to know where the event stream is, and
+// also where the handler is mounted since the paths of updated output files
+// don't include that prefix. Paths already start with the public path if
+// there is one, so the prefix is only needed when there isn't.
+func (h *apiHandler) hmrServerScriptForRequest(req *http.Request) string {
+	prefix := ""
+	if h.publicPath == "" {
+		prefix = mountPrefixForRequest(req)
+	}
+	return fmt.Sprintf("<script>globalThis.__esbuild_hmr_server__={eventStream:%s,prefix:%s}</script>",
+		quoteForScriptElement(eventStreamPathForRequest(req)), quoteForScriptElement(prefix))
+}
+
 func (h *apiHandler) ServeHTTP(res http.ResponseWriter, req *http.Request) {
 	start := time.Now()
 
@@ -109,6 +148,14 @@ func (h *apiHandler) ServeHTTP(res http.ResponseWriter, req *http.Request) {
 		return
 	}
 
+	// Forward requests that match a proxy rule to the upstream server
+	for i := range h.proxyRules {
+		if rule := &h.proxyRules[i]; rule.matches(req.URL.Path) {
+			h.serveProxy(start, rule, req, res)
+			return
+		}
+	}
+
 	// HEAD requests omit the body
 	maybeWriteResponseBody := func(bytes []byte) { res.Write(bytes) }
 	isHEAD := req.Method == "HEAD"
@@ -122,18 +169,23 @@ func (h *apiHandler) ServeHTTP(res http.ResponseWriter, req *http.Request) {
 		queryPath := path.Clean(req.URL.Path)[1:]
 		result := h.rebuild()
 
-		// Requests fail if the build had errors
-		if len(result.Errors) > 0 {
+		// Requests fail if the build had errors. HTML pages are still served when
+		// the error overlay is enabled since the overlay can show the errors.
+		failWithBuildErrors := func() {
 			res.Header().Set("Content-Type", "text/plain; charset=utf-8")
 			go h.notifyRequest(time.Since(start), req, http.StatusServiceUnavailable)
 			res.WriteHeader(http.StatusServiceUnavailable)
 			maybeWriteResponseBody([]byte(errorsToString(result.Errors)))
+		}
+		if len(result.Errors) > 0 && !h.errorOverlay {
+			failWithBuildErrors()
 			return
 		}
 
 		type fileToServe struct {
 			absPath  string
 			contents fs.OpenedFile
+			hash     string // Only present for output files
 		}
 
 		var kind fs.EntryKind
@@ -143,11 +195,14 @@ func (h *apiHandler) ServeHTTP(res http.ResponseWriter, req *http.Request) {
 
 		// Check for a match with the results if we're within the output directory
 		if outdirQueryPath, ok := stripDirPrefix(queryPath, h.outdirPathPrefix, "/"); ok {
-			resultKind, inMemoryBytes, absPath, isImplicitIndexHTML := h.matchQueryPathToResult(outdirQueryPath, &result, dirEntries, fileEntries)
+			resultKind, outputFile, isImplicitIndexHTML := h.matchQueryPathToResult(outdirQueryPath, &result, dirEntries, fileEntries)
 			kind = resultKind
-			file = fileToServe{
-				absPath:  absPath,
-				contents: &fs.InMemoryOpenedFile{Contents: inMemoryBytes},
+			if outputFile != nil {
+				file = fileToServe{
+					absPath:  outputFile.Path,
+					contents: &fs.InMemoryOpenedFile{Contents: outputFile.Contents},
+					hash:     outputFile.Hash,
+				}
 			}
 			if isImplicitIndexHTML {
 				queryPath = path.Join(queryPath, "index.html")
@@ -268,6 +323,12 @@ func (h *apiHandler) ServeHTTP(res http.ResponseWriter, req *http.Request) {
 			}
 		}
 
+		isHTML := kind == fs.FileEntry && strings.EqualFold(h.fs.Ext(file.absPath), ".html")
+		if len(result.Errors) > 0 && !isHTML {
+			failWithBuildErrors()
+			return
+		}
+
 		// Serve a file
 		if kind == fs.FileEntry {
 			// Default to serving the whole file
@@ -276,9 +337,10 @@ func (h *apiHandler) ServeHTTP(res http.ResponseWriter, req *http.Request) {
 			begin := 0
 			end := fileContentsLen
 			isRange := false
+			injectScripts := isHTML && (h.errorOverlay || h.hmr)
 
 			// Handle range requests so that video playback works in Safari
-			if rangeBegin, rangeEnd, ok := parseRangeHeader(req.Header.Get("Range"), fileContentsLen); ok && rangeBegin < rangeEnd {
+			if rangeBegin, rangeEnd, ok := parseRangeHeader(req.Header.Get("Range"), fileContentsLen); ok && rangeBegin < rangeEnd && !injectScripts {
 				// Note: The content range is inclusive so subtract 1 from the end
 				isRange = true
 				begin = rangeBegin
@@ -296,10 +358,54 @@ func (h *apiHandler) ServeHTTP(res http.ResponseWriter, req *http.Request) {
 			}
 
 			// If we get here, the request was successful
-			if contentType := helpers.MimeTypeByExtension(h.fs.Ext(file.absPath)); contentType != "" {
-				res.Header().Set("Content-Type", contentType)
-			} else {
-				res.Header().Set("Content-Type", "application/octet-stream")
+			if h.hmr && isHTML {
+				fileBytes = injectAtStartOfHead(fileBytes, h.hmrServerScriptForRequest(req))
+			}
+			if h.errorOverlay && isHTML {
+				fileBytes = injectErrorOverlay(fileBytes, eventStreamPathForRequest(req))
+			}
+			contentType := helpers.MimeTypeByExtension(h.fs.Ext(file.absPath))
+			if contentType == "" {
+				contentType = "application/octet-stream"
+			}
+			res.Header().Set("Content-Type", contentType)
+
+			// Output files already have a hash. Other files are hashed here, but
+			// not for range requests since the whole file isn't read then.
+			etag := file.hash
+			if etag == "" || injectScripts {
+				etag = ""
+				if !isRange {
+					etag = hashForETag(fileBytes)
+				}
+			}
+
+			// Each content encoding needs a different ETag
+			encoding := ""
+			if h.compress && !isRange && isCompressibleContentType(contentType) {
+				res.Header().Add("Vary", "Accept-Encoding")
+				if encoding = negotiateContentEncoding(req.Header.Get("Accept-Encoding")); encoding != "" && etag != "" {
+					etag += "-" + encoding
+				}
+			}
+			if etag != "" {
+				etag = "\"" + etag + "\""
+				res.Header().Set("ETag", etag)
+			}
+			if cacheControl := h.cacheControlForPath(req.URL.Path); cacheControl != "" {
+				res.Header().Set("Cache-Control", cacheControl)
+			}
+
+			// The client already has this file
+			if etag != "" && etagMatches(req.Header.Get("If-None-Match"), etag) {
+				go h.notifyRequest(time.Since(start), req, http.StatusNotModified)
+				res.WriteHeader(http.StatusNotModified)
+				return
+			}
+
+			if encoding != "" {
+				fileBytes = h.compressContents(encoding, etag, fileBytes)
+				res.Header().Set("Content-Encoding", encoding)
 			}
 			if isRange {
 				res.Header().Set("Content-Range", fmt.Sprintf("bytes %d-%d/%d", begin, end-1, fileContentsLen))
@@ -338,6 +444,7 @@ func (h *apiHandler) serveEventStream(start time.Time, req *http.Request, res ht
 			stream := make(chan serverSentEvent)
 			h.mutex.Lock()
 			h.activeStreams = append(h.activeStreams, stream)
+			currentErrors := h.currentErrors
 			h.mutex.Unlock()
 
 			// Start the event stream
@@ -348,6 +455,11 @@ func (h *apiHandler) serveEventStream(start time.Time, req *http.Request, res ht
 			go h.notifyRequest(time.Since(start), req, http.StatusOK)
 			res.WriteHeader(http.StatusOK)
 			res.Write([]byte("retry: 500\n"))
+
+			// Let new clients know about errors from the most recent build
+			if currentErrors != "" {
+				res.Write([]byte(fmt.Sprintf("event: errors\ndata: %s\n\n", currentErrors)))
+			}
 			flusher.Flush()
 
 			// Send incoming messages over the stream
@@ -405,12 +517,13 @@ func (h *apiHandler) serveEventStream(start time.Time, req *http.Request, res ht
 	res.Write([]byte("500 - Event stream error"))
 }
 
-func (h *apiHandler) broadcastBuildResult(result BuildResult, newHashes map[string]string) {
+func (h *apiHandler) broadcastBuildResult(result BuildResult, newHashes map[string]string, newModules map[string]map[string]uint64) {
 	h.mutex.Lock()
 
 	var added []string
 	var removed []string
 	var updated []string
+	var hmrUpdates []string
 
 	urlForPath := func(absPath string) (string, bool) {
 		if relPath, ok := stripDirPrefix(absPath, h.absOutputDir, "\\/"); ok {
@@ -451,6 +564,45 @@ func (h *apiHandler) broadcastBuildResult(result BuildResult, newHashes map[stri
 				}
 			}
 		}
+
+		// When hot module replacement is enabled, also diff the modules inside
+		// each output file. The empty path stands for all code that can't be
+		// replaced individually, and changes to it require a full reload.
+		oldModules := h.currentModules
+		h.currentModules = newModules
+		for absPath, modules := range newModules {
+			oldModules, ok := oldModules[absPath]
+			if !ok {
+				continue
+			}
+			url, ok := urlForPath(absPath)
+			if !ok {
+				continue
+			}
+			var changed []string
+			for module, hash := range modules {
+				if oldHash, ok := oldModules[module]; module != "" && (!ok || oldHash != hash) {
+					changed = append(changed, module)
+				}
+			}
+			reload := modules[""] != oldModules[""]
+			if len(changed) == 0 && !reload {
+				continue
+			}
+			sort.Strings(changed)
+			var sb strings.Builder
+			sb.WriteString("{\"url\":")
+			sb.Write(helpers.QuoteForJSON(url, false))
+			sb.WriteString(",\"modules\":[")
+			for i, module := range changed {
+				if i > 0 {
+					sb.WriteRune(',')
+				}
+				sb.Write(helpers.QuoteForJSON(module, false))
+			}
+			fmt.Fprintf(&sb, "],\"reload\":%v}", reload)
+			hmrUpdates = append(hmrUpdates, sb.String())
+		}
 	}
 
 	// Only notify listeners if there's a change that's worth sending. That way
@@ -493,6 +645,38 @@ func (h *apiHandler) broadcastBuildResult(result BuildResult, newHashes map[stri
 		}
 	}
 
+	// Send the errors from failed builds to clients so they can be shown in the
+	// browser. An empty list is sent once a build succeeds again.
+	if len(result.Errors) > 0 || h.currentErrors != "" {
+		json := messagesToJSON(result.Errors)
+		if len(result.Errors) > 0 {
+			h.currentErrors = json
+		} else {
+			h.currentErrors = ""
+		}
+		for _, stream := range h.activeStreams {
+			stream <- serverSentEvent{event: "errors", data: json}
+		}
+	}
+
+	// Hot module replacement clients get a separate event that says which
+	// modules to re-run. Updated stylesheets are swapped out in place.
+	var hmrCSS []string
+	for _, path := range updated {
+		if strings.HasSuffix(path, ".css") {
+			hmrCSS = append(hmrCSS, string(helpers.QuoteForJSON(path, false)))
+		}
+	}
+	if h.currentModules != nil && (len(hmrUpdates) > 0 || len(hmrCSS) > 0) {
+		sort.Strings(hmrUpdates)
+		sort.Strings(hmrCSS)
+		json := fmt.Sprintf("{\"updates\":[%s],\"css\":[%s]}", strings.Join(hmrUpdates, ","), strings.Join(hmrCSS, ","))
+
+		for _, stream := range h.activeStreams {
+			stream <- serverSentEvent{event: "hmr", data: json}
+		}
+	}
+
 	h.mutex.Unlock()
 }
 
@@ -535,7 +719,7 @@ func (h *apiHandler) matchQueryPathToResult(
 	result *BuildResult,
 	dirEntries map[string]bool,
 	fileEntries map[string]bool,
-) (fs.EntryKind, []byte, string, bool) {
+) (fs.EntryKind, *OutputFile, bool) {
 	queryIsDir := false
 	queryDir := queryPath
 	if queryDir != "" {
@@ -543,18 +727,19 @@ func (h *apiHandler) matchQueryPathToResult(
 	}
 
 	// Check the output files for a match
-	for _, file := range result.OutputFiles {
+	for i := range result.OutputFiles {
+		file := &result.OutputFiles[i]
 		if relPath, ok := h.fs.Rel(h.absOutputDir, file.Path); ok {
 			relPath = strings.ReplaceAll(relPath, "\\", "/")
 
 			// An exact match
 			if relPath == queryPath {
-				return fs.FileEntry, file.Contents, file.Path, false
+				return fs.FileEntry, file, false
 			}
 
 			// Serve an "index.html" file if present
 			if dir, base := path.Split(relPath); base == "index.html" && queryDir == dir {
-				return fs.FileEntry, file.Contents, file.Path, true
+				return fs.FileEntry, file, true
 			}
 
 			// A match inside this directory
@@ -572,10 +757,10 @@ func (h *apiHandler) matchQueryPathToResult(
 
 	// Treat this as a directory if it's non-empty
 	if queryIsDir {
-		return fs.DirEntry, nil, "", false
+		return fs.DirEntry, nil, false
 	}
 
-	return 0, nil, "", false
+	return 0, nil, false
 }
 
 func respondWithDirList(queryPath string, dirEntries map[string]bool, fileEntries map[string]bool) []byte {
@@ -661,23 +846,18 @@ func prettyPrintPath(fs fs.FS, path string) string {
 	return path
 }
 
-func (ctx *internalContext) Serve(serveOptions ServeOptions) (ServeResult, error) {
-	ctx.mutex.Lock()
-	defer ctx.mutex.Unlock()
-
+// This validates everything that isn't specific to the network and creates
+// the handler. It's shared by "Serve()" and "Handler()". The paths in the
+// options are made absolute. The caller must hold the context's mutex.
+func (ctx *internalContext) newHandler(serveOptions *ServeOptions) (*apiHandler, error) {
 	// Ignore disposed contexts
 	if ctx.didDispose {
-		return ServeResult{}, errors.New("Cannot serve a disposed context")
+		return nil, errors.New("Cannot serve a disposed context")
 	}
 
 	// Don't allow starting serve mode multiple times
 	if ctx.handler != nil {
-		return ServeResult{}, errors.New("Serve mode has already been enabled")
-	}
-
-	// Don't allow starting serve mode multiple times
-	if (serveOptions.Keyfile != "") != (serveOptions.Certfile != "") {
-		return ServeResult{}, errors.New("Must specify both key and certificate for HTTPS")
+		return nil, errors.New("Serve mode has already been enabled")
 	}
 
 	// Validate the "servedir" path
@@ -685,7 +865,7 @@ func (ctx *internalContext) Serve(serveOptions ServeOptions) (ServeResult, error
 		if absPath, ok := ctx.realFS.Abs(serveOptions.Servedir); ok {
 			serveOptions.Servedir = absPath
 		} else {
-			return ServeResult{}, fmt.Errorf("Invalid serve path: %s", serveOptions.Servedir)
+			return nil, fmt.Errorf("Invalid serve path: %s", serveOptions.Servedir)
 		}
 	}
 
@@ -694,10 +874,30 @@ func (ctx *internalContext) Serve(serveOptions ServeOptions) (ServeResult, error
 		if absPath, ok := ctx.realFS.Abs(serveOptions.Fallback); ok {
 			serveOptions.Fallback = absPath
 		} else {
-			return ServeResult{}, fmt.Errorf("Invalid fallback path: %s", serveOptions.Fallback)
+			return nil, fmt.Errorf("Invalid fallback path: %s", serveOptions.Fallback)
 		}
 	}
 
+	// HTTPS-related files should be absolute paths
+	if serveOptions.Keyfile != "" {
+		serveOptions.Keyfile, _ = ctx.realFS.Abs(serveOptions.Keyfile)
+	}
+	if serveOptions.Certfile != "" {
+		serveOptions.Certfile, _ = ctx.realFS.Abs(serveOptions.Certfile)
+	}
+
+	// Validate the proxy rules
+	proxyRules, err := parseProxyRules(serveOptions.Proxy)
+	if err != nil {
+		return nil, err
+	}
+
+	// Validate the cache control rules
+	cacheControlRules, err := parseCacheControlRules(serveOptions.CacheControl)
+	if err != nil {
+		return nil, err
+	}
+
 	// Stuff related to the output directory only matters if there are entry points
 	outdirPathPrefix := ""
 	if len(ctx.args.entryPoints) > 0 {
@@ -707,7 +907,7 @@ func (ctx *internalContext) Serve(serveOptions ServeOptions) (ServeResult, error
 			if len(ctx.args.entryPoints) == 1 {
 				what = "an entry point"
 			}
-			return ServeResult{}, fmt.Errorf("Cannot serve %s without an output path", what)
+			return nil, fmt.Errorf("Cannot serve %s without an output path", what)
 		}
 
 		// Compute the output path prefix
@@ -715,12 +915,12 @@ func (ctx *internalContext) Serve(serveOptions ServeOptions) (ServeResult, error
 			// Make sure the output directory is contained in the "servedir" directory
 			relPath, ok := ctx.realFS.Rel(serveOptions.Servedir, ctx.args.options.AbsOutputDir)
 			if !ok {
-				return ServeResult{}, fmt.Errorf(
+				return nil, fmt.Errorf(
 					"Cannot compute relative path from %q to %q\n", serveOptions.Servedir, ctx.args.options.AbsOutputDir)
 			}
 			relPath = strings.ReplaceAll(relPath, "\\", "/") // Fix paths on Windows
 			if relPath == ".." || strings.HasPrefix(relPath, "../") {
-				return ServeResult{}, fmt.Errorf(
+				return nil, fmt.Errorf(
 					"Output directory %q must be contained in serve directory %q",
 					prettyPrintPath(ctx.realFS, ctx.args.options.AbsOutputDir),
 					prettyPrintPath(ctx.realFS, serveOptions.Servedir),
@@ -732,6 +932,57 @@ func (ctx *internalContext) Serve(serveOptions ServeOptions) (ServeResult, error
 		}
 	}
 
+	// The first build will just build normally
+	handler := &apiHandler{
+		onRequest:         serveOptions.OnRequest,
+		outdirPathPrefix:  outdirPathPrefix,
+		absOutputDir:      ctx.args.options.AbsOutputDir,
+		publicPath:        ctx.args.options.PublicPath,
+		servedir:          serveOptions.Servedir,
+		keyfileToLower:    strings.ToLower(serveOptions.Keyfile),
+		certfileToLower:   strings.ToLower(serveOptions.Certfile),
+		fallback:          serveOptions.Fallback,
+		proxyRules:        proxyRules,
+		cacheControlRules: cacheControlRules,
+		compress:          serveOptions.Compress,
+		errorOverlay:      serveOptions.ErrorOverlay,
+		hmr:               ctx.args.options.HMR,
+		fs:                ctx.realFS,
+	}
+	handler.rebuild = func() BuildResult {
+		if atomic.LoadInt32(&handler.shouldStop) != 0 {
+			// Don't start more rebuilds if we were told to stop
+			return BuildResult{}
+		} else {
+			return ctx.activeBuildOrRecentBuildOrRebuild()
+		}
+	}
+	return handler, nil
+}
+
+func (h *apiHandler) closeEventStreams() {
+	h.mutex.Lock()
+	for _, stream := range h.activeStreams {
+		close(stream)
+	}
+	h.activeStreams = nil
+	h.mutex.Unlock()
+}
+
+func (ctx *internalContext) Serve(serveOptions ServeOptions) (ServeResult, error) {
+	ctx.mutex.Lock()
+	defer ctx.mutex.Unlock()
+
+	// Don't allow starting serve mode multiple times
+	if (serveOptions.Keyfile != "") != (serveOptions.Certfile != "") {
+		return ServeResult{}, errors.New("Must specify both key and certificate for HTTPS")
+	}
+
+	handler, err := ctx.newHandler(&serveOptions)
+	if err != nil {
+		return ServeResult{}, err
+	}
+
 	// Determine the host
 	var listener net.Listener
 	network := "tcp4"
@@ -776,53 +1027,20 @@ func (ctx *internalContext) Serve(serveOptions ServeOptions) (ServeResult, error
 		}
 	}
 
-	// HTTPS-related files should be absolute paths
 	isHTTPS := serveOptions.Keyfile != "" && serveOptions.Certfile != ""
-	if isHTTPS {
-		serveOptions.Keyfile, _ = ctx.realFS.Abs(serveOptions.Keyfile)
-		serveOptions.Certfile, _ = ctx.realFS.Abs(serveOptions.Certfile)
-	}
-
-	var shouldStop int32
-
-	// The first build will just build normally
-	handler := &apiHandler{
-		onRequest:        serveOptions.OnRequest,
-		outdirPathPrefix: outdirPathPrefix,
-		absOutputDir:     ctx.args.options.AbsOutputDir,
-		publicPath:       ctx.args.options.PublicPath,
-		servedir:         serveOptions.Servedir,
-		keyfileToLower:   strings.ToLower(serveOptions.Keyfile),
-		certfileToLower:  strings.ToLower(serveOptions.Certfile),
-		fallback:         serveOptions.Fallback,
-		rebuild: func() BuildResult {
-			if atomic.LoadInt32(&shouldStop) != 0 {
-				// Don't start more rebuilds if we were told to stop
-				return BuildResult{}
-			} else {
-				return ctx.activeBuildOrRecentBuildOrRebuild()
-			}
-		},
-		fs: ctx.realFS,
-	}
 
 	// Create the server
 	server := &http.Server{Addr: addr, Handler: handler}
 
 	// When stop is called, block further rebuilds and then close the server
 	handler.stop = func() {
-		atomic.StoreInt32(&shouldStop, 1)
+		atomic.StoreInt32(&handler.shouldStop, 1)
 
 		// Close the server and wait for it to close
 		server.Close()
 
 		// Close all open event streams
-		handler.mutex.Lock()
-		for _, stream := range handler.activeStreams {
-			close(stream)
-		}
-		handler.activeStreams = nil
-		handler.mutex.Unlock()
+		handler.closeEventStreams()
 
 		handler.serveWaitGroup.Wait()
 	}
@@ -972,3 +1190,42 @@ func printURLs(host string, port uint16, https bool, useColor logger.UseColor) {
 		return sb.String()
 	})
 }
+
+// This returns the request handler that "Serve" would use for the given build
+// context so that it can be mounted in your own HTTP server. Requests still
+// trigger rebuilds and the "/esbuild" event stream still works, but the port,
+// host, and HTTPS options are ignored. This can't be combined with "Serve".
+// The handler can be mounted under a path prefix using "http.StripPrefix", in
+// which case the event stream is at that prefix followed by "/esbuild".
+//
+// This is a function instead of a method on "BuildContext" because referencing
+// "net/http" there would make the WebAssembly build a lot bigger. It's not
+// available when using WebAssembly.
+func Handler(ctx BuildContext, options ServeOptions) (http.Handler, error) {
+	if internal, ok := ctx.(*internalContext); ok {
+		return internal.serveHandler(options)
+	}
+	return nil, errors.New("The build context must have been created by \"Context\"")
+}
+
+func (ctx *internalContext) serveHandler(serveOptions ServeOptions) (http.Handler, error) {
+	ctx.mutex.Lock()
+	defer ctx.mutex.Unlock()
+
+	handler, err := ctx.newHandler(&serveOptions)
+	if err != nil {
+		return nil, err
+	}
+
+	// There's no server to close because the caller owns it. Stopping just
+	// prevents further rebuilds and ends any open event streams.
+	handler.stop = func() {
+		atomic.StoreInt32(&handler.shouldStop, 1)
+		handler.closeEventStreams()
+	}
+	ctx.handler = handler
+
+	// Start the first build in the background like "Serve()" does
+	go handler.rebuild()
+	return handler, nil
+}
@@ -0,0 +1,136 @@
+//go:build !js || !wasm
+// +build !js !wasm
+
+package api
+
+import (
+	"bufio"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path"
+	"strings"
+	"testing"
+
+	"github.com/evanw/esbuild/internal/test"
+)
+
+func TestContextHandler(t *testing.T) {
+	dir, err := ioutil.TempDir("", "esbuild-handler-test")
+	test.AssertEqual(t, err, nil)
+	defer os.RemoveAll(dir)
+	ioutil.WriteFile(path.Join(dir, "in.js"), []byte("console.log(123)\n"), 0644)
+	ioutil.WriteFile(path.Join(dir, "index.html"), []byte("<head></head>"), 0644)
+
+	ctx, ctxErr := Context(BuildOptions{
+		EntryPoints:   []string{path.Join(dir, "in.js")},
+		Outdir:        path.Join(dir, "out"),
+		AbsWorkingDir: dir,
+		Bundle:        true,
+		HMR:           true,
+		LogLevel:      LogLevelSilent,
+	})
+	if ctxErr != nil {
+		t.Fatal(ctxErr)
+	}
+	defer ctx.Dispose()
+
+	handler, err := Handler(ctx, ServeOptions{Servedir: dir, ErrorOverlay: true})
+	test.AssertEqual(t, err, nil)
+
+	// Serve mode can only be enabled once
+	_, err = Handler(ctx, ServeOptions{})
+	test.AssertEqual(t, err.Error(), "Serve mode has already been enabled")
+	_, err = ctx.Serve(ServeOptions{})
+	test.AssertEqual(t, err.Error(), "Serve mode has already been enabled")
+
+	// Mount the handler under a prefix to check that it composes with others
+	mux := http.NewServeMux()
+	mux.Handle("/static/", http.StripPrefix("/static", handler))
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	res, err := http.Get(server.URL + "/static/out/in.js")
+	test.AssertEqual(t, err, nil)
+	body, _ := ioutil.ReadAll(res.Body)
+	res.Body.Close()
+	test.AssertEqual(t, res.StatusCode, http.StatusOK)
+	test.AssertEqual(t, strings.Contains(string(body), "console.log(123);\n"), true)
+
+	// The error overlay and the HMR client both connect to the event stream
+	// under the same prefix
+	res, err = http.Get(server.URL + "/static/")
+	test.AssertEqual(t, err, nil)
+	body, _ = ioutil.ReadAll(res.Body)
+	res.Body.Close()
+	test.AssertEqual(t, res.StatusCode, http.StatusOK)
+	test.AssertEqual(t, strings.Contains(string(body), `})("/static/esbuild")</script>`), true)
+	test.AssertEqual(t, strings.Contains(string(body), `{eventStream:"/static/esbuild",prefix:"/static"}`), true)
+
+	// The event stream is available too
+	req, _ := http.NewRequest("GET", server.URL+"/static/esbuild", nil)
+	req.Header.Set("Accept", "text/event-stream")
+	res, err = http.DefaultClient.Do(req)
+	test.AssertEqual(t, err, nil)
+	defer res.Body.Close()
+	test.AssertEqual(t, res.StatusCode, http.StatusOK)
+	line, _ := bufio.NewReader(res.Body).ReadString('\n')
+	test.AssertEqual(t, strings.TrimSpace(line), "retry: 500")
+}
+
+func TestHMRHandlerUnderPrefix(t *testing.T) {
+	dir, err := ioutil.TempDir("", "esbuild-hmr-handler-test")
+	test.AssertEqual(t, err, nil)
+	defer os.RemoveAll(dir)
+	ioutil.WriteFile(path.Join(dir, "in.js"), []byte("console.log(123)\n"), 0644)
+	ioutil.WriteFile(path.Join(dir, "index.html"), []byte("<!DOCTYPE html><head><script src=\"out/in.js\"></script></head>"), 0644)
+
+	ctx, ctxErr := Context(BuildOptions{
+		EntryPoints:   []string{path.Join(dir, "in.js")},
+		Outdir:        path.Join(dir, "out"),
+		AbsWorkingDir: dir,
+		Bundle:        true,
+		HMR:           true,
+		LogLevel:      LogLevelSilent,
+	})
+	if ctxErr != nil {
+		t.Fatal(ctxErr)
+	}
+	defer ctx.Dispose()
+	handler, err := Handler(ctx, ServeOptions{Servedir: dir})
+	test.AssertEqual(t, err, nil)
+	mux := http.NewServeMux()
+	mux.Handle("/static/", http.StripPrefix("/static", handler))
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	// The HMR client is told where the event stream is before the page's own
+	// scripts run, and which prefix to add to the paths of updated files
+	res, err := http.Get(server.URL + "/static/")
+	test.AssertEqual(t, err, nil)
+	body, _ := ioutil.ReadAll(res.Body)
+	res.Body.Close()
+	test.AssertEqual(t, res.StatusCode, http.StatusOK)
+	test.AssertEqual(t, string(body), `<!DOCTYPE html><head>`+
+		`<script>globalThis.__esbuild_hmr_server__={eventStream:"/static/esbuild",prefix:"/static"}</script>`+
+		`<script src="out/in.js"></script></head>`)
+
+	// The bundle reads that configuration instead of assuming "/esbuild"
+	res, err = http.Get(server.URL + "/static/out/in.js")
+	test.AssertEqual(t, err, nil)
+	body, _ = ioutil.ReadAll(res.Body)
+	res.Body.Close()
+	test.AssertEqual(t, res.StatusCode, http.StatusOK)
+	test.AssertEqual(t, strings.Contains(string(body), "globalThis.__esbuild_hmr_server__"), true)
+	test.AssertEqual(t, strings.Contains(string(body), "server.eventStream || \"/esbuild\""), true)
+}
+
+type mockBuildContext struct {
+	BuildContext
+}
+
+func TestHandlerWithOtherContext(t *testing.T) {
+	_, err := Handler(mockBuildContext{}, ServeOptions{})
+	test.AssertEqual(t, err.Error(), "The build context must have been created by \"Context\"")
+}
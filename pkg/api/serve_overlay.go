@@ -0,0 +1,185 @@
+//go:build !js || !wasm
+// +build !js !wasm
+
+package api
+
+// This implements the optional error overlay for the development server. Build
+// errors are sent over the "/esbuild" event stream as JSON and a small client
+// script that's injected into served HTML pages renders them on top of the
+// page. The overlay is removed again once a build succeeds. The helpers that
+// insert scripts into served HTML pages are also used for hot module
+// replacement.
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+
+	"github.com/evanw/esbuild/internal/helpers"
+)
+
+// The JSON format here is the same as the "Message" type in the JavaScript API
+func messagesToJSON(msgs []Message) string {
+	sb := strings.Builder{}
+	sb.WriteRune('[')
+	for i, msg := range msgs {
+		if i > 0 {
+			sb.WriteRune(',')
+		}
+		sb.WriteString("{\"id\":")
+		sb.Write(helpers.QuoteForJSON(msg.ID, false))
+		sb.WriteString(",\"pluginName\":")
+		sb.Write(helpers.QuoteForJSON(msg.PluginName, false))
+		sb.WriteString(",\"text\":")
+		sb.Write(helpers.QuoteForJSON(msg.Text, false))
+		sb.WriteString(",\"location\":")
+		writeLocationJSON(&sb, msg.Location)
+		sb.WriteString(",\"notes\":[")
+		for j, note := range msg.Notes {
+			if j > 0 {
+				sb.WriteRune(',')
+			}
+			sb.WriteString("{\"text\":")
+			sb.Write(helpers.QuoteForJSON(note.Text, false))
+			sb.WriteString(",\"location\":")
+			writeLocationJSON(&sb, note.Location)
+			sb.WriteRune('}')
+		}
+		sb.WriteString("]}")
+	}
+	sb.WriteRune(']')
+	return sb.String()
+}
+
+func writeLocationJSON(sb *strings.Builder, loc *Location) {
+	if loc == nil {
+		sb.WriteString("null")
+		return
+	}
+	sb.WriteString("{\"file\":")
+	sb.Write(helpers.QuoteForJSON(loc.File, false))
+	sb.WriteString(",\"namespace\":")
+	sb.Write(helpers.QuoteForJSON(loc.Namespace, false))
+
+	// The overlay slices "lineText" in JavaScript, which uses UTF-16 indices
+	column, length := loc.Column, loc.Length
+	if column >= 0 && length >= 0 && column+length <= len(loc.LineText) {
+		column = len(helpers.StringToUTF16(loc.LineText[:loc.Column]))
+		length = len(helpers.StringToUTF16(loc.LineText[loc.Column : loc.Column+loc.Length]))
+	}
+	fmt.Fprintf(sb, ",\"line\":%d,\"column\":%d,\"length\":%d,\"lineText\":", loc.Line, column, length)
+	sb.Write(helpers.QuoteForJSON(loc.LineText, false))
+	sb.WriteString(",\"suggestion\":")
+	sb.Write(helpers.QuoteForJSON(loc.Suggestion, false))
+	sb.WriteRune('}')
+}
+
+// This is inserted into HTML pages, so it must not contain "</script>". It's
+// followed by the path of the event stream and then by "errorOverlayScriptEnd".
+const errorOverlayScript = `<script>(eventStream => {
+  let overlay
+  let show = errors => {
+    if (overlay) overlay.remove(), overlay = null
+    if (!errors.length) return
+    let el = (tag, style, text) => {
+      let node = document.createElement(tag)
+      node.style.cssText = style
+      if (text) node.textContent = text
+      return node
+    }
+    let where = loc => loc ? loc.file + ':' + loc.line + ':' + loc.column + ': ' : ''
+    let snippet = (parent, loc) => {
+      if (!loc || !loc.lineText) return
+      let pre = parent.appendChild(el('pre', 'margin:8px 0 0 16px;color:#ccc'))
+      pre.appendChild(el('span', '', loc.lineText.slice(0, loc.column)))
+      pre.appendChild(el('span', 'color:#ff8080;text-decoration:underline', loc.lineText.slice(loc.column, loc.column + loc.length) || ' '))
+      pre.appendChild(el('span', '', loc.lineText.slice(loc.column + loc.length)))
+    }
+    overlay = el('div', 'position:fixed;inset:0;z-index:2147483647;overflow:auto;padding:32px;' +
+      'background:rgba(20,20,20,0.95);color:#eee;font:14px/1.5 Menlo,Consolas,monospace;white-space:pre-wrap')
+    overlay.appendChild(el('div', 'font-size:18px;color:#ff8080;margin-bottom:16px',
+      errors.length === 1 ? 'Build failed with 1 error' : 'Build failed with ' + errors.length + ' errors'))
+    for (let msg of errors) {
+      let box = overlay.appendChild(el('div', 'margin-bottom:24px'))
+      box.appendChild(el('div', 'font-weight:bold', where(msg.location) + 'ERROR: ' + (msg.pluginName ? '[plugin ' + msg.pluginName + '] ' : '') + msg.text))
+      snippet(box, msg.location)
+      for (let note of msg.notes) {
+        let item = box.appendChild(el('div', 'margin:8px 0 0 16px;color:#aaa', where(note.location) + 'NOTE: ' + note.text))
+        snippet(item, note.location)
+      }
+    }
+    let close = overlay.appendChild(el('button', 'position:absolute;top:16px;right:16px;font:inherit;cursor:pointer', 'Close'))
+    close.onclick = () => show([])
+    document.documentElement.appendChild(overlay)
+  }
+  new EventSource(eventStream).addEventListener('errors', e => show(JSON.parse(e.data)))
+})(`
+
+const errorOverlayScriptEnd = `)</script>`
+
+// Strings in inline scripts are quoted with "<" escaped so that they can't end
+// the script element early
+func quoteForScriptElement(text string) string {
+	return strings.ReplaceAll(string(helpers.QuoteForJSON(text, false)), "<", "\\u003C")
+}
+
+// The path of the event stream depends on where the handler is mounted
+func errorOverlayScriptFor(eventStreamPath string) string {
+	return errorOverlayScript + quoteForScriptElement(eventStreamPath) + errorOverlayScriptEnd
+}
+
+// Insert the overlay script at the end of the "<head>" element if there is one
+// so that it runs before the page's own scripts.
+func injectErrorOverlay(html []byte, eventStreamPath string) []byte {
+	script := errorOverlayScriptFor(eventStreamPath)
+	insertAt := len(html)
+	lower := bytes.ToLower(html)
+	if i := bytes.Index(lower, []byte("</head>")); i != -1 {
+		insertAt = i
+	} else if i := bytes.Index(lower, []byte("<body")); i != -1 {
+		insertAt = i
+	}
+	return insertIntoHTML(html, insertAt, script)
+}
+
+// Insert a script that must run before any of the page's own scripts right
+// after the opening "<head>" or "<html>" tag. Pages without either tag get it
+// after the doctype, or at the very start if there's no doctype either.
+func injectAtStartOfHead(html []byte, script string) []byte {
+	lower := bytes.ToLower(html)
+	for _, name := range []string{"head", "html", "!doctype"} {
+		if end := endOfOpeningTag(lower, name); end != -1 {
+			return insertIntoHTML(html, end, script)
+		}
+	}
+	return insertIntoHTML(html, 0, script)
+}
+
+// This returns the index after the ">" of the first opening tag with the given
+// name, or -1 if there isn't one. It makes sure "<head" doesn't match "<header".
+func endOfOpeningTag(lower []byte, name string) int {
+	prefix := []byte("<" + name)
+	for offset := 0; ; {
+		i := bytes.Index(lower[offset:], prefix)
+		if i == -1 {
+			return -1
+		}
+		i += offset + len(prefix)
+		if i < len(lower) {
+			if c := lower[i]; c == '>' || c == '/' || c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == '\f' {
+				if end := bytes.IndexByte(lower[i:], '>'); end != -1 {
+					return i + end + 1
+				}
+				return -1
+			}
+		}
+		offset = i
+	}
+}
+
+func insertIntoHTML(html []byte, insertAt int, text string) []byte {
+	result := make([]byte, 0, len(html)+len(text))
+	result = append(result, html[:insertAt]...)
+	result = append(result, text...)
+	return append(result, html[insertAt:]...)
+}
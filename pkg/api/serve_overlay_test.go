@@ -0,0 +1,49 @@
+//go:build !js || !wasm
+// +build !js !wasm
+
+package api
+
+import (
+	"testing"
+
+	"github.com/evanw/esbuild/internal/test"
+)
+
+func TestInjectErrorOverlay(t *testing.T) {
+	script := errorOverlayScript + `"/esbuild"` + errorOverlayScriptEnd
+	test.AssertEqual(t, string(injectErrorOverlay([]byte("<html><HEAD><title>x</title></HEAD><body></body></html>"), "/esbuild")),
+		"<html><HEAD><title>x</title>"+script+"</HEAD><body></body></html>")
+	test.AssertEqual(t, string(injectErrorOverlay([]byte("<p>no head</p><body>"), "/esbuild")), "<p>no head</p>"+script+"<body>")
+	test.AssertEqual(t, string(injectErrorOverlay([]byte("text"), "/esbuild")), "text"+script)
+
+	// The path of the event stream must not be able to end the script early
+	test.AssertEqual(t, errorOverlayScriptFor("/a</script>/esbuild"),
+		errorOverlayScript+`"/a\u003C/script>/esbuild"`+errorOverlayScriptEnd)
+}
+
+func TestInjectAtStartOfHead(t *testing.T) {
+	script := "<script>x</script>"
+	test.AssertEqual(t, string(injectAtStartOfHead([]byte("<!DOCTYPE html><html><HEAD lang=en><script src=a.js></script></HEAD></html>"), script)),
+		"<!DOCTYPE html><html><HEAD lang=en>"+script+"<script src=a.js></script></HEAD></html>")
+	test.AssertEqual(t, string(injectAtStartOfHead([]byte("<!doctype html><html><body><header></header></body></html>"), script)),
+		"<!doctype html><html>"+script+"<body><header></header></body></html>")
+	test.AssertEqual(t, string(injectAtStartOfHead([]byte("<!doctype html><header></header>"), script)),
+		"<!doctype html>"+script+"<header></header>")
+	test.AssertEqual(t, string(injectAtStartOfHead([]byte("<script src=a.js></script>"), script)), script+"<script src=a.js></script>")
+}
+
+func TestMessagesToJSON(t *testing.T) {
+	test.AssertEqual(t, messagesToJSON(nil), "[]")
+	test.AssertEqual(t, messagesToJSON([]Message{{
+		Text:     "Could not resolve \"x\"",
+		Location: &Location{File: "a.js", Namespace: "file", Line: 1, Column: 7, Length: 3, LineText: "import \"x\""},
+		Notes:    []Note{{Text: "note"}},
+	}}), `[{"id":"","pluginName":"","text":"Could not resolve \"x\"","location":{"file":"a.js","namespace":"file",`+
+		`"line":1,"column":7,"length":3,"lineText":"import \"x\"","suggestion":""},"notes":[{"text":"note","location":null}]}]`)
+
+	// Columns are converted from bytes to UTF-16 code units for JavaScript
+	test.AssertEqual(t, messagesToJSON([]Message{{
+		Location: &Location{Line: 1, Column: 13, Length: 5, LineText: "/* é😀 */ 𐀀x"},
+	}}), `[{"id":"","pluginName":"","text":"","location":{"file":"","namespace":"",`+
+		`"line":1,"column":10,"length":3,"lineText":"/* é😀 */ 𐀀x","suggestion":""},"notes":[]}]`)
+}
@@ -0,0 +1,130 @@
+//go:build !js || !wasm
+// +build !js !wasm
+
+package api
+
+// This implements the proxy rules for the development server. Requests whose
+// path matches a rule are forwarded to an upstream server instead of being
+// served from the build output or the "servedir" directory. This is useful
+// when the front end talks to a back end API server during development, since
+// everything can then be served from the same origin. WebSocket connections
+// are forwarded too because "httputil.ReverseProxy" handles protocol upgrades.
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+	"strings"
+	"time"
+)
+
+type proxyRule struct {
+	prefix     string
+	rewrite    string
+	hasRewrite bool
+	target     *url.URL
+	headers    map[string]string
+}
+
+func parseProxyRules(options []ServeProxy) ([]proxyRule, error) {
+	var rules []proxyRule
+	for _, option := range options {
+		if !strings.HasPrefix(option.Path, "/") {
+			return nil, fmt.Errorf("Invalid proxy path (must start with \"/\"): %s", option.Path)
+		}
+		if option.Rewrite != "" && !strings.HasPrefix(option.Rewrite, "/") {
+			return nil, fmt.Errorf("Invalid proxy rewrite (must start with \"/\"): %s", option.Rewrite)
+		}
+
+		target, err := url.Parse(option.Target)
+		if err != nil || target.Host == "" {
+			return nil, fmt.Errorf("Invalid proxy target: %s", option.Target)
+		}
+
+		// WebSocket URLs are accepted for convenience, but the upgrade handshake
+		// itself is a normal HTTP request
+		switch target.Scheme {
+		case "http", "https":
+		case "ws":
+			target.Scheme = "http"
+		case "wss":
+			target.Scheme = "https"
+		default:
+			return nil, fmt.Errorf("Invalid proxy target (must be an HTTP URL): %s", option.Target)
+		}
+
+		rules = append(rules, proxyRule{
+			prefix:     strings.TrimSuffix(option.Path, "/"),
+			rewrite:    option.Rewrite,
+			hasRewrite: option.Rewrite != "",
+			target:     target,
+			headers:    option.Headers,
+		})
+	}
+	return rules, nil
+}
+
+// The prefix only matches whole path segments, so "/api" matches "/api" and
+// "/api/users" but not "/apis"
+func (rule *proxyRule) matches(urlPath string) bool {
+	return strings.HasPrefix(urlPath, rule.prefix) &&
+		(len(urlPath) == len(rule.prefix) || urlPath[len(rule.prefix)] == '/')
+}
+
+func (rule *proxyRule) upstreamPath(urlPath string) string {
+	if rule.hasRewrite {
+		rest := urlPath[len(rule.prefix):]
+		if strings.HasSuffix(rule.rewrite, "/") && strings.HasPrefix(rest, "/") {
+			rest = rest[1:]
+		}
+		urlPath = rule.rewrite + rest
+	}
+	if base := strings.TrimSuffix(rule.target.Path, "/"); base != "" {
+		urlPath = base + urlPath
+	}
+	return urlPath
+}
+
+func (h *apiHandler) serveProxy(start time.Time, rule *proxyRule, req *http.Request, res http.ResponseWriter) {
+	proxy := &httputil.ReverseProxy{
+		Director: func(out *http.Request) {
+			out.URL.Scheme = rule.target.Scheme
+			out.URL.Host = rule.target.Host
+			out.URL.Path = rule.upstreamPath(req.URL.Path)
+			out.URL.RawPath = ""
+			if rule.target.RawQuery == "" || out.URL.RawQuery == "" {
+				out.URL.RawQuery = rule.target.RawQuery + out.URL.RawQuery
+			} else {
+				out.URL.RawQuery = rule.target.RawQuery + "&" + out.URL.RawQuery
+			}
+
+			// Don't let Go add its own user agent
+			if _, ok := out.Header["User-Agent"]; !ok {
+				out.Header.Set("User-Agent", "")
+			}
+
+			for key, value := range rule.headers {
+				if strings.EqualFold(key, "Host") {
+					out.Host = value
+				} else {
+					out.Header.Set(key, value)
+				}
+			}
+		},
+
+		ModifyResponse: func(upstream *http.Response) error {
+			go h.notifyRequest(time.Since(start), req, upstream.StatusCode)
+			return nil
+		},
+
+		ErrorHandler: func(res http.ResponseWriter, _ *http.Request, err error) {
+			res.Header().Set("Content-Type", "text/plain; charset=utf-8")
+			go h.notifyRequest(time.Since(start), req, http.StatusBadGateway)
+			res.WriteHeader(http.StatusBadGateway)
+			res.Write([]byte(fmt.Sprintf("502 - Bad Gateway: %s", err.Error())))
+		},
+	}
+
+	proxy.ServeHTTP(res, req)
+}
@@ -0,0 +1,140 @@
+//go:build !js || !wasm
+// +build !js !wasm
+
+package api
+
+import (
+	"bufio"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/evanw/esbuild/internal/test"
+)
+
+func TestProxyRuleMatching(t *testing.T) {
+	rules, err := parseProxyRules([]ServeProxy{
+		{Path: "/api/", Target: "http://localhost:3000"},
+		{Path: "/ws", Target: "ws://localhost:3001/socket", Rewrite: "/"},
+		{Path: "/v1", Target: "https://example.com/base/", Rewrite: "/v2"},
+	})
+	test.AssertEqual(t, err, nil)
+
+	apiRule, wsRule, v1Rule := &rules[0], &rules[1], &rules[2]
+	test.AssertEqual(t, apiRule.matches("/api"), true)
+	test.AssertEqual(t, apiRule.matches("/api/users"), true)
+	test.AssertEqual(t, apiRule.matches("/apis"), false)
+	test.AssertEqual(t, apiRule.matches("/"), false)
+
+	test.AssertEqual(t, apiRule.upstreamPath("/api/users"), "/api/users")
+	test.AssertEqual(t, wsRule.target.Scheme, "http")
+	test.AssertEqual(t, wsRule.upstreamPath("/ws"), "/socket/")
+	test.AssertEqual(t, wsRule.upstreamPath("/ws/chat"), "/socket/chat")
+	test.AssertEqual(t, v1Rule.upstreamPath("/v1/users"), "/base/v2/users")
+}
+
+func TestProxyRuleErrors(t *testing.T) {
+	_, err := parseProxyRules([]ServeProxy{{Path: "api", Target: "http://localhost:3000"}})
+	test.AssertEqual(t, err.Error(), "Invalid proxy path (must start with \"/\"): api")
+
+	_, err = parseProxyRules([]ServeProxy{{Path: "/api", Target: "ftp://localhost:3000"}})
+	test.AssertEqual(t, err.Error(), "Invalid proxy target (must be an HTTP URL): ftp://localhost:3000")
+
+	_, err = parseProxyRules([]ServeProxy{{Path: "/api", Target: "http://"}})
+	test.AssertEqual(t, err.Error(), "Invalid proxy target: http://")
+
+	_, err = parseProxyRules([]ServeProxy{{Path: "/api", Target: "http://localhost:3000", Rewrite: "v2"}})
+	test.AssertEqual(t, err.Error(), "Invalid proxy rewrite (must start with \"/\"): v2")
+}
+
+func TestProxyForwarding(t *testing.T) {
+	upstream := httptest.NewServer(http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+		res.WriteHeader(http.StatusTeapot)
+		res.Write([]byte(req.Host + " " + req.URL.RequestURI() + " " + req.Header.Get("X-Token")))
+	}))
+	defer upstream.Close()
+
+	rules, err := parseProxyRules([]ServeProxy{{
+		Path:    "/api",
+		Target:  upstream.URL + "?key=1",
+		Rewrite: "/",
+		Headers: map[string]string{"Host": "example.com", "X-Token": "secret"},
+	}})
+	test.AssertEqual(t, err, nil)
+
+	var status int
+	done := make(chan struct{})
+	handler := &apiHandler{
+		proxyRules: rules,
+		onRequest: func(args ServeOnRequestArgs) {
+			status = args.Status
+			close(done)
+		},
+	}
+
+	res := httptest.NewRecorder()
+	handler.ServeHTTP(res, httptest.NewRequest("GET", "/api/users?id=2", nil))
+	body, _ := ioutil.ReadAll(res.Body)
+	<-done
+
+	test.AssertEqual(t, res.Code, http.StatusTeapot)
+	test.AssertEqual(t, status, http.StatusTeapot)
+	test.AssertEqual(t, string(body), "example.com /users?key=1&id=2 secret")
+}
+
+func TestProxyUpstreamDown(t *testing.T) {
+	upstream := httptest.NewServer(http.NotFoundHandler())
+	target := upstream.URL
+	upstream.Close()
+
+	rules, err := parseProxyRules([]ServeProxy{{Path: "/", Target: target}})
+	test.AssertEqual(t, err, nil)
+
+	res := httptest.NewRecorder()
+	handler := &apiHandler{proxyRules: rules}
+	handler.ServeHTTP(res, httptest.NewRequest("GET", "/index.html", nil))
+	test.AssertEqual(t, res.Code, http.StatusBadGateway)
+}
+
+func TestProxyUpgrade(t *testing.T) {
+	// This upstream server echoes everything back after switching protocols
+	upstream := httptest.NewServer(http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+		if req.Header.Get("Upgrade") != "websocket" {
+			res.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		conn, rw, err := res.(http.Hijacker).Hijack()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		rw.WriteString("HTTP/1.1 101 Switching Protocols\r\nConnection: Upgrade\r\nUpgrade: websocket\r\n\r\n")
+		rw.Flush()
+		line, _ := rw.ReadString('\n')
+		rw.WriteString(line)
+		rw.Flush()
+	}))
+	defer upstream.Close()
+
+	rules, err := parseProxyRules([]ServeProxy{{Path: "/ws", Target: strings.Replace(upstream.URL, "http:", "ws:", 1)}})
+	test.AssertEqual(t, err, nil)
+	server := httptest.NewServer(&apiHandler{proxyRules: rules})
+	defer server.Close()
+
+	conn, err := net.Dial("tcp", server.Listener.Addr().String())
+	test.AssertEqual(t, err, nil)
+	defer conn.Close()
+	conn.Write([]byte("GET /ws HTTP/1.1\r\nHost: localhost\r\nConnection: Upgrade\r\nUpgrade: websocket\r\n\r\n"))
+
+	reader := bufio.NewReader(conn)
+	res, err := http.ReadResponse(reader, nil)
+	test.AssertEqual(t, err, nil)
+	test.AssertEqual(t, res.StatusCode, http.StatusSwitchingProtocols)
+
+	conn.Write([]byte("ping\n"))
+	line, _ := reader.ReadString('\n')
+	test.AssertEqual(t, line, "ping\n")
+}
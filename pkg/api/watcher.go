@@ -1,8 +1,9 @@
 package api
 
-// This file implements a polling file watcher for esbuild (i.e. it detects
-// when files are changed by repeatedly checking their contents). Polling is
-// used instead of more efficient platform-specific file system APIs because:
+// This file implements the file watcher for esbuild. By default changes are
+// detected by polling (i.e. by repeatedly checking file contents). Polling is
+// used by default instead of more efficient platform-specific file system APIs
+// because:
 //
 //   * Go's standard library doesn't have built-in APIs for file watching
 //   * Using platform-specific APIs means using cgo, which I want to avoid
@@ -21,22 +22,33 @@ package api
 // change's path goes on a short list of recently changed paths which are
 // checked on every scan, so further changes to recently changed files should
 // be noticed almost instantly.
+//
+// A native backend can be requested instead (currently only inotify on Linux,
+// which is implemented using raw syscalls and doesn't need cgo). It only tells
+// us which directories had activity. Those paths are still verified using the
+// same checks as polling, so spurious events never trigger a rebuild. If the
+// native backend is unavailable, the watcher silently uses polling instead. If
+// it fails later (e.g. because the inotify watch limit was reached), a warning
+// is logged and the watcher falls back to polling.
 
 import (
 	"fmt"
 	"math/rand"
 	"os"
+	"regexp"
+	"strings"
 	"sync"
 	"sync/atomic"
 	"time"
 
 	"github.com/evanw/esbuild/internal/fs"
+	"github.com/evanw/esbuild/internal/helpers"
 	"github.com/evanw/esbuild/internal/logger"
 	"github.com/evanw/esbuild/internal/resolver"
 )
 
-// The time to wait between watch intervals
-const watchIntervalSleep = 100 * time.Millisecond
+// The default time to wait between watch intervals
+const defaultWatchIntervalSleep = 100 * time.Millisecond
 
 // The maximum number of recently-edited items to check every interval
 const maxRecentItemCount = 16
@@ -51,20 +63,126 @@ type watcher struct {
 	data              fs.WatchData
 	fs                fs.FS
 	rebuild           func() fs.WatchData
+	native            nativeWatcher
+	logOptions        logger.OutputOptions
+	ignore            []*regexp.Regexp
 	recentItems       []string
 	itemsToScan       []string
 	mutex             sync.Mutex
+	interval          time.Duration
+	debounce          time.Duration
 	itemsPerIteration int
 	shouldStop        int32
 	stopWaitGroup     sync.WaitGroup
 }
 
+// A native watcher reports paths that may have changed. It doesn't need to be
+// precise since every reported path is double-checked before rebuilding.
+type nativeWatcher interface {
+	// This replaces the set of directories that are being watched
+	watchDirs(dirs map[string]bool) error
+
+	// This blocks until there's activity or until the timeout expires. If some
+	// events were lost, "overflow" is true and all paths should be checked.
+	wait(timeout time.Duration) (paths []string, overflow bool)
+
+	close()
+}
+
+func validateWatchOptions(options WatchOptions, realFS fs.FS) (*watcher, error) {
+	w := &watcher{
+		fs:       realFS,
+		interval: defaultWatchIntervalSleep,
+	}
+
+	if options.PollInterval < 0 {
+		return nil, fmt.Errorf("Invalid poll interval: %d", options.PollInterval)
+	} else if options.PollInterval > 0 {
+		w.interval = time.Duration(options.PollInterval) * time.Millisecond
+	}
+
+	if options.Debounce < 0 {
+		return nil, fmt.Errorf("Invalid debounce delay: %d", options.Debounce)
+	}
+	w.debounce = time.Duration(options.Debounce) * time.Millisecond
+
+	for _, pattern := range options.Ignore {
+		if pattern == "" {
+			return nil, fmt.Errorf("Invalid ignore pattern: %q", pattern)
+		}
+		w.ignore = append(w.ignore, helpers.GlobPatternToRegexp(helpers.ParseGlobPattern(pattern)))
+	}
+
+	switch options.Backend {
+	case WatchBackendDefault, WatchBackendPolling:
+	case WatchBackendNative:
+		// Silently fall back to polling if there's no native backend
+		if native, err := newNativeWatcher(); err == nil {
+			w.native = native
+		}
+	default:
+		return nil, fmt.Errorf("Invalid watch backend: %d", options.Backend)
+	}
+	return w, nil
+}
+
+func (w *watcher) isIgnored(absPath string) bool {
+	if len(w.ignore) == 0 {
+		return false
+	}
+
+	// Ignore patterns are relative to the working directory
+	relPath, ok := w.fs.Rel(w.fs.Cwd(), absPath)
+	if !ok {
+		return false
+	}
+	relPath = strings.ReplaceAll(relPath, "\\", "/")
+	for _, re := range w.ignore {
+		if re.MatchString(relPath) {
+			return true
+		}
+	}
+	return false
+}
+
 func (w *watcher) setWatchData(data fs.WatchData) {
 	defer w.mutex.Unlock()
 	w.mutex.Lock()
+
+	// Drop ignored paths up front so they are never checked
+	if len(w.ignore) > 0 {
+		paths := make(map[string]func() string, len(data.Paths))
+		for path, check := range data.Paths {
+			if !w.isIgnored(path) {
+				paths[path] = check
+			}
+		}
+		data.Paths = paths
+	}
+
 	w.data = data
 	w.itemsToScan = w.itemsToScan[:0] // Reuse memory
 
+	// Update the set of watched directories for the native backend
+	if w.native != nil {
+		dirs := make(map[string]bool)
+		for path := range data.Paths {
+			dirs[w.fs.Dir(path)] = true
+			dirs[path] = true // This may not be a directory, in which case it's ignored
+		}
+		if err := w.native.watchDirs(dirs); err != nil {
+			// Fall back to polling if the native backend stops working. This can
+			// only happen once since the native backend isn't used after that.
+			w.native.close()
+			w.native = nil
+			log := logger.NewStderrLog(w.logOptions)
+			log.AddIDWithNotes(logger.MsgID_None, logger.Warning, nil, logger.Range{},
+				"Falling back to polling because the native file watcher failed",
+				[]logger.MsgData{{Text: err.Error()}})
+			log.Done()
+		}
+	}
+
 	// Remove any recent items that weren't a part of the latest build
 	end := 0
 	for _, path := range w.recentItems {
@@ -93,11 +211,33 @@ func (w *watcher) start(logLevel logger.LogLevel, useColor logger.UseColor) {
 		}
 
 		for atomic.LoadInt32(&w.shouldStop) == 0 {
-			// Sleep for the watch interval
-			time.Sleep(watchIntervalSleep)
+			// Wait for activity or sleep for the watch interval
+			var absPath string
+			if native := w.nativeBackend(); native != nil {
+				paths, overflow := native.wait(w.interval)
+				absPath = w.tryToFindDirtyPathAmong(paths, overflow)
+			} else {
+				time.Sleep(w.interval)
+				absPath = w.tryToFindDirtyPath()
+			}
 
 			// Rebuild if we're dirty
-			if absPath := w.tryToFindDirtyPath(); absPath != "" {
+			if absPath != "" {
+				// Wait for things to settle down if a debounce delay was requested.
+				// With the native backend this waits until there has been no activity
+				// for the whole delay, which handles tools that write many files.
+				if w.debounce > 0 {
+					if native := w.nativeBackend(); native != nil {
+						for atomic.LoadInt32(&w.shouldStop) == 0 {
+							if paths, overflow := native.wait(w.debounce); len(paths) == 0 && !overflow {
+								break
+							}
+						}
+					} else {
+						time.Sleep(w.debounce)
+					}
+				}
+
 				if shouldLog {
 					logger.PrintTextWithColor(os.Stderr, useColor, func(colors logger.Colors) string {
 						prettyPath := resolver.PrettyPath(w.fs, logger.Path{Text: absPath, Namespace: "file"})
@@ -123,6 +263,48 @@ func (w *watcher) start(logLevel logger.LogLevel, useColor logger.UseColor) {
 func (w *watcher) stop() {
 	atomic.StoreInt32(&w.shouldStop, 1)
 	w.stopWaitGroup.Wait()
+
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	if w.native != nil {
+		w.native.close()
+		w.native = nil
+	}
+}
+
+func (w *watcher) nativeBackend() nativeWatcher {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	return w.native
+}
+
+// This is used with the native backend. It only checks the paths that had
+// activity, plus the directories containing them (since a directory's check
+// function detects added and removed entries).
+func (w *watcher) tryToFindDirtyPathAmong(paths []string, overflow bool) string {
+	defer w.mutex.Unlock()
+	w.mutex.Lock()
+
+	// If events were dropped, we have no idea what changed so check everything
+	if overflow {
+		for _, check := range w.data.Paths {
+			if dirtyPath := check(); dirtyPath != "" {
+				return dirtyPath
+			}
+		}
+		return ""
+	}
+
+	for _, path := range paths {
+		for _, candidate := range [2]string{path, w.fs.Dir(path)} {
+			if check := w.data.Paths[candidate]; check != nil {
+				if dirtyPath := check(); dirtyPath != "" {
+					return dirtyPath
+				}
+			}
+		}
+	}
+	return ""
 }
 
 func (w *watcher) tryToFindDirtyPath() string {
@@ -0,0 +1,175 @@
+//go:build linux
+// +build linux
+
+package api
+
+// This is a native file watcher backend using Linux's inotify API. It talks to
+// the kernel using raw syscalls so it doesn't need cgo. Directories are watched
+// instead of individual files because a directory watch also reports changes to
+// the files inside it, which keeps the number of watches (a limited resource)
+// proportional to the number of directories instead of the number of files.
+
+import (
+	"errors"
+	"strings"
+	"sync"
+	"time"
+	"unsafe"
+
+	"golang.org/x/sys/unix"
+)
+
+const inotifyMask = unix.IN_ONLYDIR |
+	unix.IN_ATTRIB |
+	unix.IN_CLOSE_WRITE |
+	unix.IN_CREATE |
+	unix.IN_DELETE |
+	unix.IN_DELETE_SELF |
+	unix.IN_MODIFY |
+	unix.IN_MOVE_SELF |
+	unix.IN_MOVED_FROM |
+	unix.IN_MOVED_TO
+
+type inotifyWatcher struct {
+	mutex   sync.Mutex
+	dirToWD map[string]int
+	wdToDir map[int][]string
+
+	// Paths that we know aren't directories. This avoids a failing syscall for
+	// every watched file every time the set of directories is updated.
+	notDirs map[string]bool
+
+	buffer []byte
+	fd     int
+}
+
+func newNativeWatcher() (nativeWatcher, error) {
+	fd, err := unix.InotifyInit1(unix.IN_CLOEXEC | unix.IN_NONBLOCK)
+	if err != nil {
+		return nil, err
+	}
+	return &inotifyWatcher{
+		fd:      fd,
+		dirToWD: make(map[string]int),
+		wdToDir: make(map[int][]string),
+		notDirs: make(map[string]bool),
+		buffer:  make([]byte, 64*1024),
+	}, nil
+}
+
+func (w *inotifyWatcher) watchDirs(dirs map[string]bool) error {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	// Stop watching directories that are no longer relevant
+	for dir, wd := range w.dirToWD {
+		if !dirs[dir] {
+			w.forgetDir(dir, wd)
+		}
+	}
+	for path := range w.notDirs {
+		if !dirs[path] {
+			delete(w.notDirs, path)
+		}
+	}
+
+	// Start watching new directories. Note that multiple paths can map to the
+	// same watch descriptor if they refer to the same directory via symlinks.
+	for dir := range dirs {
+		if _, ok := w.dirToWD[dir]; ok || w.notDirs[dir] {
+			continue
+		}
+		wd, err := unix.InotifyAddWatch(w.fd, dir, inotifyMask)
+		if err != nil {
+			switch err {
+			case unix.ENOSPC, unix.ENOMEM:
+				return errors.New("The limit on the number of inotify watches was reached. " +
+					"You can raise it using \"sysctl fs.inotify.max_user_watches=<limit>\".")
+			case unix.ENOTDIR:
+				w.notDirs[dir] = true
+			}
+
+			// Otherwise the directory doesn't exist (anymore). If it's created
+			// later, that shows up as an event on its parent directory.
+			continue
+		}
+		w.dirToWD[dir] = wd
+		w.wdToDir[wd] = append(w.wdToDir[wd], dir)
+	}
+	return nil
+}
+
+func (w *inotifyWatcher) forgetDir(dir string, wd int) {
+	delete(w.dirToWD, dir)
+	others := w.wdToDir[wd][:0]
+	for _, other := range w.wdToDir[wd] {
+		if other != dir {
+			others = append(others, other)
+		}
+	}
+	if len(others) > 0 {
+		w.wdToDir[wd] = others
+	} else {
+		delete(w.wdToDir, wd)
+		unix.InotifyRmWatch(w.fd, uint32(wd))
+	}
+}
+
+func (w *inotifyWatcher) wait(timeout time.Duration) (paths []string, overflow bool) {
+	fds := []unix.PollFd{{Fd: int32(w.fd), Events: unix.POLLIN}}
+	if n, err := unix.Poll(fds, int(timeout/time.Millisecond)); err != nil || n == 0 {
+		return
+	}
+
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	for {
+		n, err := unix.Read(w.fd, w.buffer)
+		if err != nil || n <= 0 {
+			// This will be "EAGAIN" once all pending events have been read
+			break
+		}
+
+		for offset := 0; offset+unix.SizeofInotifyEvent <= n; {
+			event := (*unix.InotifyEvent)(unsafe.Pointer(&w.buffer[offset]))
+			nameStart := offset + unix.SizeofInotifyEvent
+			nameEnd := nameStart + int(event.Len)
+			offset = nameEnd
+			if nameEnd > n {
+				break
+			}
+
+			if event.Mask&unix.IN_Q_OVERFLOW != 0 {
+				overflow = true
+				continue
+			}
+
+			wd := int(event.Wd)
+			name := strings.TrimRight(string(w.buffer[nameStart:nameEnd]), "\x00")
+			for _, dir := range w.wdToDir[wd] {
+				if name != "" {
+					paths = append(paths, dir+"/"+name)
+				} else {
+					paths = append(paths, dir)
+				}
+			}
+
+			// The kernel removes the watch when the directory is deleted. It will
+			// be added again by the next build if the directory comes back.
+			if event.Mask&unix.IN_IGNORED != 0 {
+				for _, dir := range w.wdToDir[wd] {
+					delete(w.dirToWD, dir)
+				}
+				delete(w.wdToDir, wd)
+			}
+		}
+	}
+	return
+}
+
+func (w *inotifyWatcher) close() {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	unix.Close(w.fd)
+}
@@ -0,0 +1,70 @@
+//go:build linux
+// +build linux
+
+package api
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+)
+
+func TestInotifyWatcher(t *testing.T) {
+	dir, err := ioutil.TempDir("", "esbuild-watcher-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	native, err := newNativeWatcher()
+	if err != nil {
+		t.Skipf("inotify is unavailable: %s", err.Error())
+	}
+	defer native.close()
+
+	// Files can be passed too, and should just be skipped
+	file := dir + "/file.js"
+	if err := ioutil.WriteFile(file, []byte("1"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := native.watchDirs(map[string]bool{dir: true, file: true}); err != nil {
+		t.Fatal(err)
+	}
+
+	expectPath := func(expected string) {
+		t.Helper()
+		deadline := time.Now().Add(5 * time.Second)
+		for time.Now().Before(deadline) {
+			paths, _ := native.wait(100 * time.Millisecond)
+			for _, path := range paths {
+				if path == expected {
+					return
+				}
+			}
+		}
+		t.Fatalf("Timed out waiting for an event for %q", expected)
+	}
+
+	if err := ioutil.WriteFile(file, []byte("2"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	expectPath(file)
+
+	if err := ioutil.WriteFile(dir+"/new.js", nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	expectPath(dir + "/new.js")
+
+	// Nothing should be reported after the directory is no longer watched
+	if err := native.watchDirs(map[string]bool{}); err != nil {
+		t.Fatal(err)
+	}
+	native.wait(100 * time.Millisecond) // Drain anything left over
+	if err := ioutil.WriteFile(file, []byte("3"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if paths, _ := native.wait(200 * time.Millisecond); len(paths) != 0 {
+		t.Fatalf("Unexpected events: %v", paths)
+	}
+}
@@ -0,0 +1,10 @@
+//go:build !linux
+// +build !linux
+
+package api
+
+import "errors"
+
+func newNativeWatcher() (nativeWatcher, error) {
+	return nil, errors.New("Native file watching is not supported on this platform")
+}
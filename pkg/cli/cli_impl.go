@@ -50,9 +50,10 @@ const (
 )
 
 type parseOptionsExtras struct {
-	watch       bool
-	metafile    *string
-	mangleCache *string
+	watch        bool
+	watchOptions api.WatchOptions
+	metafile     *string
+	mangleCache  *string
 }
 
 func isBoolFlag(arg string, flag string) bool {
@@ -113,6 +114,20 @@ func parseOptionsImpl(
 				buildOpts.Splitting = value
 			}
 
+		case isBoolFlag(arg, "--hmr") && buildOpts != nil:
+			if value, err := parseBoolFlag(arg, true); err != nil {
+				return parseOptionsExtras{}, err
+			} else {
+				buildOpts.HMR = value
+			}
+
+		case isBoolFlag(arg, "--metafile-included-by") && buildOpts != nil:
+			if value, err := parseBoolFlag(arg, true); err != nil {
+				return parseOptionsExtras{}, err
+			} else {
+				buildOpts.MetafileIncludedBy = value
+			}
+
 		case isBoolFlag(arg, "--allow-overwrite") && buildOpts != nil:
 			if value, err := parseBoolFlag(arg, true); err != nil {
 				return parseOptionsExtras{}, err
@@ -127,6 +142,45 @@ func parseOptionsImpl(
 				extras.watch = value
 			}
 
+		case strings.HasPrefix(arg, "--watch-backend=") && buildOpts != nil:
+			value := arg[len("--watch-backend="):]
+			switch value {
+			case "polling":
+				extras.watchOptions.Backend = api.WatchBackendPolling
+			case "native":
+				extras.watchOptions.Backend = api.WatchBackendNative
+			default:
+				return parseOptionsExtras{}, cli_helpers.MakeErrorWithNote(
+					fmt.Sprintf("Invalid value %q in %q", value, arg),
+					"Valid values are \"polling\" or \"native\".",
+				)
+			}
+
+		case strings.HasPrefix(arg, "--watch-poll-interval=") && buildOpts != nil:
+			value := arg[len("--watch-poll-interval="):]
+			interval, err := strconv.Atoi(value)
+			if err != nil || interval <= 0 {
+				return parseOptionsExtras{}, cli_helpers.MakeErrorWithNote(
+					fmt.Sprintf("Invalid value %q in %q", value, arg),
+					"The poll interval must be a positive number of milliseconds.",
+				)
+			}
+			extras.watchOptions.PollInterval = interval
+
+		case strings.HasPrefix(arg, "--watch-debounce=") && buildOpts != nil:
+			value := arg[len("--watch-debounce="):]
+			debounce, err := strconv.Atoi(value)
+			if err != nil || debounce < 0 {
+				return parseOptionsExtras{}, cli_helpers.MakeErrorWithNote(
+					fmt.Sprintf("Invalid value %q in %q", value, arg),
+					"The debounce delay must be a non-negative number of milliseconds.",
+				)
+			}
+			extras.watchOptions.Debounce = debounce
+
+		case strings.HasPrefix(arg, "--watch-ignore:") && buildOpts != nil:
+			extras.watchOptions.Ignore = append(extras.watchOptions.Ignore, arg[len("--watch-ignore:"):])
+
 		case isBoolFlag(arg, "--minify"):
 			if value, err := parseBoolFlag(arg, true); err != nil {
 				return parseOptionsExtras{}, err
@@ -355,6 +409,28 @@ func parseOptionsImpl(
 				transformOpts.SourceRoot = sourceRoot
 			}
 
+		case strings.HasPrefix(arg, "--sourcemap-ignore-list="):
+			patterns := splitWithEmptyCheck(arg[len("--sourcemap-ignore-list="):], ",")
+			if buildOpts != nil {
+				buildOpts.SourcemapIgnoreList = patterns
+			} else {
+				transformOpts.SourcemapIgnoreList = patterns
+			}
+
+		case isBoolFlag(arg, "--sourcemap-sections") && buildOpts != nil:
+			if value, err := parseBoolFlag(arg, true); err != nil {
+				return parseOptionsExtras{}, err
+			} else {
+				buildOpts.SourcemapSections = value
+			}
+
+		case isBoolFlag(arg, "--debug-ids") && buildOpts != nil:
+			if value, err := parseBoolFlag(arg, true); err != nil {
+				return parseOptionsExtras{}, err
+			} else {
+				buildOpts.DebugIDs = value
+			}
+
 		case isBoolFlag(arg, "--sources-content"):
 			if value, err := parseBoolFlag(arg, true); err != nil {
 				return parseOptionsExtras{}, err
@@ -418,6 +494,9 @@ func parseOptionsImpl(
 		case strings.HasPrefix(arg, "--outbase=") && buildOpts != nil:
 			buildOpts.Outbase = arg[len("--outbase="):]
 
+		case strings.HasPrefix(arg, "--cache-dir=") && buildOpts != nil:
+			buildOpts.CacheDir = arg[len("--cache-dir="):]
+
 		case strings.HasPrefix(arg, "--tsconfig=") && buildOpts != nil:
 			buildOpts.Tsconfig = arg[len("--tsconfig="):]
 
@@ -623,6 +702,20 @@ func parseOptionsImpl(
 			}
 			buildOpts.Packages = packages
 
+		case strings.HasPrefix(arg, "--wasm-bytes=") && buildOpts != nil:
+			value := arg[len("--wasm-bytes="):]
+			switch value {
+			case "inline":
+				buildOpts.WasmBytes = api.WasmBytesInline
+			case "file":
+				buildOpts.WasmBytes = api.WasmBytesFile
+			default:
+				return parseOptionsExtras{}, cli_helpers.MakeErrorWithNote(
+					fmt.Sprintf("Invalid value %q in %q", value, arg),
+					"Valid values are \"inline\" or \"file\".",
+				)
+			}
+
 		case strings.HasPrefix(arg, "--external:") && buildOpts != nil:
 			buildOpts.External = append(buildOpts.External, arg[len("--external:"):])
 
@@ -644,6 +737,53 @@ func parseOptionsImpl(
 			}
 			buildOpts.Alias[value[:equals]] = value[equals+1:]
 
+		case strings.HasPrefix(arg, "--manual-chunk:") && buildOpts != nil:
+			value := arg[len("--manual-chunk:"):]
+			equals := strings.IndexByte(value, '=')
+			if equals == -1 {
+				return parseOptionsExtras{}, cli_helpers.MakeErrorWithNote(
+					fmt.Sprintf("Missing \"=\" in %q", arg),
+					"You need to use \"=\" to specify both the chunk name and what goes in it. "+
+						"For example, \"--manual-chunk:vendor=react,react-dom\" puts the packages \"react\" and \"react-dom\" in a chunk named \"vendor\".",
+				)
+			}
+			if buildOpts.ManualChunks == nil {
+				buildOpts.ManualChunks = make(map[string][]string)
+			}
+			name := value[:equals]
+			buildOpts.ManualChunks[name] = append(buildOpts.ManualChunks[name], splitWithEmptyCheck(value[equals+1:], ",")...)
+
+		case strings.HasPrefix(arg, "--budget:") && buildOpts != nil:
+			value := arg[len("--budget:"):]
+			equals := strings.IndexByte(value, '=')
+			if equals == -1 {
+				return parseOptionsExtras{}, cli_helpers.MakeErrorWithNote(
+					fmt.Sprintf("Missing \"=\" in %q", arg),
+					"You need to use \"=\" to specify both the output path pattern and the budget. "+
+						"For example, \"--budget:*.js=100kb,gzip=30kb\" limits each \".js\" output file to 100kb, or 30kb after gzip compression.",
+				)
+			}
+			budget := api.Budget{Path: value[:equals]}
+			for _, item := range splitWithEmptyCheck(value[equals+1:], ",") {
+				ok := true
+				switch {
+				case item == "warn":
+					budget.Warn = true
+				case strings.HasPrefix(item, "gzip="):
+					budget.MaxGzipBytes, ok = parseByteCount(item[len("gzip="):])
+				default:
+					budget.MaxBytes, ok = parseByteCount(item)
+				}
+				if !ok {
+					return parseOptionsExtras{}, cli_helpers.MakeErrorWithNote(
+						fmt.Sprintf("Invalid budget %q in %q", item, arg),
+						"Valid budgets are a size (e.g. \"100kb\"), a size after gzip compression (e.g. \"gzip=30kb\"), and \"warn\" "+
+							"to report a warning instead of an error. Sizes can end in \"b\", \"kb\", or \"mb\".",
+					)
+				}
+			}
+			buildOpts.Budgets = append(buildOpts.Budgets, budget)
+
 		case strings.HasPrefix(arg, "--jsx="):
 			value := arg[len("--jsx="):]
 			var mode api.JSX
@@ -819,6 +959,7 @@ func parseOptionsImpl(
 				"allow-overwrite":    true,
 				"bundle":             true,
 				"ignore-annotations": true,
+				"hmr":                true,
 				"jsx-dev":            true,
 				"jsx-side-effects":   true,
 				"keep-names":         true,
@@ -833,77 +974,87 @@ func parseOptionsImpl(
 			}
 
 			equals := map[string]bool{
-				"allow-overwrite":    true,
-				"asset-names":        true,
-				"banner":             true,
-				"bundle":             true,
-				"certfile":           true,
-				"charset":            true,
-				"chunk-names":        true,
-				"color":              true,
-				"conditions":         true,
-				"drop-labels":        true,
-				"entry-names":        true,
-				"footer":             true,
-				"format":             true,
-				"global-name":        true,
-				"ignore-annotations": true,
-				"jsx-factory":        true,
-				"jsx-fragment":       true,
-				"jsx-import-source":  true,
-				"jsx":                true,
-				"keep-names":         true,
-				"keyfile":            true,
-				"legal-comments":     true,
-				"loader":             true,
-				"log-level":          true,
-				"log-limit":          true,
-				"main-fields":        true,
-				"mangle-cache":       true,
-				"mangle-props":       true,
-				"mangle-quoted":      true,
-				"metafile":           true,
-				"minify-identifiers": true,
-				"minify-syntax":      true,
-				"minify-whitespace":  true,
-				"minify":             true,
-				"outbase":            true,
-				"outdir":             true,
-				"outfile":            true,
-				"packages":           true,
-				"platform":           true,
-				"preserve-symlinks":  true,
-				"public-path":        true,
-				"reserve-props":      true,
-				"resolve-extensions": true,
-				"serve-fallback":     true,
-				"serve":              true,
-				"servedir":           true,
-				"source-root":        true,
-				"sourcefile":         true,
-				"sourcemap":          true,
-				"sources-content":    true,
-				"splitting":          true,
-				"target":             true,
-				"tree-shaking":       true,
-				"tsconfig-raw":       true,
-				"tsconfig":           true,
-				"watch":              true,
+				"allow-overwrite":     true,
+				"asset-names":         true,
+				"banner":              true,
+				"bundle":              true,
+				"cache-dir":           true,
+				"certfile":            true,
+				"charset":             true,
+				"chunk-names":         true,
+				"color":               true,
+				"conditions":          true,
+				"drop-labels":         true,
+				"entry-names":         true,
+				"footer":              true,
+				"format":              true,
+				"global-name":         true,
+				"hmr":                 true,
+				"ignore-annotations":  true,
+				"jsx-factory":         true,
+				"jsx-fragment":        true,
+				"jsx-import-source":   true,
+				"jsx":                 true,
+				"keep-names":          true,
+				"keyfile":             true,
+				"legal-comments":      true,
+				"loader":              true,
+				"log-level":           true,
+				"log-limit":           true,
+				"main-fields":         true,
+				"mangle-cache":        true,
+				"mangle-props":        true,
+				"mangle-quoted":       true,
+				"metafile":            true,
+				"minify-identifiers":  true,
+				"minify-syntax":       true,
+				"minify-whitespace":   true,
+				"minify":              true,
+				"outbase":             true,
+				"outdir":              true,
+				"outfile":             true,
+				"packages":            true,
+				"platform":            true,
+				"preserve-symlinks":   true,
+				"public-path":         true,
+				"reserve-props":       true,
+				"resolve-extensions":  true,
+				"serve-fallback":      true,
+				"serve":               true,
+				"servedir":            true,
+				"source-root":         true,
+				"sourcefile":          true,
+				"sourcemap":           true,
+				"sources-content":     true,
+				"splitting":           true,
+				"target":              true,
+				"tree-shaking":        true,
+				"tsconfig-raw":        true,
+				"tsconfig":            true,
+				"wasm-bytes":          true,
+				"watch-backend":       true,
+				"watch-debounce":      true,
+				"watch-poll-interval": true,
+				"watch":               true,
 			}
 
 			colon := map[string]bool{
-				"alias":         true,
-				"banner":        true,
-				"define":        true,
-				"drop":          true,
-				"external":      true,
-				"footer":        true,
-				"inject":        true,
-				"loader":        true,
-				"log-override":  true,
-				"out-extension": true,
-				"pure":          true,
-				"supported":     true,
+				"alias":               true,
+				"banner":              true,
+				"define":              true,
+				"drop":                true,
+				"external":            true,
+				"footer":              true,
+				"inject":              true,
+				"loader":              true,
+				"log-override":        true,
+				"manual-chunk":        true,
+				"out-extension":       true,
+				"pure":                true,
+				"serve-cache-control": true,
+				"serve-proxy":         true,
+				"supported":           true,
+				"watch-ignore":        true,
 			}
 
 			note := ""
@@ -1077,6 +1228,24 @@ func splitWithEmptyCheck(s string, sep string) []string {
 	return strings.Split(s, sep)
 }
 
+// Sizes use the same units as the output of "--analyze"
+func parseByteCount(text string) (int, bool) {
+	scale := 1
+	switch {
+	case strings.HasSuffix(text, "kb"):
+		text, scale = text[:len(text)-2], 1024
+	case strings.HasSuffix(text, "mb"):
+		text, scale = text[:len(text)-2], 1024*1024
+	case strings.HasSuffix(text, "b"):
+		text = text[:len(text)-1]
+	}
+	value, err := strconv.ParseFloat(text, 64)
+	if err != nil || value <= 0 {
+		return 0, false
+	}
+	return int(value * float64(scale)), true
+}
+
 type analyzeMode uint8
 
 const (
@@ -1085,25 +1254,33 @@ const (
 	analyzeVerbose
 )
 
-func filterAnalyzeFlags(osArgs []string) ([]string, analyzeMode) {
+func filterAnalyzeFlags(osArgs []string) ([]string, analyzeMode, string) {
 	analyze := analyzeDisabled
+	why := ""
 	end := 0
 	for _, arg := range osArgs {
-		switch arg {
-		case "--analyze":
+		switch {
+		case arg == "--analyze":
 			analyze = analyzeEnabled
-		case "--analyze=verbose":
+		case arg == "--analyze=verbose":
 			analyze = analyzeVerbose
+		case strings.HasPrefix(arg, "--why="):
+			why = arg[len("--why="):]
 		default:
 			osArgs[end] = arg
 			end++
 		}
 	}
-	return osArgs[:end], analyze
+
+	// Asking why something is included implies analyzing the bundle
+	if why != "" && analyze == analyzeDisabled {
+		analyze = analyzeEnabled
+	}
+	return osArgs[:end], analyze, why
 }
 
 // Print metafile analysis after the build if it's enabled
-func addAnalyzePlugin(buildOptions *api.BuildOptions, analyze analyzeMode, osArgs []string) {
+func addAnalyzePlugin(buildOptions *api.BuildOptions, analyze analyzeMode, why string, osArgs []string) {
 	buildOptions.Plugins = append(buildOptions.Plugins, api.Plugin{
 		Name: "PrintAnalysis",
 		Setup: func(build api.PluginBuild) {
@@ -1114,6 +1291,7 @@ func addAnalyzePlugin(buildOptions *api.BuildOptions, analyze analyzeMode, osArg
 						return api.AnalyzeMetafile(result.Metafile, api.AnalyzeMetafileOptions{
 							Color:   colors != logger.Colors{},
 							Verbose: analyze == analyzeVerbose,
+							Why:     why,
 						})
 					})
 					os.Stderr.WriteString("\n")
@@ -1125,6 +1303,11 @@ func addAnalyzePlugin(buildOptions *api.BuildOptions, analyze analyzeMode, osArg
 
 	// Always generate a metafile if we're analyzing, even if it won't be written out
 	buildOptions.Metafile = true
+
+	// The recorded reasons are what explain why something is included
+	if why != "" {
+		buildOptions.MetafileIncludedBy = true
+	}
 }
 
 func runImpl(osArgs []string) int {
@@ -1139,10 +1322,10 @@ func runImpl(osArgs []string) int {
 		}
 	}
 
-	osArgs, analyze := filterAnalyzeFlags(osArgs)
+	osArgs, analyze, why := filterAnalyzeFlags(osArgs)
 	buildOptions, transformOptions, extras, err := parseOptionsForRun(osArgs)
 	if analyze != analyzeDisabled {
-		addAnalyzePlugin(buildOptions, analyze, osArgs)
+		addAnalyzePlugin(buildOptions, analyze, why, osArgs)
 	}
 
 	switch {
@@ -1307,7 +1490,10 @@ func runImpl(osArgs []string) int {
 				return 1
 			}
 
-			ctx.Watch(api.WatchOptions{})
+			if err := ctx.Watch(extras.watchOptions); err != nil {
+				logger.PrintErrorWithNoteToStderr(osArgs, err.Error(), "")
+				return 1
+			}
 
 			// Do not exit if we're in watch mode
 			<-make(chan struct{})
@@ -1354,6 +1540,10 @@ func parseServeOptionsImpl(osArgs []string) (api.ServeOptions, []string, error)
 	keyfile := ""
 	certfile := ""
 	fallback := ""
+	errorOverlay := false
+	compress := false
+	var proxy []api.ServeProxy
+	var cacheControl []api.ServeCacheControl
 
 	// Filter out server-specific flags
 	filteredArgs := make([]string, 0, len(osArgs))
@@ -1370,6 +1560,38 @@ func parseServeOptionsImpl(osArgs []string) (api.ServeOptions, []string, error)
 			certfile = arg[len("--certfile="):]
 		} else if strings.HasPrefix(arg, "--serve-fallback=") {
 			fallback = arg[len("--serve-fallback="):]
+		} else if isBoolFlag(arg, "--serve-error-overlay") {
+			value, err := parseBoolFlag(arg, true)
+			if err != nil {
+				return api.ServeOptions{}, nil, fmt.Errorf("%s", err.Text)
+			}
+			errorOverlay = value
+		} else if isBoolFlag(arg, "--serve-compress") {
+			value, err := parseBoolFlag(arg, true)
+			if err != nil {
+				return api.ServeOptions{}, nil, fmt.Errorf("%s", err.Text)
+			}
+			compress = value
+		} else if strings.HasPrefix(arg, "--serve-cache-control:") {
+			value := arg[len("--serve-cache-control:"):]
+			equals := strings.IndexByte(value, '=')
+			if equals == -1 {
+				return api.ServeOptions{}, nil, fmt.Errorf("Missing \"=\" in %q", arg)
+			}
+			cacheControl = append(cacheControl, api.ServeCacheControl{
+				Path:  value[:equals],
+				Value: value[equals+1:],
+			})
+		} else if strings.HasPrefix(arg, "--serve-proxy:") {
+			value := arg[len("--serve-proxy:"):]
+			equals := strings.IndexByte(value, '=')
+			if equals == -1 {
+				return api.ServeOptions{}, nil, fmt.Errorf("Missing \"=\" in %q", arg)
+			}
+			proxy = append(proxy, api.ServeProxy{
+				Path:   value[:equals],
+				Target: value[equals+1:],
+			})
 		} else {
 			filteredArgs = append(filteredArgs, arg)
 		}
@@ -1394,12 +1616,16 @@ func parseServeOptionsImpl(osArgs []string) (api.ServeOptions, []string, error)
 	}
 
 	return api.ServeOptions{
-		Port:     uint16(port),
-		Host:     host,
-		Servedir: servedir,
-		Keyfile:  keyfile,
-		Certfile: certfile,
-		Fallback: fallback,
+		Port:         uint16(port),
+		Host:         host,
+		Servedir:     servedir,
+		Keyfile:      keyfile,
+		Certfile:     certfile,
+		Fallback:     fallback,
+		ErrorOverlay: errorOverlay,
+		Proxy:        proxy,
+		Compress:     compress,
+		CacheControl: cacheControl,
 	}, filteredArgs, nil
 }
 
@@ -1416,14 +1642,14 @@ func serveImpl(osArgs []string) {
 	options.LogLimit = 5
 	options.LogLevel = api.LogLevelInfo
 
-	filteredArgs, analyze := filterAnalyzeFlags(filteredArgs)
+	filteredArgs, analyze, why := filterAnalyzeFlags(filteredArgs)
 	extras, errWithNote := parseOptionsImpl(filteredArgs, &options, nil, kindInternal)
 	if errWithNote != nil {
 		logger.PrintErrorWithNoteToStderr(osArgs, errWithNote.Text, errWithNote.Note)
 		return
 	}
 	if analyze != analyzeDisabled {
-		addAnalyzePlugin(&options, analyze, osArgs)
+		addAnalyzePlugin(&options, analyze, why, osArgs)
 	}
 
 	serveOptions.OnRequest = func(args api.ServeOnRequestArgs) {
@@ -1454,7 +1680,7 @@ func serveImpl(osArgs []string) {
 
 	// Also enable watch mode if it was requested
 	if extras.watch {
-		if err := ctx.Watch(api.WatchOptions{}); err != nil {
+		if err := ctx.Watch(extras.watchOptions); err != nil {
 			logger.PrintErrorWithNoteToStderr(osArgs, err.Error(), "")
 			return
 		}
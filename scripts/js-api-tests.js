@@ -1026,6 +1026,40 @@ body {
     assert.strictEqual(value.outputFiles[1].text, '\uFFFD\uFFFD')
   },
 
+  async wasmLoader({ esbuild, testDir }) {
+    // This module imports "double" from "./double.js" and exports "quadruple"
+    const wasm = Buffer.from([
+      0x00, 0x61, 0x73, 0x6D, 0x01, 0x00, 0x00, 0x00,
+      0x01, 0x06, 0x01, 0x60, 0x01, 0x7F, 0x01, 0x7F, // Type section
+      0x02, 0x16, 0x01, 0x0B, ...Buffer.from('./double.js'), 0x06, ...Buffer.from('double'), 0x00, 0x00, // Import section
+      0x03, 0x02, 0x01, 0x00, // Function section
+      0x07, 0x0D, 0x01, 0x09, ...Buffer.from('quadruple'), 0x00, 0x01, // Export section
+      0x0A, 0x0A, 0x01, 0x08, 0x00, 0x20, 0x00, 0x10, 0x00, 0x10, 0x00, 0x0B, // Code section
+    ])
+    const input = path.join(testDir, 'in.js')
+    await writeFileAsync(input, `import { quadruple } from './quadruple.wasm'; export let value = quadruple(5)`)
+    await writeFileAsync(path.join(testDir, 'quadruple.wasm'), wasm)
+    await writeFileAsync(path.join(testDir, 'double.js'), `export let double = x => x * 2`)
+
+    for (const wasmBytes of ['inline', 'file']) {
+      const outdir = path.join(testDir, wasmBytes)
+      const value = await esbuild.build({
+        entryPoints: [input],
+        bundle: true,
+        outdir,
+        format: 'esm',
+        platform: 'node',
+        outExtension: { '.js': '.mjs' },
+        loader: { '.wasm': 'wasm' },
+        wasmBytes,
+      })
+      assert.strictEqual(value.outputFiles, void 0)
+      assert.strictEqual(fs.readdirSync(outdir).some(name => name.endsWith('.wasm')), wasmBytes === 'file')
+      const result = await import('./' + path.relative(__dirname, path.join(outdir, 'in.mjs')))
+      assert.strictEqual(result.value, 20)
+    }
+  },
+
   async metafile({ esbuild, testDir }) {
     const entry = path.join(testDir, 'entry.js')
     const imported = path.join(testDir, 'imported.js')
@@ -1670,6 +1704,67 @@ body {
     assert.strictEqual(result.default, 123)
   },
 
+  async splittingIIFEEvaluationOrder({ esbuild, testDir }) {
+    const e1 = path.join(testDir, 'e1.js')
+    const e2 = path.join(testDir, 'e2.js')
+    const e3 = path.join(testDir, 'e3.js')
+    const outdir = path.join(testDir, 'out')
+    await writeFileAsync(path.join(testDir, 's1.js'), `log.push('s1'); export let v1 = 1`)
+    await writeFileAsync(path.join(testDir, 's2.js'), `log.push('s2'); export let v2 = 2`)
+    await writeFileAsync(path.join(testDir, 'dyn.js'), `import { v2 } from './s2'; export let d = v2 * 10`)
+    await writeFileAsync(e1, `import { v1 } from './s1'; import { v2 } from './s2'; log.push('e1:' + (v1 + v2)); import('./dyn').then(m => log.push('dyn:' + m.d))`)
+    await writeFileAsync(e2, `import { v1 } from './s1'; log.push(v1)`)
+    await writeFileAsync(e3, `import { v2 } from './s2'; log.push(v2)`)
+    await esbuild.build({ entryPoints: [e1, e2, e3], bundle: true, splitting: true, format: 'iife', outdir })
+
+    const base = 'http://example.com/out/'
+    let read = url => fs.readFileSync(path.join(outdir, url.slice(base.length)), 'utf8')
+    const done = () => new Promise(r => setTimeout(r, 100))
+
+    // Chunks that are appended later finish loading first. Note that "URL"
+    // isn't available since the loader must also work in older browsers.
+    const load = async entry => {
+      const browser = { log: [], setTimeout, Promise }
+      browser.self = browser
+      let pending = 0
+      browser.document = {
+        currentScript: { src: base + entry },
+        head: {
+          appendChild(script) {
+            setTimeout(() => {
+              browser.document.currentScript = { src: script.src }
+              vm.runInContext(read(script.src), browser)
+            }, 50 - 10 * pending++)
+          },
+        },
+        createElement: () => ({}),
+      }
+      vm.createContext(browser)
+      vm.runInContext(read(base + entry), browser)
+      await done()
+      return browser.log
+    }
+    assert.deepStrictEqual(await load('e1.js'), ['s1', 's2', 'e1:3', 'dyn:20'])
+
+    // Web Workers don't have "document" and use "importScripts()" instead
+    const worker = { log: [], setTimeout, Promise, location: { href: base + 'e1.js' } }
+    worker.self = worker
+    worker.importScripts = url => vm.runInContext(read(url), worker)
+    vm.createContext(worker)
+    vm.runInContext(read(base + 'e1.js'), worker)
+    await done()
+    assert.deepStrictEqual(worker.log, ['s1', 's2', 'e1:3', 'dyn:20'])
+
+    // Chunks in other directories are resolved relative to the importing chunk
+    const nested = path.join(testDir, 'nested')
+    await esbuild.build({
+      entryPoints: [e1, e2, e3], bundle: true, splitting: true, format: 'iife', outdir: nested,
+      entryNames: 'entries/[name]', chunkNames: 'chunks/[name]-[hash]',
+    })
+    read = url => fs.readFileSync(path.join(nested, url.slice(base.length)), 'utf8')
+    assert.deepStrictEqual(await load('entries/e1.js'), ['s1', 's2', 'e1:3', 'dyn:20'])
+  },
+
   async splittingRelativeSameDir({ esbuild, testDir }) {
     const inputA = path.join(testDir, 'a.js')
     const inputB = path.join(testDir, 'b.js')
@@ -5142,6 +5237,32 @@ let transformTests = {
     }
   },
 
+  async transformMany({ esbuild }) {
+    const results = await esbuild.transformMany([
+      { input: `let x: number = 1`, options: { loader: 'ts' } },
+      { input: `a { color: red }`, options: { loader: 'css', minify: true } },
+      { input: `let y = (` },
+      { input: new TextEncoder().encode(`let z = 2`), options: { sourcemap: true, sourcefile: 'z.js' } },
+    ])
+    assert.strictEqual(results.length, 4)
+    assert.strictEqual(results[0].code, `let x = 1;\n`)
+    assert.strictEqual(results[1].code, `a{color:red}\n`)
+    assert(results[2] instanceof Error)
+    assert.strictEqual(results[2].errors[0].text, 'Unexpected end of file')
+    assert.strictEqual(results[3].code, `let z = 2;\n`)
+    assert.deepStrictEqual(JSON.parse(results[3].map).sources, ['z.js'])
+    assert.deepStrictEqual(await esbuild.transformMany([]), [])
+  },
+
+  async transformManyWithBadOptions({ esbuild }) {
+    try {
+      await esbuild.transformMany([{ input: ``, options: { jsxFactory: ['React', 'createElement'] } }])
+      throw new Error('Expected an error to be thrown');
+    } catch (e) {
+      assert.strictEqual(e.message, '"jsxFactory" must be a string')
+    }
+  },
+
   async version({ esbuild }) {
     const version = fs.readFileSync(path.join(repoDir, 'version.txt'), 'utf8').trim()
     assert.strictEqual(esbuild.version, version);
@@ -367,6 +367,10 @@ const testCaseNames = {
     foo = class { /**/mangle_ = 0 }
     foo = class { /**/'mangle_' = 0 }
     foo = /**/'mangle_' in bar
+
+    // Test shorthand properties where only the key is mangled
+    var { /**/mangle_ } = foo
+    foo = { /**/mangle_ }
   `,
   'nested1.js': `
     import { foo } from './nested2'